@@ -2,20 +2,34 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/signal"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
 	"syscall"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/internal/crypto"
 	"github.com/koltyakov/ora2csv/internal/db"
 	"github.com/koltyakov/ora2csv/internal/exporter"
 	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/metrics"
+	"github.com/koltyakov/ora2csv/internal/notify"
+	"github.com/koltyakov/ora2csv/internal/render"
 	"github.com/koltyakov/ora2csv/internal/state"
 	"github.com/koltyakov/ora2csv/internal/storage"
+	"github.com/koltyakov/ora2csv/internal/tracing"
 	"github.com/koltyakov/ora2csv/pkg/types"
 )
 
@@ -24,6 +38,9 @@ var (
 	version = "dev"
 	// BuildTime is set at build time
 	buildTime = "unknown"
+
+	// pagerDutyEndpoint is the PagerDuty Events API URL, replaced in tests
+	pagerDutyEndpoint = notify.DefaultPagerDutyEndpoint
 )
 
 var rootCmd = &cobra.Command{
@@ -51,20 +68,207 @@ var validateCmd = &cobra.Command{
 	SilenceUsage: true, // Don't print usage on error
 }
 
+var encryptSQLCmd = &cobra.Command{
+	Use:          "encrypt-sql <file.sql>",
+	Short:        "Encrypt a SQL file",
+	Long:         "Encrypt a SQL file with the AES-256 key from --sql-encryption-key so it can be stored in shared repositories",
+	Args:         cobra.ExactArgs(1),
+	RunE:         runEncryptSQL,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var importEntitiesCmd = &cobra.Command{
+	Use:          "import-entities",
+	Short:        "Import entities from a CSV or JSON Lines file into the state file",
+	Long:         "Add entities listed in a CSV file (columns: entity, active, last_run_time or days_back, tags) or a .jsonl file with the same fields to the state file; <sql-dir>/<entity>.sql must exist unless --force or --create-sql is set",
+	RunE:         runImportEntities,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var addEntityCmd = &cobra.Command{
+	Use:          "add-entity",
+	Short:        "Add an entity to the state file",
+	Long:         "Append --entity to the state file with an empty lastRunTime, and with daysBack set to --days-back when given; <sql-dir>/<entity>.sql must exist unless --force is set",
+	RunE:         runAddEntity,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var discoverCmd = &cobra.Command{
+	Use:          "discover",
+	Short:        "Add state entries and SQL templates for database tables",
+	Long:         "List the tables of --schema (or of the connected user) matching --pattern and add each one missing from the state file with a SQL template in --sql-dir filtering on --ts-column; existing entities are left unchanged",
+	RunE:         runDiscover,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var generateStateCmd = &cobra.Command{
+	Use:          "generate-state",
+	Short:        "Create the state file from the SQL files in the SQL directory",
+	Long:         "Write a new state file with an entity for every *.sql file in --sql-dir, sorted by name",
+	RunE:         runGenerateState,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var pruneManifestsCmd = &cobra.Command{
+	Use:          "prune-manifests",
+	Short:        "Delete old manifest files from the export directory",
+	Long:         "Delete manifests in <export-dir>/manifests (and S3 when enabled) outside the retention policy",
+	RunE:         runPruneManifests,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var purgeCmd = &cobra.Command{
+	Use:          "purge",
+	Short:        "Delete old export files from the export directory",
+	Long:         "Delete export files in <export-dir> (and S3 when enabled) whose start date in the file name is older than --older-than",
+	RunE:         runPurge,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var testQueryCmd = &cobra.Command{
+	Use:          "test-query",
+	Short:        "Preview the first rows of an entity query",
+	Long:         "Run the SQL of --entity over its next export window with a row limit and print the result as a table (state is not changed)",
+	RunE:         runTestQuery,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var listCmd = &cobra.Command{
+	Use:          "list",
+	Short:        "Show the status of the entities in the state file",
+	Long:         "Print each entity with its active flag, last run time, age since the last run and whether its SQL file exists (read-only)",
+	RunE:         runList,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var diffCmd = &cobra.Command{
+	Use:          "diff",
+	Short:        "Compare two state files",
+	Long:         "Print the entities added, removed or modified from the --from state file to the --to state file, e.g. before deploying a new state (read-only)",
+	RunE:         runDiff,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var statusCmd = &cobra.Command{
+	Use:          "status",
+	Short:        "Show the last run of each entity",
+	Long:         "Print each entity with its last run time, age, row count and result from the last run manifest and whether its SQL file exists (read-only)",
+	RunE:         runStatus,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var verifyCmd = &cobra.Command{
+	Use:          "verify",
+	Short:        "Check exported files against the manifest row counts",
+	Long:         "Count the rows of the latest exported file of each entity and compare them to the row count in the run manifests, and with --recount-from-db to a COUNT(*) of the entity query over the same window",
+	RunE:         runVerify,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var disableEntityCmd = &cobra.Command{
+	Use:          "disable-entity",
+	Short:        "Mark entities inactive in the state file",
+	Long:         "Set active to false for --entity or for all entities whose name matches the --pattern regular expression",
+	RunE:         runDisableEntity,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var enableEntityCmd = &cobra.Command{
+	Use:          "enable-entity",
+	Short:        "Mark an entity active in the state file",
+	Long:         "Set active to true for --entity so it is exported again",
+	RunE:         runEnableEntity,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var backupStateCmd = &cobra.Command{
+	Use:          "backup-state",
+	Short:        "Copy the state file to a backup file",
+	Long:         "Copy the state file, downloaded from cloud storage when configured, to --output or to <state-file>.<timestamp>, e.g. before a backfill",
+	RunE:         runBackupState,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var envHelpCmd = &cobra.Command{
+	Use:          "env-help",
+	Short:        "List the supported environment variables",
+	Long:         "Print the environment variables read by ora2csv with their descriptions, defaults and config fields, as a table or with --output=markdown as a Markdown table",
+	Args:         cobra.NoArgs,
+	RunE:         runEnvHelp,
+	SilenceUsage: true, // Don't print usage on error
+}
+
+var resetCmd = &cobra.Command{
+	Use:          "reset",
+	Short:        "Clear lastRunTime for one or all entities",
+	Long:         "Clear the lastRunTime of --entity or of --all entities so their next export starts from --days-back",
+	RunE:         runReset,
+	SilenceUsage: true, // Don't print usage on error
+}
+
 func init() {
 	// Common flags
+	rootCmd.PersistentFlags().String("config-file", "", "YAML or TOML config file (default ./ora2csv.yaml, ./ora2csv.yml or ./ora2csv.toml if present)")
+	rootCmd.PersistentFlags().String("env-file", "", "Set environment variables from this .env file, variables already set are kept")
 	rootCmd.PersistentFlags().String("db-host", config.DefaultDBHost, "Database host")
 	rootCmd.PersistentFlags().Int("db-port", config.DefaultDBPort, "Database port")
 	rootCmd.PersistentFlags().String("db-service", config.DefaultDBService, "Database service name")
 	rootCmd.PersistentFlags().String("db-user", config.DefaultDBUser, "Database user")
+	rootCmd.PersistentFlags().String("gcp-secret-name", "", "GCP Secret Manager secret holding the database password")
+	rootCmd.PersistentFlags().String("gcp-project-id", "", "GCP project of the secret (default: metadata server project)")
+	rootCmd.PersistentFlags().String("gcp-secret-version", config.DefaultGCPSecretVersion, "GCP secret version to read")
+	rootCmd.PersistentFlags().Int("fetch-size", config.DefaultFetchSize, "Rows prefetched per database round-trip (0 uses the driver default)")
+	rootCmd.PersistentFlags().Bool("lob-fetch-inline", false, "Fetch LOB content with the row instead of a separate round-trip")
+	rootCmd.PersistentFlags().Bool("db-external-auth", false, "Use externally-authenticated connection (OS auth, Kerberos)")
 	rootCmd.PersistentFlags().String("state-file", config.DefaultStateFile, "Path to state.json file")
+	rootCmd.PersistentFlags().StringSlice("state-files", nil, "Comma-separated state files to combine (overrides --state-file)")
 	rootCmd.PersistentFlags().String("sql-dir", config.DefaultSQLDir, "Path to SQL directory")
 	rootCmd.PersistentFlags().String("export-dir", config.DefaultExportDir, "Path to export directory")
+	rootCmd.PersistentFlags().String("temp-dir", "", "Directory for cloud upload temp files with --cloud-temp-file (default: export directory)")
+	rootCmd.PersistentFlags().String("file-mode", "0644", "Permissions for exported files (octal)")
+	rootCmd.PersistentFlags().String("state-file-mode", "0644", "Permissions for the state file (octal)")
+	rootCmd.PersistentFlags().String("dir-mode", "0755", "Permissions for created directories (octal)")
 	rootCmd.PersistentFlags().Int("days-back", config.DefaultDaysBack, "Default days to look back for first run")
-	rootCmd.PersistentFlags().Bool("dry-run", false, "Validate without executing")
+	rootCmd.PersistentFlags().Bool("dry-run", false, "Validate without executing (export also estimates row counts)")
 	rootCmd.PersistentFlags().Bool("verbose", false, "Enable verbose logging")
+	rootCmd.PersistentFlags().String("log-format", config.LogFormatText, "Log line format: text or json")
+	rootCmd.PersistentFlags().String("log-file", "", "Also append export and validate logs to this file")
+	rootCmd.PersistentFlags().Int("log-rotate-size-mb", 0, "Rotate --log-file when it exceeds this many MB (0 disables)")
+	rootCmd.PersistentFlags().Int("log-rotate-count", config.DefaultLogRotateCount, "Rotated log files to keep")
+	rootCmd.PersistentFlags().Int64("max-result-set-rows", 0, "Fail an entity whose query returns more rows than this (0 is unlimited)")
+	rootCmd.PersistentFlags().Int("fetch-rows-hint", 0, "Append a \"-- ROWS=<n>\" fetch size comment to export queries (0 disables)")
+	rootCmd.PersistentFlags().Int("max-file-size-mb", 0, "Fail an entity whose output file exceeds this many MB, with --max-rows-per-file start a new part instead (0 is unlimited)")
+	rootCmd.PersistentFlags().Int("max-memory-mb", 0, "Fail an entity when the heap exceeds this many MB (0 disables)")
 	rootCmd.PersistentFlags().Duration("connect-timeout", config.DefaultConnectTimeoutSecs*time.Second, "Connection timeout")
+	rootCmd.PersistentFlags().Duration("wallet-refresh-interval", 0, "Reconnect at this interval to re-read Oracle Wallet files (0 disables)")
+	rootCmd.PersistentFlags().Int("connect-retry-count", config.DefaultConnectRetryCount, "Connection retries on startup for transient errors (0 disables)")
+	rootCmd.PersistentFlags().Duration("connect-retry-delay", config.DefaultConnectRetryDelay*time.Second, "Delay before the first connection retry, doubled after each retry")
+	rootCmd.PersistentFlags().Int("max-retries", config.DefaultMaxRetries, "Query retries for transient errors such as a lost connection (0 disables)")
+	rootCmd.PersistentFlags().Duration("retry-backoff", config.DefaultRetryBackoff*time.Second, "Delay before the first query retry, doubled after each retry")
 	rootCmd.PersistentFlags().Duration("query-timeout", config.DefaultQueryTimeoutSecs*time.Second, "Query timeout")
+	rootCmd.PersistentFlags().Duration("oracle-session-timeout", 0, "Driver-side call timeout, go-ora interrupts queries running longer on the server, greater than --query-timeout (0 disables)")
+	rootCmd.PersistentFlags().StringArray("oracle-session-param", nil, "Session parameter set with ALTER SESSION on every connection as NAME=value, e.g. NLS_DATE_FORMAT=YYYY-MM-DD (repeatable)")
+	rootCmd.PersistentFlags().String("csv-quote-char", string(config.DefaultCSVQuoteChar), "Character used to quote CSV fields")
+	rootCmd.PersistentFlags().String("delimiter", string(config.DefaultDelimiter), "CSV field delimiter: a single character such as , | ; or tab (\\t)")
+	rootCmd.PersistentFlags().String("filename-template", config.DefaultFilenameTemplate, "Export file name template with {{.Entity}}, {{.StartDate}}, {{.TillDate}} and {{.RunID}}")
+	rootCmd.PersistentFlags().Bool("output-dir-per-entity", false, "Write export files to <export-dir>/<entity>/ subdirectories")
+	rootCmd.PersistentFlags().String("blob-encoding", config.BlobEncodingHex, "BLOB and LONG RAW column encoding: hex, base64 or skip (written as NULL)")
+	rootCmd.PersistentFlags().Int("clob-max-length", 0, "Truncate CLOB values to this many characters (0 disables)")
+	rootCmd.PersistentFlags().String("file-extension", "", "Output file extension (default: csv, tsv for a tab delimiter, jsonl)")
+	rootCmd.PersistentFlags().String("schema-dir", "", "Directory with <entity>.columns.txt files defining the output column order")
+	rootCmd.PersistentFlags().Bool("ignore-column-additions", false, "Drop query columns missing from the column order file instead of failing")
+	rootCmd.PersistentFlags().String("column-map-file", "", "YAML or JSON file renaming columns in the header row, e.g. CREATED_DT: created_at")
+	rootCmd.PersistentFlags().String("sql-encryption-key", "", "Hex-encoded AES-256 key for encrypted SQL files")
+	rootCmd.PersistentFlags().String("record-separator", config.DefaultRecordSeparator, "CSV record separator: lf, crlf, rs or a hex value such as \\x1E")
+	rootCmd.PersistentFlags().String("file-format", config.FileFormatCSV, "Export file format: csv, jsonl (one JSON object per row, NULL as null) or parquet")
+	rootCmd.PersistentFlags().Int("parquet-row-group-mb", config.DefaultParquetRowGroupMB, "Uncompressed data size of a Parquet row group, buffered in memory")
+	rootCmd.PersistentFlags().String("compress", "", "Compress output files: none or gzip (adds a .gz suffix)")
+	rootCmd.PersistentFlags().Bool("enable-result-cache", false, "Add the Oracle RESULT_CACHE hint to export queries")
+	rootCmd.PersistentFlags().StringSlice("result-cache-entities", nil, "Comma-separated entities that use the result cache hint (default: all)")
+	rootCmd.PersistentFlags().Int("parallelism", config.DefaultParallelism, "Number of entities exported at the same time")
+	rootCmd.PersistentFlags().StringSlice("schema-limit", nil, "Maximum concurrent exports per schema as SCHEMA=N (repeatable)")
+	rootCmd.PersistentFlags().Bool("dedup-output", false, "Skip rows already exported in previous runs (bloom filter)")
+	rootCmd.PersistentFlags().Int("bloom-filter-size", config.DefaultBloomFilterSize, "Expected number of rows tracked by the dedup bloom filter")
 
 	// S3 flags
 	rootCmd.PersistentFlags().String("s3-bucket", "", "S3 bucket name")
@@ -73,51 +277,233 @@ func init() {
 	rootCmd.PersistentFlags().String("s3-secret-key", "", "S3 secret key (for S3-compatible services)")
 	rootCmd.PersistentFlags().String("s3-session-token", "", "S3 session token (for S3-compatible services)")
 	rootCmd.PersistentFlags().String("s3-endpoint", "", "S3 endpoint URL (for S3-compatible services like MinIO)")
+	rootCmd.PersistentFlags().Bool("s3-track-versions", false, "Record state file S3 versions to restore from on corruption")
+	rootCmd.PersistentFlags().String("s3-sse", "", "S3 server-side encryption: AES256 or aws:kms (default: bucket setting)")
+	rootCmd.PersistentFlags().String("sftp-host", "", "SFTP server (enables SFTP storage instead of S3, password from ORA2CSV_SFTP_PASSWORD)")
+	rootCmd.PersistentFlags().Int("sftp-port", config.DefaultSFTPPort, "SFTP server port")
+	rootCmd.PersistentFlags().String("sftp-user", "", "SFTP user")
+	rootCmd.PersistentFlags().String("sftp-key", "", "SFTP private key file (preferred over the password)")
+	rootCmd.PersistentFlags().String("sftp-path", "", "SFTP directory for exports and the state file (default: login directory)")
+	rootCmd.PersistentFlags().String("sftp-known-hosts", "", "known_hosts file to verify the SFTP server (default: ~/.ssh/known_hosts)")
+	rootCmd.PersistentFlags().String("s3-storage-class", "", "S3 storage class of exports and the state file, e.g. STANDARD_IA (default: bucket setting)")
+	rootCmd.PersistentFlags().String("s3-sse-kms-key-id", "", "KMS key ID for S3 aws:kms encryption (implies --s3-sse aws:kms)")
+
+	// Azure flags (connection string from AZURE_STORAGE_CONNECTION_STRING)
+	rootCmd.PersistentFlags().String("azure-container", "", "Azure Blob Storage container (enables Azure storage instead of S3)")
+	rootCmd.PersistentFlags().String("azure-prefix", "", "Azure blob name prefix")
+	rootCmd.PersistentFlags().String("gcs-bucket", "", "Google Cloud Storage bucket (enables GCS storage instead of S3)")
+	rootCmd.PersistentFlags().String("gcs-prefix", "", "GCS object name prefix")
 
 	// Validate-specific flags
-	validateCmd.Flags().Bool("test-connection", false, "Test database connection")
+	validateCmd.Flags().Bool("test-connection", false, "Test database connection (and the SFTP server when configured)")
+
+	// Import-entities flags
+	importEntitiesCmd.Flags().String("file", "", "CSV file with entity, active, last_run_time or days_back and tags columns, or JSON Lines (.jsonl, .ndjson, .json)")
+	importEntitiesCmd.Flags().String("csv", "", "CSV file to import")
+	_ = importEntitiesCmd.Flags().MarkDeprecated("csv", "use --file instead")
+	importEntitiesCmd.Flags().Bool("skip-existing", false, "Skip entities that already exist (the default)")
+	importEntitiesCmd.Flags().Bool("overwrite", false, "Replace entities that already exist")
+	importEntitiesCmd.Flags().Bool("fail-on-duplicate", false, "Fail if an entity already exists instead of skipping it")
+	importEntitiesCmd.Flags().Bool("force", false, "Import entities without a SQL file")
+	importEntitiesCmd.Flags().Bool("create-sql", false, "Create a SQL template file for each added entity")
+
+	// Generate-state flags
+	// Add-entity flags
+	addEntityCmd.Flags().String("entity", "", "Entity name, matching <sql-dir>/<entity>.sql")
+	addEntityCmd.Flags().Bool("active", true, "Mark the entity active")
+	addEntityCmd.Flags().Bool("force", false, "Add the entity even if its SQL file is missing")
+	addEntityCmd.Flags().StringSlice("tags", nil, "Entity tags, lowercase identifiers such as daily or billing")
+	_ = addEntityCmd.MarkFlagRequired("entity")
+
+	// Discover flags
+	discoverCmd.Flags().String("schema", "", "Schema whose tables are listed from ALL_TABLES (default: the connected user's tables)")
+	discoverCmd.Flags().String("pattern", "", "Regular expression table names must match, case-insensitive, e.g. '.*_LOG'")
+	discoverCmd.Flags().String("ts-column", exporter.DefaultTimestampColumn, "Timestamp column the SQL templates filter the export window on")
+	discoverCmd.Flags().Bool("active", true, "Mark discovered entities active")
+
+	generateStateCmd.Flags().String("active-pattern", "", "Glob of entity names to mark active, others are inactive (default: all active)")
+	generateStateCmd.Flags().Bool("overwrite", false, "Replace an existing state file")
+	generateStateCmd.Flags().Int("initial-days-back", 0, "Set lastRunTime this many days ago (0 leaves it empty)")
+
+	// Prune-manifests flags
+	pruneManifestsCmd.Flags().Int("keep-last-n", 30, "Keep the newest N manifests (0 disables)")
+	pruneManifestsCmd.Flags().Duration("older-than", 0, "Only delete manifests older than this (0 disables)")
+
+	// Purge flags
+	purgeCmd.Flags().String("older-than", "", "Delete export files starting before this age, e.g. 30d or 36h")
+	_ = purgeCmd.MarkFlagRequired("older-than")
+
+	// Test-query flags
+	testQueryCmd.Flags().String("entity", "", "Entity to query")
+	testQueryCmd.Flags().Int("limit", 5, "Maximum rows to fetch")
+	testQueryCmd.Flags().String("output", config.OutputFormatText, "Output format: text or json (JSON Lines)")
+	_ = testQueryCmd.MarkFlagRequired("entity")
+
+	// Verify flags
+	verifyCmd.Flags().Bool("recount-from-db", false, "Also compare with a COUNT(*) of the entity query over the exported window")
+
+	// Disable-entity and enable-entity flags
+	disableEntityCmd.Flags().String("entity", "", "Entity to disable")
+	disableEntityCmd.Flags().String("pattern", "", "Disable all entities whose name matches this regular expression")
+	disableEntityCmd.Flags().Bool("auto-backup", true, "Copy the state file to <state-file>.<timestamp> before changing it")
+	enableEntityCmd.Flags().String("entity", "", "Entity to enable")
+	_ = enableEntityCmd.MarkFlagRequired("entity")
+
+	// Reset flags
+	resetCmd.Flags().String("entity", "", "Entity to reset")
+	resetCmd.Flags().Bool("all", false, "Reset all entities")
+	resetCmd.Flags().Bool("auto-backup", true, "Copy the state file to <state-file>.<timestamp> before changing it")
+
+	// Backup-state flags
+	backupStateCmd.Flags().String("output", "", "Backup file (default: <state-file>.<timestamp>)")
+
+	// Env-help flags
+	envHelpCmd.Flags().String("output", config.OutputFormatText, "Output format: text or markdown")
+
+	// List flags
+	listCmd.Flags().String("output", config.OutputFormatText, "Output format: text or json")
+
+	// Diff flags
+	diffCmd.Flags().String("from", "", "Old state file")
+	diffCmd.Flags().String("to", "", "New state file")
+	diffCmd.Flags().String("output", config.OutputFormatText, "Output format: text or json")
+	_ = diffCmd.MarkFlagRequired("from")
+	_ = diffCmd.MarkFlagRequired("to")
+
+	// Status flags
+	statusCmd.Flags().String("entity", "", "Show only this entity")
+	statusCmd.Flags().String("output", config.OutputFormatText, "Output format: text, json or csv (csv lists the state file only)")
+
+	// Encrypt-sql flags
+	encryptSQLCmd.Flags().StringP("output", "o", "", "Output file (default: stdout)")
+
+	// Export flags
+	exportCmd.Flags().Bool("auto-discover", false, "Export SQL files in the SQL directory that have no state entry yet")
+	exportCmd.Flags().String("auto-discover-prefix", "", "Only auto-discover SQL files starting with this prefix")
+	exportCmd.Flags().Bool("include-inactive", false, "Also export inactive entities for this run, leaving their state unchanged")
+	exportCmd.Flags().String("entity", "", "Export only this entity (must be active unless --include-inactive is set)")
+	exportCmd.Flags().StringSlice("include-tags", nil, "Export only entities with one of these tags, e.g. billing,daily")
+	exportCmd.Flags().StringSlice("exclude-tags", nil, "Skip entities with any of these tags, e.g. experimental")
+	exportCmd.Flags().Bool("progress", false, "Show live row counts on stderr while exporting (terminals only)")
+	exportCmd.Flags().Int("progress-interval", config.DefaultProgressInterval, "Log the row count of an export every N rows, with an estimate from the previous export (0 disables)")
+	exportCmd.Flags().Duration("ping-interval", config.DefaultPingInterval*time.Second, "Ping the database at this interval while exporting and reconnect when the connection is lost (0 disables)")
+	exportCmd.Flags().Bool("write-checksum", false, "Write a .sha256 file in sha256sum format next to each export file")
+	exportCmd.Flags().Bool("write-meta", false, "Write a .meta.json file with row count, columns and SHA-256 next to each export file")
+	exportCmd.Flags().Int64("max-rows-global", 0, "Fail entities once the run has exported this many rows in total (0 is unlimited)")
+	exportCmd.Flags().Int64("max-rows-per-file", 0, "Split export files into <name>__part001, __part002, ... of at most this many rows (0 disables)")
+	exportCmd.Flags().Bool("skip-if-overlapping", false, "Skip entities with an existing export file starting inside the current window")
+	exportCmd.Flags().String("pagerduty-routing-key", "", "PagerDuty Events API v2 routing key for failure incidents")
+	exportCmd.Flags().Int("pagerduty-threshold", config.DefaultPagerDutyThreshold, "Failed entities that trigger a PagerDuty incident")
+	exportCmd.Flags().String("webhook-url", "", "URL to POST the JSON run result to when the export finishes")
+	exportCmd.Flags().Bool("webhook-on-failure", false, "Only call the webhook when the run or an entity fails")
+	exportCmd.Flags().Duration("webhook-timeout", config.DefaultWebhookTimeout*time.Second, "Webhook request timeout")
+	exportCmd.Flags().Bool("cloud-temp-file", false, "Write cloud exports to a temp file before uploading instead of streaming them")
+	exportCmd.Flags().String("also-local-path", "", "Also write each cloud export to this directory, e.g. to verify files before archiving")
+	exportCmd.Flags().String("since", "", "Export from this time instead of lastRunTime, e.g. 2024-01-01T00:00:00 (state is not updated)")
+	exportCmd.Flags().String("until", "", "Export up to this time instead of now, e.g. 2024-12-31T23:59:59 (state is not updated)")
+	exportCmd.Flags().String("metrics-addr", "", "Serve Prometheus metrics at /metrics on this address while exporting, e.g. :9090")
+	exportCmd.Flags().String("otel-endpoint", "", "Send OpenTelemetry traces to this OTLP collector, e.g. localhost:4317")
+	exportCmd.Flags().String("otel-protocol", config.DefaultOTelProtocol, "OTLP protocol: grpc or http")
+	exportCmd.Flags().String("header-case", config.DefaultHeaderCase, "Header row case: upper, lower, title, snake, asis or original")
+	exportCmd.Flags().StringSlice("param", nil, "Bind variable and SQL template value for every entity as key=value (repeatable)")
+	exportCmd.Flags().String("output-format", config.OutputFormatText, "Run summary format: text or json (json is printed to stdout, logs go to stderr)")
+	exportCmd.Flags().String("result-file", "", "Write the complete export result as JSON to this file after the run, - for stdout (logs go to stderr)")
 }
 
 func main() {
 	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(validateCmd)
+	rootCmd.AddCommand(encryptSQLCmd)
+	rootCmd.AddCommand(importEntitiesCmd)
+	rootCmd.AddCommand(addEntityCmd)
+	rootCmd.AddCommand(discoverCmd)
+	rootCmd.AddCommand(generateStateCmd)
+	rootCmd.AddCommand(pruneManifestsCmd)
+	rootCmd.AddCommand(purgeCmd)
+	rootCmd.AddCommand(testQueryCmd)
+	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(verifyCmd)
+	rootCmd.AddCommand(resetCmd)
+	rootCmd.AddCommand(backupStateCmd)
+	rootCmd.AddCommand(envHelpCmd)
+	rootCmd.AddCommand(disableEntityCmd)
+	rootCmd.AddCommand(enableEntityCmd)
 
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
-// setupContext creates a context with cancellation and signal handling
-func setupContext() (context.Context, context.CancelFunc) {
-	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+// exitError ends the process with a specific exit code once the command returns
+type exitError struct {
+	code int
+	err  error
 }
 
-// connectDatabase establishes a connection to the Oracle database
-func connectDatabase(ctx context.Context, cfg *config.Config) (*db.OracleDB, error) {
-	connCtx, connCancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
-	defer connCancel()
+func (e *exitError) Error() string { return e.err.Error() }
 
-	database, err := db.ConnectString(
-		connCtx,
-		cfg.ConnectionString(),
-		"", // user and password are already in connection string
-		"",
-		cfg.ConnectTimeout,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to connect to database: %w", err)
+func (e *exitError) Unwrap() error { return e.err }
+
+// exitCode returns the process exit code for a command error, 1 unless it is an exitError
+func exitCode(err error) int {
+	var exitErr *exitError
+	if errors.As(err, &exitErr) {
+		return exitErr.code
 	}
+	return 1
+}
 
-	return database, nil
+// setupContext creates a context with cancellation and signal handling
+func setupContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 }
 
 // executeExport runs the export process
-func executeExport(ctx context.Context, cfg *config.Config, database *db.OracleDB, st *state.File, logger *logging.Logger, s3Client *storage.S3Client) (*types.ExportResult, error) {
+func executeExport(ctx context.Context, cfg *config.Config, database db.DB, st state.Store, logger *logging.Logger, store storage.BlobStore, reg *metrics.Registry) (*types.ExportResult, error) {
 	// Create and run exporter
-	exp := exporter.New(cfg, database, st, logger, s3Client)
+	exp := exporter.New(cfg, database, st, logger, store)
+	exp.SetVersion(version)
+	if reg != nil {
+		exp.SetMetrics(reg)
+	}
 	return exp.Run(ctx)
 }
 
+// runEstimate writes the dry run manifest with COUNT(*) row estimates
+// Without a database connection only the configuration is validated.
+func runEstimate(ctx context.Context, cfg *config.Config, st state.Store, logger *logging.Logger) error {
+	if err := cfg.EnsureDirs(); err != nil {
+		logger.Error("Failed to create directories: %v", err)
+		return err
+	}
+
+	// Estimates are best effort, a dry run does not wait for connection retries
+	connCfg := *cfg
+	connCfg.ConnectRetryCount = 0
+	database, err := db.Connect(ctx, &connCfg, logger)
+	if err != nil {
+		logger.Info("Warning: skipping row count estimates, database unavailable: %v", err)
+		return nil
+	}
+	defer func() {
+		if closeErr := database.Close(); closeErr != nil {
+			logger.Error("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	exp := exporter.New(cfg, database, st, logger, nil)
+	exp.SetVersion(version)
+	result, err := exp.Estimate(ctx)
+	if err != nil {
+		logger.Error("Row count estimate failed: %v", err)
+		return err
+	}
+	logger.Info("Estimated %d entities (%d failed)", result.ProcessedCount, result.FailedCount)
+	return nil
+}
+
 // printSummary prints the export result summary
 func printSummary(result *types.ExportResult, cfg *config.Config, logger *logging.Logger) {
 	duration := result.Duration
@@ -132,7 +518,7 @@ func printSummary(result *types.ExportResult, cfg *config.Config, logger *loggin
 	if result.FailedCount > 0 {
 		logger.Error("Failed entities: %d", result.FailedCount)
 	}
-	logger.Info("Skipped (inactive): %d", result.TotalEntities-result.ProcessedCount)
+	logger.Info("Skipped (inactive or not scheduled): %d", result.SkippedCount)
 	logger.Info("==================================================")
 
 	// Print per-entity results if verbose
@@ -141,13 +527,71 @@ func printSummary(result *types.ExportResult, cfg *config.Config, logger *loggin
 			if r.Success {
 				logger.Info("  ✓ %s: %d rows (%v)", r.Entity, r.RowCount, r.Duration)
 			} else {
-				logger.Error("  ✗ %s: %v", r.Entity, r.Error)
+				var entityErr *exporter.EntityError
+				if errors.As(r.Error, &entityErr) {
+					logger.Error("  ✗ %s [%s] %s: %v", r.Entity, entityErr.Phase, entityErr.SQLFile, entityErr.Err)
+				} else {
+					logger.Error("  ✗ %s: %v", r.Entity, r.Error)
+				}
 			}
 		}
 	}
 }
 
-func runExport(cmd *cobra.Command, args []string) error {
+// writeJSONSummary prints the machine-readable run summary
+func writeJSONSummary(w io.Writer, result *types.ExportResult, runErr error, duration time.Duration) error {
+	summary := types.NewMachineReadableSummary(result, runErr, duration.Milliseconds())
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to marshal summary: %w", err)
+	}
+	_, err = fmt.Fprintln(w, string(data))
+	return err
+}
+
+// newLogger creates a logger writing to console and, with --log-file, appending to the log file
+func newLogger(cfg *config.Config, console io.Writer) (*logging.Logger, error) {
+	logger := logging.NewWithWriter(console, cfg.Verbose)
+	if cfg.LogFile != "" {
+		if cfg.LogRotateSizeMB < 0 || cfg.LogRotateCount < 0 {
+			return nil, fmt.Errorf("log rotation settings cannot be negative")
+		}
+		maxBytes := int64(cfg.LogRotateSizeMB) * 1024 * 1024
+		fileLogger, err := logging.NewWithRotatingFile(console, cfg.LogFile, cfg.Verbose, maxBytes, cfg.LogRotateCount)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create logger: %w", err)
+		}
+		logger = fileLogger
+	}
+	logger.SetFormat(cfg.LogFormat)
+	return logger, nil
+}
+
+// closeStore releases the storage connection, e.g. the SFTP session, failures are only reported
+func closeStore(store interface{}) {
+	if err := storage.Close(store); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to close storage connection: %v\n", err)
+	}
+}
+
+// closeState releases the state file locks, failures are only reported
+func closeState(st state.Store) {
+	if err := st.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to release state file lock: %v\n", err)
+	}
+}
+
+// stateFilesDescription returns the state file path(s) for logging
+func stateFilesDescription(cfg *config.Config) string {
+	if len(cfg.StateFiles) > 0 {
+		return strings.Join(cfg.StateFiles, ", ")
+	}
+	return cfg.StateFile
+}
+
+func runExport(cmd *cobra.Command, args []string) (retErr error) {
+	startTime := time.Now()
+
 	// Load configuration
 	cfg, err := config.FromCommand(cmd)
 	if err != nil {
@@ -158,8 +602,32 @@ func runExport(cmd *cobra.Command, args []string) error {
 	ctx, cancel := setupContext()
 	defer cancel()
 
+	// With JSON output stdout carries only the summary, logs go to stderr
+	jsonOutput := cfg.OutputFormat == config.OutputFormatJSON
+	var result *types.ExportResult
+	summaryWritten := false
+	emitSummary := func(runErr error) {
+		if !jsonOutput || summaryWritten {
+			return
+		}
+		summaryWritten = true
+		if err := writeJSONSummary(cmd.OutOrStdout(), result, runErr, time.Since(startTime)); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to write summary: %v\n", err)
+		}
+	}
+	defer func() {
+		emitSummary(retErr)
+	}()
+
 	// Create logger
-	logger := logging.New(cfg.Verbose)
+	var console io.Writer = os.Stdout
+	if jsonOutput || cfg.ResultFile == "-" {
+		console = os.Stderr
+	}
+	logger, err := newLogger(cfg, console)
+	if err != nil {
+		return err
+	}
 	defer func() {
 		if closeErr := logger.Close(); closeErr != nil {
 			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
@@ -174,63 +642,75 @@ func runExport(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	// Initialize S3 client if enabled
-	var s3Client *storage.S3Client
-	var s3StateKey string
-	if cfg.S3.Bucket != "" {
-		logger.Info("S3 destination enabled (bucket: %s)", cfg.S3.Bucket)
-		client, err := storage.NewS3Client(&cfg.S3)
-		if err != nil {
-			logger.Error("Failed to initialize S3 client: %v", err)
-			return fmt.Errorf("failed to initialize S3 client: %w", err)
+	// Aborted runs, e.g. a lost connection or a failed state upload, trigger an incident too
+	defer func() {
+		runErr := retErr
+		var exitErr *exitError
+		if errors.As(runErr, &exitErr) {
+			runErr = nil
 		}
-		s3Client = client
-		s3StateKey = cfg.S3.StateKey()
-		logger.Info("S3 client initialized")
+		sendPagerDuty(ctx, cfg, result, runErr, logger)
+	}()
+
+	// Initialize the cloud storage client if enabled
+	store, remoteState, err := storage.FromConfig(cfg)
+	if err != nil {
+		logger.Error("%v", err)
+		return err
+	}
+	defer closeStore(store)
+	if store != nil {
+		logger.Info("%s destination enabled", store.Name())
 
-		// Check S3 connectivity before starting export
-		logger.Info("Checking S3 connectivity...")
+		// Check connectivity before starting export
+		logger.Info("Checking %s connectivity...", store.Name())
 		checkCtx, checkCancel := context.WithTimeout(ctx, 10*time.Second)
 		defer checkCancel()
-		if err := s3Client.CheckConnection(checkCtx); err != nil {
-			logger.Error("S3 connectivity check failed: %v", err)
-			return fmt.Errorf("S3 connectivity check failed: %w", err)
+		if err := store.CheckConnection(checkCtx); err != nil {
+			logger.Error("%s connectivity check failed: %v", store.Name(), err)
+			return fmt.Errorf("%s connectivity check failed: %w", store.Name(), err)
 		}
-		logger.Info("S3 connectivity verified")
+		logger.Info("%s connectivity verified", store.Name())
 	}
 
-	// Load state file (with S3 sync if enabled)
-	st, err := state.Load(cfg.StateFile, s3Client, s3StateKey)
+	// Load state file (with S3 or GCS sync if enabled)
+	st, err := state.LoadFromConfig(cfg, remoteState)
 	if err != nil {
 		logger.Error("Failed to load state file: %v", err)
 		return fmt.Errorf("failed to load state file: %w", err)
 	}
+	defer closeState(st)
 
 	logger.Info("Loaded state file: %s (%d entities, %d active)",
-		cfg.StateFile, st.TotalCount(), st.ActiveCount())
+		stateFilesDescription(cfg), st.TotalCount(), st.ActiveCount())
 
 	// Dry run mode
 	if cfg.DryRun {
-		logger.Info("Dry run mode - validating configuration only")
+		logger.Info("Dry run mode - validating configuration and estimating row counts")
 		if err := exporter.Validate(cfg, st, false); err != nil {
 			logger.Error("Validation failed: %v", err)
 			return err
 		}
 		logger.Info("Validation successful")
-		return nil
+		return runEstimate(ctx, cfg, st, logger)
 	}
 
 	// Ensure export directory exists
-	if err := cfg.EnsureDirs(); err != nil {
+	if err := cfg.EnsureDirs(state.ActiveEntityNames(st)...); err != nil {
 		logger.Error("Failed to create directories: %v", err)
 		return err
 	}
 
 	// Connect to database
-	logger.Info("Connecting to database: %s@%s:%d/%s",
-		cfg.DBUser, cfg.DBHost, cfg.DBPort, cfg.DBService)
+	if cfg.DBExternalAuth {
+		logger.Info("Connecting to database (external auth): %s:%d/%s",
+			cfg.DBHost, cfg.DBPort, cfg.DBService)
+	} else {
+		logger.Info("Connecting to database: %s@%s:%d/%s",
+			cfg.DBUser, cfg.DBHost, cfg.DBPort, cfg.DBService)
+	}
 
-	database, err := connectDatabase(ctx, cfg)
+	database, err := db.Connect(ctx, cfg, logger)
 	if err != nil {
 		logger.Error("Failed to connect to database: %v", err)
 		return err
@@ -243,8 +723,64 @@ func runExport(cmd *cobra.Command, args []string) error {
 
 	logger.Info("Database connection established")
 
+	// Reconnect when a ping fails during the export, stop the export if that fails too
+	if cfg.PingInterval > 0 {
+		var connectionLost context.CancelCauseFunc
+		ctx, connectionLost = context.WithCancelCause(ctx)
+		defer connectionLost(nil)
+		database = db.StartConnectivityMonitor(ctx, database, cfg.PingInterval, cfg.MaxRetries, cfg.RetryBackoff,
+			db.Reconnector(cfg, logger), logger, func(err error) {
+				logger.Error("Stopping export: %v", err)
+				connectionLost(err)
+			})
+	}
+
+	// Send traces until the export returns, pending spans are flushed on exit
+	if cfg.OTelEndpoint != "" {
+		shutdown, err := tracing.Setup(ctx, cfg.OTelEndpoint, cfg.OTelProtocol, version)
+		if err != nil {
+			logger.Error("Failed to set up tracing: %v", err)
+			return err
+		}
+		defer func() {
+			shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+			defer cancel()
+			if err := shutdown(shutdownCtx); err != nil {
+				logger.Error("Failed to flush traces: %v", err)
+			}
+		}()
+		logger.Info("Sending traces to %s (%s)", cfg.OTelEndpoint, cfg.OTelProtocol)
+	}
+
 	// Execute export
-	result, err := executeExport(ctx, cfg, database, st, logger, s3Client)
+	// Serve metrics until the export returns
+	var reg *metrics.Registry
+	if cfg.MetricsAddr != "" {
+		metricsCtx, stopMetrics := context.WithCancel(ctx)
+		defer stopMetrics()
+		reg = metrics.NewRegistry()
+		server, err := metrics.Serve(metricsCtx, cfg.MetricsAddr, reg)
+		if err != nil {
+			logger.Error("Failed to start metrics server: %v", err)
+			return err
+		}
+		logger.Info("Serving metrics at http://%s/metrics", server.Addr())
+	}
+
+	result, err = executeExport(ctx, cfg, database, st, logger, store, reg)
+	if cause := context.Cause(ctx); err != nil && errors.Is(cause, db.ErrConnectionLost) {
+		err = fmt.Errorf("%w: %w", err, cause)
+	}
+	sendWebhook(ctx, cfg, result, err, logger)
+	// The result file is written for failed entities too, before the exit code is set
+	if cfg.ResultFile != "" && result != nil {
+		if writeErr := result.WriteJSON(cfg.ResultFile); writeErr != nil {
+			logger.Error("Failed to write result file: %v", writeErr)
+			if err == nil {
+				return writeErr
+			}
+		}
+	}
 	if err != nil {
 		logger.Error("Export failed: %v", err)
 		return err
@@ -253,22 +789,58 @@ func runExport(cmd *cobra.Command, args []string) error {
 	// Print summary
 	printSummary(result, cfg, logger)
 
-	// Exit with appropriate code
+	// Exit with appropriate code, deferred cleanup still runs
 	if result.FailedCount > 0 {
 		logger.Info("Export completed with %d failures", result.FailedCount)
-		os.Exit(2)
+		emitSummary(nil)
+		// The failures are already logged, cobra doesn't need to print the error
+		cmd.SilenceErrors = true
+		return &exitError{code: 2, err: fmt.Errorf("export completed with %d failures", result.FailedCount)}
 	}
 
 	return nil
 }
 
+// sendWebhook posts the run result to the configured webhook; failures are only logged
+func sendWebhook(ctx context.Context, cfg *config.Config, result *types.ExportResult, runErr error, logger *logging.Logger) {
+	if cfg.WebhookURL == "" {
+		return
+	}
+	webhook := notify.NewWebhook(cfg.WebhookURL, cfg.WebhookOnFailure, cfg.WebhookTimeout)
+	// Interrupted runs are reported too, the client timeout bounds the call
+	if sent, err := webhook.Notify(context.WithoutCancel(ctx), result, runErr); err != nil {
+		logger.Error("Webhook notification failed: %v", err)
+	} else if sent {
+		logger.Info("Webhook notification sent")
+	}
+}
+
+// sendPagerDuty triggers or resolves the PagerDuty incident; failures are only logged
+// Dry runs and runs that stop before exporting without an error send nothing.
+func sendPagerDuty(ctx context.Context, cfg *config.Config, result *types.ExportResult, runErr error, logger *logging.Logger) {
+	if cfg.PagerDutyRoutingKey == "" || cfg.DryRun || (result == nil && runErr == nil) {
+		return
+	}
+	pd := notify.NewPagerDuty(cfg.PagerDutyRoutingKey, cfg.PagerDutyThreshold, cfg.ExportDir)
+	pd.Endpoint = pagerDutyEndpoint
+	// Interrupted runs are reported too, the client timeout bounds the call
+	if action, err := pd.Notify(context.WithoutCancel(ctx), result, runErr); err != nil {
+		logger.Error("PagerDuty notification failed: %v", err)
+	} else if action != "" {
+		logger.Info("PagerDuty %s event sent", action)
+	}
+}
+
 func runValidate(cmd *cobra.Command, args []string) error {
 	cfg, err := config.FromCommand(cmd)
 	if err != nil {
 		return fmt.Errorf("failed to load configuration: %w", err)
 	}
 
-	logger := logging.New(cfg.Verbose)
+	logger, err := newLogger(cfg, os.Stdout)
+	if err != nil {
+		return err
+	}
 	defer func() {
 		if closeErr := logger.Close(); closeErr != nil {
 			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
@@ -284,11 +856,12 @@ func runValidate(cmd *cobra.Command, args []string) error {
 	}
 
 	// Load state file (no S3 for validation)
-	st, err := state.Load(cfg.StateFile, nil, "")
+	st, err := state.LoadFromConfig(cfg, nil)
 	if err != nil {
 		logger.Error("Failed to load state file: %v", err)
 		return fmt.Errorf("failed to load state file: %w", err)
 	}
+	defer closeState(st)
 
 	// Get test connection flag
 	testConn, _ := cmd.Flags().GetBool("test-connection")
@@ -307,5 +880,1332 @@ func runValidate(cmd *cobra.Command, args []string) error {
 		logger.Info("Database connection: OK")
 	}
 
+	if testConn && cfg.SFTP.Host != "" {
+		if err := checkSFTP(cfg); err != nil {
+			logger.Error("%v", err)
+			return err
+		}
+		logger.Info("SFTP connection: OK")
+	}
+
+	return nil
+}
+
+// checkSFTP logs in to the SFTP server and verifies the remote directory is writable
+func checkSFTP(cfg *config.Config) error {
+	client, err := storage.NewSFTPClient(&cfg.SFTP)
+	if err != nil {
+		return fmt.Errorf("failed to initialize SFTP client: %w", err)
+	}
+	defer func() { _ = client.Close() }()
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+	defer cancel()
+	return client.CheckConnection(ctx)
+}
+
+// runEncryptSQL encrypts a SQL file with the configured key
+func runEncryptSQL(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	if cfg.SQLEncryptionKey == "" {
+		return fmt.Errorf("sql_encryption_key is required (set via --sql-encryption-key or ORA2CSV_SQL_ENCRYPTION_KEY)")
+	}
+	key, err := crypto.ParseKey(cfg.SQLEncryptionKey)
+	if err != nil {
+		return fmt.Errorf("invalid sql_encryption_key: %w", err)
+	}
+
+	content, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read SQL file: %w", err)
+	}
+	if crypto.IsEncrypted(string(content)) {
+		return fmt.Errorf("SQL file is already encrypted: %s", args[0])
+	}
+
+	encrypted, err := crypto.EncryptSQL(string(content), key)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt SQL file: %w", err)
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output == "" {
+		_, err = fmt.Fprint(os.Stdout, encrypted)
+		return err
+	}
+	mode := cfg.FileMode
+	if mode == 0 {
+		mode = config.DefaultFileMode
+	}
+	if err := os.WriteFile(output, []byte(encrypted), mode); err != nil {
+		return fmt.Errorf("failed to write encrypted SQL file: %w", err)
+	}
+	return nil
+}
+
+// sqlTemplate is written for imported entities with --create-sql
+const sqlTemplate = `-- TODO: replace with the export query for %s
+SELECT *
+FROM %s
+WHERE updated >= TO_DATE(:startDate, 'YYYY-MM-DD"T"HH24:MI:SS')
+  AND updated < TO_DATE(:tillDate, 'YYYY-MM-DD"T"HH24:MI:SS')
+ORDER BY updated ASC
+`
+
+// runImportEntities adds entities from a CSV file to the state
+func runImportEntities(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	path, _ := cmd.Flags().GetString("file")
+	if path == "" {
+		path, _ = cmd.Flags().GetString("csv")
+	}
+	if path == "" {
+		return fmt.Errorf("--file is required")
+	}
+	skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+	failOnDuplicate, _ := cmd.Flags().GetBool("fail-on-duplicate")
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+	if (skipExisting && overwrite) || (skipExisting && failOnDuplicate) || (overwrite && failOnDuplicate) {
+		return fmt.Errorf("only one of --skip-existing, --overwrite and --fail-on-duplicate can be set")
+	}
+	force, _ := cmd.Flags().GetBool("force")
+	createSQL, _ := cmd.Flags().GetBool("create-sql")
+	opts := state.ImportOptions{FailOnDuplicate: failOnDuplicate, Overwrite: overwrite}
+	// Templates are created after the import, so their SQL files don't exist yet
+	if !force && !createSQL {
+		opts.SQLDir = cfg.SQLDir
+	}
+
+	// Keep the remote copy of the state in sync, otherwise the next export would overwrite the import
+	_, remoteState, err := storage.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore(remoteState)
+
+	st, err := state.LoadFromConfig(cfg, remoteState)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open import file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	importEntities := state.ImportEntitiesCSV
+	if state.IsJSONLinesFile(path) {
+		importEntities = state.ImportEntitiesJSONL
+	}
+	result, importErr := importEntities(st, file, opts)
+	if result != nil {
+		for _, name := range result.Replaced {
+			logger.Info("Replaced existing entity: %s", name)
+		}
+		for _, name := range result.Skipped {
+			logger.Info("Warning: skipping existing entity: %s", name)
+		}
+		for _, err := range result.Failed {
+			logger.Error("Failed to import entity: %v", err)
+		}
+		if createSQL {
+			for _, name := range result.Added {
+				created, err := createSQLTemplate(cfg, st.GetSQLPath(cfg.SQLDir, name), name)
+				if err != nil {
+					return fmt.Errorf("failed to create SQL template for %s: %w", name, err)
+				}
+				if created {
+					logger.Info("Created SQL template: %s", st.GetSQLPath(cfg.SQLDir, name))
+				}
+			}
+		}
+		logger.Info("Import finished: %d added, %d replaced, %d skipped, %d failed",
+			len(result.Added), len(result.Replaced), len(result.Skipped), len(result.Failed))
+	}
+	if importErr != nil {
+		return fmt.Errorf("failed to import entities: %w", importErr)
+	}
+	if len(result.Failed) > 0 {
+		return fmt.Errorf("%d entities failed to import", len(result.Failed))
+	}
 	return nil
 }
+
+// runReset clears entity timestamps in the state file
+func runDisableEntity(cmd *cobra.Command, args []string) error {
+	return setEntitiesActive(cmd, false)
+}
+
+func runEnableEntity(cmd *cobra.Command, args []string) error {
+	return setEntitiesActive(cmd, true)
+}
+
+// setEntitiesActive sets the active flag of --entity, or of the --pattern matches when disabling
+func setEntitiesActive(cmd *cobra.Command, active bool) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	entity, _ := cmd.Flags().GetString("entity")
+	pattern := ""
+	if !active {
+		pattern, _ = cmd.Flags().GetString("pattern")
+		if (entity == "") == (pattern == "") {
+			return fmt.Errorf("exactly one of --entity or --pattern is required")
+		}
+	}
+	var re *regexp.Regexp
+	if pattern != "" {
+		if re, err = regexp.Compile(pattern); err != nil {
+			return fmt.Errorf("invalid --pattern: %w", err)
+		}
+	}
+
+	// Keep the remote copy of the state in sync, otherwise the next export would restore the flags
+	_, remoteState, err := storage.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore(remoteState)
+	st, err := state.LoadFromConfig(cfg, remoteState)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+	if autoBackup, _ := cmd.Flags().GetBool("auto-backup"); !active && autoBackup {
+		st.EnableBackup()
+	}
+
+	var names []string
+	if re != nil {
+		for _, e := range st.GetEntities() {
+			if re.MatchString(e.Entity) {
+				names = append(names, e.Entity)
+			}
+		}
+		if len(names) == 0 {
+			return fmt.Errorf("no entities match pattern %q", pattern)
+		}
+	} else {
+		if _, ok := st.FindEntity(entity); !ok {
+			return fmt.Errorf("entity not found: %s", entity)
+		}
+		names = []string{entity}
+	}
+
+	action := "Enabled"
+	if !active {
+		action = "Disabled"
+	}
+	before := st.ActiveCount()
+	for _, name := range names {
+		if cfg.DryRun {
+			logger.Info("Would set active=%t for entity: %s", active, name)
+			continue
+		}
+		if err := st.SetActive(name, active); err != nil {
+			return fmt.Errorf("failed to update %s: %w", name, err)
+		}
+		logger.Info("%s entity: %s", action, name)
+	}
+	if cfg.DryRun {
+		return nil
+	}
+	logger.Info("Active entities: %d -> %d", before, st.ActiveCount())
+	return nil
+}
+
+func runReset(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	entity, _ := cmd.Flags().GetString("entity")
+	all, _ := cmd.Flags().GetBool("all")
+	if (entity == "") == !all {
+		return fmt.Errorf("exactly one of --entity or --all is required")
+	}
+
+	// Keep the remote copy of the state in sync, otherwise the next export would restore the timestamps
+	_, remoteState, err := storage.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore(remoteState)
+	st, err := state.LoadFromConfig(cfg, remoteState)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+	if autoBackup, _ := cmd.Flags().GetBool("auto-backup"); autoBackup {
+		st.EnableBackup()
+	}
+
+	if cfg.DryRun {
+		for _, e := range st.GetEntities() {
+			if (all && e.LastRunTime != "") || e.Entity == entity {
+				logger.Info("Would reset entity: %s (lastRunTime: %s)", e.Entity, e.LastRunTime)
+			}
+		}
+		if entity != "" {
+			if _, ok := st.FindEntity(entity); !ok {
+				return fmt.Errorf("entity not found: %s", entity)
+			}
+		}
+		return nil
+	}
+
+	if entity != "" {
+		if err := st.ResetTimestamp(entity); err != nil {
+			return fmt.Errorf("failed to reset %s: %w", entity, err)
+		}
+		logger.Info("Reset entity: %s", entity)
+		return nil
+	}
+
+	reset, err := st.ResetAll()
+	for _, name := range reset {
+		logger.Info("Reset entity: %s", name)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to reset entities: %w", err)
+	}
+	logger.Info("Reset %d entities", len(reset))
+	return nil
+}
+
+// runBackupState copies each state file to its backup
+func runEnvHelp(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	switch output {
+	case config.OutputFormatText:
+		return writeEnvHelp(cmd.OutOrStdout(), config.EnvVarDocs)
+	case "markdown":
+		return writeEnvHelpMarkdown(cmd.OutOrStdout(), config.EnvVarDocs)
+	}
+	return fmt.Errorf("invalid --output %q: must be text or markdown", output)
+}
+
+// writeEnvHelp prints environment variable docs as an aligned table
+func writeEnvHelp(w io.Writer, docs []config.EnvVarDoc) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "VARIABLE\tDEFAULT\tCONFIG FIELD\tDESCRIPTION")
+	for _, doc := range docs {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", doc.Name, orDash(doc.Default), orDash(doc.ConfigField), doc.Description)
+	}
+	return tw.Flush()
+}
+
+// writeEnvHelpMarkdown prints environment variable docs as a Markdown table
+func writeEnvHelpMarkdown(w io.Writer, docs []config.EnvVarDoc) error {
+	var b strings.Builder
+	b.WriteString("| Variable | Description | Default | Config field |\n")
+	b.WriteString("|----------|-------------|---------|--------------|\n")
+	cell := func(s string) string {
+		if s == "" {
+			return ""
+		}
+		return "`" + s + "`"
+	}
+	for _, doc := range docs {
+		description := strings.NewReplacer("|", "\\|", "<", "&lt;").Replace(doc.Description)
+		fmt.Fprintf(&b, "| `%s` | %s | %s | %s |\n", doc.Name, description, cell(doc.Default), cell(doc.ConfigField))
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func runBackupState(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	output, _ := cmd.Flags().GetString("output")
+	paths := cfg.StateFiles
+	if len(paths) == 0 {
+		paths = []string{cfg.StateFile}
+	}
+	if output != "" && len(paths) > 1 {
+		return fmt.Errorf("--output cannot be used with multiple --state-files")
+	}
+
+	// Loading downloads the remote state to the local files, so the backup is the current state
+	_, remoteState, err := storage.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore(remoteState)
+	st, err := state.LoadFromConfig(cfg, remoteState)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+
+	now := time.Now()
+	for _, path := range paths {
+		dest := output
+		if dest == "" {
+			dest = state.BackupPath(path, now)
+		}
+		if cfg.DryRun {
+			logger.Info("Would back up %s to %s", path, dest)
+			continue
+		}
+		if err := state.Backup(path, dest, cfg.StateFileMode); err != nil {
+			return fmt.Errorf("failed to back up %s: %w", path, err)
+		}
+		logger.Info("Backed up %s to %s", path, dest)
+	}
+	return nil
+}
+
+// createSQLTemplate writes a SQL template unless the file already exists
+func createSQLTemplate(cfg *config.Config, sqlPath, entity string) (bool, error) {
+	if _, err := os.Stat(sqlPath); err == nil {
+		return false, nil
+	}
+	if err := os.MkdirAll(cfg.SQLDir, cfg.DirModeOrDefault()); err != nil {
+		return false, err
+	}
+	mode := cfg.FileMode
+	if mode == 0 {
+		mode = config.DefaultFileMode
+	}
+	if err := os.WriteFile(sqlPath, []byte(fmt.Sprintf(sqlTemplate, entity, entity)), mode); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// runGenerateState writes a new state file from the SQL files in the SQL directory
+func runAddEntity(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	name, _ := cmd.Flags().GetString("entity")
+	active, _ := cmd.Flags().GetBool("active")
+	force, _ := cmd.Flags().GetBool("force")
+	if name == "" {
+		return fmt.Errorf("--entity is required")
+	}
+
+	tags, _ := cmd.Flags().GetStringSlice("tags")
+	entity := types.EntityState{Entity: name, Active: active, Tags: tags}
+	// Only an explicit --days-back is stored on the entity, otherwise the export default applies
+	// 0 has no override to store and starts the entity from now.
+	if cmd.Flags().Changed("days-back") {
+		if cfg.DefaultDaysBack < 0 || cfg.DefaultDaysBack > config.MaxDaysBack {
+			return fmt.Errorf("--days-back must be between 0 and %d", config.MaxDaysBack)
+		}
+		entity.DaysBack = cfg.DefaultDaysBack
+		if entity.DaysBack == 0 {
+			entity.LastRunTime = time.Now().UTC().Format(config.DateTimeLayout)
+		}
+	}
+
+	// Keep the remote copy of the state in sync, otherwise the next export would drop the entity
+	_, remoteState, err := storage.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore(remoteState)
+
+	st, err := state.LoadFromConfig(cfg, remoteState)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+
+	if _, ok := st.FindEntity(name); ok {
+		return fmt.Errorf("entity already exists: %s", name)
+	}
+
+	sqlPath := st.GetSQLPath(cfg.SQLDir, name)
+	if _, err := os.Stat(sqlPath); err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to check SQL file: %w", err)
+		}
+		if !force {
+			return fmt.Errorf("SQL file %s not found (use --force to add the entity anyway)", sqlPath)
+		}
+		logger.Info("Warning: SQL file %s not found", sqlPath)
+	}
+
+	if cfg.DryRun {
+		logger.Info("Would add entity: %s (active: %t, lastRunTime: %q, daysBack: %d)", entity.Entity, entity.Active, entity.LastRunTime, entity.DaysBack)
+		return nil
+	}
+	if err := st.AddEntity(entity); err != nil {
+		return fmt.Errorf("failed to add entity: %w", err)
+	}
+	logger.Info("Added entity: %s (active: %t, lastRunTime: %q, daysBack: %d)", entity.Entity, entity.Active, entity.LastRunTime, entity.DaysBack)
+	return nil
+}
+
+// runDiscover adds an entity and a SQL template for each discovered table missing from the state
+func runDiscover(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	schema, _ := cmd.Flags().GetString("schema")
+	pattern, _ := cmd.Flags().GetString("pattern")
+	tsColumn, _ := cmd.Flags().GetString("ts-column")
+	active, _ := cmd.Flags().GetBool("active")
+	if strings.TrimSpace(tsColumn) == "" {
+		return fmt.Errorf("--ts-column cannot be empty")
+	}
+
+	// Keep the remote copy of the state in sync, otherwise the next export would drop the entities
+	_, remoteState, err := storage.FromConfig(cfg)
+	if err != nil {
+		return err
+	}
+	defer closeStore(remoteState)
+
+	st, err := state.LoadFromConfig(cfg, remoteState)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	database, err := db.Connect(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := database.Close(); closeErr != nil {
+			logger.Error("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	tables, err := exporter.DiscoverTables(ctx, database, schema, pattern)
+	if err != nil {
+		return err
+	}
+	return addDiscoveredTables(cfg, st, tables, tsColumn, active, logger)
+}
+
+// addDiscoveredTables adds the tables missing from the state with their SQL templates
+// Existing entities and SQL files are left unchanged.
+func addDiscoveredTables(cfg *config.Config, st state.Store, tables []exporter.Table, tsColumn string, active bool, logger *logging.Logger) error {
+	added, skipped := 0, 0
+	for _, table := range tables {
+		name := table.Entity()
+		if _, ok := st.FindEntity(name); ok {
+			logger.Debug("Skipping existing entity: %s", name)
+			skipped++
+			continue
+		}
+
+		sqlPath := st.GetSQLPath(cfg.SQLDir, name)
+		if cfg.DryRun {
+			logger.Info("Would add entity: %s (active: %t, SQL file: %s)", name, active, sqlPath)
+			added++
+			continue
+		}
+
+		if _, err := os.Stat(sqlPath); err == nil {
+			logger.Info("Keeping existing SQL file: %s", sqlPath)
+		} else {
+			if err := os.MkdirAll(cfg.SQLDir, cfg.DirModeOrDefault()); err != nil {
+				return fmt.Errorf("failed to create SQL directory: %w", err)
+			}
+			mode := cfg.FileMode
+			if mode == 0 {
+				mode = config.DefaultFileMode
+			}
+			if err := os.WriteFile(sqlPath, []byte(exporter.TableSQLTemplate(table, tsColumn)), mode); err != nil {
+				return fmt.Errorf("failed to create SQL template for %s: %w", name, err)
+			}
+			logger.Info("Created SQL template: %s", sqlPath)
+		}
+
+		if err := st.AddEntity(types.EntityState{Entity: name, Active: active}); err != nil {
+			return fmt.Errorf("failed to add entity %s: %w", name, err)
+		}
+		logger.Info("Added entity: %s (active: %t)", name, active)
+		added++
+	}
+
+	verb := "added"
+	if cfg.DryRun {
+		verb = "would be added"
+	}
+	logger.Info("Discovered %d tables: %d %s, %d already in the state file", len(tables), added, verb, skipped)
+	return nil
+}
+
+func runGenerateState(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	pattern, _ := cmd.Flags().GetString("active-pattern")
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+	initialDaysBack, _ := cmd.Flags().GetInt("initial-days-back")
+	if initialDaysBack < 0 {
+		return fmt.Errorf("--initial-days-back cannot be negative")
+	}
+
+	if _, err := os.Stat(cfg.StateFile); err == nil && !overwrite {
+		return fmt.Errorf("state file %s already exists (use --overwrite to replace it)", cfg.StateFile)
+	}
+
+	entities, err := state.GenerateStateFromSQLDir(cfg.SQLDir, pattern, initialDaysBack)
+	if err != nil {
+		return err
+	}
+	if len(entities) == 0 {
+		return fmt.Errorf("no SQL files found in %s", cfg.SQLDir)
+	}
+
+	if cfg.DryRun {
+		for _, e := range entities {
+			logger.Info("Would add entity: %s (active: %t)", e.Entity, e.Active)
+		}
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(cfg.StateFile), cfg.DirModeOrDefault()); err != nil {
+		return fmt.Errorf("failed to create state directory: %w", err)
+	}
+	if err := state.WriteNew(cfg.StateFile, entities, cfg.StateFileMode); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+
+	active := 0
+	for _, e := range entities {
+		if e.Active {
+			active++
+		}
+	}
+	logger.Info("Generated %s with %d entities (%d active)", cfg.StateFile, len(entities), active)
+	return nil
+}
+
+// runList prints the status of every entity in the state file
+func runList(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output != config.OutputFormatText && output != config.OutputFormatJSON {
+		return fmt.Errorf("invalid --output %q: must be text or json", output)
+	}
+
+	// Local state only, listing never touches remote storage or the database
+	st, err := state.LoadFromConfig(cfg, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+	statuses := state.ListEntities(st, cfg.SQLDir, time.Now().UTC())
+
+	if output == config.OutputFormatJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(statuses)
+	}
+	return writeEntityTable(cmd.OutOrStdout(), statuses)
+}
+
+// writeEntityTable writes entity statuses as an aligned table
+func writeEntityTable(w io.Writer, statuses []state.EntityStatus) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ENTITY\tSTATUS\tLAST RUN\tAGE\tSQL FILE")
+	for _, s := range statuses {
+		status := "inactive"
+		if s.Active {
+			status = "active"
+		}
+		lastRun := s.LastRunTime
+		if lastRun == "" {
+			lastRun = "-"
+		}
+		sqlFile := "ok"
+		if !s.SQLExists {
+			sqlFile = "missing"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Entity, status, lastRun, s.Age, sqlFile)
+	}
+	return tw.Flush()
+}
+
+// runDiff prints the entities that differ between two state files
+func runDiff(cmd *cobra.Command, args []string) error {
+	fromPath, _ := cmd.Flags().GetString("from")
+	toPath, _ := cmd.Flags().GetString("to")
+	output, _ := cmd.Flags().GetString("output")
+	if output != config.OutputFormatText && output != config.OutputFormatJSON {
+		return fmt.Errorf("invalid --output %q: must be text or json", output)
+	}
+
+	from, err := state.Load(fromPath, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", fromPath, err)
+	}
+	defer closeState(from)
+	to, err := state.Load(toPath, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", toPath, err)
+	}
+	defer closeState(to)
+	entries := state.Diff(from, to)
+
+	if output == config.OutputFormatJSON {
+		if entries == nil {
+			entries = []state.DiffEntry{}
+		}
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(entries)
+	}
+	return writeDiff(cmd.OutOrStdout(), entries)
+}
+
+// writeDiff writes diff entries as "+ added (active)", "- removed" and "~ modified (fields changed)"
+func writeDiff(w io.Writer, entries []state.DiffEntry) error {
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(w, "No differences")
+		return err
+	}
+	for _, entry := range entries {
+		var line string
+		switch entry.Change {
+		case state.DiffAdded:
+			status := "inactive"
+			if entry.Active {
+				status = "active"
+			}
+			line = fmt.Sprintf("+ %s (%s)", entry.Entity, status)
+		case state.DiffRemoved:
+			line = "- " + entry.Entity
+		default:
+			line = fmt.Sprintf("~ %s (%s changed)", entry.Entity, strings.Join(entry.Fields, ", "))
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statusReport is the output of the status command
+type statusReport struct {
+	// LastRun is the run timestamp of the manifest, empty without one
+	LastRun  string         `json:"last_run,omitempty"`
+	Entities []entityStatus `json:"entities"`
+}
+
+// entityStatus adds the entity's result in the last run manifest to its listing
+type entityStatus struct {
+	state.EntityStatus
+	RowCount *int   `json:"row_count,omitempty"` // nil when the last run did not export the entity
+	Result   string `json:"last_result,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// runStatus prints the state of each entity with its result in the last run manifest
+// statusOutputCSV is the status --output value that writes the state file as CSV
+const statusOutputCSV = "csv"
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	output, _ := cmd.Flags().GetString("output")
+	if output != config.OutputFormatText && output != config.OutputFormatJSON && output != statusOutputCSV {
+		return fmt.Errorf("invalid --output %q: must be text, json or csv", output)
+	}
+	entity, _ := cmd.Flags().GetString("entity")
+	if output == statusOutputCSV && entity != "" {
+		return fmt.Errorf("--entity cannot be used with --output csv")
+	}
+
+	// Local files only, like list
+	st, err := state.LoadFromConfig(cfg, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+
+	// CSV is the state file for reporting tools, without the manifest results
+	if output == statusOutputCSV {
+		return st.ExportStateCSV(cmd.OutOrStdout())
+	}
+	if entity != "" {
+		if _, ok := st.FindEntity(entity); !ok {
+			return fmt.Errorf("entity not found in state: %s", entity)
+		}
+	}
+
+	manifest, err := types.LoadManifest(filepath.Join(cfg.ExportDir, exporter.ManifestFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	report := statusReport{Entities: []entityStatus{}}
+	results := map[string]types.ManifestEntity{}
+	if manifest != nil {
+		report.LastRun = manifest.RunTimestamp
+		for _, r := range manifest.Entities {
+			results[r.Entity] = r
+		}
+	}
+	for _, s := range state.ListEntities(st, cfg.SQLDir, time.Now().UTC()) {
+		if entity != "" && s.Entity != entity {
+			continue
+		}
+		status := entityStatus{EntityStatus: s}
+		if r, ok := results[s.Entity]; ok {
+			rowCount := r.RowCount
+			status.RowCount = &rowCount
+			status.Result = "success"
+			if !r.Success {
+				status.Result = "failed"
+			}
+			status.Error = r.Error
+		}
+		report.Entities = append(report.Entities, status)
+	}
+
+	if output == config.OutputFormatJSON {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return writeStatusTable(cmd.OutOrStdout(), report)
+}
+
+// writeStatusTable writes the status report as an aligned table
+func writeStatusTable(w io.Writer, report statusReport) error {
+	if report.LastRun != "" {
+		_, _ = fmt.Fprintf(w, "Last run: %s\n\n", report.LastRun)
+	} else {
+		_, _ = fmt.Fprintf(w, "Last run: no manifest found\n\n")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ENTITY\tLAST RUN\tAGE\tROWS\tRESULT\tSQL FILE")
+	for _, s := range report.Entities {
+		lastRun := s.LastRunTime
+		if lastRun == "" {
+			lastRun = "-"
+		}
+		rows, result := "-", "-"
+		if s.RowCount != nil {
+			rows = strconv.Itoa(*s.RowCount)
+			result = s.Result
+		}
+		sqlFile := "ok"
+		if !s.SQLExists {
+			sqlFile = "missing"
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Entity, lastRun, s.Age, rows, result, sqlFile)
+	}
+	return tw.Flush()
+}
+
+// fileCheck is the verify result of an entity's latest export file
+type fileCheck struct {
+	Entity       string
+	File         string // Empty when no manifest lists an export file
+	FileRows     *int   // nil when the file could not be read
+	ManifestRows int
+	DBRows       *int // Set with --recount-from-db
+	Err          string
+}
+
+// runVerify counts the rows of the latest export files and compares them to the manifests
+func runVerify(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+	recount, _ := cmd.Flags().GetBool("recount-from-db")
+
+	// The report goes to stdout, logs to stderr
+	logger := logging.NewWithWriter(cmd.ErrOrStderr(), cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	st, err := state.LoadFromConfig(cfg, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+
+	exports, err := exporter.LatestExports(cfg.ExportDir)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	var database db.DB
+	if recount {
+		database, err = db.Connect(ctx, cfg, logger)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer func() {
+			if closeErr := database.Close(); closeErr != nil {
+				logger.Error("Failed to close database connection: %v", closeErr)
+			}
+		}()
+	}
+	exp := exporter.New(cfg, database, st, logger, nil)
+
+	var checks []fileCheck
+	checked, failed := 0, 0
+	for _, entity := range st.GetEntities() {
+		export, ok := exports[entity.Entity]
+		if !ok {
+			checks = append(checks, fileCheck{Entity: entity.Entity})
+			continue
+		}
+		checked++
+		check := fileCheck{Entity: entity.Entity, File: export.FilePath, ManifestRows: export.RowCount}
+		// Split exports are checked as a whole against the manifest row count
+		var fileRows int
+		var err error
+		for _, path := range exporter.ExportFiles(export) {
+			var rows int
+			if rows, err = exporter.CountExportRows(path, exp.CSVOptions()); err != nil {
+				break
+			}
+			fileRows += rows
+		}
+		if err != nil {
+			check.Err = err.Error()
+		} else {
+			check.FileRows = &fileRows
+			if fileRows != export.RowCount {
+				check.Err = "row count differs from manifest"
+			}
+		}
+		if recount && check.Err == "" {
+			dbRows, err := exp.RecountRows(ctx, entity.Entity, export.StartDate, export.TillDate)
+			if err != nil {
+				check.Err = err.Error()
+			} else {
+				check.DBRows = &dbRows
+				if dbRows != fileRows {
+					check.Err = "row count differs from database"
+				}
+			}
+		}
+		if check.Err != "" {
+			failed++
+		}
+		checks = append(checks, check)
+	}
+
+	if err := writeFileChecks(cmd.OutOrStdout(), checks, recount); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("verification failed for %d of %d export files", failed, checked)
+	}
+	return nil
+}
+
+// writeFileChecks writes verify results as an aligned table
+func writeFileChecks(w io.Writer, checks []fileCheck, recount bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	header := "ENTITY\tFILE\tROWS\tMANIFEST"
+	if recount {
+		header += "\tDATABASE"
+	}
+	_, _ = fmt.Fprintln(tw, header+"\tRESULT")
+	for _, c := range checks {
+		if c.File == "" {
+			dash := "-\t-\t-"
+			if recount {
+				dash += "\t-"
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\tno export\n", c.Entity, dash)
+			continue
+		}
+		rows := "-"
+		if c.FileRows != nil {
+			rows = strconv.Itoa(*c.FileRows)
+		}
+		line := fmt.Sprintf("%s\t%s\t%s\t%d", c.Entity, c.File, rows, c.ManifestRows)
+		if recount {
+			dbRows := "-"
+			if c.DBRows != nil {
+				dbRows = strconv.Itoa(*c.DBRows)
+			}
+			line += "\t" + dbRows
+		}
+		result := "ok"
+		if c.Err != "" {
+			result = "FAILED: " + c.Err
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\n", line, result)
+	}
+	return tw.Flush()
+}
+
+// runPruneManifests deletes manifests outside the retention policy
+func runPruneManifests(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	keepN, _ := cmd.Flags().GetInt("keep-last-n")
+	olderThan, _ := cmd.Flags().GetDuration("older-than")
+
+	var s3Client *storage.S3Client
+	if cfg.S3.Bucket != "" {
+		client, err := storage.NewS3Client(&cfg.S3)
+		if err != nil {
+			return fmt.Errorf("failed to initialize S3 client: %w", err)
+		}
+		s3Client = client
+	}
+
+	dir := filepath.Join(cfg.ExportDir, exporter.ManifestDir)
+	pruned, err := exporter.PruneManifests(dir, keepN, olderThan, cfg.DryRun)
+	if err != nil {
+		return fmt.Errorf("failed to prune manifests: %w", err)
+	}
+
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	for _, path := range pruned {
+		key := cfg.S3.Key(exporter.ManifestDir + "/" + filepath.Base(path))
+		if cfg.DryRun {
+			logger.Info("Would delete manifest: %s", path)
+			if s3Client != nil {
+				logger.Info("Would delete S3 manifest: %s", key)
+			}
+			continue
+		}
+		logger.Info("Deleted manifest: %s", path)
+		if s3Client != nil {
+			if err := s3Client.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to delete S3 manifest %s: %w", key, err)
+			}
+			logger.Info("Deleted S3 manifest: %s", key)
+		}
+	}
+
+	logger.Info("Pruned %d manifests", len(pruned))
+	return nil
+}
+
+// runPurge deletes export files older than --older-than locally and in S3
+func runPurge(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	logger := logging.New(cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	olderThanStr, _ := cmd.Flags().GetString("older-than")
+	olderThan, err := parseAge(olderThanStr)
+	if err != nil {
+		return fmt.Errorf("invalid --older-than: %w", err)
+	}
+	cutoff := time.Now().UTC().Add(-olderThan)
+
+	var s3Client *storage.S3Client
+	if cfg.S3.Bucket != "" {
+		client, err := storage.NewS3Client(&cfg.S3)
+		if err != nil {
+			return fmt.Errorf("failed to initialize S3 client: %w", err)
+		}
+		s3Client = client
+	}
+
+	dirs, err := purgeDirs(cfg)
+	if err != nil {
+		return err
+	}
+	var purged []exporter.PurgedFile
+	for _, dir := range dirs {
+		var files []exporter.PurgedFile
+		files, err = exporter.PurgeExports(dir, cfg.FileExtension, cutoff, cfg.DryRun)
+		purged = append(purged, files...)
+		if err != nil {
+			break
+		}
+	}
+	for _, f := range purged {
+		if cfg.DryRun {
+			logger.Info("Would delete export file: %s (%s)", f.Path, formatSize(f.Size))
+		} else {
+			logger.Info("Deleted export file: %s (%s)", f.Path, formatSize(f.Size))
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to purge export files: %w", err)
+	}
+	count := len(purged)
+	var freed int64
+	for _, f := range purged {
+		freed += f.Size
+	}
+
+	if s3Client != nil {
+		ctx, cancel := setupContext()
+		defer cancel()
+
+		prefix := ""
+		if cfg.S3.Prefix != "" {
+			prefix = strings.TrimSuffix(cfg.S3.Prefix, "/") + "/"
+		}
+		objects, err := s3Client.ListObjects(ctx, prefix)
+		if err != nil {
+			return fmt.Errorf("failed to list S3 exports: %w", err)
+		}
+		for _, obj := range objects {
+			t, ok := exporter.ExportFileTime(path.Base(obj.Key), cfg.FileExtension)
+			if !ok || !t.Before(cutoff) {
+				continue
+			}
+			if cfg.DryRun {
+				logger.Info("Would delete S3 export: %s (%s)", obj.Key, formatSize(obj.Size))
+			} else {
+				if err := s3Client.Delete(ctx, obj.Key); err != nil {
+					return fmt.Errorf("failed to delete S3 export %s: %w", obj.Key, err)
+				}
+				logger.Info("Deleted S3 export: %s (%s)", obj.Key, formatSize(obj.Size))
+			}
+			count++
+			freed += obj.Size
+		}
+	}
+
+	if cfg.DryRun {
+		logger.Info("Would purge %d files, freeing %s", count, formatSize(freed))
+	} else {
+		logger.Info("Purged %d files, freed %s", count, formatSize(freed))
+	}
+	return nil
+}
+
+func runTestQuery(cmd *cobra.Command, args []string) error {
+	cfg, err := config.FromCommand(cmd)
+	if err != nil {
+		return fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	entityName, _ := cmd.Flags().GetString("entity")
+	limit, _ := cmd.Flags().GetInt("limit")
+	if limit <= 0 {
+		return fmt.Errorf("invalid --limit %d: must be positive", limit)
+	}
+	output, _ := cmd.Flags().GetString("output")
+	if output != config.OutputFormatText && output != config.OutputFormatJSON {
+		return fmt.Errorf("invalid --output %q: must be text or json", output)
+	}
+
+	// Rows go to stdout, logs to stderr so the output can be piped
+	logger := logging.NewWithWriter(cmd.ErrOrStderr(), cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	defer func() {
+		if closeErr := logger.Close(); closeErr != nil {
+			fmt.Fprintf(os.Stderr, "warning: failed to close logger: %v\n", closeErr)
+		}
+	}()
+
+	st, err := state.LoadFromConfig(cfg, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer closeState(st)
+
+	ctx, cancel := setupContext()
+	defer cancel()
+
+	database, err := db.Connect(ctx, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer func() {
+		if closeErr := database.Close(); closeErr != nil {
+			logger.Error("Failed to close database connection: %v", closeErr)
+		}
+	}()
+
+	exp := exporter.New(cfg, database, st, logger, nil)
+	columns, rows, err := exp.SampleRows(ctx, entityName, limit)
+	if err != nil {
+		return fmt.Errorf("failed to run query for %s: %w", entityName, err)
+	}
+
+	if output == config.OutputFormatJSON {
+		return render.JSONLines(cmd.OutOrStdout(), columns, rows)
+	}
+	return render.Table(cmd.OutOrStdout(), columns, rows)
+}
+
+// purgeDirs returns the local directories holding export files
+// With --output-dir-per-entity these are the subdirectories of the export directory.
+func purgeDirs(cfg *config.Config) ([]string, error) {
+	dirs := []string{cfg.ExportDir}
+	if !cfg.OutputPerEntityDir {
+		return dirs, nil
+	}
+	entries, err := os.ReadDir(cfg.ExportDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return dirs, nil
+		}
+		return nil, fmt.Errorf("failed to read export directory: %w", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && entry.Name() != exporter.ManifestDir {
+			dirs = append(dirs, filepath.Join(cfg.ExportDir, entry.Name()))
+		}
+	}
+	return dirs, nil
+}
+
+// parseAge parses a duration that also accepts whole days, e.g. 30d
+func parseAge(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid number of days %q", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("age must be positive, got %q", s)
+	}
+	return d, nil
+}
+
+// formatSize formats a byte count with a binary unit, e.g. 1.5 MiB
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
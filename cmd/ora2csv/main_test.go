@@ -0,0 +1,743 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/internal/exporter"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// runCaptured executes the root command with args and returns what was written to stdout
+func runCaptured(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	if !exportCmd.HasParent() {
+		rootCmd.AddCommand(exportCmd, validateCmd, encryptSQLCmd, importEntitiesCmd, addEntityCmd, discoverCmd, generateStateCmd, pruneManifestsCmd, purgeCmd, testQueryCmd, listCmd, diffCmd, statusCmd, verifyCmd, resetCmd, backupStateCmd, envHelpCmd, disableEntityCmd, enableEntityCmd)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Pipe() error = %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	rootCmd.SetArgs(args)
+	runErr := rootCmd.Execute()
+
+	_ = w.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	return string(data), runErr
+}
+
+func TestExport_OutputFormatJSON(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	sqlDir := filepath.Join(tmpDir, "sql")
+	entities := testutil.NewTestState()
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, entities))
+	testutil.AssertNoError(t, testutil.CreateTestSQLFiles(sqlDir, entities))
+
+	baseArgs := []string{
+		"export",
+		"--output-format", "json",
+		"--state-file", statePath,
+		"--sql-dir", sqlDir,
+		"--export-dir", filepath.Join(tmpDir, "export"),
+	}
+
+	t.Run("dry run succeeds", func(t *testing.T) {
+		t.Setenv("ORA2CSV_DB_PASSWORD", "secret")
+
+		out, err := runCaptured(t, append(baseArgs, "--dry-run=true")...)
+		testutil.AssertNoError(t, err)
+
+		var summary types.MachineReadableSummary
+		if err := json.Unmarshal([]byte(out), &summary); err != nil {
+			t.Fatalf("stdout is not a single JSON object: %v\n%s", err, out)
+		}
+		testutil.AssertEqual(t, true, summary.Success)
+		testutil.AssertEqual(t, "", summary.Error)
+		testutil.AssertEqual(t, 0, len(summary.Entities))
+	})
+
+	t.Run("configuration error", func(t *testing.T) {
+		t.Setenv("ORA2CSV_DB_PASSWORD", "")
+
+		out, err := runCaptured(t, append(baseArgs, "--dry-run=false")...)
+		if err == nil {
+			t.Fatal("expected error without db password")
+		}
+
+		var summary types.MachineReadableSummary
+		if err := json.Unmarshal([]byte(out), &summary); err != nil {
+			t.Fatalf("stdout is not a single JSON object: %v\n%s", err, out)
+		}
+		testutil.AssertEqual(t, false, summary.Success)
+		if !strings.Contains(summary.Error, "db_password is required") {
+			t.Errorf("Error = %q, want db_password message", summary.Error)
+		}
+	})
+}
+
+func TestExport_PagerDutyAbortedRun(t *testing.T) {
+	var events []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"success","dedup_key":"dk-1"}`))
+	}))
+	defer server.Close()
+	endpoint := pagerDutyEndpoint
+	pagerDutyEndpoint = server.URL
+	t.Cleanup(func() {
+		pagerDutyEndpoint = endpoint
+		// Flags keep their values between runs of the shared commands
+		_ = exportCmd.Flags().Set("pagerduty-routing-key", "")
+		_ = rootCmd.PersistentFlags().Set("db-host", config.DefaultDBHost)
+		_ = rootCmd.PersistentFlags().Set("db-port", strconv.Itoa(config.DefaultDBPort))
+		_ = rootCmd.PersistentFlags().Set("connect-retry-count", strconv.Itoa(config.DefaultConnectRetryCount))
+	})
+
+	// Nothing listens on the port, so the run aborts when connecting
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	testutil.AssertNoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	testutil.AssertNoError(t, listener.Close())
+
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	sqlDir := filepath.Join(tmpDir, "sql")
+	entities := testutil.NewTestState()
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, entities))
+	testutil.AssertNoError(t, testutil.CreateTestSQLFiles(sqlDir, entities))
+	t.Setenv("ORA2CSV_DB_PASSWORD", "secret")
+
+	_, err = runCaptured(t, "export", "--dry-run=false",
+		"--state-file", statePath, "--sql-dir", sqlDir, "--export-dir", filepath.Join(tmpDir, "export"),
+		"--db-host", "127.0.0.1", "--db-port", strconv.Itoa(port), "--connect-retry-count", "0",
+		"--pagerduty-routing-key", "routing-key", "--pagerduty-threshold", "5")
+	if err == nil {
+		t.Fatal("expected connection error")
+	}
+
+	if len(events) != 1 || events[0]["event_action"] != "trigger" {
+		t.Fatalf("events = %v, want one trigger", events)
+	}
+	payload, _ := events[0]["payload"].(map[string]interface{})
+	if summary, _ := payload["summary"].(string); !strings.HasPrefix(summary, "ora2csv: export failed:") {
+		t.Errorf("summary = %q", summary)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	testutil.AssertEqual(t, 1, exitCode(errors.New("failed")))
+	err := fmt.Errorf("run: %w", &exitError{code: 2, err: errors.New("export completed with 1 failures")})
+	testutil.AssertEqual(t, 2, exitCode(err))
+	testutil.AssertEqual(t, "run: export completed with 1 failures", err.Error())
+}
+
+func TestImportEntities(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	sqlDir := filepath.Join(tmpDir, "sql")
+	csvPath := filepath.Join(tmpDir, "entities.csv")
+	testutil.AssertNoError(t, os.WriteFile(statePath, []byte("[]"), 0644))
+	testutil.AssertNoError(t, os.WriteFile(csvPath, []byte("entity,active,tags\ncrm.orders,true,crm;daily\n"), 0644))
+
+	args := []string{"import-entities", "--file", csvPath, "--state-file", statePath, "--sql-dir", sqlDir}
+
+	out, err := runCaptured(t, append(args, "--create-sql")...)
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "1 added, 0 replaced, 0 skipped, 0 failed") {
+		t.Errorf("output missing import report:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(sqlDir, "crm.orders.sql")); err != nil {
+		t.Errorf("SQL template not created: %v", err)
+	}
+
+	out, err = runCaptured(t, append(args, "--create-sql=false")...)
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Warning: skipping existing entity: crm.orders") {
+		t.Errorf("output missing skip warning:\n%s", out)
+	}
+
+	t.Run("json lines", func(t *testing.T) {
+		jsonlPath := filepath.Join(tmpDir, "entities.jsonl")
+		testutil.AssertNoError(t, os.WriteFile(jsonlPath, []byte(
+			`{"entity": "crm.orders", "active": false, "days_back": 7}`+"\n"+
+				`{"entity": "crm.products", "active": true}`+"\n"), 0644))
+		jsonlArgs := []string{"import-entities", "--file", jsonlPath, "--state-file", statePath, "--sql-dir", sqlDir, "--create-sql=false"}
+
+		// crm.products has no SQL file
+		out, err := runCaptured(t, append(jsonlArgs, "--overwrite", "--force=false")...)
+		if err == nil {
+			t.Fatal("expected error for missing SQL file")
+		}
+		if !strings.Contains(out, "0 added, 1 replaced, 0 skipped, 1 failed") {
+			t.Errorf("output missing import report:\n%s", out)
+		}
+
+		st, err := state.Load(statePath, nil, "")
+		testutil.AssertNoError(t, err)
+		orders, _ := st.FindEntity("crm.orders")
+		testutil.AssertEqual(t, false, orders.Active)
+		testutil.AssertEqual(t, 7, orders.DaysBack)
+		testutil.AssertNoError(t, st.Close())
+
+		out, err = runCaptured(t, append(jsonlArgs, "--overwrite=false", "--force")...)
+		testutil.AssertNoError(t, err)
+		if !strings.Contains(out, "1 added, 0 replaced, 1 skipped, 0 failed") {
+			t.Errorf("output missing import report:\n%s", out)
+		}
+	})
+}
+
+func TestAddDiscoveredTables(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, []types.EntityState{{Entity: "crm.orders", Active: false}}))
+	st, err := state.Load(cfg.StateFile, nil, "")
+	testutil.AssertNoError(t, err)
+	defer func() { testutil.AssertNoError(t, st.Close()) }()
+
+	tables := []exporter.Table{{Owner: "CRM", Name: "ORDERS"}, {Owner: "CRM", Name: "AUDIT_LOG"}}
+	testutil.AssertNoError(t, addDiscoveredTables(cfg, st, tables, "CHANGED_AT", true, logging.New(false)))
+
+	orders, _ := st.FindEntity("crm.orders")
+	testutil.AssertEqual(t, false, orders.Active)
+	if _, err := os.Stat(filepath.Join(cfg.SQLDir, "crm.orders.sql")); !os.IsNotExist(err) {
+		t.Errorf("SQL file created for existing entity: %v", err)
+	}
+
+	auditLog, ok := st.FindEntity("crm.audit_log")
+	if !ok || !auditLog.Active {
+		t.Fatalf("crm.audit_log not added: %+v", auditLog)
+	}
+	data, err := os.ReadFile(filepath.Join(cfg.SQLDir, "crm.audit_log.sql"))
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(string(data), "FROM CRM.AUDIT_LOG") || !strings.Contains(string(data), "CHANGED_AT >= ") {
+		t.Errorf("unexpected SQL template:\n%s", data)
+	}
+}
+
+func TestAddEntity(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	sqlDir := filepath.Join(tmpDir, "sql")
+	testutil.AssertNoError(t, os.MkdirAll(sqlDir, 0755))
+	testutil.AssertNoError(t, os.WriteFile(filepath.Join(sqlDir, "crm.orders.sql"), []byte("SELECT 1 FROM dual"), 0644))
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, []types.EntityState{{Entity: "crm.users", Active: true}}))
+
+	run := func(args ...string) (string, error) {
+		return runCaptured(t, append([]string{"add-entity", "--state-file", statePath, "--sql-dir", sqlDir, "--dry-run=false", "--active=true"}, args...)...)
+	}
+	entity := func(name string) types.EntityState {
+		st, err := state.Load(statePath, nil, "")
+		testutil.AssertNoError(t, err)
+		defer func() { testutil.AssertNoError(t, st.Close()) }()
+		e, ok := st.FindEntity(name)
+		if !ok {
+			t.Fatalf("entity %s not found", name)
+		}
+		return *e
+	}
+
+	_, err := run("--entity", "crm.users", "--force=false")
+	if err == nil || !strings.Contains(err.Error(), "entity already exists") {
+		t.Errorf("expected duplicate error, got %v", err)
+	}
+
+	_, err = run("--entity", "crm.missing", "--force=false")
+	if err == nil || !strings.Contains(err.Error(), "use --force") {
+		t.Errorf("expected missing SQL file error, got %v", err)
+	}
+
+	out, err := run("--entity", "crm.missing", "--force=true", "--active=false")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Warning: SQL file") {
+		t.Errorf("output missing SQL file warning:\n%s", out)
+	}
+	testutil.AssertEqual(t, false, entity("crm.missing").Active)
+
+	out, err = run("--entity", "crm.orders", "--force=false", "--days-back", "7")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Added entity: crm.orders") {
+		t.Errorf("output missing added entity:\n%s", out)
+	}
+	added := entity("crm.orders")
+	testutil.AssertEqual(t, true, added.Active)
+	testutil.AssertEqual(t, 7, added.DaysBack)
+	testutil.AssertEqual(t, "", added.LastRunTime)
+
+	_, err = run("--entity", "crm.recent", "--force=true", "--days-back", "0")
+	testutil.AssertNoError(t, err)
+	recent := entity("crm.recent")
+	testutil.AssertEqual(t, 0, recent.DaysBack)
+	lastRun, err := recent.GetLastRunTime()
+	testutil.AssertNoError(t, err)
+	if age := time.Since(lastRun); age < 0 || age > time.Minute {
+		t.Errorf("lastRunTime = %s, want now", recent.LastRunTime)
+	}
+}
+
+func TestDisableEnableEntity(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	entities := []types.EntityState{
+		{Entity: "crm.orders", Active: true},
+		{Entity: "crm.users", Active: true},
+		{Entity: "hr.people", Active: true},
+	}
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, entities))
+
+	run := func(args ...string) (string, error) {
+		return runCaptured(t, append(args, "--state-file", statePath, "--dry-run=false")...)
+	}
+	active := func(name string) bool {
+		st, err := state.Load(statePath, nil, "")
+		testutil.AssertNoError(t, err)
+		defer func() { testutil.AssertNoError(t, st.Close()) }()
+		e, ok := st.FindEntity(name)
+		if !ok {
+			t.Fatalf("entity %s not found", name)
+		}
+		return e.Active
+	}
+
+	if _, err := run("disable-entity", "--entity", "", "--pattern", ""); err == nil {
+		t.Error("expected error without --entity or --pattern")
+	}
+	if _, err := run("disable-entity", "--entity", "crm.missing", "--pattern", ""); err == nil {
+		t.Error("expected error for unknown entity")
+	}
+
+	out, err := run("disable-entity", "--entity", "", "--pattern", "^crm\\.")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Active entities: 3 -> 1") {
+		t.Errorf("output missing active counts:\n%s", out)
+	}
+	testutil.AssertEqual(t, false, active("crm.orders"))
+	testutil.AssertEqual(t, false, active("crm.users"))
+	testutil.AssertEqual(t, true, active("hr.people"))
+
+	out, err = run("enable-entity", "--entity", "crm.users")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Enabled entity: crm.users") || !strings.Contains(out, "Active entities: 1 -> 2") {
+		t.Errorf("unexpected enable output:\n%s", out)
+	}
+	testutil.AssertEqual(t, true, active("crm.users"))
+
+	if _, err := run("enable-entity", "--entity", "crm.missing"); err == nil {
+		t.Error("expected error for unknown entity")
+	}
+}
+
+func TestGenerateState(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	sqlDir := filepath.Join(tmpDir, "sql")
+	testutil.AssertNoError(t, os.MkdirAll(sqlDir, 0755))
+	for _, name := range []string{"crm.orders.sql", "hr.employees.sql"} {
+		testutil.AssertNoError(t, os.WriteFile(filepath.Join(sqlDir, name), []byte("SELECT 1 FROM dual"), 0644))
+	}
+
+	args := []string{"generate-state", "--state-file", statePath, "--sql-dir", sqlDir, "--active-pattern", "crm.*"}
+
+	out, err := runCaptured(t, args...)
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "with 2 entities (1 active)") {
+		t.Errorf("output missing summary:\n%s", out)
+	}
+
+	if _, err := runCaptured(t, args...); err == nil {
+		t.Error("expected error for existing state file without --overwrite")
+	}
+	_, err = runCaptured(t, append(args, "--overwrite")...)
+	testutil.AssertNoError(t, err)
+}
+
+func TestList(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	sqlDir := filepath.Join(tmpDir, "sql")
+	entities := []types.EntityState{
+		{Entity: "crm.orders", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		{Entity: "crm.users", Active: false},
+	}
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, entities))
+	testutil.AssertNoError(t, testutil.CreateTestSQLFiles(sqlDir, entities[:1]))
+	before, err := os.ReadFile(statePath)
+	testutil.AssertNoError(t, err)
+
+	args := []string{"list", "--state-file", statePath, "--sql-dir", sqlDir}
+
+	out, err := runCaptured(t, append(args, "--output", "json")...)
+	testutil.AssertNoError(t, err)
+	var statuses []state.EntityStatus
+	if err := json.Unmarshal([]byte(out), &statuses); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, out)
+	}
+	testutil.AssertEqual(t, 2, len(statuses))
+	testutil.AssertEqual(t, true, statuses[0].SQLExists)
+	testutil.AssertEqual(t, false, statuses[1].SQLExists)
+	testutil.AssertEqual(t, "never", statuses[1].Age)
+
+	out, err = runCaptured(t, append(args, "--output", "text")...)
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "crm.users") || !strings.Contains(out, "inactive") || !strings.Contains(out, "missing") {
+		t.Errorf("unexpected table:\n%s", out)
+	}
+
+	// Listing is read-only
+	after, err := os.ReadFile(statePath)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, string(before), string(after))
+
+	if _, err := runCaptured(t, append(args, "--output", "yaml")...); err == nil {
+		t.Error("expected error for unsupported output format")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	fromPath := filepath.Join(tmpDir, "state.json")
+	toPath := filepath.Join(tmpDir, "state.json.new")
+	testutil.AssertNoError(t, testutil.WriteStateFile(fromPath, []types.EntityState{
+		{Entity: "crm.orders", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		{Entity: "crm.legacy", Active: true},
+	}))
+	testutil.AssertNoError(t, testutil.WriteStateFile(toPath, []types.EntityState{
+		{Entity: "crm.orders", LastRunTime: "2025-01-02T00:00:00", Active: true},
+		{Entity: "crm.invoices", Active: true},
+	}))
+
+	args := []string{"diff", "--from", fromPath, "--to", toPath}
+
+	out, err := runCaptured(t, append(args, "--output", "text")...)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "+ crm.invoices (active)\n- crm.legacy\n~ crm.orders (lastRunTime changed)\n", out)
+
+	out, err = runCaptured(t, append(args, "--output", "json")...)
+	testutil.AssertNoError(t, err)
+	var entries []state.DiffEntry
+	if err := json.Unmarshal([]byte(out), &entries); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, out)
+	}
+	testutil.AssertEqual(t, 3, len(entries))
+	testutil.AssertEqual(t, state.DiffModified, entries[2].Change)
+
+	copyPath := filepath.Join(tmpDir, "state.copy.json")
+	data, err := os.ReadFile(fromPath)
+	testutil.AssertNoError(t, err)
+	testutil.AssertNoError(t, os.WriteFile(copyPath, data, 0644))
+	out, err = runCaptured(t, "diff", "--from", fromPath, "--to", copyPath, "--output", "text")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "No differences\n", out)
+}
+
+func TestStatus(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	sqlDir := filepath.Join(tmpDir, "sql")
+	exportDir := filepath.Join(tmpDir, "export")
+	entities := []types.EntityState{
+		{Entity: "crm.orders", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		{Entity: "crm.users", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		{Entity: "crm.legacy", Active: false},
+	}
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, entities))
+	testutil.AssertNoError(t, testutil.CreateTestSQLFiles(sqlDir, entities[:2]))
+	testutil.AssertNoError(t, os.MkdirAll(exportDir, 0755))
+
+	args := []string{"status", "--state-file", statePath, "--sql-dir", sqlDir, "--export-dir", exportDir, "--entity", ""}
+
+	// Without a manifest only the state is shown
+	out, err := runCaptured(t, append(args, "--output", "text")...)
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "no manifest found") || !strings.Contains(out, "crm.legacy") {
+		t.Errorf("unexpected status:\n%s", out)
+	}
+
+	testutil.AssertNoError(t, types.WriteManifest(filepath.Join(exportDir, "manifest.json"), &types.ExportResult{
+		Results: []types.EntityResult{
+			{Entity: "crm.orders", Success: true, RowCount: 42},
+			{Entity: "crm.users", Error: errors.New("query execution failed")},
+		},
+	}))
+
+	out, err = runCaptured(t, append(args, "--output", "json")...)
+	testutil.AssertNoError(t, err)
+	var report statusReport
+	if err := json.Unmarshal([]byte(out), &report); err != nil {
+		t.Fatalf("stdout is not JSON: %v\n%s", err, out)
+	}
+	testutil.AssertEqual(t, 3, len(report.Entities))
+	orders, users, legacy := report.Entities[0], report.Entities[1], report.Entities[2]
+	if orders.RowCount == nil || *orders.RowCount != 42 || orders.Result != "success" || !orders.SQLExists {
+		t.Errorf("crm.orders status = %+v", orders)
+	}
+	if users.Result != "failed" || users.Error != "query execution failed" {
+		t.Errorf("crm.users status = %+v", users)
+	}
+	if legacy.RowCount != nil || legacy.Age != "never" || legacy.SQLExists {
+		t.Errorf("crm.legacy status = %+v", legacy)
+	}
+
+	out, err = runCaptured(t, append(args, "--output", "csv")...)
+	testutil.AssertNoError(t, err)
+	want := "entity,active,last_run_time,display_name,tags\n" +
+		"crm.orders,true,2025-01-01T00:00:00,,\n" +
+		"crm.users,true,2025-01-01T00:00:00,,\n" +
+		"crm.legacy,false,,,\n"
+	testutil.AssertEqual(t, want, out)
+	if _, err := runCaptured(t, "status", "--state-file", statePath, "--entity", "crm.orders", "--output", "csv"); err == nil {
+		t.Error("expected error for --entity with csv output")
+	}
+
+	out, err = runCaptured(t, "status", "--state-file", statePath, "--sql-dir", sqlDir, "--export-dir", exportDir, "--entity", "crm.orders", "--output", "text")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "42") || strings.Contains(out, "crm.users") {
+		t.Errorf("unexpected single entity status:\n%s", out)
+	}
+
+	if _, err := runCaptured(t, append(args[:len(args)-1], "crm.missing")...); err == nil {
+		t.Error("expected error for unknown entity")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	exportDir := filepath.Join(tmpDir, "export")
+	entities := []types.EntityState{
+		{Entity: "crm.orders", Active: true},
+		{Entity: "crm.users", Active: true},
+	}
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, entities))
+	testutil.AssertNoError(t, os.MkdirAll(exportDir, 0755))
+
+	ordersPath := filepath.Join(exportDir, "crm.orders__2025-01-01T00-00-00.csv")
+	testutil.AssertNoError(t, os.WriteFile(ordersPath, []byte("ID,NOTE\n1,\"multi\nline\"\n2,b\n"), 0644))
+	manifest := func(rows int) *types.ExportResult {
+		return &types.ExportResult{Results: []types.EntityResult{{Entity: "crm.orders", Success: true, RowCount: rows, FilePath: ordersPath}}}
+	}
+	args := []string{"verify", "--state-file", statePath, "--export-dir", exportDir}
+
+	testutil.AssertNoError(t, types.WriteManifest(filepath.Join(exportDir, "manifest.json"), manifest(2)))
+	out, err := runCaptured(t, args...)
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "ok") || !strings.Contains(out, "no export") {
+		t.Errorf("unexpected verify output:\n%s", out)
+	}
+
+	testutil.AssertNoError(t, types.WriteManifest(filepath.Join(exportDir, "manifest.json"), manifest(3)))
+	out, err = runCaptured(t, args...)
+	if err == nil {
+		t.Fatal("expected error for a row count mismatch")
+	}
+	if !strings.Contains(out, "row count differs from manifest") {
+		t.Errorf("unexpected verify output:\n%s", out)
+	}
+}
+
+func TestReset(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	entities := []types.EntityState{
+		{Entity: "crm.orders", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		{Entity: "crm.users", LastRunTime: "2025-01-02T00:00:00", Active: true},
+	}
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, entities))
+
+	run := func(args ...string) (string, error) {
+		return runCaptured(t, append([]string{"reset", "--state-file", statePath}, args...)...)
+	}
+	lastRunTime := func(name string) string {
+		st, err := state.Load(statePath, nil, "")
+		testutil.AssertNoError(t, err)
+		defer func() { testutil.AssertNoError(t, st.Close()) }()
+		e, ok := st.FindEntity(name)
+		if !ok {
+			t.Fatalf("entity %s not found", name)
+		}
+		return e.LastRunTime
+	}
+
+	if _, err := run("--entity", "", "--all=false", "--dry-run=false"); err == nil {
+		t.Error("expected error without --entity or --all")
+	}
+
+	out, err := run("--entity", "", "--all=true", "--dry-run=true")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Would reset entity: crm.orders") || !strings.Contains(out, "Would reset entity: crm.users") {
+		t.Errorf("dry run output missing entities:\n%s", out)
+	}
+	testutil.AssertEqual(t, "2025-01-01T00:00:00", lastRunTime("crm.orders"))
+
+	out, err = run("--entity", "crm.orders", "--all=false", "--dry-run=false")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Reset entity: crm.orders") {
+		t.Errorf("output missing reset entity:\n%s", out)
+	}
+	testutil.AssertEqual(t, "", lastRunTime("crm.orders"))
+	testutil.AssertEqual(t, "2025-01-02T00:00:00", lastRunTime("crm.users"))
+
+	if _, err := run("--entity", "crm.missing", "--all=false", "--dry-run=false"); err == nil {
+		t.Error("expected error for unknown entity")
+	}
+
+	_, err = run("--entity", "", "--all=true", "--dry-run=false", "--auto-backup=false")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "", lastRunTime("crm.users"))
+
+	// One dated backup from the first reset, none with --auto-backup=false
+	backups, err := filepath.Glob(statePath + ".20*")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 1, len(backups))
+}
+
+func TestBackupState(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	testutil.AssertNoError(t, testutil.WriteStateFile(statePath, []types.EntityState{{Entity: "crm.orders", Active: true}}))
+	original, err := os.ReadFile(statePath)
+	testutil.AssertNoError(t, err)
+
+	backupPath := filepath.Join(tmpDir, "state.json.bak")
+	out, err := runCaptured(t, "backup-state", "--state-file", statePath, "--output", backupPath, "--dry-run=false")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Backed up "+statePath+" to "+backupPath) {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+	data, err := os.ReadFile(backupPath)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, string(original), string(data))
+
+	_, err = runCaptured(t, "backup-state", "--state-file", statePath, "--output", "", "--dry-run=false")
+	testutil.AssertNoError(t, err)
+	backups, err := filepath.Glob(statePath + ".20*")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 1, len(backups))
+}
+
+func TestEnvHelp(t *testing.T) {
+	out, err := runCaptured(t, "env-help", "--output", "text")
+	testutil.AssertNoError(t, err)
+	if !strings.HasPrefix(out, "VARIABLE") || !strings.Contains(out, "ORA2CSV_DB_PASSWORD") || !strings.Contains(out, "S3.Bucket") {
+		t.Errorf("unexpected output:\n%s", out)
+	}
+
+	out, err = runCaptured(t, "env-help", "--output", "markdown")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "| `ORA2CSV_DB_PORT` | Database port | `1521` | `DBPort` |") {
+		t.Errorf("unexpected Markdown output:\n%s", out)
+	}
+
+	if _, err := runCaptured(t, "env-help", "--output", "json"); err == nil {
+		t.Error("expected error for an unsupported output format")
+	}
+}
+
+func TestPurge(t *testing.T) {
+	exportDir := t.TempDir()
+	old := "crm.orders__" + time.Now().UTC().AddDate(0, 0, -40).Format("2006-01-02T15-04-05") + ".csv"
+	recent := "crm.orders__" + time.Now().UTC().AddDate(0, 0, -1).Format("2006-01-02T15-04-05") + ".csv"
+	for _, name := range []string{old, recent, "manifest.json"} {
+		testutil.AssertNoError(t, os.WriteFile(filepath.Join(exportDir, name), []byte("id\n1\n"), 0644))
+	}
+
+	run := func(args ...string) (string, error) {
+		return runCaptured(t, append([]string{"purge", "--export-dir", exportDir}, args...)...)
+	}
+
+	if _, err := run("--older-than", "30x", "--dry-run=true"); err == nil {
+		t.Error("expected error for invalid --older-than")
+	}
+
+	out, err := run("--older-than", "30d", "--dry-run=true")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Would delete export file: "+filepath.Join(exportDir, old)) || !strings.Contains(out, "Would purge 1 files, freeing 5 B") {
+		t.Errorf("dry run output:\n%s", out)
+	}
+
+	out, err = run("--older-than", "30d", "--dry-run=false")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Purged 1 files, freed 5 B") {
+		t.Errorf("output missing summary:\n%s", out)
+	}
+	if _, err := os.Stat(filepath.Join(exportDir, old)); !os.IsNotExist(err) {
+		t.Errorf("%s not removed", old)
+	}
+	if _, err := os.Stat(filepath.Join(exportDir, recent)); err != nil {
+		t.Errorf("%s removed: %v", recent, err)
+	}
+
+	// Entity subdirectories are only purged with --output-dir-per-entity
+	entityOld := filepath.Join(exportDir, "crm.orders", old)
+	testutil.AssertNoError(t, os.MkdirAll(filepath.Dir(entityOld), 0755))
+	testutil.AssertNoError(t, os.WriteFile(entityOld, []byte("id\n1\n"), 0644))
+	t.Cleanup(func() { _ = rootCmd.PersistentFlags().Set("output-dir-per-entity", "false") })
+	out, err = run("--older-than", "30d", "--dry-run=false", "--output-dir-per-entity=false")
+	testutil.AssertNoError(t, err)
+	if !strings.Contains(out, "Purged 0 files") {
+		t.Errorf("entity directory purged without --output-dir-per-entity:\n%s", out)
+	}
+	out, err = run("--older-than", "30d", "--dry-run=false", "--output-dir-per-entity=true")
+	testutil.AssertNoError(t, err)
+	if _, err := os.Stat(entityOld); !os.IsNotExist(err) {
+		t.Errorf("%s not removed:\n%s", entityOld, out)
+	}
+}
+
+func TestParseAge(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{"30d", 30 * 24 * time.Hour, false},
+		{"36h", 36 * time.Hour, false},
+		{"0d", 0, true},
+		{"-1h", 0, true},
+		{"d", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := parseAge(tt.input)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("parseAge(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("parseAge(%q) = %s, want %s", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	testutil.AssertEqual(t, "512 B", formatSize(512))
+	testutil.AssertEqual(t, "1.5 KiB", formatSize(1536))
+	testutil.AssertEqual(t, "2.0 GiB", formatSize(2<<30))
+}
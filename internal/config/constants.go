@@ -1,5 +1,8 @@
 package config
 
+// DateTimeLayout is the layout of lastRunTime in the state file and the --since and --until flags
+const DateTimeLayout = "2006-01-02T15:04:05"
+
 const (
 	// Default values
 	DefaultDBHost             = "dbserver"
@@ -10,8 +13,57 @@ const (
 	DefaultSQLDir             = "./sql"
 	DefaultExportDir          = "./export"
 	DefaultDaysBack           = 30
+	MaxDaysBack               = 3650
+	DefaultProgressInterval   = 10000 // rows
 	DefaultConnectTimeoutSecs = 30
 	DefaultQueryTimeoutSecs   = 300 // 5 minutes
+	DefaultConnectRetryCount  = 3
+	DefaultConnectRetryDelay  = 5 // seconds
+	DefaultMaxRetries         = 3
+	DefaultRetryBackoff       = 5  // seconds
+	DefaultPingInterval       = 30 // seconds
+	DefaultFetchSize          = 1000
+	DefaultOTelProtocol       = "grpc"
+	DefaultBloomFilterSize    = 1000000
+	DefaultCSVQuoteChar       = '"'
+	DefaultDelimiter          = ','
+	DefaultHeaderCase         = "upper"
+	DefaultRecordSeparator    = "lf"
+	DefaultGCPSecretVersion   = "latest"
+	DefaultPagerDutyThreshold = 1
+	DefaultParallelism        = 1
+	DefaultWebhookTimeout     = 10 // seconds
+	DefaultLogRotateCount     = 5
+	DefaultFileMode           = 0644
+	DefaultDirMode            = 0755
+
+	// AzureConnectionStringEnv is the standard Azure Storage connection string variable
+	AzureConnectionStringEnv = "AZURE_STORAGE_CONNECTION_STRING"
+
+	// Output file compression
+	CompressNone = "none"
+	CompressGzip = "gzip"
+
+	// BLOB column encodings in export files
+	BlobEncodingHex    = "hex"
+	BlobEncodingBase64 = "base64"
+	BlobEncodingSkip   = "skip"
+
+	// Export file formats
+	FileFormatCSV     = "csv"
+	FileFormatJSONL   = "jsonl"
+	FileFormatParquet = "parquet"
+
+	// DefaultParquetRowGroupMB is the uncompressed data size buffered per Parquet row group
+	DefaultParquetRowGroupMB = 128
+
+	// Log line formats
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+
+	// Output formats for the run summary
+	OutputFormatText = "text"
+	OutputFormatJSON = "json"
 
 	// S3 defaults
 	DefaultS3PartSize = 5 * 1024 * 1024 // 5MB
@@ -29,4 +81,7 @@ const (
 	EnvS3Bucket   = "ORA2CSV_S3_BUCKET"
 	EnvS3Prefix   = "ORA2CSV_S3_PREFIX"
 	EnvS3Endpoint = "ORA2CSV_S3_ENDPOINT"
+
+	// EnvSFTPPassword is the SFTP password, it has no flag so it stays out of the process list
+	EnvSFTPPassword = "ORA2CSV_SFTP_PASSWORD"
 )
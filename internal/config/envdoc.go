@@ -0,0 +1,139 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// EnvVarDoc documents an environment variable read by FromCommand and FromEnvironment
+type EnvVarDoc struct {
+	Name        string
+	Description string
+	Default     string // Empty when unset
+	ConfigField string // Config field set from the variable, e.g. S3.Bucket; empty for none
+}
+
+// EnvVarDocs lists the supported environment variables, printed by the env-help command
+// Every mapstructure key of Config must be listed, which is checked by the tests.
+var EnvVarDocs = []EnvVarDoc{
+	{Name: EnvConfigFile, Description: "YAML or TOML config file (default ./ora2csv.yaml, ./ora2csv.yml or ./ora2csv.toml if present)"},
+	{Name: "ORA2CSV_DB_USER", Description: "Database user", Default: DefaultDBUser, ConfigField: "DBUser"},
+	{Name: EnvDBPassword, Description: "Database password", ConfigField: "DBPassword"},
+	{Name: "ORA2CSV_DB_HOST", Description: "Database host", Default: DefaultDBHost, ConfigField: "DBHost"},
+	{Name: "ORA2CSV_DB_PORT", Description: "Database port", Default: strconv.Itoa(DefaultDBPort), ConfigField: "DBPort"},
+	{Name: "ORA2CSV_DB_SERVICE", Description: "Database service name", Default: DefaultDBService, ConfigField: "DBService"},
+	{Name: "ORA2CSV_DB_EXTERNAL_AUTH", Description: "Use externally-authenticated connection (OS auth, Kerberos)", Default: "false", ConfigField: "DBExternalAuth"},
+	{Name: "ORA2CSV_GCP_SECRET_NAME", Description: "GCP Secret Manager secret holding the database password", ConfigField: "GCPSecretName"},
+	{Name: "ORA2CSV_GCP_PROJECT_ID", Description: "GCP project of the secret (default: metadata server project)", ConfigField: "GCPProjectID"},
+	{Name: "ORA2CSV_GCP_SECRET_VERSION", Description: "GCP secret version to read", Default: DefaultGCPSecretVersion, ConfigField: "GCPSecretVersion"},
+	{Name: "ORA2CSV_FETCH_SIZE", Description: "Rows prefetched per database round-trip (0 uses the driver default)", Default: strconv.Itoa(DefaultFetchSize), ConfigField: "FetchSize"},
+	{Name: "ORA2CSV_LOB_FETCH_INLINE", Description: "Fetch LOB content with the row instead of a separate round-trip", Default: "false", ConfigField: "LOBFetchInline"},
+	{Name: "ORA2CSV_STATE_FILE", Description: "Path to state.json file", Default: DefaultStateFile, ConfigField: "StateFile"},
+	{Name: "ORA2CSV_STATE_FILES", Description: "Comma-separated state files to combine (overrides ORA2CSV_STATE_FILE)", ConfigField: "StateFiles"},
+	{Name: "ORA2CSV_SQL_DIR", Description: "Path to SQL directory", Default: DefaultSQLDir, ConfigField: "SQLDir"},
+	{Name: "ORA2CSV_SQL_ENCRYPTION_KEY", Description: "Hex-encoded AES-256 key for encrypted SQL files", ConfigField: "SQLEncryptionKey"},
+	{Name: "ORA2CSV_SCHEMA_DIR", Description: "Directory with <entity>.columns.txt files defining the output column order", ConfigField: "SchemaDir"},
+	{Name: "ORA2CSV_IGNORE_COLUMN_ADDITIONS", Description: "Drop query columns missing from the column order file instead of failing", Default: "false", ConfigField: "IgnoreColumnAdditions"},
+	{Name: "ORA2CSV_COLUMN_MAP_FILE", Description: "YAML or JSON file renaming columns in the header row, e.g. CREATED_DT: created_at", ConfigField: "ColumnMapFile"},
+	{Name: "ORA2CSV_EXPORT_DIR", Description: "Path to export directory", Default: DefaultExportDir, ConfigField: "ExportDir"},
+	{Name: "ORA2CSV_TEMP_DIR", Description: "Directory for cloud upload temp files with --cloud-temp-file (default: export directory)", ConfigField: "TempDir"},
+	{Name: "ORA2CSV_FILE_MODE", Description: "Permissions for exported files (octal)", Default: fmt.Sprintf("%04o", DefaultFileMode), ConfigField: "FileMode"},
+	{Name: "ORA2CSV_STATE_FILE_MODE", Description: "Permissions for the state file (octal)", Default: fmt.Sprintf("%04o", DefaultFileMode), ConfigField: "StateFileMode"},
+	{Name: "ORA2CSV_DIR_MODE", Description: "Permissions for created directories (octal)", Default: fmt.Sprintf("%04o", DefaultDirMode), ConfigField: "DirMode"},
+	{Name: "ORA2CSV_DAYS_BACK", Description: "Default days to look back for first run", Default: strconv.Itoa(DefaultDaysBack), ConfigField: "DefaultDaysBack"},
+	{Name: "ORA2CSV_DRY_RUN", Description: "Validate without executing (export also estimates row counts)", Default: "false", ConfigField: "DryRun"},
+	{Name: "ORA2CSV_VERBOSE", Description: "Enable verbose logging", Default: "false", ConfigField: "Verbose"},
+	{Name: "ORA2CSV_LOG_FORMAT", Description: "Log line format: text or json", Default: LogFormatText, ConfigField: "LogFormat"},
+	{Name: "ORA2CSV_LOG_FILE", Description: "Also append export and validate logs to this file", ConfigField: "LogFile"},
+	{Name: "ORA2CSV_LOG_ROTATE_SIZE_MB", Description: "Rotate --log-file when it exceeds this many MB (0 disables)", Default: "0", ConfigField: "LogRotateSizeMB"},
+	{Name: "ORA2CSV_LOG_ROTATE_COUNT", Description: "Rotated log files to keep", Default: strconv.Itoa(DefaultLogRotateCount), ConfigField: "LogRotateCount"},
+	{Name: "ORA2CSV_AUTO_DISCOVER", Description: "Export SQL files in the SQL directory that have no state entry yet", Default: "false", ConfigField: "AutoDiscover"},
+	{Name: "ORA2CSV_AUTO_DISCOVER_PREFIX", Description: "Only auto-discover SQL files starting with this prefix", ConfigField: "AutoDiscoverPrefix"},
+	{Name: "ORA2CSV_INCLUDE_INACTIVE", Description: "Also export inactive entities for this run, leaving their state unchanged", Default: "false", ConfigField: "IncludeInactive"},
+	{Name: "ORA2CSV_INCLUDE_TAGS", Description: "Export only entities with one of these tags, e.g. billing,daily (comma-separated)", ConfigField: "IncludeTags"},
+	{Name: "ORA2CSV_EXCLUDE_TAGS", Description: "Skip entities with any of these tags, e.g. experimental (comma-separated)", ConfigField: "ExcludeTags"},
+	{Name: "ORA2CSV_PROGRESS", Description: "Show live row counts on stderr while exporting (terminals only)", Default: "false", ConfigField: "Progress"},
+	{Name: "ORA2CSV_PROGRESS_INTERVAL", Description: "Log the row count of an export every N rows, with an estimate from the previous export (0 disables)", Default: strconv.Itoa(DefaultProgressInterval), ConfigField: "ProgressInterval"},
+	{Name: "ORA2CSV_ENTITY", Description: "Export only this entity", ConfigField: "Entity"},
+	{Name: "ORA2CSV_SKIP_IF_OVERLAPPING", Description: "Skip entities with an existing export file starting inside the current window", Default: "false", ConfigField: "SkipIfOverlapping"},
+	{Name: "ORA2CSV_WRITE_META", Description: "Write a .meta.json file with row count, columns and SHA-256 next to each export file", Default: "false", ConfigField: "WriteMeta"},
+	{Name: "ORA2CSV_WRITE_CHECKSUM", Description: "Write a .sha256 file in sha256sum format next to each export file", Default: "false", ConfigField: "WriteChecksum"},
+	{Name: "ORA2CSV_PAGERDUTY_ROUTING_KEY", Description: "PagerDuty Events API v2 routing key for failure incidents", ConfigField: "PagerDutyRoutingKey"},
+	{Name: "ORA2CSV_PAGERDUTY_THRESHOLD", Description: "Failed entities that trigger a PagerDuty incident", Default: strconv.Itoa(DefaultPagerDutyThreshold), ConfigField: "PagerDutyThreshold"},
+	{Name: "ORA2CSV_WEBHOOK_URL", Description: "URL to POST the JSON run result to when the export finishes", ConfigField: "WebhookURL"},
+	{Name: "ORA2CSV_WEBHOOK_ON_FAILURE", Description: "Only call the webhook when the run or an entity fails", Default: "false", ConfigField: "WebhookOnFailure"},
+	{Name: "ORA2CSV_WEBHOOK_TIMEOUT", Description: "Webhook request timeout", Default: (DefaultWebhookTimeout * time.Second).String(), ConfigField: "WebhookTimeout"},
+	{Name: "ORA2CSV_METRICS_ADDR", Description: "Serve Prometheus metrics at /metrics on this address while exporting, e.g. :9090", ConfigField: "MetricsAddr"},
+	{Name: "ORA2CSV_OTEL_ENDPOINT", Description: "Send OpenTelemetry traces to this OTLP collector, e.g. localhost:4317", ConfigField: "OTelEndpoint"},
+	{Name: "ORA2CSV_OTEL_PROTOCOL", Description: "OTLP protocol: grpc or http", Default: DefaultOTelProtocol, ConfigField: "OTelProtocol"},
+	{Name: "ORA2CSV_CLOUD_TEMP_FILE", Description: "Write cloud exports to a temp file before uploading instead of streaming them", Default: "false", ConfigField: "CloudTempFile"},
+	{Name: "ORA2CSV_ALSO_LOCAL_PATH", Description: "Also write each cloud export to this directory, e.g. to verify files before archiving", ConfigField: "AlsoLocalPath"},
+	{Name: "ORA2CSV_SINCE", Description: "Export from this time instead of lastRunTime, e.g. 2024-01-01T00:00:00 (state is not updated)", ConfigField: "Since"},
+	{Name: "ORA2CSV_UNTIL", Description: "Export up to this time instead of now, e.g. 2024-12-31T23:59:59 (state is not updated)", ConfigField: "Until"},
+	{Name: "ORA2CSV_OUTPUT_FORMAT", Description: "Run summary format: text or json (json is printed to stdout, logs go to stderr)", Default: OutputFormatText, ConfigField: "OutputFormat"},
+	{Name: "ORA2CSV_RESULT_FILE", Description: "Write the complete export result as JSON to this file after the run, - for stdout", ConfigField: "ResultFile"},
+	{Name: "ORA2CSV_HEADER_CASE", Description: "Header row case: upper, lower, title, snake, asis or original", Default: DefaultHeaderCase, ConfigField: "HeaderCase"},
+	{Name: "ORA2CSV_COMPRESS", Description: "Compress output files: none or gzip (adds a .gz suffix)", ConfigField: "Compress"},
+	{Name: "ORA2CSV_FILE_FORMAT", Description: "Export file format: csv, jsonl (one JSON object per row, NULL as null) or parquet", Default: FileFormatCSV, ConfigField: "FileFormat"},
+	{Name: "ORA2CSV_PARQUET_ROW_GROUP_MB", Description: "Uncompressed data size of a Parquet row group, buffered in memory", Default: strconv.Itoa(DefaultParquetRowGroupMB), ConfigField: "ParquetRowGroupMB"},
+	{Name: "ORA2CSV_PARALLELISM", Description: "Number of entities exported at the same time", Default: strconv.Itoa(DefaultParallelism), ConfigField: "Parallelism"},
+	{Name: "ORA2CSV_SCHEMA_LIMIT", Description: "Maximum concurrent exports per schema as comma-separated SCHEMA=N items", ConfigField: "SchemaParallelismLimits"},
+	{Name: "ORA2CSV_PARAM", Description: "Bind variables and SQL template values for every entity as comma-separated key=value items", ConfigField: "GlobalParams"},
+	{Name: "ORA2CSV_FILE_EXTENSION", Description: "Output file extension (default: csv, tsv for a tab delimiter, jsonl)", ConfigField: "FileExtension"},
+	{Name: "ORA2CSV_CSV_QUOTE_CHAR", Description: "Character used to quote CSV fields", Default: string(DefaultCSVQuoteChar), ConfigField: "CSVQuoteChar"},
+	{Name: "ORA2CSV_DELIMITER", Description: "CSV field delimiter: a single character such as , | ; or tab", Default: string(DefaultDelimiter), ConfigField: "Delimiter"},
+	{Name: "ORA2CSV_RECORD_SEPARATOR", Description: "CSV record separator: lf, crlf, rs or a hex value such as \\x1E", Default: DefaultRecordSeparator, ConfigField: "RecordSeparator"},
+	{Name: "ORA2CSV_FILENAME_TEMPLATE", Description: "Export file name template with {{.Entity}}, {{.StartDate}}, {{.TillDate}} and {{.RunID}}", Default: DefaultFilenameTemplate, ConfigField: "FilenameTemplate"},
+	{Name: "ORA2CSV_OUTPUT_DIR_PER_ENTITY", Description: "Write export files to <export-dir>/<entity>/ subdirectories", Default: "false", ConfigField: "OutputPerEntityDir"},
+	{Name: "ORA2CSV_BLOB_ENCODING", Description: "BLOB and LONG RAW column encoding: hex, base64 or skip (written as NULL)", Default: BlobEncodingHex, ConfigField: "BlobEncoding"},
+	{Name: "ORA2CSV_CLOB_MAX_LENGTH", Description: "Truncate CLOB values to this many characters (0 disables)", Default: "0", ConfigField: "ClobMaxLength"},
+	{Name: "ORA2CSV_ENABLE_RESULT_CACHE", Description: "Add the Oracle RESULT_CACHE hint to export queries", Default: "false", ConfigField: "EnableResultCache"},
+	{Name: "ORA2CSV_RESULT_CACHE_ENTITIES", Description: "Comma-separated entities that use the result cache hint (default: all)", ConfigField: "ResultCacheEntities"},
+	{Name: "ORA2CSV_DEDUP_OUTPUT", Description: "Skip rows already exported in previous runs (bloom filter)", Default: "false", ConfigField: "DeduplicateOutput"},
+	{Name: "ORA2CSV_BLOOM_FILTER_SIZE", Description: "Expected number of rows tracked by the dedup bloom filter", Default: strconv.Itoa(DefaultBloomFilterSize), ConfigField: "BloomFilterSize"},
+	{Name: "ORA2CSV_MAX_RESULT_SET_ROWS", Description: "Fail an entity whose query returns more rows than this (0 is unlimited)", Default: "0", ConfigField: "MaxResultSetRows"},
+	{Name: "ORA2CSV_MAX_ROWS_GLOBAL", Description: "Fail entities once the run has exported this many rows in total (0 is unlimited)", Default: "0", ConfigField: "MaxRowsGlobal"},
+	{Name: "ORA2CSV_MAX_ROWS_PER_FILE", Description: "Split export files into <name>__part001, __part002, ... of at most this many rows (0 disables)", Default: "0", ConfigField: "MaxRowsPerFile"},
+	{Name: "ORA2CSV_MAX_FILE_SIZE_MB", Description: "Fail an entity whose output file exceeds this many MB, with --max-rows-per-file start a new part instead (0 is unlimited)", Default: "0", ConfigField: "DefaultMaxFileSizeMB"},
+	{Name: "ORA2CSV_MAX_MEMORY_MB", Description: "Fail an entity when the heap exceeds this many MB (0 disables)", Default: "0", ConfigField: "MaxMemoryMB"},
+	{Name: "ORA2CSV_FETCH_ROWS_HINT", Description: "Append a \"-- ROWS=<n>\" fetch size comment to export queries (0 disables)", Default: "0", ConfigField: "FetchRowsHint"},
+	{Name: "ORA2CSV_CONNECT_TIMEOUT", Description: "Connection timeout", Default: (DefaultConnectTimeoutSecs * time.Second).String(), ConfigField: "ConnectTimeout"},
+	{Name: "ORA2CSV_QUERY_TIMEOUT", Description: "Query timeout", Default: (DefaultQueryTimeoutSecs * time.Second).String(), ConfigField: "QueryTimeout"},
+	{Name: "ORA2CSV_ORACLE_SESSION_TIMEOUT", Description: "Driver-side call timeout, go-ora interrupts queries running longer on the server, greater than --query-timeout (0 disables)", Default: "0s", ConfigField: "OracleSessionTimeout"},
+	{Name: "ORA2CSV_ORACLE_SESSION_PARAM", Description: "Session parameters set with ALTER SESSION on every connection as semicolon-separated NAME=value items", ConfigField: "OracleSessionParams"},
+	{Name: "ORA2CSV_CONNECT_RETRY_COUNT", Description: "Connection retries on startup for transient errors (0 disables)", Default: strconv.Itoa(DefaultConnectRetryCount), ConfigField: "ConnectRetryCount"},
+	{Name: "ORA2CSV_CONNECT_RETRY_DELAY", Description: "Delay before the first connection retry, doubled after each retry", Default: (DefaultConnectRetryDelay * time.Second).String(), ConfigField: "ConnectRetryDelay"},
+	{Name: "ORA2CSV_MAX_RETRIES", Description: "Query retries for transient errors such as a lost connection (0 disables)", Default: strconv.Itoa(DefaultMaxRetries), ConfigField: "MaxRetries"},
+	{Name: "ORA2CSV_RETRY_BACKOFF", Description: "Delay before the first query retry, doubled after each retry", Default: (DefaultRetryBackoff * time.Second).String(), ConfigField: "RetryBackoff"},
+	{Name: "ORA2CSV_WALLET_REFRESH_INTERVAL", Description: "Reconnect at this interval to re-read Oracle Wallet files (0 disables)", Default: "0s", ConfigField: "WalletRefreshInterval"},
+	{Name: "ORA2CSV_PING_INTERVAL", Description: "Ping the database at this interval while exporting and reconnect when the connection is lost (0 disables)", Default: (DefaultPingInterval * time.Second).String(), ConfigField: "PingInterval"},
+	{Name: EnvS3Bucket, Description: "S3 bucket name", ConfigField: "S3.Bucket"},
+	{Name: EnvS3Prefix, Description: "S3 key prefix", ConfigField: "S3.Prefix"},
+	{Name: "ORA2CSV_S3_ACCESS_KEY", Description: "S3 access key (for S3-compatible services)", ConfigField: "S3.AccessKey"},
+	{Name: "ORA2CSV_S3_SECRET_KEY", Description: "S3 secret key (for S3-compatible services)", ConfigField: "S3.SecretKey"},
+	{Name: "ORA2CSV_S3_SESSION_TOKEN", Description: "S3 session token (for S3-compatible services)", ConfigField: "S3.SessionToken"},
+	{Name: EnvS3Endpoint, Description: "S3 endpoint URL (for S3-compatible services like MinIO)", ConfigField: "S3.Endpoint"},
+	{Name: "ORA2CSV_S3_TRACK_VERSIONS", Description: "Record state file S3 versions to restore from on corruption", Default: "false", ConfigField: "S3.TrackVersions"},
+	{Name: "ORA2CSV_S3_SSE", Description: "S3 server-side encryption: AES256 or aws:kms (default: bucket setting)", ConfigField: "S3.SSE"},
+	{Name: "ORA2CSV_S3_KMS_KEY_ID", Description: "KMS key ID for S3 aws:kms encryption (implies --s3-sse aws:kms)", ConfigField: "S3.SSEKMSKeyID"},
+	{Name: "ORA2CSV_S3_STORAGE_CLASS", Description: "S3 storage class of exports and the state file, e.g. STANDARD_IA (default: bucket setting)", ConfigField: "S3.StorageClass"},
+	{Name: "ORA2CSV_AZURE_CONNECTION_STRING", Description: "Azure Storage connection string, overrides AZURE_STORAGE_CONNECTION_STRING", ConfigField: "AzureConnectionString"},
+	{Name: AzureConnectionStringEnv, Description: "Azure Storage connection string", ConfigField: "AzureConnectionString"},
+	{Name: "ORA2CSV_AZURE_CONTAINER", Description: "Azure Blob Storage container (enables Azure storage instead of S3)", ConfigField: "AzureContainerName"},
+	{Name: "ORA2CSV_AZURE_PREFIX", Description: "Azure blob name prefix", ConfigField: "AzurePrefix"},
+	{Name: "ORA2CSV_GCS_BUCKET", Description: "Google Cloud Storage bucket (enables GCS storage instead of S3)", ConfigField: "GCSBucket"},
+	{Name: "ORA2CSV_GCS_PREFIX", Description: "GCS object name prefix", ConfigField: "GCSPrefix"},
+	{Name: "ORA2CSV_SFTP_HOST", Description: "SFTP server (enables SFTP storage instead of S3, password from ORA2CSV_SFTP_PASSWORD)", ConfigField: "SFTP.Host"},
+	{Name: "ORA2CSV_SFTP_PORT", Description: "SFTP server port", Default: strconv.Itoa(DefaultSFTPPort), ConfigField: "SFTP.Port"},
+	{Name: "ORA2CSV_SFTP_USER", Description: "SFTP user", ConfigField: "SFTP.User"},
+	{Name: "ORA2CSV_SFTP_KEY", Description: "SFTP private key file (preferred over the password)", ConfigField: "SFTP.PrivateKeyPath"},
+	{Name: EnvSFTPPassword, Description: "SFTP password, only read from the environment", ConfigField: "SFTP.Password"},
+	{Name: "ORA2CSV_SFTP_PATH", Description: "SFTP directory for exports and the state file (default: login directory)", ConfigField: "SFTP.RemotePath"},
+	{Name: "ORA2CSV_SFTP_KNOWN_HOSTS", Description: "known_hosts file to verify the SFTP server (default: ~/.ssh/known_hosts)", ConfigField: "SFTP.KnownHostsPath"},
+	{Name: "GOOGLE_OAUTH_ACCESS_TOKEN", Description: "GCS OAuth access token, e.g. from gcloud auth print-access-token"},
+	{Name: "GOOGLE_APPLICATION_CREDENTIALS", Description: "GCS service account or authorized user key file (default: metadata server)"},
+	{Name: "AWS_ACCESS_KEY_ID", Description: "AWS access key, read by the AWS SDK with the other standard AWS variables"},
+	{Name: "AWS_SECRET_ACCESS_KEY", Description: "AWS secret key, read by the AWS SDK"},
+	{Name: "AWS_REGION", Description: "AWS region, read by the AWS SDK"},
+}
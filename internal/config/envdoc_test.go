@@ -0,0 +1,58 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEnvVarDocs(t *testing.T) {
+	documented := make(map[string]bool, len(EnvVarDocs))
+	for _, doc := range EnvVarDocs {
+		if documented[doc.Name] {
+			t.Errorf("%s is documented twice", doc.Name)
+		}
+		documented[doc.Name] = true
+		if doc.Description == "" {
+			t.Errorf("%s has no description", doc.Name)
+		}
+		if doc.ConfigField != "" && !hasConfigField(reflect.TypeOf(Config{}), doc.ConfigField) {
+			t.Errorf("%s maps to unknown config field %s", doc.Name, doc.ConfigField)
+		}
+	}
+
+	// Every setting read from the environment must be documented
+	for _, key := range mapstructureKeys(reflect.TypeOf(Config{})) {
+		if name := EnvPrefix + "_" + strings.ToUpper(key); !documented[name] {
+			t.Errorf("%s is not documented in EnvVarDocs", name)
+		}
+	}
+}
+
+// hasConfigField reports whether path, e.g. S3.Bucket, names a field of t
+func hasConfigField(t reflect.Type, path string) bool {
+	for _, name := range strings.Split(path, ".") {
+		field, ok := t.FieldByName(name)
+		if !ok {
+			return false
+		}
+		t = field.Type
+	}
+	return true
+}
+
+// mapstructureKeys returns the mapstructure keys of t, including squashed structs
+func mapstructureKeys(t reflect.Type) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("mapstructure")
+		switch {
+		case tag == ",squash":
+			keys = append(keys, mapstructureKeys(field.Type)...)
+		case tag != "" && tag != "-":
+			keys = append(keys, tag)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,144 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadEnvFile sets environment variables from a .env file of KEY=value lines
+// Blank lines and # comments are ignored, a line may start with "export ", and values may be
+// double-quoted (with \n, \t, \" and \\ escapes), single-quoted (literal) or unquoted, where a
+// # after whitespace starts a comment. Variables already set in the environment are kept, so
+// the shell overrides the file.
+func LoadEnvFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to read env file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	scanner := bufio.NewScanner(file)
+	line := 0
+	for scanner.Scan() {
+		line++
+		key, value, ok, err := parseEnvLine(scanner.Text())
+		if err != nil {
+			return fmt.Errorf("invalid env file %s line %d: %w", path, line, err)
+		}
+		if !ok {
+			continue
+		}
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("failed to set %s from env file: %w", key, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read env file %s: %w", path, err)
+	}
+	return nil
+}
+
+// parseEnvLine returns the variable of a .env line, ok is false for blank and comment lines
+func parseEnvLine(text string) (key, value string, ok bool, err error) {
+	text = strings.TrimSpace(text)
+	if text == "" || strings.HasPrefix(text, "#") {
+		return "", "", false, nil
+	}
+	if rest, found := strings.CutPrefix(text, "export"); found && (rest == "" || rest[0] == ' ' || rest[0] == '\t') {
+		text = strings.TrimSpace(rest)
+	}
+
+	key, raw, found := strings.Cut(text, "=")
+	key = strings.TrimSpace(key)
+	if !found {
+		return "", "", false, fmt.Errorf("missing = in %q", text)
+	}
+	if !isEnvName(key) {
+		return "", "", false, fmt.Errorf("invalid variable name %q", key)
+	}
+
+	value, err = parseEnvValue(strings.TrimSpace(raw))
+	if err != nil {
+		return "", "", false, fmt.Errorf("%s: %w", key, err)
+	}
+	return key, value, true, nil
+}
+
+// parseEnvValue unquotes a .env value and strips a trailing comment
+func parseEnvValue(raw string) (string, error) {
+	if raw == "" {
+		return "", nil
+	}
+
+	var value strings.Builder
+	var rest string
+	switch quote := raw[0]; quote {
+	case '"':
+		closed := false
+		for i := 1; i < len(raw); i++ {
+			c := raw[i]
+			if c == '\\' && i+1 < len(raw) {
+				i++
+				switch raw[i] {
+				case 'n':
+					value.WriteByte('\n')
+				case 't':
+					value.WriteByte('\t')
+				case '"', '\\':
+					value.WriteByte(raw[i])
+				default:
+					value.WriteByte('\\')
+					value.WriteByte(raw[i])
+				}
+				continue
+			}
+			if c == '"' {
+				closed, rest = true, raw[i+1:]
+				break
+			}
+			value.WriteByte(c)
+		}
+		if !closed {
+			return "", fmt.Errorf("unterminated double quote")
+		}
+	case '\'':
+		end := strings.IndexByte(raw[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single quote")
+		}
+		value.WriteString(raw[1 : end+1])
+		rest = raw[end+2:]
+	default:
+		// A # only starts a comment after whitespace, so values like pass#word are kept
+		for i := 1; i < len(raw); i++ {
+			if raw[i] == '#' && (raw[i-1] == ' ' || raw[i-1] == '\t') {
+				raw = raw[:i]
+				break
+			}
+		}
+		return strings.TrimSpace(raw), nil
+	}
+
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", fmt.Errorf("unexpected text after quoted value: %q", rest)
+	}
+	return value.String(), nil
+}
+
+// isEnvName reports whether name is a valid environment variable name
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c != '_' && !(c >= 'A' && c <= 'Z') && !(c >= 'a' && c <= 'z') && !(i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// unsetEnv clears variables for a test and restores them afterwards
+func unsetEnv(t *testing.T, keys ...string) {
+	t.Helper()
+	for _, key := range keys {
+		t.Setenv(key, "")
+		if err := os.Unsetenv(key); err != nil {
+			t.Fatalf("Unsetenv() error = %v", err)
+		}
+	}
+}
+
+func writeEnvFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), ".env")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	return path
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	unsetEnv(t, "ENVFILE_PLAIN", "ENVFILE_EXPORTED", "ENVFILE_DOUBLE", "ENVFILE_SINGLE", "ENVFILE_HASH", "ENVFILE_EMPTY")
+	t.Setenv("ENVFILE_PRESET", "from-shell")
+
+	path := writeEnvFile(t, `# Database settings
+ENVFILE_PLAIN=plain value # comment
+
+export ENVFILE_EXPORTED=exported
+ENVFILE_DOUBLE="line1\nline2 \"quoted\"" # comment
+ENVFILE_SINGLE='literal \n $value'
+ENVFILE_HASH=pass#word
+ENVFILE_EMPTY=
+ENVFILE_PRESET=from-file
+`)
+	if err := LoadEnvFile(path); err != nil {
+		t.Fatalf("LoadEnvFile() error = %v", err)
+	}
+
+	want := map[string]string{
+		"ENVFILE_PLAIN":    "plain value",
+		"ENVFILE_EXPORTED": "exported",
+		"ENVFILE_DOUBLE":   "line1\nline2 \"quoted\"",
+		"ENVFILE_SINGLE":   `literal \n $value`,
+		"ENVFILE_HASH":     "pass#word",
+		"ENVFILE_EMPTY":    "",
+		"ENVFILE_PRESET":   "from-shell",
+	}
+	for key, value := range want {
+		got, ok := os.LookupEnv(key)
+		if !ok {
+			t.Errorf("%s is not set", key)
+			continue
+		}
+		if got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
+func TestLoadEnvFile_Invalid(t *testing.T) {
+	unsetEnv(t, "ENVFILE_OK")
+
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing equals", "ENVFILE_OK=1\nNOT_A_VARIABLE\n"},
+		{"invalid name", "1BAD=value\n"},
+		{"unterminated double quote", "ENVFILE_QUOTE=\"value\n"},
+		{"unterminated single quote", "ENVFILE_QUOTE='value\n"},
+		{"text after quote", "ENVFILE_QUOTE=\"value\" extra\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := LoadEnvFile(writeEnvFile(t, tt.content)); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+
+	if err := LoadEnvFile(filepath.Join(t.TempDir(), "missing.env")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestFromCommand_EnvFile(t *testing.T) {
+	unsetEnv(t, EnvPrefix+"_DB_HOST", EnvPrefix+"_DB_PORT")
+
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("env-file", "", "")
+	cmd.Flags().String("db-host", DefaultDBHost, "")
+	cmd.Flags().Int("db-port", DefaultDBPort, "")
+	path := writeEnvFile(t, EnvPrefix+"_DB_HOST=db.example.com\n"+EnvPrefix+"_DB_PORT=1522\n")
+	if err := cmd.Flags().Set("env-file", path); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	cfg, err := FromCommand(cmd)
+	if err != nil {
+		t.Fatalf("FromCommand() error = %v", err)
+	}
+	if cfg.DBHost != "db.example.com" {
+		t.Errorf("DBHost = %q, want db.example.com", cfg.DBHost)
+	}
+	if cfg.DBPort != 1522 {
+		t.Errorf("DBPort = %d, want 1522", cfg.DBPort)
+	}
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvConfigFile names the config file when --config-file is not given
+const EnvConfigFile = "ORA2CSV_CONFIG_FILE"
+
+// ConfigFileNames are looked up in the working directory when no config file is named
+var ConfigFileNames = []string{"ora2csv.yaml", "ora2csv.yml", "ora2csv.toml"}
+
+// findConfigFile returns the named config file, or the first of ConfigFileNames in the
+// working directory; empty when there is none
+func findConfigFile(name string) (string, error) {
+	if name == "" {
+		name = os.Getenv(EnvConfigFile)
+	}
+	if name != "" {
+		if _, err := os.Stat(name); err != nil {
+			return "", fmt.Errorf("failed to read config file: %w", err)
+		}
+		return name, nil
+	}
+
+	for _, candidate := range ConfigFileNames {
+		info, err := os.Stat(candidate)
+		if err == nil && !info.IsDir() {
+			return candidate, nil
+		}
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("failed to check config file %s: %w", candidate, err)
+		}
+	}
+	return "", nil
+}
+
+// readConfigFile loads a YAML or TOML config file below flags and environment variables
+// Keys are the same as the environment variable suffixes, e.g. db_host or s3_bucket.
+func readConfigFile(v *viper.Viper, path string) error {
+	file := viper.New()
+	file.SetConfigFile(path)
+	if err := file.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read config file %s: %w", path, err)
+	}
+
+	settings := file.AllSettings()
+	// Unquoted YAML and TOML octal numbers are decoded as integers, modes are octal strings
+	for _, key := range []string{"file_mode", "state_file_mode", "dir_mode"} {
+		switch mode := settings[key].(type) {
+		case int:
+			settings[key] = fmt.Sprintf("%04o", mode)
+		case int64:
+			settings[key] = fmt.Sprintf("%04o", mode)
+		}
+	}
+	if err := v.MergeConfigMap(settings); err != nil {
+		return fmt.Errorf("failed to load config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// envConflicts returns the config file keys also set by their ORA2CSV_<KEY> environment variable
+func envConflicts(v *viper.Viper) []string {
+	var conflicts []string
+	for _, key := range v.AllKeys() {
+		if !v.InConfig(key) {
+			continue
+		}
+		if _, ok := os.LookupEnv(EnvPrefix + "_" + strings.ToUpper(key)); ok {
+			conflicts = append(conflicts, key)
+		}
+	}
+	sort.Strings(conflicts)
+	return conflicts
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// DefaultFilenameTemplate names export files <entity>__<startDate>.csv
+const DefaultFilenameTemplate = "{{.Entity}}__{{.StartDate}}.csv"
+
+// FilenameData is the data available to FilenameTemplate
+// Dates use the 2006-01-02T15-04-05 layout so they are safe in file names.
+type FilenameData struct {
+	Entity    string
+	StartDate string
+	TillDate  string
+	RunID     string
+}
+
+// RenderFilename evaluates an output filename template, DefaultFilenameTemplate when empty
+// The result must be a relative path inside the export directory.
+func RenderFilename(tmpl string, data FilenameData) (string, error) {
+	if tmpl == "" {
+		tmpl = DefaultFilenameTemplate
+	}
+	t, err := template.New("filename").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("invalid filename template: %w", err)
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("invalid filename template: %w", err)
+	}
+	name := filepath.FromSlash(b.String())
+	if !filepath.IsLocal(name) || strings.HasSuffix(b.String(), "/") {
+		return "", fmt.Errorf("filename template produced %q, want a relative file path", b.String())
+	}
+	return name, nil
+}
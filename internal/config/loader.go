@@ -2,14 +2,44 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+
+	apperrors "github.com/koltyakov/ora2csv/pkg/errors"
 )
 
-// FromCommand loads configuration from cobra command flags and environment variables
-func FromCommand(cmd *cobra.Command) (*Config, error) {
+// FromCommand loads configuration from cobra command flags, environment variables and a config file
+// Flags take precedence over environment variables, which take precedence over the config file.
+// Any failure, including a panic in flag handling, is returned as a config AppError
+func FromCommand(cmd *cobra.Command) (cfg *Config, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			cfg = nil
+			err = apperrors.NewConfigError("FromCommand", "panic while loading configuration", fmt.Errorf("%v", r))
+		}
+	}()
+
+	cfg, err = fromCommand(cmd)
+	if err != nil {
+		return nil, apperrors.NewConfigError("FromCommand", "invalid flag or environment value", err)
+	}
+	return cfg, nil
+}
+
+// fromCommand binds flags and environment variables and decodes them into a Config
+func fromCommand(cmd *cobra.Command) (*Config, error) {
+	// The env file only adds variables, before anything reads the environment
+	if flag := cmd.Flags().Lookup("env-file"); flag != nil && flag.Value.String() != "" {
+		if err := LoadEnvFile(flag.Value.String()); err != nil {
+			return nil, err
+		}
+	}
+
 	v := viper.New()
 
 	// Bind flags to viper
@@ -21,14 +51,91 @@ func FromCommand(cmd *cobra.Command) (*Config, error) {
 		{"db-port", "db_port"},
 		{"db-service", "db_service"},
 		{"db-user", "db_user"},
+		{"db-external-auth", "db_external_auth"},
+		{"gcp-secret-name", "gcp_secret_name"},
+		{"gcp-project-id", "gcp_project_id"},
+		{"gcp-secret-version", "gcp_secret_version"},
+		{"fetch-size", "fetch_size"},
+		{"lob-fetch-inline", "lob_fetch_inline"},
 		{"state-file", "state_file"},
+		{"state-files", "state_files"},
 		{"sql-dir", "sql_dir"},
 		{"export-dir", "export_dir"},
+		{"temp-dir", "temp_dir"},
+		{"file-mode", "file_mode"},
+		{"state-file-mode", "state_file_mode"},
+		{"dir-mode", "dir_mode"},
 		{"days-back", "days_back"},
 		{"dry-run", "dry_run"},
 		{"verbose", "verbose"},
+		{"log-format", "log_format"},
+		{"log-file", "log_file"},
+		{"log-rotate-size-mb", "log_rotate_size_mb"},
+		{"log-rotate-count", "log_rotate_count"},
+		{"output-format", "output_format"},
+		{"result-file", "result_file"},
+		{"auto-discover", "auto_discover"},
+		{"auto-discover-prefix", "auto_discover_prefix"},
+		{"include-inactive", "include_inactive"},
+		{"include-tags", "include_tags"},
+		{"exclude-tags", "exclude_tags"},
+		{"progress", "progress"},
+		{"progress-interval", "progress_interval"},
+		{"write-meta", "write_meta"},
+		{"write-checksum", "write_checksum"},
+		{"entity", "entity"},
+		{"skip-if-overlapping", "skip_if_overlapping"},
+		{"pagerduty-routing-key", "pagerduty_routing_key"},
+		{"pagerduty-threshold", "pagerduty_threshold"},
+		{"webhook-url", "webhook_url"},
+		{"webhook-on-failure", "webhook_on_failure"},
+		{"webhook-timeout", "webhook_timeout"},
+		{"metrics-addr", "metrics_addr"},
+		{"otel-endpoint", "otel_endpoint"},
+		{"otel-protocol", "otel_protocol"},
+		{"cloud-temp-file", "cloud_temp_file"},
+		{"also-local-path", "also_local_path"},
+		{"since", "since"},
+		{"until", "until"},
+		{"header-case", "header_case"},
+		{"max-memory-mb", "max_memory_mb"},
+		{"max-result-set-rows", "max_result_set_rows"},
+		{"max-rows-global", "max_rows_global"},
+		{"max-rows-per-file", "max_rows_per_file"},
+		{"max-file-size-mb", "max_file_size_mb"},
+		{"fetch-rows-hint", "fetch_rows_hint"},
 		{"connect-timeout", "connect_timeout"},
+		{"connect-retry-count", "connect_retry_count"},
+		{"connect-retry-delay", "connect_retry_delay"},
+		{"max-retries", "max_retries"},
+		{"retry-backoff", "retry_backoff"},
+		{"wallet-refresh-interval", "wallet_refresh_interval"},
+		{"ping-interval", "ping_interval"},
 		{"query-timeout", "query_timeout"},
+		{"oracle-session-timeout", "oracle_session_timeout"},
+		{"oracle-session-param", "oracle_session_param"},
+		{"dedup-output", "dedup_output"},
+		{"bloom-filter-size", "bloom_filter_size"},
+		{"csv-quote-char", "csv_quote_char"},
+		{"delimiter", "delimiter"},
+		{"file-extension", "file_extension"},
+		{"filename-template", "filename_template"},
+		{"output-dir-per-entity", "output_dir_per_entity"},
+		{"blob-encoding", "blob_encoding"},
+		{"clob-max-length", "clob_max_length"},
+		{"record-separator", "record_separator"},
+		{"compress", "compress"},
+		{"file-format", "file_format"},
+		{"parquet-row-group-mb", "parquet_row_group_mb"},
+		{"sql-encryption-key", "sql_encryption_key"},
+		{"schema-dir", "schema_dir"},
+		{"ignore-column-additions", "ignore_column_additions"},
+		{"column-map-file", "column_map_file"},
+		{"enable-result-cache", "enable_result_cache"},
+		{"result-cache-entities", "result_cache_entities"},
+		{"parallelism", "parallelism"},
+		{"schema-limit", "schema_limit"},
+		{"param", "param"},
 		// S3 flags (note: auth flags kept for non-AWS S3-compatible services)
 		{"s3-bucket", "s3_bucket"},
 		{"s3-prefix", "s3_prefix"},
@@ -36,6 +143,23 @@ func FromCommand(cmd *cobra.Command) (*Config, error) {
 		{"s3-secret-key", "s3_secret_key"},
 		{"s3-session-token", "s3_session_token"},
 		{"s3-endpoint", "s3_endpoint"},
+		{"s3-track-versions", "s3_track_versions"},
+		{"s3-sse", "s3_sse"},
+		{"s3-sse-kms-key-id", "s3_kms_key_id"},
+		{"s3-storage-class", "s3_storage_class"},
+		// Azure flags (the connection string is only read from the environment)
+		{"azure-container", "azure_container"},
+		{"azure-prefix", "azure_prefix"},
+		// GCS flags
+		{"gcs-bucket", "gcs_bucket"},
+		{"gcs-prefix", "gcs_prefix"},
+		// SFTP flags (the password is only read from the environment)
+		{"sftp-host", "sftp_host"},
+		{"sftp-port", "sftp_port"},
+		{"sftp-user", "sftp_user"},
+		{"sftp-key", "sftp_key"},
+		{"sftp-path", "sftp_path"},
+		{"sftp-known-hosts", "sftp_known_hosts"},
 	}
 
 	for _, f := range flags {
@@ -68,18 +192,71 @@ func FromCommand(cmd *cobra.Command) (*Config, error) {
 	v.SetDefault("db_port", DefaultDBPort)
 	v.SetDefault("db_service", DefaultDBService)
 	v.SetDefault("db_user", DefaultDBUser)
+	v.SetDefault("gcp_secret_version", DefaultGCPSecretVersion)
+	v.SetDefault("sftp_port", DefaultSFTPPort)
+	v.SetDefault("sftp_password", "")
+	v.SetDefault("fetch_size", DefaultFetchSize)
+	v.SetDefault("otel_protocol", DefaultOTelProtocol)
 	v.SetDefault("state_file", DefaultStateFile)
 	v.SetDefault("sql_dir", DefaultSQLDir)
 	v.SetDefault("export_dir", DefaultExportDir)
+	v.SetDefault("file_mode", fmt.Sprintf("%04o", DefaultFileMode))
+	v.SetDefault("state_file_mode", fmt.Sprintf("%04o", DefaultFileMode))
+	v.SetDefault("dir_mode", fmt.Sprintf("%04o", DefaultDirMode))
 	v.SetDefault("days_back", DefaultDaysBack)
 	v.SetDefault("dry_run", false)
 	v.SetDefault("verbose", false)
+	v.SetDefault("log_format", LogFormatText)
+	v.SetDefault("log_file", "")
+	v.SetDefault("log_rotate_size_mb", 0)
+	v.SetDefault("log_rotate_count", DefaultLogRotateCount)
+	v.SetDefault("output_format", OutputFormatText)
+	v.SetDefault("header_case", DefaultHeaderCase)
 	v.SetDefault("connect_timeout", DefaultConnectTimeoutSecs*time.Second)
+	v.SetDefault("connect_retry_count", DefaultConnectRetryCount)
+	v.SetDefault("connect_retry_delay", DefaultConnectRetryDelay*time.Second)
+	v.SetDefault("max_retries", DefaultMaxRetries)
+	v.SetDefault("retry_backoff", DefaultRetryBackoff*time.Second)
+	v.SetDefault("ping_interval", DefaultPingInterval*time.Second)
 	v.SetDefault("query_timeout", DefaultQueryTimeoutSecs*time.Second)
+	v.SetDefault("dedup_output", false)
+	v.SetDefault("bloom_filter_size", DefaultBloomFilterSize)
+	v.SetDefault("progress_interval", DefaultProgressInterval)
+	v.SetDefault("csv_quote_char", string(DefaultCSVQuoteChar))
+	v.SetDefault("delimiter", string(DefaultDelimiter))
+	v.SetDefault("record_separator", DefaultRecordSeparator)
+	v.SetDefault("file_format", FileFormatCSV)
+	v.SetDefault("parquet_row_group_mb", DefaultParquetRowGroupMB)
+	v.SetDefault("filename_template", DefaultFilenameTemplate)
+	v.SetDefault("blob_encoding", BlobEncodingHex)
+	v.SetDefault("enable_result_cache", false)
+	v.SetDefault("parallelism", DefaultParallelism)
+	v.SetDefault("pagerduty_threshold", DefaultPagerDutyThreshold)
+	v.SetDefault("webhook_on_failure", false)
+	v.SetDefault("webhook_timeout", DefaultWebhookTimeout*time.Second)
 
 	// S3 defaults
 	// No defaults - using AWS SDK default region and credential chain
 
+	// Config file values apply below flags and environment variables
+	var configFile string
+	if flag := cmd.Flags().Lookup("config-file"); flag != nil {
+		configFile = flag.Value.String()
+	}
+	path, err := findConfigFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	if path != "" {
+		if err := readConfigFile(v, path); err != nil {
+			return nil, err
+		}
+		for _, key := range envConflicts(v) {
+			fmt.Fprintf(cmd.ErrOrStderr(), "warning: %s is set in both %s and %s_%s, using the environment variable\n",
+				key, path, EnvPrefix, strings.ToUpper(key))
+		}
+	}
+
 	// Unmarshal to config
 	result := &Config{}
 	if err := v.Unmarshal(result); err != nil {
@@ -88,7 +265,476 @@ func FromCommand(cmd *cobra.Command) (*Config, error) {
 
 	// Set durations from duration flags
 	result.ConnectTimeout = v.GetDuration("connect_timeout")
+	result.ConnectRetryDelay = v.GetDuration("connect_retry_delay")
+	result.RetryBackoff = v.GetDuration("retry_backoff")
+	result.WebhookTimeout = v.GetDuration("webhook_timeout")
+	result.WalletRefreshInterval = v.GetDuration("wallet_refresh_interval")
+	result.PingInterval = v.GetDuration("ping_interval")
 	result.QueryTimeout = v.GetDuration("query_timeout")
+	result.OracleSessionTimeout = v.GetDuration("oracle_session_timeout")
+
+	if result.AzureConnectionString == "" {
+		result.AzureConnectionString = os.Getenv(AzureConnectionStringEnv)
+	}
+
+	quoteChar, err := parseQuoteChar(v.GetString("csv_quote_char"))
+	if err != nil {
+		return nil, err
+	}
+	result.CSVQuoteChar = quoteChar
+
+	delimiter, err := parseDelimiter(v.GetString("delimiter"))
+	if err != nil {
+		return nil, err
+	}
+	result.Delimiter = delimiter
+
+	recordSep, err := parseRecordSeparator(v.GetString("record_separator"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid record_separator: %w", err)
+	}
+	result.RecordSeparator = recordSep
+	result.Compress = normalizeCompress(result.Compress)
+
+	// Permissions are given in octal notation
+	modes := []struct {
+		key    string
+		target *os.FileMode
+	}{
+		{"file_mode", &result.FileMode},
+		{"state_file_mode", &result.StateFileMode},
+		{"dir_mode", &result.DirMode},
+	}
+	for _, m := range modes {
+		mode, err := parseFileMode(v.GetString(m.key))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", m.key, err)
+		}
+		*m.target = mode
+	}
+
+	result.ResultCacheEntities = getList(v, "result_cache_entities")
+	result.StateFiles = getList(v, "state_files")
+	result.IncludeTags = getList(v, "include_tags")
+	result.ExcludeTags = getList(v, "exclude_tags")
+
+	limits, err := parseSchemaLimits(getList(v, "schema_limit"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid schema_limit: %w", err)
+	}
+	result.SchemaParallelismLimits = limits
+
+	params, err := parseParams(getList(v, "param"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid param: %w", err)
+	}
+	result.GlobalParams = params
+
+	sessionParams, err := parseParams(getSessionParams(v))
+	if err != nil {
+		return nil, fmt.Errorf("invalid oracle_session_param: %w", err)
+	}
+	result.OracleSessionParams = sessionParams
 
 	return result, nil
 }
+
+// parseQuoteChar checks that the quote character is a single character
+func parseQuoteChar(s string) (rune, error) {
+	quoteChar := []rune(s)
+	if len(quoteChar) != 1 {
+		return 0, fmt.Errorf("csv_quote_char must be a single character")
+	}
+	return quoteChar[0], nil
+}
+
+// parseDelimiter parses a single-character field delimiter, "tab" or "\t" for a tab
+func parseDelimiter(s string) (rune, error) {
+	if s == "tab" || s == `\t` {
+		return '\t', nil
+	}
+	delimiter := []rune(s)
+	if len(delimiter) != 1 {
+		return 0, fmt.Errorf("delimiter must be a single character or \"tab\"")
+	}
+	return delimiter[0], nil
+}
+
+// parseFileMode parses an octal permission string such as "0600"
+func parseFileMode(s string) (os.FileMode, error) {
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not an octal file mode", s)
+	}
+	if mode == 0 || mode > 0777 {
+		return 0, fmt.Errorf("%q must be between 0001 and 0777", s)
+	}
+	return os.FileMode(mode), nil
+}
+
+// normalizeCompress maps the "none" compression to an empty string
+func normalizeCompress(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == CompressNone {
+		return ""
+	}
+	return s
+}
+
+// parseRecordSeparator parses lf, crlf, rs or a hex-escaped value such as "\x1E" or "0x1E"
+func parseRecordSeparator(s string) (string, error) {
+	switch strings.ToLower(s) {
+	case "", "lf":
+		return "\n", nil
+	case "crlf":
+		return "\r\n", nil
+	case "rs":
+		return "\x1e", nil
+	}
+
+	// One or more bytes, each written as \xHH, or a single 0xHH byte
+	rest := strings.ToLower(s)
+	if strings.HasPrefix(rest, "0x") {
+		rest = `\x` + rest[2:]
+	}
+	var sep []byte
+	for rest != "" {
+		if len(rest) < 4 || !strings.HasPrefix(rest, `\x`) {
+			return "", fmt.Errorf("%q must be lf, crlf, rs or a hex value such as \\x1E", s)
+		}
+		b, err := strconv.ParseUint(rest[2:4], 16, 8)
+		if err != nil {
+			return "", fmt.Errorf("%q is not a valid hex value", s)
+		}
+		sep = append(sep, byte(b))
+		rest = rest[4:]
+	}
+	return string(sep), nil
+}
+
+// parseSchemaLimits parses SCHEMA=N items into a map keyed by upper-case schema
+func parseSchemaLimits(items []string) (map[string]int, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	limits := make(map[string]int, len(items))
+	for _, item := range items {
+		schema, n, ok := strings.Cut(item, "=")
+		schema = strings.TrimSpace(schema)
+		if !ok || schema == "" {
+			return nil, fmt.Errorf("%q must be in SCHEMA=N format", item)
+		}
+		limit, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil || limit < 1 {
+			return nil, fmt.Errorf("%q must have a positive limit", item)
+		}
+		limits[strings.ToUpper(schema)] = limit
+	}
+	return limits, nil
+}
+
+// parseParams parses key=value items into a map, later items override earlier ones
+func parseParams(items []string) (map[string]string, error) {
+	if len(items) == 0 {
+		return nil, nil
+	}
+	params := make(map[string]string, len(items))
+	for _, item := range items {
+		key, value, ok := strings.Cut(item, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("%q must be in key=value format", item)
+		}
+		params[key] = value
+	}
+	return params, nil
+}
+
+// getList reads a list that may come from a slice flag or a comma-separated env var
+func getList(v *viper.Viper, key string) []string {
+	var result []string
+	for _, item := range v.GetStringSlice(key) {
+		for _, name := range strings.Split(item, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				result = append(result, name)
+			}
+		}
+	}
+	return result
+}
+
+// getSessionParams reads the Oracle session parameters
+// Values such as NLS_NUMERIC_CHARACTERS=., contain commas, so the repeatable flag is
+// taken as-is and the environment variable or config file string is split on semicolons.
+func getSessionParams(v *viper.Viper) []string {
+	if value, ok := v.Get("oracle_session_param").(string); ok {
+		return splitSessionParams(value)
+	}
+	return v.GetStringSlice("oracle_session_param")
+}
+
+// splitSessionParams splits a semicolon-separated list of session parameters
+func splitSessionParams(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// FromEnvironment loads configuration from ORA2CSV_* environment variables only
+// It applies the same defaults as FromCommand and is meant for using ora2csv as a
+// library without cobra; each setting is read from ORA2CSV_<KEY>, e.g. ORA2CSV_DB_HOST
+func FromEnvironment() (*Config, error) {
+	e := &envReader{}
+	cfg := &Config{
+		DBUser:                e.string("db_user", DefaultDBUser),
+		DBPassword:            e.string("db_password", ""),
+		DBHost:                e.string("db_host", DefaultDBHost),
+		DBPort:                e.int("db_port", DefaultDBPort),
+		DBService:             e.string("db_service", DefaultDBService),
+		DBExternalAuth:        e.bool("db_external_auth"),
+		GCPSecretName:         e.string("gcp_secret_name", ""),
+		GCPProjectID:          e.string("gcp_project_id", ""),
+		GCPSecretVersion:      e.string("gcp_secret_version", DefaultGCPSecretVersion),
+		FetchSize:             e.int("fetch_size", DefaultFetchSize),
+		LOBFetchInline:        e.bool("lob_fetch_inline"),
+		StateFile:             e.string("state_file", DefaultStateFile),
+		StateFiles:            e.list("state_files"),
+		SQLDir:                e.string("sql_dir", DefaultSQLDir),
+		SQLEncryptionKey:      e.string("sql_encryption_key", ""),
+		SchemaDir:             e.string("schema_dir", ""),
+		IgnoreColumnAdditions: e.bool("ignore_column_additions"),
+		ColumnMapFile:         e.string("column_map_file", ""),
+		ExportDir:             e.string("export_dir", DefaultExportDir),
+		TempDir:               e.string("temp_dir", ""),
+		FileMode:              e.fileMode("file_mode", DefaultFileMode),
+		StateFileMode:         e.fileMode("state_file_mode", DefaultFileMode),
+		DirMode:               e.fileMode("dir_mode", DefaultDirMode),
+		DefaultDaysBack:       e.int("days_back", DefaultDaysBack),
+		DryRun:                e.bool("dry_run"),
+		Verbose:               e.bool("verbose"),
+		LogFormat:             e.string("log_format", LogFormatText),
+		LogFile:               e.string("log_file", ""),
+		LogRotateSizeMB:       e.int("log_rotate_size_mb", 0),
+		LogRotateCount:        e.int("log_rotate_count", DefaultLogRotateCount),
+		AutoDiscover:          e.bool("auto_discover"),
+		AutoDiscoverPrefix:    e.string("auto_discover_prefix", ""),
+		IncludeInactive:       e.bool("include_inactive"),
+		IncludeTags:           e.list("include_tags"),
+		ExcludeTags:           e.list("exclude_tags"),
+		Progress:              e.bool("progress"),
+		ProgressInterval:      e.int("progress_interval", DefaultProgressInterval),
+		Entity:                e.string("entity", ""),
+		SkipIfOverlapping:     e.bool("skip_if_overlapping"),
+		WriteMeta:             e.bool("write_meta"),
+		WriteChecksum:         e.bool("write_checksum"),
+		PagerDutyRoutingKey:   e.string("pagerduty_routing_key", ""),
+		PagerDutyThreshold:    e.int("pagerduty_threshold", DefaultPagerDutyThreshold),
+		WebhookURL:            e.string("webhook_url", ""),
+		WebhookOnFailure:      e.bool("webhook_on_failure"),
+		WebhookTimeout:        e.duration("webhook_timeout", DefaultWebhookTimeout*time.Second),
+		MetricsAddr:           e.string("metrics_addr", ""),
+		OTelEndpoint:          e.string("otel_endpoint", ""),
+		OTelProtocol:          e.string("otel_protocol", DefaultOTelProtocol),
+		CloudTempFile:         e.bool("cloud_temp_file"),
+		AlsoLocalPath:         e.string("also_local_path", ""),
+		Since:                 e.string("since", ""),
+		Until:                 e.string("until", ""),
+		OutputFormat:          e.string("output_format", OutputFormatText),
+		ResultFile:            e.string("result_file", ""),
+		HeaderCase:            e.string("header_case", DefaultHeaderCase),
+		Compress:              e.string("compress", ""),
+		FileFormat:            e.string("file_format", FileFormatCSV),
+		ParquetRowGroupMB:     e.int("parquet_row_group_mb", DefaultParquetRowGroupMB),
+		Parallelism:           e.int("parallelism", DefaultParallelism),
+		FileExtension:         e.string("file_extension", ""),
+		FilenameTemplate:      e.string("filename_template", DefaultFilenameTemplate),
+		OutputPerEntityDir:    e.bool("output_dir_per_entity"),
+		BlobEncoding:          e.string("blob_encoding", BlobEncodingHex),
+		ClobMaxLength:         e.int("clob_max_length", 0),
+		EnableResultCache:     e.bool("enable_result_cache"),
+		ResultCacheEntities:   e.list("result_cache_entities"),
+		DeduplicateOutput:     e.bool("dedup_output"),
+		BloomFilterSize:       e.int("bloom_filter_size", DefaultBloomFilterSize),
+		MaxResultSetRows:      e.int64("max_result_set_rows", 0),
+		MaxRowsGlobal:         e.int64("max_rows_global", 0),
+		MaxRowsPerFile:        e.int64("max_rows_per_file", 0),
+		DefaultMaxFileSizeMB:  e.int("max_file_size_mb", 0),
+		MaxMemoryMB:           e.int("max_memory_mb", 0),
+		FetchRowsHint:         e.int("fetch_rows_hint", 0),
+		ConnectTimeout:        e.duration("connect_timeout", DefaultConnectTimeoutSecs*time.Second),
+		QueryTimeout:          e.duration("query_timeout", DefaultQueryTimeoutSecs*time.Second),
+		OracleSessionTimeout:  e.duration("oracle_session_timeout", 0),
+		ConnectRetryCount:     e.int("connect_retry_count", DefaultConnectRetryCount),
+		ConnectRetryDelay:     e.duration("connect_retry_delay", DefaultConnectRetryDelay*time.Second),
+		MaxRetries:            e.int("max_retries", DefaultMaxRetries),
+		RetryBackoff:          e.duration("retry_backoff", DefaultRetryBackoff*time.Second),
+		WalletRefreshInterval: e.duration("wallet_refresh_interval", 0),
+		PingInterval:          e.duration("ping_interval", DefaultPingInterval*time.Second),
+		S3: S3Config{
+			Bucket:        e.string("s3_bucket", ""),
+			Prefix:        e.string("s3_prefix", ""),
+			AccessKey:     e.string("s3_access_key", ""),
+			SecretKey:     e.string("s3_secret_key", ""),
+			SessionToken:  e.string("s3_session_token", ""),
+			Endpoint:      e.string("s3_endpoint", ""),
+			TrackVersions: e.bool("s3_track_versions"),
+			SSE:           e.string("s3_sse", ""),
+			SSEKMSKeyID:   e.string("s3_kms_key_id", ""),
+			StorageClass:  e.string("s3_storage_class", ""),
+		},
+		AzureConnectionString: e.string("azure_connection_string", os.Getenv(AzureConnectionStringEnv)),
+		AzureContainerName:    e.string("azure_container", ""),
+		AzurePrefix:           e.string("azure_prefix", ""),
+		GCSBucket:             e.string("gcs_bucket", ""),
+		GCSPrefix:             e.string("gcs_prefix", ""),
+		SFTP: SFTPConfig{
+			Host:           e.string("sftp_host", ""),
+			Port:           e.int("sftp_port", DefaultSFTPPort),
+			User:           e.string("sftp_user", ""),
+			PrivateKeyPath: e.string("sftp_key", ""),
+			Password:       e.string("sftp_password", ""),
+			RemotePath:     e.string("sftp_path", ""),
+			KnownHostsPath: e.string("sftp_known_hosts", ""),
+		},
+	}
+
+	if e.err == nil {
+		if cfg.SchemaParallelismLimits, e.err = parseSchemaLimits(e.list("schema_limit")); e.err != nil {
+			e.err = fmt.Errorf("invalid schema_limit: %w", e.err)
+		}
+	}
+	if e.err == nil {
+		if cfg.GlobalParams, e.err = parseParams(e.list("param")); e.err != nil {
+			e.err = fmt.Errorf("invalid param: %w", e.err)
+		}
+	}
+	if e.err == nil {
+		sessionParams, _ := e.lookup("oracle_session_param")
+		if cfg.OracleSessionParams, e.err = parseParams(splitSessionParams(sessionParams)); e.err != nil {
+			e.err = fmt.Errorf("invalid oracle_session_param: %w", e.err)
+		}
+	}
+	if quoteChar := e.string("csv_quote_char", string(DefaultCSVQuoteChar)); e.err == nil {
+		cfg.CSVQuoteChar, e.err = parseQuoteChar(quoteChar)
+	}
+	if delimiter := e.string("delimiter", string(DefaultDelimiter)); e.err == nil {
+		cfg.Delimiter, e.err = parseDelimiter(delimiter)
+	}
+	if recordSep := e.string("record_separator", DefaultRecordSeparator); e.err == nil {
+		if cfg.RecordSeparator, e.err = parseRecordSeparator(recordSep); e.err != nil {
+			e.err = fmt.Errorf("invalid record_separator: %w", e.err)
+		}
+	}
+
+	cfg.Compress = normalizeCompress(cfg.Compress)
+
+	if e.err != nil {
+		return nil, apperrors.NewConfigError("FromEnvironment", "invalid environment value", e.err)
+	}
+	return cfg, nil
+}
+
+// envReader reads typed ORA2CSV_* environment variables and keeps the first parse error
+type envReader struct {
+	err error
+}
+
+// lookup returns the value of the environment variable for a config key
+func (e *envReader) lookup(key string) (string, bool) {
+	return os.LookupEnv(EnvPrefix + "_" + strings.ToUpper(key))
+}
+
+// fail records a parse error for key unless one is already recorded
+func (e *envReader) fail(key, value string, err error) {
+	if e.err == nil {
+		e.err = fmt.Errorf("invalid %s %q: %w", EnvPrefix+"_"+strings.ToUpper(key), value, err)
+	}
+}
+
+func (e *envReader) string(key, def string) string {
+	if value, ok := e.lookup(key); ok {
+		return value
+	}
+	return def
+}
+
+func (e *envReader) int(key string, def int) int {
+	value, ok := e.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		e.fail(key, value, err)
+		return def
+	}
+	return n
+}
+
+func (e *envReader) int64(key string, def int64) int64 {
+	value, ok := e.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil {
+		e.fail(key, value, err)
+		return def
+	}
+	return n
+}
+
+func (e *envReader) bool(key string) bool {
+	value, ok := e.lookup(key)
+	if !ok || value == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(value))
+	if err != nil {
+		e.fail(key, value, err)
+		return false
+	}
+	return b
+}
+
+func (e *envReader) duration(key string, def time.Duration) time.Duration {
+	value, ok := e.lookup(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil {
+		e.fail(key, value, err)
+		return def
+	}
+	return d
+}
+
+func (e *envReader) fileMode(key string, def os.FileMode) os.FileMode {
+	value, ok := e.lookup(key)
+	if !ok {
+		return def
+	}
+	mode, err := parseFileMode(strings.TrimSpace(value))
+	if err != nil {
+		e.fail(key, value, err)
+		return def
+	}
+	return mode
+}
+
+// list splits a comma-separated value, dropping empty items
+func (e *envReader) list(key string) []string {
+	value, _ := e.lookup(key)
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
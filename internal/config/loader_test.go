@@ -0,0 +1,334 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	apperrors "github.com/koltyakov/ora2csv/pkg/errors"
+)
+
+func TestFromCommand(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().Int("db-port", DefaultDBPort, "")
+	cmd.Flags().String("file-mode", "", "")
+	if err := cmd.Flags().Set("db-port", "1522"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if err := cmd.Flags().Set("file-mode", "0600"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	cfg, err := FromCommand(cmd)
+	if err != nil {
+		t.Fatalf("FromCommand() error = %v", err)
+	}
+	if cfg.DBPort != 1522 {
+		t.Errorf("DBPort = %d, want 1522", cfg.DBPort)
+	}
+	if cfg.FileMode != 0600 {
+		t.Errorf("FileMode = %o, want 600", cfg.FileMode)
+	}
+}
+
+func TestFromCommand_Params(t *testing.T) {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().StringSlice("param", nil, "")
+	for _, item := range []string{"tenantId=acme", "env=prod"} {
+		if err := cmd.Flags().Set("param", item); err != nil {
+			t.Fatalf("Set() error = %v", err)
+		}
+	}
+
+	cfg, err := FromCommand(cmd)
+	if err != nil {
+		t.Fatalf("FromCommand() error = %v", err)
+	}
+	want := map[string]string{"tenantId": "acme", "env": "prod"}
+	if !reflect.DeepEqual(cfg.GlobalParams, want) {
+		t.Errorf("GlobalParams = %v, want %v", cfg.GlobalParams, want)
+	}
+
+	if err := cmd.Flags().Set("param", "broken"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if _, err := FromCommand(cmd); err == nil {
+		t.Error("expected error for param without value")
+	}
+}
+
+func TestFromCommand_OracleSessionParams(t *testing.T) {
+	want := map[string]string{
+		"NLS_DATE_FORMAT":        "YYYY-MM-DD HH24:MI:SS",
+		"NLS_NUMERIC_CHARACTERS": ".,",
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		cmd := &cobra.Command{Use: "test"}
+		cmd.Flags().StringArray("oracle-session-param", nil, "")
+		for _, item := range []string{"NLS_DATE_FORMAT=YYYY-MM-DD HH24:MI:SS", "NLS_NUMERIC_CHARACTERS=.,"} {
+			if err := cmd.Flags().Set("oracle-session-param", item); err != nil {
+				t.Fatalf("Set() error = %v", err)
+			}
+		}
+		cfg, err := FromCommand(cmd)
+		if err != nil {
+			t.Fatalf("FromCommand() error = %v", err)
+		}
+		if !reflect.DeepEqual(cfg.OracleSessionParams, want) {
+			t.Errorf("OracleSessionParams = %v, want %v", cfg.OracleSessionParams, want)
+		}
+	})
+
+	t.Run("environment", func(t *testing.T) {
+		t.Setenv("ORA2CSV_ORACLE_SESSION_PARAM", "NLS_DATE_FORMAT=YYYY-MM-DD HH24:MI:SS; NLS_NUMERIC_CHARACTERS=.,")
+		cfg, err := FromCommand(&cobra.Command{Use: "test"})
+		if err != nil {
+			t.Fatalf("FromCommand() error = %v", err)
+		}
+		if !reflect.DeepEqual(cfg.OracleSessionParams, want) {
+			t.Errorf("OracleSessionParams = %v, want %v", cfg.OracleSessionParams, want)
+		}
+
+		fromEnv, err := FromEnvironment()
+		if err != nil {
+			t.Fatalf("FromEnvironment() error = %v", err)
+		}
+		if !reflect.DeepEqual(fromEnv.OracleSessionParams, want) {
+			t.Errorf("FromEnvironment() OracleSessionParams = %v, want %v", fromEnv.OracleSessionParams, want)
+		}
+	})
+}
+
+func TestFromCommand_ConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "custom.yaml")
+	yamlContent := "db_host: file-host\ndb_port: 1600\ndb_service: FILEPDB\ns3_bucket: file-bucket\nquery_timeout: 2m\nfile_mode: 0640\nstate_files:\n  - a.json\n  - b.json\n"
+	if err := os.WriteFile(yamlPath, []byte(yamlContent), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ORA2CSV_DB_SERVICE", "ENVPDB")
+
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("config-file", "", "")
+	cmd.Flags().Int("db-port", DefaultDBPort, "")
+	cmd.Flags().String("db-host", DefaultDBHost, "")
+	if err := cmd.Flags().Set("config-file", yamlPath); err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Flags().Set("db-port", "1700"); err != nil {
+		t.Fatal(err)
+	}
+	var stderr bytes.Buffer
+	cmd.SetErr(&stderr)
+
+	cfg, err := FromCommand(cmd)
+	if err != nil {
+		t.Fatalf("FromCommand() error = %v", err)
+	}
+	// Flags > env vars > config file > defaults
+	got := map[string]interface{}{
+		"DBHost":       cfg.DBHost,
+		"DBPort":       cfg.DBPort,
+		"DBService":    cfg.DBService,
+		"DBUser":       cfg.DBUser,
+		"S3.Bucket":    cfg.S3.Bucket,
+		"QueryTimeout": cfg.QueryTimeout,
+		"FileMode":     cfg.FileMode,
+		"StateFiles":   cfg.StateFiles,
+	}
+	want := map[string]interface{}{
+		"DBHost":       "file-host",
+		"DBPort":       1700,
+		"DBService":    "ENVPDB",
+		"DBUser":       DefaultDBUser,
+		"S3.Bucket":    "file-bucket",
+		"QueryTimeout": 2 * time.Minute,
+		"FileMode":     os.FileMode(0640),
+		"StateFiles":   []string{"a.json", "b.json"},
+	}
+	for field, w := range want {
+		if !reflect.DeepEqual(got[field], w) {
+			t.Errorf("%s = %#v, want %#v", field, got[field], w)
+		}
+	}
+	if !strings.Contains(stderr.String(), "db_service is set in both") || strings.Contains(stderr.String(), "db_host") {
+		t.Errorf("unexpected warnings: %q", stderr.String())
+	}
+
+	// ora2csv.toml is picked up from the working directory
+	tomlDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tomlDir, "ora2csv.toml"), []byte("db_host = \"toml-host\"\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(tomlDir)
+	cfg, err = FromCommand(&cobra.Command{Use: "test"})
+	if err != nil {
+		t.Fatalf("FromCommand() error = %v", err)
+	}
+	if cfg.DBHost != "toml-host" {
+		t.Errorf("DBHost = %q, want toml-host", cfg.DBHost)
+	}
+
+	t.Setenv(EnvConfigFile, filepath.Join(dir, "missing.yaml"))
+	if _, err := FromCommand(&cobra.Command{Use: "test"}); err == nil {
+		t.Error("expected error for missing config file")
+	}
+}
+
+func TestFromCommand_TypeMismatch(t *testing.T) {
+	// db-port is decoded as an int but registered as a string flag
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String("db-port", "", "")
+	if err := cmd.Flags().Set("db-port", "not-a-number"); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+
+	cfg, err := FromCommand(cmd)
+	if err == nil {
+		t.Fatal("expected error for type-mismatched flag")
+	}
+	if cfg != nil {
+		t.Error("expected nil config on error")
+	}
+	if !apperrors.IsType(err, apperrors.ErrorTypeConfig) {
+		t.Errorf("error %v is not a config AppError", err)
+	}
+}
+
+func TestFromCommand_RecoversPanic(t *testing.T) {
+	cfg, err := FromCommand(nil)
+	if err == nil {
+		t.Fatal("expected error for nil command")
+	}
+	if cfg != nil {
+		t.Error("expected nil config on error")
+	}
+	if !apperrors.IsType(err, apperrors.ErrorTypeConfig) {
+		t.Errorf("error %v is not a config AppError", err)
+	}
+}
+
+func TestFromEnvironment(t *testing.T) {
+	env := map[string]string{
+		"ORA2CSV_DB_HOST":               "oracle.internal",
+		"ORA2CSV_DB_PORT":               "1522",
+		"ORA2CSV_DB_SERVICE":            "PROD",
+		"ORA2CSV_DB_USER":               "exporter",
+		"ORA2CSV_DB_PASSWORD":           "secret",
+		"ORA2CSV_STATE_FILE":            "/data/state.json",
+		"ORA2CSV_STATE_FILES":           "a.json, b.json",
+		"ORA2CSV_SQL_DIR":               "/data/sql",
+		"ORA2CSV_EXPORT_DIR":            "/data/export",
+		"ORA2CSV_DAYS_BACK":             "7",
+		"ORA2CSV_VERBOSE":               "true",
+		"ORA2CSV_FILE_MODE":             "0600",
+		"ORA2CSV_CSV_QUOTE_CHAR":        "'",
+		"ORA2CSV_RECORD_SEPARATOR":      "crlf",
+		"ORA2CSV_MAX_RESULT_SET_ROWS":   "5000000",
+		"ORA2CSV_QUERY_TIMEOUT":         "10m",
+		"ORA2CSV_RESULT_CACHE_ENTITIES": "crm.products",
+		"ORA2CSV_S3_BUCKET":             "exports",
+		"ORA2CSV_S3_TRACK_VERSIONS":     "1",
+	}
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	cfg, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("FromEnvironment() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"DBHost":              "oracle.internal",
+		"DBPort":              1522,
+		"DBService":           "PROD",
+		"DBUser":              "exporter",
+		"DBPassword":          "secret",
+		"StateFile":           "/data/state.json",
+		"StateFiles":          []string{"a.json", "b.json"},
+		"SQLDir":              "/data/sql",
+		"ExportDir":           "/data/export",
+		"DefaultDaysBack":     7,
+		"Verbose":             true,
+		"FileMode":            os.FileMode(0600),
+		"CSVQuoteChar":        '\'',
+		"RecordSeparator":     "\r\n",
+		"MaxResultSetRows":    int64(5000000),
+		"QueryTimeout":        10 * time.Minute,
+		"ResultCacheEntities": []string{"crm.products"},
+		"S3.Bucket":           "exports",
+		"S3.TrackVersions":    true,
+		// Unset values keep their defaults
+		"FetchSize":      DefaultFetchSize,
+		"DirMode":        os.FileMode(DefaultDirMode),
+		"HeaderCase":     DefaultHeaderCase,
+		"ConnectTimeout": DefaultConnectTimeoutSecs * time.Second,
+	}
+	got := map[string]interface{}{
+		"DBHost":              cfg.DBHost,
+		"DBPort":              cfg.DBPort,
+		"DBService":           cfg.DBService,
+		"DBUser":              cfg.DBUser,
+		"DBPassword":          cfg.DBPassword,
+		"StateFile":           cfg.StateFile,
+		"StateFiles":          cfg.StateFiles,
+		"SQLDir":              cfg.SQLDir,
+		"ExportDir":           cfg.ExportDir,
+		"DefaultDaysBack":     cfg.DefaultDaysBack,
+		"Verbose":             cfg.Verbose,
+		"FileMode":            cfg.FileMode,
+		"CSVQuoteChar":        cfg.CSVQuoteChar,
+		"RecordSeparator":     cfg.RecordSeparator,
+		"MaxResultSetRows":    cfg.MaxResultSetRows,
+		"QueryTimeout":        cfg.QueryTimeout,
+		"ResultCacheEntities": cfg.ResultCacheEntities,
+		"S3.Bucket":           cfg.S3.Bucket,
+		"S3.TrackVersions":    cfg.S3.TrackVersions,
+		"FetchSize":           cfg.FetchSize,
+		"DirMode":             cfg.DirMode,
+		"HeaderCase":          cfg.HeaderCase,
+		"ConnectTimeout":      cfg.ConnectTimeout,
+	}
+	for field, w := range want {
+		if !reflect.DeepEqual(got[field], w) {
+			t.Errorf("%s = %#v, want %#v", field, got[field], w)
+		}
+	}
+}
+
+func TestFromEnvironment_Defaults(t *testing.T) {
+	fromEnv, err := FromEnvironment()
+	if err != nil {
+		t.Fatalf("FromEnvironment() error = %v", err)
+	}
+	fromCmd, err := FromCommand(&cobra.Command{Use: "test"})
+	if err != nil {
+		t.Fatalf("FromCommand() error = %v", err)
+	}
+	if !reflect.DeepEqual(fromEnv, fromCmd) {
+		t.Errorf("defaults differ:\nFromEnvironment() = %+v\nFromCommand()     = %+v", fromEnv, fromCmd)
+	}
+}
+
+func TestFromEnvironment_InvalidValue(t *testing.T) {
+	t.Setenv("ORA2CSV_DB_PORT", "not-a-number")
+
+	cfg, err := FromEnvironment()
+	if err == nil {
+		t.Fatal("expected error for invalid db port")
+	}
+	if cfg != nil {
+		t.Error("expected nil config on error")
+	}
+	if !apperrors.IsType(err, apperrors.ErrorTypeConfig) {
+		t.Errorf("error %v is not a config AppError", err)
+	}
+}
@@ -1,8 +1,18 @@
 package config
 
 import (
+	"fmt"
 	"path/filepath"
+	"slices"
 	"strings"
+
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3 server-side encryption modes
+const (
+	S3SSEAES256 = "AES256"
+	S3SSEKMS    = "aws:kms"
 )
 
 // S3Config holds S3 destination configuration
@@ -13,6 +23,18 @@ type S3Config struct {
 	SecretKey    string `mapstructure:"s3_secret_key"`
 	SessionToken string `mapstructure:"s3_session_token"`
 	Endpoint     string `mapstructure:"s3_endpoint"` // For MinIO, Wasabi, etc.
+
+	// TrackVersions records state file VersionIds for restore on versioned buckets
+	TrackVersions bool `mapstructure:"s3_track_versions"`
+
+	// SSE is the server-side encryption of uploaded objects: AES256 or aws:kms (empty uses the bucket default)
+	SSE string `mapstructure:"s3_sse"`
+
+	// SSEKMSKeyID is the KMS key for aws:kms encryption, setting it implies aws:kms
+	SSEKMSKeyID string `mapstructure:"s3_kms_key_id"`
+
+	// StorageClass of uploaded objects, e.g. STANDARD_IA (empty uses the bucket default)
+	StorageClass string `mapstructure:"s3_storage_class"`
 }
 
 // Validate checks if S3 configuration is valid
@@ -21,6 +43,18 @@ func (c *S3Config) Validate() error {
 		return nil
 	}
 
+	switch c.SSE {
+	case "", S3SSEAES256, S3SSEKMS:
+	default:
+		return fmt.Errorf("s3_sse must be %q or %q", S3SSEAES256, S3SSEKMS)
+	}
+	if c.SSE == S3SSEAES256 && c.SSEKMSKeyID != "" {
+		return fmt.Errorf("s3_kms_key_id requires s3_sse %q", S3SSEKMS)
+	}
+	if err := ValidateS3StorageClass(c.StorageClass); err != nil {
+		return fmt.Errorf("s3_storage_class: %w", err)
+	}
+
 	// Clean up prefix - ensure it doesn't start/end with slash
 	c.Prefix = strings.Trim(c.Prefix, "/")
 	if c.Prefix != "" {
@@ -43,6 +77,30 @@ func (c *S3Config) StateKey() string {
 	return c.Key("state.json")
 }
 
+// ServerSideEncryption returns the effective encryption mode, aws:kms when only a KMS key is set
+func (c *S3Config) ServerSideEncryption() string {
+	if c.SSE == "" && c.SSEKMSKeyID != "" {
+		return S3SSEKMS
+	}
+	return c.SSE
+}
+
+// ValidateS3StorageClass checks that class is empty or a storage class known to the AWS SDK
+func ValidateS3StorageClass(class string) error {
+	if class == "" {
+		return nil
+	}
+	known := s3types.StorageClass("").Values()
+	if slices.Contains(known, s3types.StorageClass(class)) {
+		return nil
+	}
+	names := make([]string, len(known))
+	for i, k := range known {
+		names[i] = string(k)
+	}
+	return fmt.Errorf("unknown storage class %q, want one of %s", class, strings.Join(names, ", "))
+}
+
 // IsMinIO returns true if the configuration appears to be for MinIO or similar S3-compatible service
 func (c *S3Config) IsMinIO() bool {
 	return c.Endpoint != "" && !strings.Contains(c.Endpoint, "amazonaws.com")
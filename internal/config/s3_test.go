@@ -90,6 +90,45 @@ func TestS3Config_Validate(t *testing.T) {
 			t.Errorf("Prefix = %q, want empty string", cfg.Prefix)
 		}
 	})
+
+	t.Run("storage class", func(t *testing.T) {
+		for _, class := range []string{"", "STANDARD", "STANDARD_IA", "GLACIER_IR"} {
+			cfg := &S3Config{Bucket: "test-bucket", StorageClass: class}
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("Validate(%q) error = %v", class, err)
+			}
+		}
+		cfg := &S3Config{Bucket: "test-bucket", StorageClass: "standard_ia"}
+		if err := cfg.Validate(); err == nil {
+			t.Error("expected error for unknown storage class")
+		}
+	})
+
+	t.Run("server-side encryption", func(t *testing.T) {
+		tests := []struct {
+			sse, keyID string
+			wantErr    bool
+			want       string
+		}{
+			{"", "", false, ""},
+			{S3SSEAES256, "", false, S3SSEAES256},
+			{S3SSEKMS, "", false, S3SSEKMS},
+			{"", "arn:aws:kms:us-east-1:111122223333:key/abc", false, S3SSEKMS},
+			{S3SSEAES256, "arn:aws:kms:us-east-1:111122223333:key/abc", true, ""},
+			{"aes256", "", true, ""},
+		}
+		for _, tt := range tests {
+			cfg := &S3Config{Bucket: "test-bucket", SSE: tt.sse, SSEKMSKeyID: tt.keyID}
+			err := cfg.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate(sse=%q, key=%q) error = %v, wantErr %v", tt.sse, tt.keyID, err, tt.wantErr)
+				continue
+			}
+			if !tt.wantErr && cfg.ServerSideEncryption() != tt.want {
+				t.Errorf("ServerSideEncryption(sse=%q, key=%q) = %q, want %q", tt.sse, tt.keyID, cfg.ServerSideEncryption(), tt.want)
+			}
+		}
+	})
 }
 
 func TestS3Config_Key(t *testing.T) {
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultSFTPPort is the SSH port used unless --sftp-port is set
+const DefaultSFTPPort = 22
+
+// SFTPConfig holds SFTP destination configuration
+type SFTPConfig struct {
+	Host string `mapstructure:"sftp_host"`
+	Port int    `mapstructure:"sftp_port"`
+	User string `mapstructure:"sftp_user"`
+
+	// PrivateKeyPath is an unencrypted private key, tried before Password
+	PrivateKeyPath string `mapstructure:"sftp_key"`
+	Password       string `mapstructure:"sftp_password"`
+
+	// RemotePath is the directory exports and the state file are written to (default: login directory)
+	RemotePath string `mapstructure:"sftp_path"`
+
+	// KnownHostsPath verifies the server host key (default: ~/.ssh/known_hosts)
+	KnownHostsPath string `mapstructure:"sftp_known_hosts"`
+}
+
+// Validate checks if SFTP configuration is valid
+func (c *SFTPConfig) Validate() error {
+	if c.Host == "" {
+		return nil
+	}
+	if c.User == "" {
+		return fmt.Errorf("sftp_user is required with sftp_host")
+	}
+	if c.PrivateKeyPath == "" && c.Password == "" {
+		return fmt.Errorf("sftp_host requires sftp_key or the %s environment variable", EnvSFTPPassword)
+	}
+	if c.Port < 1 || c.Port > 65535 {
+		return fmt.Errorf("sftp_port must be between 1 and 65535")
+	}
+	if len(c.RemotePath) > 1 {
+		c.RemotePath = strings.TrimSuffix(c.RemotePath, "/")
+	}
+	return nil
+}
+
+// Address returns host:port for dialing
+func (c *SFTPConfig) Address() string {
+	return fmt.Sprintf("%s:%d", c.Host, c.Port)
+}
+
+// Key returns the remote path for a given filename
+func (c *SFTPConfig) Key(filename string) string {
+	if c.RemotePath == "" {
+		return filename
+	}
+	return path.Join(c.RemotePath, filename)
+}
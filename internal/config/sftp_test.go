@@ -0,0 +1,58 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSFTPConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     SFTPConfig
+		wantErr string
+	}{
+		{name: "empty host is valid", cfg: SFTPConfig{}},
+		{name: "key auth", cfg: SFTPConfig{Host: "sftp.example.com", Port: 22, User: "u", PrivateKeyPath: "id_ed25519"}},
+		{name: "password auth", cfg: SFTPConfig{Host: "sftp.example.com", Port: 22, User: "u", Password: "secret"}},
+		{name: "missing user", cfg: SFTPConfig{Host: "sftp.example.com", Port: 22, PrivateKeyPath: "k"}, wantErr: "sftp_user"},
+		{name: "missing credentials", cfg: SFTPConfig{Host: "sftp.example.com", Port: 22, User: "u"}, wantErr: EnvSFTPPassword},
+		{name: "invalid port", cfg: SFTPConfig{Host: "sftp.example.com", Port: 70000, User: "u", Password: "p"}, wantErr: "sftp_port"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("Validate() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Validate() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	t.Run("trims trailing slash of remote path", func(t *testing.T) {
+		cfg := SFTPConfig{Host: "h", Port: 22, User: "u", Password: "p", RemotePath: "/upload/"}
+		if err := cfg.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Key("a.csv") != "/upload/a.csv" {
+			t.Errorf("Key() = %q", cfg.Key("a.csv"))
+		}
+	})
+
+}
+
+func TestSFTPConfig_Key(t *testing.T) {
+	if got := (&SFTPConfig{}).Key("a.csv"); got != "a.csv" {
+		t.Errorf("Key() = %q, want a.csv", got)
+	}
+	if got := (&SFTPConfig{RemotePath: "/"}).Key("a.csv"); got != "/a.csv" {
+		t.Errorf("Key() = %q, want /a.csv", got)
+	}
+	if got := (&SFTPConfig{Host: "h", Port: 2222}).Address(); got != "h:2222" {
+		t.Errorf("Address() = %q", got)
+	}
+}
@@ -2,17 +2,44 @@ package config
 
 import (
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
+
+	"github.com/koltyakov/ora2csv/internal/crypto"
 )
 
+// paramNamePattern matches a plain Oracle bind variable name
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+// tagPattern matches an entity tag: a lowercase identifier such as daily or billing_eu
+var tagPattern = regexp.MustCompile(`^[a-z][a-z0-9_-]*$`)
+
+// ValidateTag checks that tag is a non-empty lowercase identifier
+func ValidateTag(tag string) error {
+	if !tagPattern.MatchString(tag) {
+		return fmt.Errorf("invalid tag %q: tags are lowercase identifiers such as daily or billing_eu", tag)
+	}
+	return nil
+}
+
 // Validate checks if the configuration is valid
 func (c *Config) Validate() error {
-	if c.DBUser == "" {
-		return fmt.Errorf("db_user is required")
+	// Credentials are optional with OS or Kerberos authentication
+	if !c.DBExternalAuth {
+		if c.DBUser == "" {
+			return fmt.Errorf("db_user is required")
+		}
+		if c.DBPassword == "" && c.GCPSecretName == "" {
+			return fmt.Errorf("db_password is required (set %s env var)", EnvDBPassword)
+		}
 	}
-	if c.DBPassword == "" {
-		return fmt.Errorf("db_password is required (set %s env var)", EnvDBPassword)
+	if c.GCPSecretName != "" && c.GCPSecretVersion == "" {
+		return fmt.Errorf("gcp_secret_version is required with gcp_secret_name")
 	}
 	if c.DBHost == "" {
 		return fmt.Errorf("db_host is required")
@@ -23,6 +50,9 @@ func (c *Config) Validate() error {
 	if c.DBService == "" {
 		return fmt.Errorf("db_service is required")
 	}
+	if c.FetchSize < 0 {
+		return fmt.Errorf("fetch_size cannot be negative")
+	}
 	if c.StateFile == "" {
 		return fmt.Errorf("state_file is required")
 	}
@@ -37,13 +67,208 @@ func (c *Config) Validate() error {
 	if c.ConnectTimeout < time.Second || c.ConnectTimeout > time.Hour {
 		return fmt.Errorf("connect_timeout must be between 1s and 1h")
 	}
+	if c.WalletRefreshInterval < 0 || (c.WalletRefreshInterval > 0 && c.WalletRefreshInterval < time.Minute) {
+		return fmt.Errorf("wallet_refresh_interval must be at least 1m when set")
+	}
+	if c.PingInterval < 0 || (c.PingInterval > 0 && c.PingInterval < time.Second) {
+		return fmt.Errorf("ping_interval must be at least 1s when set")
+	}
+	if c.ConnectRetryCount < 0 {
+		return fmt.Errorf("connect_retry_count cannot be negative")
+	}
+	if c.ConnectRetryCount > 0 && c.ConnectRetryDelay <= 0 {
+		return fmt.Errorf("connect_retry_delay must be positive when connect_retry_count is set")
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("max_retries cannot be negative")
+	}
+	if c.MaxRetries > 0 && c.RetryBackoff <= 0 {
+		return fmt.Errorf("retry_backoff must be positive when max_retries is set")
+	}
+	since, until, err := c.DateRange()
+	if err != nil {
+		return err
+	}
+	if !since.IsZero() && !until.IsZero() && since.After(until) {
+		return fmt.Errorf("since must not be after until")
+	}
 	if c.QueryTimeout < time.Second || c.QueryTimeout > 24*time.Hour {
 		return fmt.Errorf("query_timeout must be between 1s and 24h")
 	}
+	if c.OracleSessionTimeout < 0 {
+		return fmt.Errorf("oracle_session_timeout cannot be negative")
+	}
+	if c.OracleSessionTimeout > 0 && c.OracleSessionTimeout <= c.QueryTimeout {
+		return fmt.Errorf("oracle_session_timeout must be greater than query_timeout")
+	}
+
+	if c.PagerDutyRoutingKey != "" && c.PagerDutyThreshold < 1 {
+		return fmt.Errorf("pagerduty_threshold must be at least 1")
+	}
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("webhook_url must be an http or https URL")
+		}
+		if c.WebhookTimeout <= 0 {
+			return fmt.Errorf("webhook_timeout must be positive")
+		}
+	}
+	if c.MetricsAddr != "" {
+		if _, _, err := net.SplitHostPort(c.MetricsAddr); err != nil {
+			return fmt.Errorf("metrics_addr must be host:port or :port: %w", err)
+		}
+	}
+	if c.OTelProtocol != "" && c.OTelProtocol != "grpc" && c.OTelProtocol != "http" {
+		return fmt.Errorf("otel_protocol must be grpc or http")
+	}
 
 	// Validate days_back
-	if c.DefaultDaysBack < 0 || c.DefaultDaysBack > 3650 {
-		return fmt.Errorf("days_back must be between 0 and 3650")
+	if c.DefaultDaysBack < 0 || c.DefaultDaysBack > MaxDaysBack {
+		return fmt.Errorf("days_back must be between 0 and %d", MaxDaysBack)
+	}
+
+	switch c.OutputFormat {
+	case "", OutputFormatText, OutputFormatJSON:
+	default:
+		return fmt.Errorf("output_format must be %q or %q", OutputFormatText, OutputFormatJSON)
+	}
+	if c.ResultFile == "-" && c.OutputFormat == OutputFormatJSON {
+		return fmt.Errorf("result_file cannot be written to stdout with output_format json")
+	}
+
+	switch c.LogFormat {
+	case "", LogFormatText, LogFormatJSON:
+	default:
+		return fmt.Errorf("log_format must be %q or %q", LogFormatText, LogFormatJSON)
+	}
+
+	if c.LogRotateSizeMB < 0 {
+		return fmt.Errorf("log_rotate_size_mb cannot be negative")
+	}
+	if c.LogRotateCount < 0 {
+		return fmt.Errorf("log_rotate_count cannot be negative")
+	}
+
+	switch c.HeaderCase {
+	case "", "upper", "lower", "title", "snake", "asis", "original":
+	default:
+		return fmt.Errorf("header_case must be one of upper, lower, title, snake, asis, original")
+	}
+
+	if c.MaxResultSetRows < 0 {
+		return fmt.Errorf("max_result_set_rows cannot be negative")
+	}
+	if c.MaxRowsGlobal < 0 {
+		return fmt.Errorf("max_rows_global cannot be negative")
+	}
+	if c.ProgressInterval < 0 {
+		return fmt.Errorf("progress_interval cannot be negative")
+	}
+	if c.MaxRowsPerFile < 0 {
+		return fmt.Errorf("max_rows_per_file cannot be negative")
+	}
+	if c.MaxRowsPerFile > 0 && (c.WriteMeta || c.WriteChecksum) {
+		return fmt.Errorf("max_rows_per_file cannot be combined with write_meta or write_checksum")
+	}
+	if c.MaxMemoryMB < 0 {
+		return fmt.Errorf("max_memory_mb cannot be negative")
+	}
+	if c.DefaultMaxFileSizeMB < 0 {
+		return fmt.Errorf("max_file_size_mb cannot be negative")
+	}
+	if c.FetchRowsHint < 0 {
+		return fmt.Errorf("fetch_rows_hint cannot be negative")
+	}
+
+	// Validate field delimiter (zero means default)
+	delimiter := c.Delimiter
+	if delimiter == 0 {
+		delimiter = DefaultDelimiter
+	}
+	if delimiter == '\r' || delimiter == '\n' {
+		return fmt.Errorf("delimiter cannot be a line break")
+	}
+
+	// Validate CSV quote character (zero means default)
+	switch c.CSVQuoteChar {
+	case delimiter, '\r', '\n':
+		return fmt.Errorf("csv_quote_char cannot be the delimiter or a line break")
+	}
+	if c.CSVQuoteChar == 0 && delimiter == DefaultCSVQuoteChar {
+		return fmt.Errorf("delimiter cannot be the quote character")
+	}
+
+	// Record separator must not clash with the field separator or quote character
+	if c.RecordSeparator != "" {
+		if strings.ContainsRune(c.RecordSeparator, delimiter) ||
+			(c.CSVQuoteChar != 0 && strings.ContainsRune(c.RecordSeparator, c.CSVQuoteChar)) {
+			return fmt.Errorf("record_separator cannot contain the delimiter or the quote character")
+		}
+	}
+
+	// Validate output compression (empty means none)
+	if c.Compress != "" && c.Compress != CompressGzip {
+		return fmt.Errorf("invalid compress: %s (must be %s or %s)", c.Compress, CompressNone, CompressGzip)
+	}
+
+	// Validate export file format (empty means csv)
+	if _, err := RenderFilename(c.FilenameTemplate, FilenameData{
+		Entity:    "schema.entity",
+		StartDate: "2006-01-02T15-04-05",
+		TillDate:  "2006-01-02T15-04-05",
+		RunID:     "2006-01-02T15-04-05",
+	}); err != nil {
+		return fmt.Errorf("filename_template: %w", err)
+	}
+	switch c.BlobEncoding {
+	case "", BlobEncodingHex, BlobEncodingBase64, BlobEncodingSkip:
+	default:
+		return fmt.Errorf("blob_encoding must be %q, %q or %q", BlobEncodingHex, BlobEncodingBase64, BlobEncodingSkip)
+	}
+	if c.ClobMaxLength < 0 {
+		return fmt.Errorf("clob_max_length cannot be negative")
+	}
+	switch c.FileFormat {
+	case "", FileFormatCSV, FileFormatJSONL:
+	case FileFormatParquet:
+		// Parquet column chunks are Snappy compressed, gzip would hide the footer
+		if c.Compress != "" {
+			return fmt.Errorf("compress cannot be used with file_format %q", FileFormatParquet)
+		}
+	default:
+		return fmt.Errorf("file_format must be %q, %q or %q", FileFormatCSV, FileFormatJSONL, FileFormatParquet)
+	}
+	if c.Parallelism < 0 {
+		return fmt.Errorf("parallelism cannot be negative")
+	}
+	if c.ParquetRowGroupMB < 0 {
+		return fmt.Errorf("parquet_row_group_mb cannot be negative")
+	}
+
+	if err := validateParams(c.GlobalParams); err != nil {
+		return err
+	}
+	for _, tag := range slices.Concat(c.IncludeTags, c.ExcludeTags) {
+		if err := ValidateTag(tag); err != nil {
+			return err
+		}
+	}
+	for name := range c.OracleSessionParams {
+		if !paramNamePattern.MatchString(name) {
+			return fmt.Errorf("oracle_session_param %q is not a valid parameter name", name)
+		}
+	}
+
+	// Encryption key is only checked for format, it is needed once an encrypted file is read
+	if c.SQLEncryptionKey != "" {
+		if _, err := crypto.ParseKey(c.SQLEncryptionKey); err != nil {
+			return fmt.Errorf("invalid sql_encryption_key: %w", err)
+		}
+	}
+
+	// Validate deduplication settings
+	if c.DeduplicateOutput && c.BloomFilterSize <= 0 {
+		return fmt.Errorf("bloom_filter_size must be positive when dedup_output is enabled")
 	}
 
 	// Validate S3 configuration
@@ -51,6 +276,47 @@ func (c *Config) Validate() error {
 		return err
 	}
 
+	// Validate Azure configuration, only one cloud destination can be used
+	if c.AzureContainerName != "" {
+		if c.S3.Bucket != "" {
+			return fmt.Errorf("azure_container and s3_bucket cannot both be set")
+		}
+		if c.AzureConnectionString == "" {
+			return fmt.Errorf("azure_container requires the %s environment variable", AzureConnectionStringEnv)
+		}
+	}
+	if c.GCSBucket != "" && (c.S3.Bucket != "" || c.AzureContainerName != "") {
+		return fmt.Errorf("gcs_bucket cannot be combined with s3_bucket or azure_container")
+	}
+	if c.SFTP.Host != "" && (c.S3.Bucket != "" || c.AzureContainerName != "" || c.GCSBucket != "") {
+		return fmt.Errorf("sftp_host cannot be combined with s3_bucket, azure_container or gcs_bucket")
+	}
+	if err := c.SFTP.Validate(); err != nil {
+		return err
+	}
+	if c.AlsoLocalPath != "" && c.S3.Bucket == "" && c.AzureContainerName == "" && c.GCSBucket == "" {
+		return fmt.Errorf("also_local_path requires s3_bucket, azure_container or gcs_bucket")
+	}
+
+	return nil
+}
+
+// ReservedParams are the bind variables set by the exporter, they cannot be passed with --param
+var ReservedParams = []string{"startDate", "tillDate", "startValue", "tillValue"}
+
+// validateParams checks that global param names are bind variable names and not reserved
+func validateParams(params map[string]string) error {
+	for key := range params {
+		if !paramNamePattern.MatchString(key) {
+			return fmt.Errorf("param %q is not a valid bind variable name", key)
+		}
+		for _, reserved := range ReservedParams {
+			// Oracle bind names are case-insensitive
+			if strings.EqualFold(key, reserved) {
+				return fmt.Errorf("param %q is reserved", key)
+			}
+		}
+	}
 	return nil
 }
 
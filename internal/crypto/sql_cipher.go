@@ -0,0 +1,102 @@
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Magic prefixes the base64-encoded AES-256-GCM ciphertext of an encrypted SQL file
+const Magic = "ORASQL-ENC-v1:"
+
+// KeySize is the AES-256 key length in bytes
+const KeySize = 32
+
+// ErrNoKey is returned when an encrypted SQL file is loaded without a key
+var ErrNoKey = errors.New("SQL file is encrypted but no encryption key is configured")
+
+// ParseKey decodes a hex-encoded AES-256 key
+func ParseKey(hexKey string) ([]byte, error) {
+	key, err := hex.DecodeString(strings.TrimSpace(hexKey))
+	if err != nil {
+		return nil, fmt.Errorf("encryption key must be hex-encoded: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes (%d hex characters), got %d bytes", KeySize, KeySize*2, len(key))
+	}
+	return key, nil
+}
+
+// IsEncrypted reports whether content starts with the encrypted SQL header
+func IsEncrypted(content string) bool {
+	return strings.HasPrefix(content, Magic)
+}
+
+// EncryptSQL encrypts SQL with AES-256-GCM and returns the header followed by
+// base64 of the nonce and ciphertext
+func EncryptSQL(sql string, key []byte) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(sql), nil)
+	return Magic + base64.StdEncoding.EncodeToString(sealed) + "\n", nil
+}
+
+// DecryptSQL returns the SQL from content, decrypting it if it has the encrypted
+// header; unencrypted content is returned unchanged
+func DecryptSQL(content string, key []byte) (string, error) {
+	if !IsEncrypted(content) {
+		return content, nil
+	}
+	if len(key) == 0 {
+		return "", ErrNoKey
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(strings.TrimPrefix(content, Magic)))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode encrypted SQL: %w", err)
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", fmt.Errorf("encrypted SQL is too short")
+	}
+
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt SQL (wrong key or corrupted file): %w", err)
+	}
+	return string(plain), nil
+}
+
+// newGCM creates an AES-GCM cipher for a 32-byte key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("encryption key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
@@ -0,0 +1,96 @@
+package crypto
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testKeyHex = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+
+func TestEncryptDecryptSQL(t *testing.T) {
+	key, err := ParseKey(testKeyHex)
+	if err != nil {
+		t.Fatalf("ParseKey() error = %v", err)
+	}
+
+	sql := "SELECT id, name\nFROM crm.products\nWHERE updated >= :startDate -- ünïcode"
+	encrypted, err := EncryptSQL(sql, key)
+	if err != nil {
+		t.Fatalf("EncryptSQL() error = %v", err)
+	}
+	if !IsEncrypted(encrypted) {
+		t.Fatalf("encrypted content missing header: %q", encrypted)
+	}
+	if strings.Contains(encrypted, "crm.products") {
+		t.Error("encrypted content contains plaintext")
+	}
+
+	decrypted, err := DecryptSQL(encrypted, key)
+	if err != nil {
+		t.Fatalf("DecryptSQL() error = %v", err)
+	}
+	if decrypted != sql {
+		t.Errorf("DecryptSQL() = %q, want %q", decrypted, sql)
+	}
+}
+
+func TestDecryptSQL(t *testing.T) {
+	key, _ := ParseKey(testKeyHex)
+	encrypted, err := EncryptSQL("SELECT 1 FROM dual", key)
+	if err != nil {
+		t.Fatalf("EncryptSQL() error = %v", err)
+	}
+
+	t.Run("unencrypted passes through", func(t *testing.T) {
+		sql := "SELECT 1 FROM dual"
+		got, err := DecryptSQL(sql, nil)
+		if err != nil {
+			t.Fatalf("DecryptSQL() error = %v", err)
+		}
+		if got != sql {
+			t.Errorf("DecryptSQL() = %q, want %q", got, sql)
+		}
+	})
+
+	t.Run("missing key", func(t *testing.T) {
+		if _, err := DecryptSQL(encrypted, nil); !errors.Is(err, ErrNoKey) {
+			t.Errorf("DecryptSQL() error = %v, want ErrNoKey", err)
+		}
+	})
+
+	t.Run("wrong key", func(t *testing.T) {
+		wrong := make([]byte, KeySize)
+		if _, err := DecryptSQL(encrypted, wrong); err == nil {
+			t.Error("expected error for wrong key")
+		}
+	})
+
+	t.Run("corrupted payload", func(t *testing.T) {
+		if _, err := DecryptSQL(Magic+"not base64!", key); err == nil {
+			t.Error("expected error for corrupted payload")
+		}
+	})
+}
+
+func TestParseKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		wantErr bool
+	}{
+		{"valid", testKeyHex, false},
+		{"uppercase", strings.ToUpper(testKeyHex), false},
+		{"too short", "0001", true},
+		{"not hex", strings.Repeat("zz", KeySize), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseKey(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseKey(%q) error = %v, wantErr %v", tt.input, err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/secrets"
+)
+
+// Connect establishes a connection to the Oracle database from configuration
+// Transient failures are retried with backoff, credential errors fail immediately
+// A configured secret provider supplies the password before connecting
+func Connect(ctx context.Context, cfg *config.Config, logger *logging.Logger) (DB, error) {
+	if err := secrets.ResolveDBPassword(ctx, cfg); err != nil {
+		return nil, err
+	}
+
+	attempts := cfg.ConnectRetryCount + 1
+	onRetry := func(attempt int, err error, wait time.Duration) {
+		logger.Info("Connection attempt %d/%d failed: %v, retrying in %s", attempt, attempts, err, wait)
+	}
+
+	connect := connector(cfg)
+	database, err := ConnectWithRetry(ctx, cfg.ConnectRetryCount, cfg.ConnectRetryDelay, onRetry, connect)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return withWalletRefresh(ctx, cfg, logger, database, connect), nil
+}
+
+// Reconnector returns a function opening a new connection like Connect, without retries
+// The password resolved by Connect is reused. It is the reconnect of StartConnectivityMonitor.
+func Reconnector(cfg *config.Config, logger *logging.Logger) func(ctx context.Context) (DB, error) {
+	connect := connector(cfg)
+	return func(ctx context.Context) (DB, error) {
+		database, err := connect(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return withWalletRefresh(ctx, cfg, logger, database, connect), nil
+	}
+}
+
+// connector returns a function opening a single connection from configuration
+func connector(cfg *config.Config) func(ctx context.Context) (DB, error) {
+	return func(ctx context.Context) (DB, error) {
+		connCtx, connCancel := context.WithTimeout(ctx, cfg.ConnectTimeout)
+		defer connCancel()
+
+		database, err := ConnectString(
+			connCtx,
+			cfg.ConnectionString(),
+			"", // user and password are already in connection string
+			"",
+			cfg.ConnectTimeout,
+			sessionStatements(cfg)...,
+		)
+		if err != nil {
+			return nil, err
+		}
+		database.SetGlobalParams(cfg.GlobalParams)
+		if err := database.SetSessionTimeout(ctx, cfg.OracleSessionTimeout); err != nil {
+			_ = database.Close()
+			return nil, err
+		}
+		return database, nil
+	}
+}
+
+// withWalletRefresh reconnects periodically so renewed wallet certificates are picked up
+// database is returned as is unless WalletRefreshInterval is set.
+func withWalletRefresh(ctx context.Context, cfg *config.Config, logger *logging.Logger, database DB, connect func(ctx context.Context) (DB, error)) DB {
+	if cfg.WalletRefreshInterval <= 0 {
+		return database
+	}
+	return StartWalletRefresh(ctx, database, cfg.WalletRefreshInterval, connect, func(err error) {
+		if err != nil {
+			logger.Error("Wallet refresh failed, keeping current connection: %v", err)
+			return
+		}
+		logger.Debug("Database connection refreshed")
+	})
+}
+
+// sessionStatements returns the ALTER SESSION statements run on every connection
+// NLS and other session parameters keep the output format independent of the instance defaults.
+func sessionStatements(cfg *config.Config) []string {
+	return SessionParamStatements(cfg.OracleSessionParams)
+}
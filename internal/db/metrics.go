@@ -0,0 +1,122 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+)
+
+// QueryMetrics describes the rows read by a query, see MeteredRows
+type QueryMetrics struct {
+	RowsScanned  int64
+	BytesScanned int64         // Size of the text and binary values scanned
+	Duration     time.Duration // From executing the query until its rows were done or closed
+}
+
+// RowsPerSec returns the rows scanned per second, 0 without a duration
+func (m QueryMetrics) RowsPerSec() float64 {
+	if m.Duration <= 0 {
+		return 0
+	}
+	return float64(m.RowsScanned) / m.Duration.Seconds()
+}
+
+// BytesPerSec returns the bytes scanned per second, 0 without a duration
+func (m QueryMetrics) BytesPerSec() float64 {
+	if m.Duration <= 0 {
+		return 0
+	}
+	return float64(m.BytesScanned) / m.Duration.Seconds()
+}
+
+// MeteredRows wraps *sql.Rows, counting the rows and bytes scanned
+// The counters are atomic, so Metrics may be read while another goroutine iterates the rows.
+type MeteredRows struct {
+	*sql.Rows
+	start    time.Time
+	rows     atomic.Int64
+	bytes    atomic.Int64
+	duration atomic.Int64 // Set once the rows are done
+}
+
+// QueryMetered executes a query like database.QueryContext and meters its rows
+// The timer starts before the query is executed, so the duration includes waiting for the first rows.
+func QueryMetered(ctx context.Context, database DB, query string, args map[string]interface{}) (*MeteredRows, error) {
+	start := time.Now()
+	rows, err := database.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &MeteredRows{Rows: rows, start: start}, nil
+}
+
+// Next advances to the next row, counting it
+func (r *MeteredRows) Next() bool {
+	if r.Rows.Next() {
+		r.rows.Add(1)
+		return true
+	}
+	r.finish()
+	return false
+}
+
+// Scan copies the current row into dest, counting the size of its values
+func (r *MeteredRows) Scan(dest ...interface{}) error {
+	if err := r.Rows.Scan(dest...); err != nil {
+		return err
+	}
+	r.bytes.Add(scannedBytes(dest))
+	return nil
+}
+
+// Close closes the rows, stopping the timer if the rows were not read to the end
+func (r *MeteredRows) Close() error {
+	r.finish()
+	return r.Rows.Close()
+}
+
+// Metrics returns the rows and bytes scanned so far
+// Until the rows are done the duration is the time elapsed since the query started.
+func (r *MeteredRows) Metrics() QueryMetrics {
+	duration := time.Duration(r.duration.Load())
+	if duration == 0 {
+		duration = time.Since(r.start)
+	}
+	return QueryMetrics{
+		RowsScanned:  r.rows.Load(),
+		BytesScanned: r.bytes.Load(),
+		Duration:     duration,
+	}
+}
+
+// finish stops the timer, the first call wins
+func (r *MeteredRows) finish() {
+	r.duration.CompareAndSwap(0, int64(max(time.Since(r.start), 1)))
+}
+
+// scannedBytes returns the size of the text and binary values in scan destinations
+// Other types (numbers, times) are not counted, NULLs count as empty.
+func scannedBytes(dest []interface{}) int64 {
+	var n int64
+	for _, d := range dest {
+		switch v := d.(type) {
+		case *sql.NullString:
+			n += int64(len(v.String))
+		case *string:
+			n += int64(len(*v))
+		case *[]byte:
+			n += int64(len(*v))
+		case *sql.RawBytes:
+			n += int64(len(*v))
+		case *interface{}:
+			switch value := (*v).(type) {
+			case string:
+				n += int64(len(value))
+			case []byte:
+				n += int64(len(value))
+			}
+		}
+	}
+	return n
+}
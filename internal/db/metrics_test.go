@@ -0,0 +1,44 @@
+package db
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func TestQueryMetrics_Rates(t *testing.T) {
+	m := QueryMetrics{RowsScanned: 1000, BytesScanned: 50000, Duration: 2 * time.Second}
+	if got := m.RowsPerSec(); got != 500 {
+		t.Errorf("RowsPerSec() = %v, want 500", got)
+	}
+	if got := m.BytesPerSec(); got != 25000 {
+		t.Errorf("BytesPerSec() = %v, want 25000", got)
+	}
+
+	empty := QueryMetrics{RowsScanned: 10}
+	if empty.RowsPerSec() != 0 || empty.BytesPerSec() != 0 {
+		t.Error("expected zero rates without a duration")
+	}
+}
+
+func TestScannedBytes(t *testing.T) {
+	text := "abc"
+	data := []byte("12345")
+	raw := sql.RawBytes("xy")
+	var anyString interface{} = "hello"
+	var anyNumber interface{} = int64(42)
+	dest := []interface{}{
+		&sql.NullString{String: "four", Valid: true},
+		&sql.NullString{},
+		&text,
+		&data,
+		&raw,
+		&anyString,
+		&anyNumber,
+		new(int64),
+	}
+
+	if got := scannedBytes(dest); got != 4+3+5+2+5 {
+		t.Errorf("scannedBytes() = %d, want 19", got)
+	}
+}
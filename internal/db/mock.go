@@ -108,6 +108,8 @@ func (m *MockRowScanner) Scan(dest ...interface{}) error {
 			case *sql.NullString:
 				ptr.String = val
 				ptr.Valid = true
+			case *[]byte:
+				*ptr = []byte(val)
 			}
 		}
 	}
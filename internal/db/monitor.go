@@ -0,0 +1,132 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/logging"
+)
+
+// ErrConnectionLost is the cause passed to onLost when reconnecting failed, see StartConnectivityMonitor
+var ErrConnectionLost = errors.New("database connection lost")
+
+// ConnectivityMonitor is a DB that pings its connection at a fixed interval and reconnects when
+// the ping fails, so long runs survive a dropped connection between entities.
+// Queries hold a read lock while starting, the swap to the new connection holds the write lock.
+type ConnectivityMonitor struct {
+	mu        sync.RWMutex
+	db        DB
+	reconnect func(ctx context.Context) (DB, error)
+	retries   int
+	backoff   time.Duration
+	logger    *logging.Logger
+	onLost    func(err error)
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// StartConnectivityMonitor wraps database and pings it every interval until ctx is canceled or
+// Close is called. A failed ping reconnects with reconnect, up to retries attempts with backoff
+// doubled between them. When all attempts fail, monitoring stops and onLost is called with an
+// error wrapping ErrConnectionLost, e.g. to cancel the export.
+func StartConnectivityMonitor(ctx context.Context, database DB, interval time.Duration, retries int, backoff time.Duration,
+	reconnect func(ctx context.Context) (DB, error), logger *logging.Logger, onLost func(err error)) *ConnectivityMonitor {
+	ctx, cancel := context.WithCancel(ctx)
+	m := &ConnectivityMonitor{
+		db:        database,
+		reconnect: reconnect,
+		retries:   max(retries, 1),
+		backoff:   backoff,
+		logger:    logger,
+		onLost:    onLost,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		defer close(m.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pingCtx, pingCancel := context.WithTimeout(ctx, interval)
+				err := m.Ping(pingCtx)
+				pingCancel()
+				if err == nil || ctx.Err() != nil {
+					continue
+				}
+				if err := m.reconnectAfter(ctx, err); err != nil {
+					if ctx.Err() == nil && m.onLost != nil {
+						m.onLost(err)
+					}
+					return
+				}
+			}
+		}
+	}()
+
+	return m
+}
+
+// reconnectAfter reconnects after pingErr, swaps in the new connection and closes the old one
+// The old connection is closed outside the lock since sql.DB.Close waits for running queries
+func (m *ConnectivityMonitor) reconnectAfter(ctx context.Context, pingErr error) error {
+	m.logger.Info("Warning: database ping failed: %v, reconnecting", pingErr)
+	var fresh DB
+	onRetry := func(attempt int, err error, wait time.Duration) {
+		m.logger.Info("Warning: reconnect attempt %d/%d failed: %v, retrying in %s", attempt, m.retries, err, wait.Round(time.Millisecond))
+	}
+	err := RetryWithBackoff(ctx, m.retries-1, m.backoff, IsRetryableConnectError, onRetry, func() error {
+		conn, err := m.reconnect(ctx)
+		if err != nil {
+			return err
+		}
+		fresh = conn
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%w: reconnect failed: %w", ErrConnectionLost, err)
+	}
+
+	m.mu.Lock()
+	old := m.db
+	m.db = fresh
+	m.mu.Unlock()
+
+	m.logger.Info("Database connection re-established")
+	if err := old.Close(); err != nil {
+		m.logger.Debug("Failed to close lost database connection: %v", err)
+	}
+	return nil
+}
+
+// Close stops monitoring and closes the current connection
+func (m *ConnectivityMonitor) Close() error {
+	m.cancel()
+	<-m.done
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.db.Close()
+}
+
+// QueryContext executes a query on the current connection
+func (m *ConnectivityMonitor) QueryContext(ctx context.Context, query string, args map[string]interface{}) (*sql.Rows, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.db.QueryContext(ctx, query, args)
+}
+
+// Ping checks if the current connection is alive
+func (m *ConnectivityMonitor) Ping(ctx context.Context) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.db.Ping(ctx)
+}
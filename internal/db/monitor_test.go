@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/logging"
+)
+
+// lostDB returns a MockDB whose pings fail
+func lostDB() *MockDB {
+	conn := NewMockDB()
+	conn.PingFunc = func(ctx context.Context) error { return errors.New("ORA-03113: end-of-file on communication channel") }
+	return conn
+}
+
+func TestConnectivityMonitor_Reconnects(t *testing.T) {
+	initial := lostDB()
+	replaced := make(chan struct{})
+	initial.CloseFunc = func() error {
+		close(replaced)
+		return nil
+	}
+	fresh := NewMockDB()
+	var attempts atomic.Int32
+	reconnect := func(ctx context.Context) (DB, error) {
+		if attempts.Add(1) == 1 {
+			return nil, errors.New("connection refused")
+		}
+		return fresh, nil
+	}
+
+	lost := make(chan error, 1)
+	m := StartConnectivityMonitor(context.Background(), initial, 10*time.Millisecond, 3, time.Millisecond,
+		reconnect, logging.New(false), func(err error) { lost <- err })
+
+	select {
+	case <-replaced:
+	case err := <-lost:
+		t.Fatalf("connection reported lost: %v", err)
+	case <-time.After(time.Second):
+		t.Fatal("lost connection was not replaced")
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("reconnect called %d times, want 2", got)
+	}
+	if !fresh.Closed {
+		t.Error("new connection not closed by Close()")
+	}
+}
+
+func TestConnectivityMonitor_Lost(t *testing.T) {
+	var attempts atomic.Int32
+	reconnect := func(ctx context.Context) (DB, error) {
+		attempts.Add(1)
+		return nil, errors.New("connection refused")
+	}
+
+	lost := make(chan error, 1)
+	m := StartConnectivityMonitor(context.Background(), lostDB(), 10*time.Millisecond, 2, time.Millisecond,
+		reconnect, logging.New(false), func(err error) { lost <- err })
+	defer func() { _ = m.Close() }()
+
+	select {
+	case err := <-lost:
+		if !errors.Is(err, ErrConnectionLost) {
+			t.Errorf("onLost error = %v, want ErrConnectionLost", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("onLost was not called")
+	}
+	if got := attempts.Load(); got != 2 {
+		t.Errorf("reconnect called %d times, want 2", got)
+	}
+
+	// Monitoring stops once the connection is reported lost
+	select {
+	case <-m.done:
+	case <-time.After(time.Second):
+		t.Fatal("monitor goroutine did not stop")
+	}
+}
+
+func TestConnectivityMonitor_HealthyConnection(t *testing.T) {
+	reconnect := func(ctx context.Context) (DB, error) {
+		t.Error("reconnect called for a healthy connection")
+		return NewMockDB(), nil
+	}
+	m := StartConnectivityMonitor(context.Background(), NewMockDB(), time.Millisecond, 3, time.Millisecond,
+		reconnect, logging.New(false), nil)
+	time.Sleep(20 * time.Millisecond)
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
@@ -2,7 +2,15 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strconv"
 	"testing"
+	"time"
+
+	"github.com/sijms/go-ora/v2/configurations"
 )
 
 func TestNew(t *testing.T) {
@@ -25,6 +33,24 @@ func TestNew(t *testing.T) {
 	}
 }
 
+func TestConfig_ConnectionString(t *testing.T) {
+	cfg := &Config{User: "test", Password: "test", Host: "localhost", Port: 1521, Service: "TEST"}
+	for _, fetchSize := range []int{0, 5000} {
+		cfg.FetchSize = fetchSize
+		parsed, err := configurations.ParseConfig(cfg.ConnectionString())
+		if err != nil {
+			t.Fatalf("ParseConfig(%q) error = %v", cfg.ConnectionString(), err)
+		}
+		want := fetchSize
+		if want == 0 {
+			want = 25 // go-ora default
+		}
+		if parsed.PrefetchRows != want {
+			t.Errorf("FetchSize %d: PrefetchRows = %d, want %d", fetchSize, parsed.PrefetchRows, want)
+		}
+	}
+}
+
 func TestConnectString_Empty(t *testing.T) {
 	_, err := ConnectString(context.Background(), "", "", "", 0)
 	if err == nil {
@@ -32,6 +58,95 @@ func TestConnectString_Empty(t *testing.T) {
 	}
 }
 
+func TestNewFromConn(t *testing.T) {
+	db := NewFromConn(nil, BindPositional)
+	if db.bindStyle != BindPositional {
+		t.Errorf("bindStyle = %v, want BindPositional", db.bindStyle)
+	}
+	if err := db.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+}
+
+func TestArgsToPositional(t *testing.T) {
+	args := map[string]interface{}{
+		"zeta":      3,
+		"tillDate":  "2025-01-02T00:00:00",
+		"alpha":     2,
+		"startDate": "2025-01-01T00:00:00",
+	}
+
+	got := argsToPositional("SELECT * FROM t WHERE updated >= ? AND updated < ?", args)
+	want := []interface{}{"2025-01-01T00:00:00", "2025-01-02T00:00:00", 2, 3}
+	if len(got) != len(want) {
+		t.Fatalf("got %d args, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("arg[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	if argsToPositional("", nil) != nil {
+		t.Error("expected nil for empty args")
+	}
+
+	// Binds written as :name are passed by name
+	got = argsToPositional("SELECT table_name FROM all_tables WHERE owner = :owner", map[string]interface{}{"owner": "CRM"})
+	if len(got) != 1 || got[0] != sql.Named("owner", "CRM") {
+		t.Errorf("got %v, want named owner argument", got)
+	}
+}
+
+func TestArgsToSlice_SequenceParams(t *testing.T) {
+	args := map[string]interface{}{
+		"tillValue":  int64(20),
+		"startValue": int64(10),
+	}
+
+	got := argsToSlice("", args, nil)
+	want := []sql.NamedArg{sql.Named("startValue", int64(10)), sql.Named("tillValue", int64(20))}
+	if len(got) != len(want) {
+		t.Fatalf("got %d args, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("arg[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestArgsToSlice_GlobalParams(t *testing.T) {
+	query := "SELECT * FROM t WHERE tenant = :TenantId AND d >= :startDate"
+	globals := map[string]string{"tenantId": "acme", "region": "eu"}
+
+	t.Run("referenced global params are added", func(t *testing.T) {
+		got := argsToSlice(query, map[string]interface{}{"startDate": "2025-01-01T00:00:00"}, globals)
+		want := []sql.NamedArg{sql.Named("startDate", "2025-01-01T00:00:00"), sql.Named("tenantId", "acme")}
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("arg[%d] = %v, want %v", i, got[i], want[i])
+			}
+		}
+	})
+
+	t.Run("args override global params", func(t *testing.T) {
+		got := argsToSlice(query, map[string]interface{}{"tenantId": "other"}, globals)
+		if len(got) != 1 || got[0] != sql.Named("tenantId", "other") {
+			t.Errorf("got %v, want only the tenantId arg", got)
+		}
+	})
+
+	t.Run("unreferenced global params are skipped", func(t *testing.T) {
+		if got := argsToSlice("SELECT 1 FROM dual", nil, globals); got != nil {
+			t.Errorf("got %v, want nil", got)
+		}
+	})
+}
+
 func TestMockDB(t *testing.T) {
 	t.Run("Close", func(t *testing.T) {
 		mock := NewMockDB()
@@ -190,3 +305,61 @@ type testErr string
 func (e testErr) Error() string {
 	return string(e)
 }
+
+// batchRows returns total rows, waiting roundTrip for every fetchSize rows like a driver prefetching them
+type batchRows struct {
+	total, fetchSize, next int
+	roundTrip              time.Duration
+}
+
+func (r *batchRows) Columns() []string { return []string{"ID", "NAME"} }
+func (r *batchRows) Close() error      { return nil }
+
+func (r *batchRows) Next(dest []driver.Value) error {
+	if r.next >= r.total {
+		return io.EOF
+	}
+	if r.next%r.fetchSize == 0 {
+		time.Sleep(r.roundTrip)
+	}
+	r.next++
+	dest[0] = strconv.Itoa(r.next)
+	dest[1] = "name"
+	return nil
+}
+
+// BenchmarkFetchSize measures rows/sec reading a result set with different fetch sizes
+// The mock driver waits a simulated round-trip per fetched batch, so it shows the effect of
+// fewer round trips, not go-ora's own throughput.
+func BenchmarkFetchSize(b *testing.B) {
+	const total = 10000
+	for _, fetchSize := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("fetch_size=%d", fetchSize), func(b *testing.B) {
+			fake := &fakeConnector{rows: func() driver.Rows {
+				return &batchRows{total: total, fetchSize: fetchSize, roundTrip: 100 * time.Microsecond}
+			}}
+			database := NewFromConn(sql.OpenDB(fake), BindNamed)
+			defer func() { _ = database.Close() }()
+
+			var metrics QueryMetrics
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				rows, err := QueryMetered(context.Background(), database, "SELECT id, name FROM crm.orders", nil)
+				if err != nil {
+					b.Fatalf("QueryMetered() error = %v", err)
+				}
+				var id, name sql.NullString
+				for rows.Next() {
+					if err := rows.Scan(&id, &name); err != nil {
+						b.Fatalf("Scan() error = %v", err)
+					}
+				}
+				_ = rows.Close()
+				m := rows.Metrics()
+				metrics.RowsScanned += m.RowsScanned
+				metrics.Duration += m.Duration
+			}
+			b.ReportMetric(metrics.RowsPerSec(), "rows/s")
+		})
+	}
+}
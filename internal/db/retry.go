@@ -0,0 +1,143 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+)
+
+// nonRetryableConnectErrors are error fragments that retrying cannot fix
+var nonRetryableConnectErrors = []string{
+	"ORA-01017", // invalid username/password
+	"ORA-01005", // null password
+	"ORA-28000", // account locked
+	"ORA-28001", // password expired
+	"invalid username",
+	"invalid credentials",
+	"logon denied",
+	"empty connection string",
+}
+
+// IsRetryableConnectError reports whether a connection error may be transient
+// (connection refused, timeouts, listener not ready) rather than a credentials problem
+func IsRetryableConnectError(err error) bool {
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range nonRetryableConnectErrors {
+		if strings.Contains(msg, strings.ToLower(fragment)) {
+			return false
+		}
+	}
+	return true
+}
+
+// nonRetryableQueryErrors are query errors that fail the same way on every attempt
+var nonRetryableQueryErrors = []string{
+	"ORA-00942", // table or view does not exist
+	"ORA-00904", // invalid identifier
+	"ORA-01017", // invalid username/password
+	"ORA-01031", // insufficient privileges
+}
+
+// retryableQueryErrors are error fragments of lost connections and network failures
+var retryableQueryErrors = []string{
+	"ORA-12541", // TNS no listener
+	"ORA-03135", // connection lost contact
+	"ORA-03113", // end-of-file on communication channel
+	"ORA-03114", // not connected to Oracle
+	"ORA-12170", // TNS connect timeout
+	"ORA-12537", // TNS connection closed
+	"ORA-25408", // can not safely replay call
+	"connection reset",
+	"connection refused",
+	"broken pipe",
+	"i/o timeout",
+}
+
+// IsRetryableQueryError reports whether a query failed for a transient reason
+// (lost connection, listener down, network timeout) so running it again may succeed.
+// Unknown errors, SQL errors and context cancellation are not retryable.
+func IsRetryableQueryError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	for _, fragment := range nonRetryableQueryErrors {
+		if strings.Contains(msg, strings.ToLower(fragment)) {
+			return false
+		}
+	}
+
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return true
+	}
+	for _, fragment := range retryableQueryErrors {
+		if strings.Contains(msg, strings.ToLower(fragment)) {
+			return true
+		}
+	}
+	return false
+}
+
+// withJitter adds up to 25% random delay so parallel retries spread out
+func withJitter(delay time.Duration) time.Duration {
+	if delay < 4 {
+		return delay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay/4)))
+}
+
+// RetryWithBackoff calls fn up to retries+1 times, doubling delay after each failure
+// It stops early when retryable reports false or ctx is done and returns the last error.
+// onRetry, if set, is called before each wait with the failed attempt number (1-based).
+// Each wait adds up to 25% jitter to the doubled delay.
+func RetryWithBackoff(ctx context.Context, retries int, delay time.Duration, retryable func(error) bool,
+	onRetry func(attempt int, err error, wait time.Duration), fn func() error) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt > retries || (retryable != nil && !retryable(err)) {
+			return err
+		}
+
+		wait := withJitter(delay)
+		if onRetry != nil {
+			onRetry(attempt, err, wait)
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// ConnectWithRetry calls connect until it succeeds, retrying transient connection errors
+func ConnectWithRetry(ctx context.Context, retries int, delay time.Duration,
+	onRetry func(attempt int, err error, wait time.Duration), connect func(ctx context.Context) (DB, error)) (DB, error) {
+	var database DB
+	err := RetryWithBackoff(ctx, retries, delay, IsRetryableConnectError, onRetry, func() error {
+		conn, err := connect(ctx)
+		if err != nil {
+			return err
+		}
+		database = conn
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return database, nil
+}
@@ -0,0 +1,133 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestConnectWithRetry(t *testing.T) {
+	refused := errors.New("dial tcp 127.0.0.1:1521: connect: connection refused")
+
+	t.Run("succeeds after failures", func(t *testing.T) {
+		const failures = 2
+		calls := 0
+		var retried []int
+		mock := NewMockDB()
+
+		database, err := ConnectWithRetry(context.Background(), 3, time.Millisecond,
+			func(attempt int, err error, wait time.Duration) { retried = append(retried, attempt) },
+			func(ctx context.Context) (DB, error) {
+				calls++
+				if calls <= failures {
+					return nil, refused
+				}
+				return mock, nil
+			})
+		if err != nil {
+			t.Fatalf("ConnectWithRetry() error = %v", err)
+		}
+		if database != mock {
+			t.Error("expected the mock database")
+		}
+		if calls != failures+1 {
+			t.Errorf("connect called %d times, want %d", calls, failures+1)
+		}
+		if len(retried) != failures {
+			t.Errorf("onRetry called %d times, want %d", len(retried), failures)
+		}
+	})
+
+	t.Run("gives up after retries", func(t *testing.T) {
+		calls := 0
+		_, err := ConnectWithRetry(context.Background(), 2, time.Millisecond, nil,
+			func(ctx context.Context) (DB, error) {
+				calls++
+				return nil, refused
+			})
+		if !errors.Is(err, refused) {
+			t.Errorf("error = %v, want last connect error", err)
+		}
+		if calls != 3 {
+			t.Errorf("connect called %d times, want 3", calls)
+		}
+	})
+
+	t.Run("invalid credentials are not retried", func(t *testing.T) {
+		calls := 0
+		_, err := ConnectWithRetry(context.Background(), 3, time.Millisecond, nil,
+			func(ctx context.Context) (DB, error) {
+				calls++
+				return nil, errors.New("ORA-01017: invalid username/password; logon denied")
+			})
+		if err == nil {
+			t.Fatal("expected error")
+		}
+		if calls != 1 {
+			t.Errorf("connect called %d times, want 1", calls)
+		}
+	})
+
+	t.Run("stops when context is canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		_, err := ConnectWithRetry(ctx, 5, time.Hour, func(int, error, time.Duration) { cancel() },
+			func(ctx context.Context) (DB, error) {
+				calls++
+				return nil, refused
+			})
+		if err == nil || calls != 1 {
+			t.Errorf("calls = %d, err = %v; want 1 call and an error", calls, err)
+		}
+	})
+}
+
+func TestIsRetryableConnectError(t *testing.T) {
+	tests := []struct {
+		err  string
+		want bool
+	}{
+		{"dial tcp: connect: connection refused", true},
+		{"i/o timeout", true},
+		{"ORA-12541: TNS:no listener", true},
+		{"ORA-01017: invalid username/password; logon denied", false},
+		{"ORA-28000: the account is locked", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err, func(t *testing.T) {
+			if got := IsRetryableConnectError(errors.New(tt.err)); got != tt.want {
+				t.Errorf("IsRetryableConnectError(%q) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsRetryableQueryError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"no listener", errors.New("ORA-12541: TNS:no listener"), true},
+		{"connection lost", errors.New("ORA-03135: connection lost contact"), true},
+		{"network timeout", errors.New("read tcp 10.0.0.1:1521: i/o timeout"), true},
+		{"bad connection", fmt.Errorf("query failed: %w", driver.ErrBadConn), true},
+		{"table not found", errors.New("ORA-00942: table or view does not exist"), false},
+		{"invalid credentials", errors.New("ORA-01017: invalid username/password; logon denied"), false},
+		{"unknown", errors.New("ORA-01722: invalid number"), false},
+		{"canceled", context.Canceled, false},
+		{"deadline", fmt.Errorf("query failed: %w", context.DeadlineExceeded), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsRetryableQueryError(tt.err); got != tt.want {
+				t.Errorf("IsRetryableQueryError(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// sessionConnector runs session setup statements, e.g. ALTER SESSION, on every new connection
+// database/sql may replace a connection after an idle close or a bad connection error;
+// running the statements in Connect keeps the settings on each replacement.
+type sessionConnector struct {
+	driver.Connector
+	statements []string
+}
+
+// Connect opens a connection and applies the session statements to it
+func (c *sessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, statement := range c.statements {
+		if err := execConn(ctx, conn, statement); err != nil {
+			_ = conn.Close()
+			return nil, fmt.Errorf("failed to set up session (%s): %w", statement, err)
+		}
+	}
+	return conn, nil
+}
+
+// execConn executes a statement without arguments on a driver connection
+func execConn(ctx context.Context, conn driver.Conn, query string) error {
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, query, nil)
+		if !errors.Is(err, driver.ErrSkip) {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(query)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = stmt.Close()
+	}()
+	if execer, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = execer.ExecContext(ctx, nil)
+		return err
+	}
+	_, err = stmt.Exec(nil) //nolint:staticcheck // fallback for drivers without StmtExecContext
+	return err
+}
+
+// plainSessionValue matches values that ALTER SESSION accepts without quotes, e.g. AMERICAN or 50
+var plainSessionValue = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// SessionParamStatements returns ALTER SESSION statements setting params, e.g. NLS_DATE_FORMAT,
+// sorted by name. Values other than plain words and numbers are quoted unless already quoted.
+func SessionParamStatements(params map[string]string) []string {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statements := make([]string, 0, len(names))
+	for _, name := range names {
+		value := params[name]
+		quoted := len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'")
+		if !quoted && !plainSessionValue.MatchString(value) {
+			value = "'" + strings.ReplaceAll(value, "'", "''") + "'"
+		}
+		statements = append(statements, fmt.Sprintf("ALTER SESSION SET %s = %s", strings.ToUpper(name), value))
+	}
+	return statements
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fakeConnector counts connections and records statements executed on them
+type fakeConnector struct {
+	connects int
+	executed []string
+	execErr  error
+	last     *fakeConn
+
+	// Context of the last query
+	queryCtx context.Context
+	queryErr error
+	// rows, if set, returns the result of each query instead of no rows
+	rows func() driver.Rows
+}
+
+func (c *fakeConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.connects++
+	c.last = &fakeConn{connector: c}
+	return c.last, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver { return nil }
+
+type fakeConn struct {
+	connector *fakeConnector
+	closed    bool
+}
+
+// QueryContext records the query context and returns the connector rows, or queryErr
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.connector.queryCtx = ctx
+	if c.connector.queryErr != nil {
+		return nil, c.connector.queryErr
+	}
+	if c.connector.rows != nil {
+		return c.connector.rows(), nil
+	}
+	return fakeRows{}, nil
+}
+
+// fakeRows is an empty result set
+type fakeRows struct{}
+
+func (fakeRows) Columns() []string              { return []string{"X"} }
+func (fakeRows) Close() error                   { return nil }
+func (fakeRows) Next(dest []driver.Value) error { return io.EOF }
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.connector.executed = append(c.connector.executed, query)
+	return driver.RowsAffected(0), c.connector.execErr
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestSessionParamStatements(t *testing.T) {
+	got := SessionParamStatements(map[string]string{
+		"nls_numeric_characters": ".,",
+		"NLS_DATE_FORMAT":        "YYYY-MM-DD HH24:MI:SS",
+		"NLS_LANGUAGE":           "AMERICAN",
+		"TIME_ZONE":              "'+00:00'",
+		"NLS_CURRENCY":           "O'K",
+	})
+	want := []string{
+		"ALTER SESSION SET NLS_CURRENCY = 'O''K'",
+		"ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD HH24:MI:SS'",
+		"ALTER SESSION SET NLS_LANGUAGE = AMERICAN",
+		"ALTER SESSION SET TIME_ZONE = '+00:00'",
+		"ALTER SESSION SET NLS_NUMERIC_CHARACTERS = '.,'",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SessionParamStatements() = %q, want %q", got, want)
+	}
+	if got := SessionParamStatements(nil); len(got) != 0 {
+		t.Errorf("SessionParamStatements(nil) = %q, want none", got)
+	}
+}
+
+func TestSessionConnector(t *testing.T) {
+	statement := "ALTER SESSION SET NLS_DATE_FORMAT = 'YYYY-MM-DD'"
+
+	t.Run("every new connection runs the statements", func(t *testing.T) {
+		fake := &fakeConnector{}
+		conn := sql.OpenDB(&sessionConnector{Connector: fake, statements: []string{statement}})
+		defer func() { _ = conn.Close() }()
+		// Without idle connections every ping opens a replacement connection
+		conn.SetMaxIdleConns(0)
+
+		for i := 0; i < 3; i++ {
+			if err := conn.PingContext(context.Background()); err != nil {
+				t.Fatalf("PingContext() error = %v", err)
+			}
+		}
+		if fake.connects != 3 || len(fake.executed) != 3 {
+			t.Fatalf("connects = %d, executed = %v, want 3 of each", fake.connects, fake.executed)
+		}
+		for _, query := range fake.executed {
+			if query != statement {
+				t.Errorf("executed %q, want %q", query, statement)
+			}
+		}
+	})
+
+	t.Run("failed statement closes the connection", func(t *testing.T) {
+		fake := &fakeConnector{execErr: errors.New("ORA-02248: invalid option for ALTER SESSION")}
+		connector := &sessionConnector{Connector: fake, statements: []string{statement}}
+		conn, err := connector.Connect(context.Background())
+		if err == nil {
+			t.Fatal("expected error from ALTER SESSION")
+		}
+		if conn != nil || !fake.last.closed {
+			t.Error("expected the connection to be closed and not returned")
+		}
+	})
+}
+
+func TestOracleDB_SetSessionTimeout(t *testing.T) {
+	fake := &fakeConnector{}
+	database := NewFromConn(sql.OpenDB(fake), BindNamed)
+	defer func() { _ = database.Close() }()
+
+	if err := database.SetSessionTimeout(context.Background(), -time.Second); err == nil {
+		t.Error("expected error for negative timeout")
+	}
+
+	// Without a timeout the query context is passed as is
+	rows, err := database.QueryContext(context.Background(), "SELECT 1 FROM dual", nil)
+	if err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+	_ = rows.Close()
+	if fake.queryCtx.Done() != nil {
+		t.Error("expected a query context without cancellation when the timeout is disabled")
+	}
+
+	// The query, and reading its rows, is cancelled once the timeout expires
+	if err := database.SetSessionTimeout(context.Background(), 50*time.Millisecond); err != nil {
+		t.Fatalf("SetSessionTimeout() error = %v", err)
+	}
+	rows, err = database.QueryContext(context.Background(), "SELECT 1 FROM dual", nil)
+	if err != nil {
+		t.Fatalf("QueryContext() error = %v", err)
+	}
+	defer func() { _ = rows.Close() }()
+	if fake.queryCtx.Err() != nil {
+		t.Fatal("query context cancelled before the timeout")
+	}
+	select {
+	case <-fake.queryCtx.Done():
+	case <-time.After(5 * time.Second):
+		t.Error("query context not cancelled after the timeout")
+	}
+
+	// A failed query releases its timer right away
+	fake.queryErr = errors.New("ORA-00942: table or view does not exist")
+	if _, err := database.QueryContext(context.Background(), "SELECT 1 FROM missing", nil); err == nil {
+		t.Fatal("expected query error")
+	}
+	if fake.queryCtx.Err() == nil {
+		t.Error("expected the context of a failed query to be cancelled")
+	}
+}
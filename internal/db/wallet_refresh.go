@@ -0,0 +1,97 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// RefreshingDB is a DB that reconnects at a fixed interval
+// A fresh go-ora connector re-reads Oracle Wallet files, so long runs pick up renewed certificates.
+// Queries hold a read lock while starting, the swap to the new connection holds the write lock.
+type RefreshingDB struct {
+	mu        sync.RWMutex
+	db        DB
+	connect   func(ctx context.Context) (DB, error)
+	onRefresh func(err error)
+	cancel    context.CancelFunc
+	done      chan struct{}
+}
+
+// StartWalletRefresh wraps database and reconnects with connect every interval until ctx is
+// canceled or Close is called. onRefresh, if set, is called after each attempt with its error.
+func StartWalletRefresh(ctx context.Context, database DB, interval time.Duration,
+	connect func(ctx context.Context) (DB, error), onRefresh func(err error)) *RefreshingDB {
+	ctx, cancel := context.WithCancel(ctx)
+	r := &RefreshingDB{
+		db:        database,
+		connect:   connect,
+		onRefresh: onRefresh,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+
+	go func() {
+		defer close(r.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				err := r.refresh(ctx)
+				if r.onRefresh != nil {
+					r.onRefresh(err)
+				}
+			}
+		}
+	}()
+
+	return r
+}
+
+// refresh opens a new connection, swaps it in and closes the old one
+// The old connection is closed outside the lock since sql.DB.Close waits for running queries
+func (r *RefreshingDB) refresh(ctx context.Context) error {
+	fresh, err := r.connect(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to refresh database connection: %w", err)
+	}
+
+	r.mu.Lock()
+	old := r.db
+	r.db = fresh
+	r.mu.Unlock()
+
+	if err := old.Close(); err != nil {
+		return fmt.Errorf("failed to close previous database connection: %w", err)
+	}
+	return nil
+}
+
+// Close stops refreshing and closes the current connection
+func (r *RefreshingDB) Close() error {
+	r.cancel()
+	<-r.done
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.db.Close()
+}
+
+// QueryContext executes a query on the current connection
+func (r *RefreshingDB) QueryContext(ctx context.Context, query string, args map[string]interface{}) (*sql.Rows, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.QueryContext(ctx, query, args)
+}
+
+// Ping checks if the current connection is alive
+func (r *RefreshingDB) Ping(ctx context.Context) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.db.Ping(ctx)
+}
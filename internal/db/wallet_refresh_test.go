@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStartWalletRefresh(t *testing.T) {
+	var mu sync.Mutex
+	var conns []*MockDB
+	connect := func(ctx context.Context) (DB, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		conn := NewMockDB()
+		conns = append(conns, conn)
+		return conn, nil
+	}
+
+	initial := NewMockDB()
+	refreshed := make(chan error, 10)
+	r := StartWalletRefresh(context.Background(), initial, 10*time.Millisecond, connect,
+		func(err error) { refreshed <- err })
+
+	for i := 0; i < 3; i++ {
+		select {
+		case err := <-refreshed:
+			if err != nil {
+				t.Fatalf("refresh error = %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("refresh %d did not happen", i+1)
+		}
+	}
+
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(conns) < 3 {
+		t.Fatalf("connect called %d times, want at least 3", len(conns))
+	}
+	if !initial.Closed {
+		t.Error("initial connection not closed after refresh")
+	}
+	for i, conn := range conns {
+		if !conn.Closed {
+			t.Errorf("connection %d not closed", i)
+		}
+	}
+}
+
+func TestStartWalletRefresh_FailureKeepsConnection(t *testing.T) {
+	initial := NewMockDB()
+	refreshed := make(chan error, 10)
+	r := StartWalletRefresh(context.Background(), initial, 10*time.Millisecond,
+		func(ctx context.Context) (DB, error) { return nil, errors.New("wallet expired") },
+		func(err error) { refreshed <- err })
+
+	select {
+	case err := <-refreshed:
+		if err == nil {
+			t.Error("expected refresh error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("refresh did not happen")
+	}
+
+	if initial.Closed {
+		t.Error("connection closed after failed refresh")
+	}
+	if err := r.Ping(context.Background()); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+	if err := r.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+}
+
+func TestStartWalletRefresh_StopsOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := StartWalletRefresh(ctx, NewMockDB(), time.Hour, nil, nil)
+	cancel()
+
+	select {
+	case <-r.done:
+	case <-time.After(time.Second):
+		t.Fatal("refresh goroutine did not stop on cancel")
+	}
+}
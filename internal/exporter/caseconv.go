@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Header case modes
+const (
+	CaseUpper = "upper"
+	CaseLower = "lower"
+	CaseTitle = "title"
+	CaseSnake = "snake"
+	CaseAsIs  = "asis"
+	// CaseOriginal is an alias of CaseAsIs
+	CaseOriginal = "original"
+)
+
+// ConvertCase transforms a column name according to mode
+// Unknown or empty modes leave the name unchanged
+func ConvertCase(s, mode string) string {
+	switch mode {
+	case CaseUpper:
+		return strings.ToUpper(s)
+	case CaseLower:
+		return strings.ToLower(s)
+	case CaseTitle:
+		return titleCase(s)
+	case CaseSnake:
+		return snakeCase(s)
+	default:
+		return s
+	}
+}
+
+// snakeCase lower-cases s and separates its words with '_'
+// Words of mixed-case names (quoted Oracle identifiers such as "OrderID") start at each upper-case
+// letter following a lower-case one or ending an acronym, so OrderID becomes order_id and
+// HTTPStatus http_status. Other runes that are not letters or digits become '_', without repeats.
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	b.Grow(len(s) + 4)
+	separated := true // No '_' at the start or after another one
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if !separated {
+				b.WriteByte('_')
+				separated = true
+			}
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 && !separated {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+		separated = false
+	}
+	return strings.TrimSuffix(b.String(), "_")
+}
+
+// titleCase upper-cases the first letter of each word and lower-cases the rest
+// Any rune that is not a letter or digit (e.g. '_') separates words
+func titleCase(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	wordStart := true
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			wordStart = true
+			b.WriteRune(r)
+			continue
+		}
+		if wordStart {
+			b.WriteRune(unicode.ToTitle(r))
+			wordStart = false
+		} else {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,68 @@
+package exporter
+
+import "testing"
+
+func TestConvertCase(t *testing.T) {
+	columns := []string{"CUSTOMER_ID", "ORDER_DATE", "FIRST_NAME"}
+	tests := []struct {
+		mode string
+		want []string
+	}{
+		{CaseUpper, []string{"CUSTOMER_ID", "ORDER_DATE", "FIRST_NAME"}},
+		{CaseLower, []string{"customer_id", "order_date", "first_name"}},
+		{CaseTitle, []string{"Customer_Id", "Order_Date", "First_Name"}},
+		{CaseSnake, []string{"customer_id", "order_date", "first_name"}},
+		{CaseAsIs, []string{"CUSTOMER_ID", "ORDER_DATE", "FIRST_NAME"}},
+		{CaseOriginal, []string{"CUSTOMER_ID", "ORDER_DATE", "FIRST_NAME"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			for i, col := range columns {
+				if got := ConvertCase(col, tt.mode); got != tt.want[i] {
+					t.Errorf("ConvertCase(%q, %q) = %q, want %q", col, tt.mode, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestConvertCase_Unicode(t *testing.T) {
+	tests := []struct {
+		in, mode, want string
+	}{
+		{"ÉTAT_CLIENT", CaseTitle, "État_Client"},
+		{"ÉTAT_CLIENT", CaseLower, "état_client"},
+		{"ADDRESS_LINE2", CaseTitle, "Address_Line2"},
+		{"Mixed_Case", CaseAsIs, "Mixed_Case"},
+		{"Mixed_Case", CaseOriginal, "Mixed_Case"},
+		{"ÉTAT_CLIENT", CaseSnake, "état_client"},
+	}
+
+	for _, tt := range tests {
+		if got := ConvertCase(tt.in, tt.mode); got != tt.want {
+			t.Errorf("ConvertCase(%q, %q) = %q, want %q", tt.in, tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestConvertCase_Snake(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"MY_COLUMN", "my_column"},
+		{"OrderID", "order_id"},
+		{"customerName", "customer_name"},
+		{"HTTPStatus", "http_status"},
+		{"Address Line2", "address_line2"},
+		{"Line2Total", "line2_total"},
+		{"_ROW__NUM_", "row_num"},
+		{"already_snake", "already_snake"},
+	}
+
+	for _, tt := range tests {
+		if got := ConvertCase(tt.in, CaseSnake); got != tt.want {
+			t.Errorf("ConvertCase(%q, snake) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
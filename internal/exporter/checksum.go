@@ -0,0 +1,65 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// checksumExtension is appended to an export file name for its checksum file
+const checksumExtension = ".sha256"
+
+// WriteChecksumFile writes the SHA-256 of csvPath to <csvPath>.sha256 and returns the hex digest
+// The file has the sha256sum format, "<digest>  <file name>", so `sha256sum -c` verifies it
+// from the export directory.
+func WriteChecksumFile(csvPath string) (string, error) {
+	return writeChecksumFile(csvPath, 0644)
+}
+
+// writeChecksumFile is WriteChecksumFile creating the file with mode
+func writeChecksumFile(csvPath string, mode os.FileMode) (string, error) {
+	_, digest, err := fileDigest(csvPath)
+	if err != nil {
+		return "", err
+	}
+	if err := writeChecksumLine(csvPath+checksumExtension, digest, filepath.Base(csvPath), mode); err != nil {
+		return "", err
+	}
+	return digest, nil
+}
+
+// writeChecksumLine writes a sha256sum line for name to path
+func writeChecksumLine(path, digest, name string, mode os.FileMode) error {
+	if err := os.WriteFile(path, []byte(digest+"  "+name+"\n"), mode); err != nil {
+		return fmt.Errorf("failed to write checksum file: %w", err)
+	}
+	return nil
+}
+
+// writeChecksum writes the checksum file of an exported entity next to its file
+// Cloud exports were hashed while streaming, the checksum file is written to the export
+// directory from that digest and uploaded next to the object.
+func (e *Exporter) writeChecksum(ctx context.Context, result *types.EntityResult) (string, error) {
+	checksumPath := result.FilePath + checksumExtension
+	if e.store == nil || result.ObjectKey == "" {
+		digest, err := writeChecksumFile(result.FilePath, e.cfg.FileModeOrDefault())
+		if err != nil {
+			return "", err
+		}
+		result.Checksum = digest
+		return checksumPath, nil
+	}
+
+	if err := writeChecksumLine(checksumPath, result.Checksum, path.Base(result.ObjectKey), e.cfg.FileModeOrDefault()); err != nil {
+		return "", err
+	}
+	key := result.ObjectKey + checksumExtension
+	if err := e.uploadFile(ctx, key, checksumPath); err != nil {
+		return "", err
+	}
+	return key, nil
+}
@@ -0,0 +1,33 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriteChecksumFile(t *testing.T) {
+	csvPath := filepath.Join(t.TempDir(), "crm.orders__2025-01-01T00-00-00.csv")
+	mustWriteTestFile(t, csvPath, "test")
+
+	digest, err := WriteChecksumFile(csvPath)
+	if err != nil {
+		t.Fatalf("WriteChecksumFile() error: %v", err)
+	}
+	const want = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	if digest != want {
+		t.Errorf("WriteChecksumFile() = %s, want %s", digest, want)
+	}
+
+	data, err := os.ReadFile(csvPath + ".sha256")
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if got := string(data); got != want+"  crm.orders__2025-01-01T00-00-00.csv\n" {
+		t.Errorf("checksum file = %q, want sha256sum format", got)
+	}
+
+	if _, err := WriteChecksumFile(filepath.Join(t.TempDir(), "missing.csv")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
@@ -0,0 +1,237 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+
+	"github.com/koltyakov/ora2csv/internal/crypto"
+)
+
+// LoadColumnMap reads a column alias file, a YAML (or JSON) mapping of column names to output names
+// e.g. {"CREATED_DT": "created_at"}. Keys are returned upper-cased, empty aliases are an error.
+func LoadColumnMap(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read column map file: %w", err)
+	}
+	var aliases map[string]string
+	if err := yaml.Unmarshal(data, &aliases); err != nil {
+		return nil, fmt.Errorf("failed to parse column map file %s: %w", path, err)
+	}
+	columnMap, err := mergeColumnMaps(aliases)
+	if err != nil {
+		return nil, fmt.Errorf("invalid column map file %s: %w", path, err)
+	}
+	return columnMap, nil
+}
+
+// mergeColumnMaps merges alias maps into one keyed by upper-cased column name, later maps win
+func mergeColumnMaps(maps ...map[string]string) (map[string]string, error) {
+	var merged map[string]string
+	for _, m := range maps {
+		for column, alias := range m {
+			if strings.TrimSpace(alias) == "" {
+				return nil, fmt.Errorf("empty alias for column %s", column)
+			}
+			if merged == nil {
+				merged = make(map[string]string)
+			}
+			merged[strings.ToUpper(column)] = alias
+		}
+	}
+	return merged, nil
+}
+
+// columnMap returns the aliases of an entity: the column map file overridden by its columnMap
+func (e *Exporter) columnMap(entityColumnMap map[string]string) (map[string]string, error) {
+	var fileMap map[string]string
+	if e.cfg.ColumnMapFile != "" {
+		var err error
+		if fileMap, err = LoadColumnMap(e.cfg.ColumnMapFile); err != nil {
+			return nil, err
+		}
+	}
+	columnMap, err := mergeColumnMaps(fileMap, entityColumnMap)
+	if err != nil {
+		return nil, fmt.Errorf("invalid columnMap: %w", err)
+	}
+	return columnMap, nil
+}
+
+// applyColumnMap returns the header names of columns: the alias of mapped columns, matched
+// case-insensitively, and the others converted to headerCase. Aliases are written as they are.
+func applyColumnMap(columns []string, columnMap map[string]string, headerCase string) []string {
+	headers := make([]string, len(columns))
+	for i, name := range columns {
+		if alias, ok := columnMap[strings.ToUpper(name)]; ok {
+			headers[i] = alias
+		} else {
+			headers[i] = ConvertCase(name, headerCase)
+		}
+	}
+	return headers
+}
+
+// validateColumnMap checks that the columnMap keys of an entity name columns selected by its SQL file
+// The select list is parsed lightly; encrypted files and queries selecting * are not checked.
+func validateColumnMap(sqlPath string, columnMap map[string]string) error {
+	if len(columnMap) == 0 {
+		return nil
+	}
+	content, err := os.ReadFile(sqlPath)
+	if err != nil {
+		return fmt.Errorf("failed to read SQL file %s: %w", sqlPath, err)
+	}
+	if crypto.IsEncrypted(string(content)) {
+		return nil
+	}
+	selected, ok := selectColumns(string(content))
+	if !ok {
+		return nil
+	}
+
+	known := make(map[string]bool, len(selected))
+	for _, name := range selected {
+		known[strings.ToUpper(name)] = true
+	}
+	var missing []string
+	for column := range columnMap {
+		if !known[strings.ToUpper(column)] {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("columnMap references columns not selected by %s: %s", sqlPath, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// selectColumns returns the column names of the outermost select list of sqlContent
+// Names are aliases, or the column of plain references like t.col; unquoted names are upper-cased
+// like Oracle does. Expressions without an alias are left out. It reports false when the list
+// cannot be determined, e.g. for SELECT * or when no top-level SELECT ... FROM is found.
+func selectColumns(sqlContent string) ([]string, bool) {
+	items, ok := selectList(sqlContent)
+	if !ok {
+		return nil, false
+	}
+	var names []string
+	for _, item := range items {
+		item = strings.TrimSpace(item)
+		if item == "*" || strings.HasSuffix(item, ".*") {
+			return nil, false
+		}
+		if name := selectItemName(item); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names, true
+}
+
+// selectList splits the text between the first top-level SELECT and its FROM at top-level commas
+// Comments, string literals, quoted identifiers and parenthesized subqueries are skipped over.
+func selectList(sqlContent string) ([]string, bool) {
+	var items []string
+	depth, start := 0, -1
+	for i := 0; i < len(sqlContent); i++ {
+		c := sqlContent[i]
+		switch {
+		case strings.HasPrefix(sqlContent[i:], "--"):
+			end := strings.IndexByte(sqlContent[i:], '\n')
+			if end < 0 {
+				return nil, false
+			}
+			i += end
+		case strings.HasPrefix(sqlContent[i:], "/*"):
+			end := strings.Index(sqlContent[i+2:], "*/")
+			if end < 0 {
+				return nil, false
+			}
+			i += end + 3
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(sqlContent[i+1:], c)
+			if end < 0 {
+				return nil, false
+			}
+			i += end + 1
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && c == ',' && start >= 0:
+			items = append(items, sqlContent[start:i])
+			start = i + 1
+		case depth == 0 && isKeywordAt(sqlContent, i, "SELECT") && start < 0:
+			i += len("SELECT") - 1
+			start = i + 1
+			// DISTINCT, UNIQUE and ALL are not part of the first column
+			for _, modifier := range []string{"DISTINCT", "UNIQUE", "ALL"} {
+				j := len(sqlContent) - len(strings.TrimLeft(sqlContent[start:], " \t\r\n"))
+				if isKeywordAt(sqlContent, j, modifier) {
+					start = j + len(modifier)
+					i = start - 1
+					break
+				}
+			}
+		case depth == 0 && start >= 0 && isKeywordAt(sqlContent, i, "FROM"):
+			return append(items, sqlContent[start:i]), true
+		}
+	}
+	return nil, false
+}
+
+// isKeywordAt reports whether the word at offset i of s is keyword, case-insensitive
+func isKeywordAt(s string, i int, keyword string) bool {
+	if i+len(keyword) > len(s) || !strings.EqualFold(s[i:i+len(keyword)], keyword) {
+		return false
+	}
+	if i > 0 && isIdentifierByte(s[i-1]) {
+		return false
+	}
+	return i+len(keyword) == len(s) || !isIdentifierByte(s[i+len(keyword)])
+}
+
+// isIdentifierByte reports whether c can be part of an unquoted Oracle identifier
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '$' || c == '#' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// selectItemName returns the output name of a select list item, empty for unnamed expressions
+func selectItemName(item string) string {
+	var name, prefix string
+	if strings.HasSuffix(item, `"`) {
+		open := strings.LastIndexByte(item[:len(item)-1], '"')
+		if open < 0 {
+			return ""
+		}
+		name, prefix = item[open+1:len(item)-1], item[:open]
+	} else {
+		i := len(item)
+		for i > 0 && isIdentifierByte(item[i-1]) {
+			i--
+		}
+		name, prefix = strings.ToUpper(item[i:]), item[:i]
+		if name == "" || name[0] >= '0' && name[0] <= '9' {
+			return ""
+		}
+	}
+
+	trimmed := strings.TrimRight(prefix, " \t\r\n")
+	switch {
+	case prefix == "", strings.HasSuffix(prefix, "."):
+		// A column, or a qualified column like t.col
+		return name
+	case trimmed != prefix && name != "END":
+		// An alias, with or without AS, follows a complete expression
+		last := trimmed[len(trimmed)-1]
+		if isIdentifierByte(last) || last == ')' || last == '\'' || last == '"' {
+			return name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestLoadColumnMap(t *testing.T) {
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "aliases.yaml")
+	mustWriteTestFile(t, yamlPath, "# Renamed columns\nCREATED_DT: created_at\nupd_user_id: updated_by\n")
+	got, err := LoadColumnMap(yamlPath)
+	testutil.AssertNoError(t, err)
+	want := map[string]string{"CREATED_DT": "created_at", "UPD_USER_ID": "updated_by"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadColumnMap() = %v, want %v", got, want)
+	}
+
+	jsonPath := filepath.Join(dir, "aliases.json")
+	mustWriteTestFile(t, jsonPath, `{"CREATED_DT": "created_at"}`)
+	got, err = LoadColumnMap(jsonPath)
+	testutil.AssertNoError(t, err)
+	if got["CREATED_DT"] != "created_at" {
+		t.Errorf("LoadColumnMap() of JSON = %v", got)
+	}
+
+	emptyPath := filepath.Join(dir, "empty.yaml")
+	mustWriteTestFile(t, emptyPath, "CREATED_DT: \"\"\n")
+	if _, err := LoadColumnMap(emptyPath); err == nil {
+		t.Error("expected error for an empty alias")
+	}
+	if _, err := LoadColumnMap(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestApplyColumnMap(t *testing.T) {
+	columnMap := map[string]string{"CREATED_DT": "created_at"}
+	got := applyColumnMap([]string{"ID", "created_dt", "ORDER_DATE"}, columnMap, CaseTitle)
+	want := []string{"Id", "created_at", "Order_Date"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("applyColumnMap() = %v, want %v", got, want)
+	}
+}
+
+func TestSelectColumns(t *testing.T) {
+	tests := []struct {
+		sql  string
+		want []string
+		ok   bool
+	}{
+		{"SELECT id, t.created_dt, upd_user_id AS updated_by FROM orders t", []string{"ID", "CREATED_DT", "UPDATED_BY"}, true},
+		{"-- header, with comma\nSELECT /*+ PARALLEL(4) */ DISTINCT a, b\nFROM t", []string{"A", "B"}, true},
+		{"SELECT NVL(a, 'x,y') a_or_x, COUNT(*), a + b, \"MixedCase\" FROM t", []string{"A_OR_X", "MixedCase"}, true},
+		{"WITH src AS (SELECT a FROM t) SELECT CASE WHEN a > 0 THEN 1 END pos, CASE WHEN a < 0 THEN 1 END FROM src", []string{"POS"}, true},
+		{"SELECT (SELECT MAX(x) FROM u) max_x, y FROM t", []string{"MAX_X", "Y"}, true},
+		{"SELECT * FROM t", nil, false},
+		{"SELECT t.* FROM t", nil, false},
+		{"DELETE t", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := selectColumns(tt.sql)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("selectColumns(%q) = %v, %t, want %v, %t", tt.sql, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestValidateColumnMap(t *testing.T) {
+	dir := t.TempDir()
+	sqlPath := filepath.Join(dir, "crm.orders.sql")
+	mustWriteTestFile(t, sqlPath, "SELECT id, created_dt FROM orders")
+
+	testutil.AssertNoError(t, validateColumnMap(sqlPath, map[string]string{"created_dt": "created_at"}))
+	err := validateColumnMap(sqlPath, map[string]string{"CREATED_DT": "created_at", "UPD_USER_ID": "updated_by"})
+	if err == nil || !strings.Contains(err.Error(), "UPD_USER_ID") {
+		t.Errorf("validateColumnMap() error = %v, want missing UPD_USER_ID", err)
+	}
+
+	starPath := filepath.Join(dir, "crm.all.sql")
+	mustWriteTestFile(t, starPath, "SELECT * FROM orders")
+	testutil.AssertNoError(t, validateColumnMap(starPath, map[string]string{"UPD_USER_ID": "updated_by"}))
+}
+
+func TestExporter_Run_ColumnMap(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		cfg.HeaderCase = CaseUpper
+		cfg.ColumnMapFile = filepath.Join(t.TempDir(), "aliases.yaml")
+		mustWriteTestFile(t, cfg.ColumnMapFile, "SKU: product_sku\nUPDATED: modified_at\n")
+		mustWriteTestFile(t, cfg.StateFile, `[{"entity":"crm.products","lastRunTime":"2025-01-01T00:00:00","active":true,"columnMap":{"updated":"updated_at"}}]`)
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+		testutil.AssertNoError(t, Validate(cfg, st, false))
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+
+		data, err := os.ReadFile(result.Results[0].FilePath)
+		testutil.AssertNoError(t, err)
+		header, _, _ := strings.Cut(string(data), "\n")
+		testutil.AssertEqual(t, "ID,NAME,product_sku,updated_at", header)
+	})
+}
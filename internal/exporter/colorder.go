@@ -0,0 +1,121 @@
+package exporter
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// LoadColumnOrder reads <schemaDir>/<entity>.columns.txt, one column name per line
+// Blank lines and lines starting with # are skipped. A missing file returns nil.
+func LoadColumnOrder(schemaDir, entityName string) ([]string, error) {
+	path := filepath.Join(schemaDir, entityName+".columns.txt")
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open column order file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var columns []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name := strings.TrimSpace(scanner.Text())
+		if name == "" || strings.HasPrefix(name, "#") {
+			continue
+		}
+		key := strings.ToUpper(name)
+		if seen[key] {
+			return nil, fmt.Errorf("duplicate column %s in %s", name, path)
+		}
+		seen[key] = true
+		columns = append(columns, name)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read column order file: %w", err)
+	}
+	return columns, nil
+}
+
+// EnforceColumnOrder matches the query columns against the defined order
+// It returns perm where perm[i] is the index in actual of defined[i]. Names are compared
+// case-insensitively. Missing columns are an error; columns not in defined are an error
+// unless ignoreAdditions is set, in which case they are left out of the output.
+func EnforceColumnOrder(defined, actual []string, ignoreAdditions bool) ([]int, error) {
+	index := make(map[string]int, len(actual))
+	for i, name := range actual {
+		index[strings.ToUpper(name)] = i
+	}
+
+	perm := make([]int, len(defined))
+	used := make(map[int]bool, len(defined))
+	var missing []string
+	for i, name := range defined {
+		j, ok := index[strings.ToUpper(name)]
+		if !ok {
+			missing = append(missing, name)
+			continue
+		}
+		perm[i] = j
+		used[j] = true
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("query is missing defined columns: %s", strings.Join(missing, ", "))
+	}
+
+	if !ignoreAdditions {
+		var extra []string
+		for j, name := range actual {
+			if !used[j] {
+				extra = append(extra, name)
+			}
+		}
+		if len(extra) > 0 {
+			return nil, fmt.Errorf("query returns columns not in the column order file: %s", strings.Join(extra, ", "))
+		}
+	}
+
+	return perm, nil
+}
+
+// orderedWriter writes the columns of a query in a defined order
+// Scan targets are routed so actual column perm[i] lands in output position i;
+// columns outside perm are scanned into a discarded value.
+type orderedWriter struct {
+	csvWriter
+	perm        []int
+	actualCount int
+	discard     interface{}
+}
+
+// newOrderedWriter wraps a writer created for len(perm) columns
+func newOrderedWriter(w csvWriter, perm []int, actualCount int) *orderedWriter {
+	return &orderedWriter{csvWriter: w, perm: perm, actualCount: actualCount}
+}
+
+// WriteHeaders writes the query column names in the defined order
+func (w *orderedWriter) WriteHeaders(columns []string) error {
+	ordered := make([]string, len(w.perm))
+	for i, j := range w.perm {
+		ordered[i] = columns[j]
+	}
+	return w.csvWriter.WriteHeaders(ordered)
+}
+
+// GetScanTargets returns scan targets in query column order
+func (w *orderedWriter) GetScanTargets() []interface{} {
+	inner := w.csvWriter.GetScanTargets()
+	targets := make([]interface{}, w.actualCount)
+	for j := range targets {
+		targets[j] = &w.discard
+	}
+	for i, j := range w.perm {
+		targets[j] = inner[i]
+	}
+	return targets
+}
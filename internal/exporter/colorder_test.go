@@ -0,0 +1,118 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestLoadColumnOrder(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteTestFile(t, filepath.Join(dir, "crm.products.columns.txt"), "# product export\nID\n\n  NAME  \nupdated\n")
+	mustWriteTestFile(t, filepath.Join(dir, "crm.dup.columns.txt"), "ID\nid\n")
+
+	t.Run("reads ordered columns", func(t *testing.T) {
+		got, err := LoadColumnOrder(dir, "crm.products")
+		testutil.AssertNoError(t, err)
+		if want := []string{"ID", "NAME", "updated"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("LoadColumnOrder() = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		got, err := LoadColumnOrder(dir, "crm.orders")
+		testutil.AssertNoError(t, err)
+		if got != nil {
+			t.Errorf("LoadColumnOrder() = %v, want nil", got)
+		}
+	})
+
+	t.Run("duplicate column", func(t *testing.T) {
+		if _, err := LoadColumnOrder(dir, "crm.dup"); err == nil {
+			t.Error("expected error for duplicate column")
+		}
+	})
+}
+
+func TestEnforceColumnOrder(t *testing.T) {
+	tests := []struct {
+		name            string
+		defined         []string
+		actual          []string
+		ignoreAdditions bool
+		want            []int
+		wantErr         bool
+	}{
+		{
+			name:    "same order",
+			defined: []string{"ID", "NAME"},
+			actual:  []string{"ID", "NAME"},
+			want:    []int{0, 1},
+		},
+		{
+			name:    "reordered case-insensitive",
+			defined: []string{"updated", "id", "name"},
+			actual:  []string{"ID", "NAME", "UPDATED"},
+			want:    []int{2, 0, 1},
+		},
+		{
+			name:    "removed column",
+			defined: []string{"ID", "NAME", "SKU"},
+			actual:  []string{"NAME", "ID"},
+			wantErr: true,
+		},
+		{
+			name:    "extra column fails",
+			defined: []string{"ID"},
+			actual:  []string{"ID", "NEW_COL"},
+			wantErr: true,
+		},
+		{
+			name:            "extra column ignored",
+			defined:         []string{"NAME", "ID"},
+			actual:          []string{"ID", "NEW_COL", "NAME"},
+			ignoreAdditions: true,
+			want:            []int{2, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EnforceColumnOrder(tt.defined, tt.actual, tt.ignoreAdditions)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnforceColumnOrder() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EnforceColumnOrder() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderedWriter(t *testing.T) {
+	columns := []string{"ID", "NEW_COL", "NAME"}
+	scanner := db.NewMockRowScanner(columns, [][]string{{"1", "x", "Widget"}, {"2", "y", "Gadget"}})
+
+	perm, err := EnforceColumnOrder([]string{"NAME", "ID"}, columns, true)
+	testutil.AssertNoError(t, err)
+
+	filePath := filepath.Join(t.TempDir(), "out.csv")
+	inner, err := NewStreamingCSVWriter(filePath, len(perm))
+	testutil.AssertNoError(t, err)
+	writer := newOrderedWriter(inner, perm, len(columns))
+
+	testutil.AssertNoError(t, writer.WriteHeaders(columns))
+	rowCount, err := streamRows(scanner, writer, nil, streamLimits{}, logging.New(false))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 2, rowCount)
+	testutil.AssertNoError(t, writer.Close())
+
+	data, err := os.ReadFile(filePath)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "NAME,ID\nWidget,1\nGadget,2\n", string(data))
+}
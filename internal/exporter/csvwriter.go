@@ -1,45 +1,150 @@
 package exporter
 
 import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
 	"context"
 	"database/sql"
 	"encoding/csv"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/koltyakov/ora2csv/internal/storage"
 )
 
+// CSVWriterOptions controls the CSV output format
+type CSVWriterOptions struct {
+	// QuoteChar encloses fields that need quoting; zero means '"' (RFC 4180)
+	QuoteChar rune
+	// FileMode is the permission of the created file; zero means 0644
+	FileMode os.FileMode
+	// HeaderCase transforms header names (see ConvertCase); empty keeps them as-is
+	HeaderCase string
+	// RecordSeparator terminates each record; empty means "\n"
+	RecordSeparator string
+	// Compress is the output compression: CompressGzip or empty for none
+	Compress string
+	// Format is the file format: FormatJSONLines, FormatParquet or empty for CSV
+	Format string
+	// Delimiter separates fields; zero means ','
+	Delimiter rune
+	// ColumnTypes are the output column types for Parquet; nil writes strings
+	ColumnTypes []ColumnType
+	// RowGroupBytes is the Parquet row group size; zero means DefaultParquetRowGroupBytes
+	RowGroupBytes int64
+}
+
+// CompressGzip compresses output files with gzip
+const CompressGzip = "gzip"
+
+// CompressedExt returns the file name suffix for a compression, empty for none
+func CompressedExt(compress string) string {
+	if compress == CompressGzip {
+		return ".gz"
+	}
+	return ""
+}
+
+// FileExt returns the default output file name suffix for the options
+// Tab-delimited files use .tsv, JSON Lines files .jsonl and Parquet files .parquet.
+func FileExt(opts CSVWriterOptions) string {
+	ext := ".csv"
+	switch {
+	case opts.Format == FormatJSONLines:
+		ext = ".jsonl"
+	case opts.Format == FormatParquet:
+		ext = ".parquet"
+	case opts.Delimiter == '\t':
+		ext = ".tsv"
+	}
+	return ext + CompressedExt(opts.Compress)
+}
+
 // CSVWriter handles streaming CSV writing with RFC 4180 compliance
 type CSVWriter struct {
-	writer   *csv.Writer
-	file     *os.File
-	headers  []string
-	rowCount int
+	writer     recordWriter
+	file       outputSink
+	gz         *gzip.Writer // nil unless compressing
+	headers    []string
+	headerCase string
+	rowCount   int
 }
 
 // NewCSVWriter creates a new CSVWriter for the given file path
 func NewCSVWriter(filePath string) (*CSVWriter, error) {
-	file, err := os.Create(filePath)
+	return NewCSVWriterWithOptions(filePath, CSVWriterOptions{})
+}
+
+// NewCSVWriterWithOptions creates a new CSVWriter using the given output options
+func NewCSVWriterWithOptions(filePath string, opts CSVWriterOptions) (*CSVWriter, error) {
+	file, err := createFileSink(filePath, opts.FileMode)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create file: %w", err)
+		return nil, err
+	}
+	return newCSVWriter(file, opts), nil
+}
+
+// newCSVWriter creates a CSVWriter writing to file, FileMode is not used
+func newCSVWriter(file outputSink, opts CSVWriterOptions) *CSVWriter {
+	var out io.Writer = file
+	var gz *gzip.Writer
+	if opts.Compress == CompressGzip {
+		gz = gzip.NewWriter(file)
+		out = gz
 	}
 
-	writer := csv.NewWriter(file)
-	// Use Unix line endings (LF)
-	writer.UseCRLF = false
+	quote := opts.QuoteChar
+	if quote == 0 {
+		quote = '"'
+	}
+	delimiter := opts.Delimiter
+	if delimiter == 0 {
+		delimiter = ','
+	}
+	recordSep := opts.RecordSeparator
+	if recordSep == "" {
+		recordSep = "\n"
+	}
+
+	// encoding/csv only supports double quotes and LF or CRLF line endings
+	if quote != '"' || (recordSep != "\n" && recordSep != "\r\n") {
+		return &CSVWriter{
+			writer:     newCustomCSVWriter(out, delimiter, quote, recordSep),
+			file:       file,
+			gz:         gz,
+			headerCase: opts.HeaderCase,
+		}
+	}
+
+	writer := csv.NewWriter(out)
+	writer.Comma = delimiter
+	// Use Unix line endings (LF) unless CRLF is configured
+	writer.UseCRLF = recordSep == "\r\n"
 
 	return &CSVWriter{
-		writer: writer,
-		file:   file,
-	}, nil
+		writer:     writer,
+		file:       file,
+		gz:         gz,
+		headerCase: opts.HeaderCase,
+	}
 }
 
 // WriteHeaders writes the CSV header row
 func (w *CSVWriter) WriteHeaders(columns []string) error {
+	if w.headerCase != "" {
+		converted := make([]string, len(columns))
+		for i, col := range columns {
+			converted[i] = ConvertCase(col, w.headerCase)
+		}
+		columns = converted
+	}
+
 	if err := w.writer.Write(columns); err != nil {
 		return fmt.Errorf("failed to write headers: %w", err)
 	}
@@ -100,10 +205,16 @@ func formatValue(v interface{}) string {
 	}
 }
 
-// Flush flushes any buffered data
+// Flush flushes any buffered data, including pending compressed output
 func (w *CSVWriter) Flush() error {
 	w.writer.Flush()
-	return w.writer.Error()
+	if err := w.writer.Error(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
 }
 
 // Close closes the writer and file
@@ -115,8 +226,14 @@ func (w *CSVWriter) Close() error {
 		}
 		w.writer = nil
 	}
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			return fmt.Errorf("failed to finish gzip stream: %w", err)
+		}
+		w.gz = nil
+	}
 	if w.file != nil {
-		if err := w.file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		if err := w.file.Close(); err != nil {
 			return err
 		}
 		w.file = nil
@@ -134,18 +251,15 @@ func (w *CSVWriter) HasData() bool {
 	return w.rowCount > 0
 }
 
-// Remove removes the file if no data was written
+// Remove discards the output: the file is deleted, an upload is aborted
 func (w *CSVWriter) Remove() error {
 	w.writer = nil
+	w.gz = nil
 	if w.file != nil {
-		path := w.file.Name()
-		if err := w.file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		if err := w.file.Discard(); err != nil {
 			return err
 		}
 		w.file = nil
-		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
-			return err
-		}
 	}
 	return nil
 }
@@ -159,7 +273,12 @@ type StreamingCSVWriter struct {
 
 // NewStreamingCSVWriter creates a writer optimized for streaming database rows
 func NewStreamingCSVWriter(filePath string, columnCount int) (*StreamingCSVWriter, error) {
-	csvWriter, err := NewCSVWriter(filePath)
+	return NewStreamingCSVWriterWithOptions(filePath, columnCount, CSVWriterOptions{})
+}
+
+// NewStreamingCSVWriterWithOptions creates a streaming writer using the given output options
+func NewStreamingCSVWriterWithOptions(filePath string, columnCount int, opts CSVWriterOptions) (*StreamingCSVWriter, error) {
+	csvWriter, err := NewCSVWriterWithOptions(filePath, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -277,6 +396,8 @@ func WriteNoDataFile(filePath string) error {
 }
 
 // IsEmpty checks if a file exists and is empty
+// Gzip files are checked by their decompressed content, not the compressed size.
+// JSON Lines files are empty when they hold no object other than {}.
 func IsEmpty(path string) bool {
 	file, err := os.Open(path)
 	if err != nil {
@@ -289,13 +410,30 @@ func IsEmpty(path string) bool {
 		}
 	}()
 
-	stat, err := file.Stat()
-	if err != nil {
+	var content io.Reader = file
+	magic := make([]byte, 2)
+	n, _ := io.ReadFull(file, magic)
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
 		return true
 	}
+	if n == 2 && bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return true
+		}
+		content = gz
+	}
+
+	if isJSONLinesPath(path) {
+		line, _ := bufio.NewReader(content).ReadString('\n')
+		line = strings.TrimSpace(line)
+		return line == "" || line == "{}"
+	}
 
-	// Check file size (less than 10 bytes = only header or empty)
-	return stat.Size() < 10
+	// Less than 10 bytes of content = only header or empty
+	const minContent = 10
+	read, _ := io.CopyN(io.Discard, content, minContent)
+	return read < minContent
 }
 
 // RemoveEmpty removes the file if it's empty or has no data rows
@@ -306,40 +444,80 @@ func RemoveEmpty(path string) error {
 	return nil
 }
 
-// S3StreamingCSVWriter streams CSV data directly to S3 via multipart upload
-// Data is buffered to a temp file during writing, then uploaded to S3 on Close()
-type S3StreamingCSVWriter struct {
-	csv         *CSVWriter
-	s3          *storage.S3Client
-	s3Key       string
-	localPath   string // For temp file during writing
+// rowFileWriter writes rows to a local file, implemented by CSVWriter, JSONLinesWriter and ParquetWriter
+type rowFileWriter interface {
+	WriteHeaders(columns []string) error
+	WriteRow(values []interface{}) error
+	Flush() error
+	Close() error
+	RowCount() int
+	Remove() error
+}
+
+// CloudStreamingCSVWriter streams CSV data to cloud storage (S3, Azure Blob Storage or GCS)
+// Rows are uploaded while they are written, or with a local path as a fallback, buffered
+// to a temp file during writing and uploaded on Close(). The output is written as
+// JSON Lines or Parquet when opts.Format is FormatJSONLines or FormatParquet.
+type CloudStreamingCSVWriter struct {
+	csv         rowFileWriter
+	store       storage.BlobStore
+	key         string
+	upload      *uploadSink // nil when buffering to localPath
+	localPath   string      // For temp file during writing, empty when streaming
+	tempDir     string      // Removed with the temp file once uploaded
 	dest        []interface{}
 	rowValues   []sql.NullString
 	columnCount int
 	skipUpload  bool
-}
+	compress    string
+	size        int64  // Uploaded file size, set by Close
+	checksum    string // Uploaded file SHA-256, set by Close
+}
+
+// NewCloudStreamingCSVWriter creates a writer that streams to store under key
+// With an empty localPath the upload starts right away and reads rows as they are
+// written. Otherwise the data is written to localPath first, then uploaded on Close(),
+// and a non-empty tempDir is removed after a successful upload.
+func NewCloudStreamingCSVWriter(store storage.BlobStore, key, localPath, tempDir string, columnCount int, opts CSVWriterOptions) (*CloudStreamingCSVWriter, error) {
+	var sink outputSink
+	var upload *uploadSink
+	if localPath == "" {
+		upload = newUploadSink(store, key, opts.Compress)
+		sink = upload
+	} else {
+		file, err := createFileSink(localPath, opts.FileMode)
+		if err != nil {
+			return nil, err
+		}
+		sink = file
+	}
 
-// NewS3StreamingCSVWriter creates a writer that streams to S3
-// The data is written to a temp file first, then uploaded to S3 on Close()
-func NewS3StreamingCSVWriter(s3 *storage.S3Client, s3Key, localPath string, columnCount int) (*S3StreamingCSVWriter, error) {
-	csvWriter, err := NewCSVWriter(localPath)
-	if err != nil {
-		return nil, err
+	var csvWriter rowFileWriter
+	switch opts.Format {
+	case FormatJSONLines:
+		csvWriter = newJSONLinesWriter(sink, columnCount, opts)
+	case FormatParquet:
+		csvWriter = newParquetWriter(sink, columnCount, opts)
+	default:
+		csvWriter = newCSVWriter(sink, opts)
 	}
 
-	return &S3StreamingCSVWriter{
+	return &CloudStreamingCSVWriter{
 		csv:         csvWriter,
-		s3:          s3,
-		s3Key:       s3Key,
+		store:       store,
+		key:         key,
+		upload:      upload,
 		localPath:   localPath,
+		tempDir:     tempDir,
 		dest:        make([]interface{}, columnCount),
 		rowValues:   make([]sql.NullString, columnCount),
 		columnCount: columnCount,
+		compress:    opts.Compress,
 	}, nil
 }
 
 // GetScanTargets returns a slice of interface{} pointers for sql.Rows.Scan
-func (w *S3StreamingCSVWriter) GetScanTargets() []interface{} {
+func (w *CloudStreamingCSVWriter) GetScanTargets() []interface{} {
 	for i := range w.dest {
 		w.rowValues[i] = sql.NullString{}
 		w.dest[i] = &w.rowValues[i]
@@ -348,7 +526,7 @@ func (w *S3StreamingCSVWriter) GetScanTargets() []interface{} {
 }
 
 // WriteScannedRow writes the most recently scanned row
-func (w *S3StreamingCSVWriter) WriteScannedRow() error {
+func (w *CloudStreamingCSVWriter) WriteScannedRow() error {
 	values := make([]interface{}, len(w.rowValues))
 	for i, v := range w.rowValues {
 		if !v.Valid {
@@ -361,28 +539,50 @@ func (w *S3StreamingCSVWriter) WriteScannedRow() error {
 }
 
 // WriteHeaders writes the header row
-func (w *S3StreamingCSVWriter) WriteHeaders(columns []string) error {
+func (w *CloudStreamingCSVWriter) WriteHeaders(columns []string) error {
 	return w.csv.WriteHeaders(columns)
 }
 
-// Close flushes, uploads to S3, and removes the local temp file
-func (w *S3StreamingCSVWriter) Close() error {
+// Close flushes, uploads to the store, and removes the local temp file
+// When streaming it ends the upload and waits for it to complete.
+func (w *CloudStreamingCSVWriter) Close() error {
+	if w.upload != nil {
+		err := w.csv.Close()
+		if w.skipUpload {
+			return nil
+		}
+		if err != nil {
+			// A failed write may have left the upload waiting for data
+			_ = w.upload.Discard()
+			return fmt.Errorf("%s upload failed: %w", w.store.Name(), err)
+		}
+		w.size, w.checksum = w.upload.Size(), w.upload.Checksum()
+		return nil
+	}
+
 	// Flush and close the local file
 	if err := w.csv.Close(); err != nil {
 		return err
 	}
 	if w.skipUpload {
-		return nil
+		return w.removeTempDir()
+	}
+
+	// Checksum the file for the run manifest before it is removed
+	size, checksum, err := fileDigest(w.localPath)
+	if err != nil {
+		return err
 	}
+	w.size, w.checksum = size, checksum
 
-	// Upload to S3
+	// Upload to cloud storage
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 	defer cancel()
 
 	// Open the file for upload
 	file, err := os.Open(w.localPath)
 	if err != nil {
-		return fmt.Errorf("failed to open file for S3 upload: %w", err)
+		return fmt.Errorf("failed to open file for %s upload: %w", w.store.Name(), err)
 	}
 	defer func() {
 		if err := file.Close(); err != nil {
@@ -390,32 +590,70 @@ func (w *S3StreamingCSVWriter) Close() error {
 		}
 	}()
 
-	// Upload to S3 via multipart upload
-	if err := w.s3.UploadStream(ctx, w.s3Key, file); err != nil {
-		// S3 upload failed - keep the local file as fallback
-		return fmt.Errorf("S3 upload failed: %w (local file kept at %s)", err, w.localPath)
+	// Upload in parts, compressed files are marked with their encoding
+	if w.compress != "" {
+		err = w.store.UploadStreamWithEncoding(ctx, w.key, w.compress, file)
+	} else {
+		err = w.store.UploadStream(ctx, w.key, file)
+	}
+	if err != nil {
+		// Upload failed - keep the local file as fallback
+		return fmt.Errorf("%s upload failed: %w (local file kept at %s)", w.store.Name(), err, w.localPath)
 	}
 
-	// S3 upload succeeded - remove local temp file
+	// Upload succeeded - remove local temp file
 	if err := os.Remove(w.localPath); err != nil && !os.IsNotExist(err) {
 		fmt.Fprintf(os.Stderr, "Warning: failed to remove local file %s: %v\n", w.localPath, err)
 	}
+	if err := w.removeTempDir(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
+	return nil
+}
+
+// Digest returns the size and hex SHA-256 of the uploaded file, zero values before Close
+func (w *CloudStreamingCSVWriter) Digest() (int64, string) {
+	return w.size, w.checksum
+}
 
+// removeTempDir removes the writer's temp directory if it owns one
+func (w *CloudStreamingCSVWriter) removeTempDir() error {
+	if w.tempDir == "" {
+		return nil
+	}
+	if err := os.RemoveAll(w.tempDir); err != nil {
+		return fmt.Errorf("failed to remove temp directory %s: %w", w.tempDir, err)
+	}
+	w.tempDir = ""
 	return nil
 }
 
+// OutputSize returns the number of bytes written so far, after compression
+// Buffered rows are not counted until flushed.
+func (w *CloudStreamingCSVWriter) OutputSize() (int64, error) {
+	if w.upload != nil {
+		return w.upload.Size(), nil
+	}
+	info, err := statFile(w.localPath)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
 // Flush flushes buffered data
-func (w *S3StreamingCSVWriter) Flush() error {
+func (w *CloudStreamingCSVWriter) Flush() error {
 	return w.csv.Flush()
 }
 
 // RowCount returns the number of rows written
-func (w *S3StreamingCSVWriter) RowCount() int {
+func (w *CloudStreamingCSVWriter) RowCount() int {
 	return w.csv.RowCount()
 }
 
-// Remove removes the temp file
-func (w *S3StreamingCSVWriter) Remove() error {
+// Remove removes the temp file, or aborts the upload when streaming
+func (w *CloudStreamingCSVWriter) Remove() error {
 	if err := w.csv.Remove(); err != nil {
 		return err
 	}
@@ -423,7 +661,7 @@ func (w *S3StreamingCSVWriter) Remove() error {
 	return nil
 }
 
-// GetLocalPath returns the local temp file path
-func (w *S3StreamingCSVWriter) GetLocalPath() string {
+// GetLocalPath returns the local temp file path, empty when streaming
+func (w *CloudStreamingCSVWriter) GetLocalPath() string {
 	return w.localPath
 }
@@ -0,0 +1,104 @@
+package exporter
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// recordWriter is implemented by csv.Writer and customCSVWriter
+type recordWriter interface {
+	Write(record []string) error
+	Flush()
+	Error() error
+}
+
+// customCSVWriter writes CSV records like encoding/csv but with a configurable
+// quote character and record separator. Quote characters inside quoted fields
+// are escaped by doubling, other characters are written as is.
+type customCSVWriter struct {
+	comma     rune
+	quote     rune
+	recordSep string
+	w         *bufio.Writer
+}
+
+// newCustomCSVWriter returns a writer that writes to w
+func newCustomCSVWriter(w io.Writer, comma, quote rune, recordSep string) *customCSVWriter {
+	return &customCSVWriter{
+		comma:     comma,
+		quote:     quote,
+		recordSep: recordSep,
+		w:         bufio.NewWriter(w),
+	}
+}
+
+// Write writes a single CSV record along with the line terminator
+func (w *customCSVWriter) Write(record []string) error {
+	for n, field := range record {
+		if n > 0 {
+			if _, err := w.w.WriteRune(w.comma); err != nil {
+				return err
+			}
+		}
+
+		if !w.fieldNeedsQuotes(field) {
+			if _, err := w.w.WriteString(field); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if _, err := w.w.WriteRune(w.quote); err != nil {
+			return err
+		}
+		for _, r := range field {
+			var err error
+			if r == w.quote {
+				_, err = w.w.WriteString(string([]rune{w.quote, w.quote}))
+			} else {
+				_, err = w.w.WriteRune(r)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := w.w.WriteRune(w.quote); err != nil {
+			return err
+		}
+	}
+
+	_, err := w.w.WriteString(w.recordSep)
+	return err
+}
+
+// Flush writes any buffered data to the underlying io.Writer
+func (w *customCSVWriter) Flush() {
+	_ = w.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Write or Flush
+func (w *customCSVWriter) Error() error {
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// fieldNeedsQuotes reports whether a field must be enclosed in quotes
+// It follows the same rules as encoding/csv using the configured quote character
+func (w *customCSVWriter) fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` {
+		return true
+	}
+	if strings.ContainsRune(field, w.comma) || strings.ContainsRune(field, w.quote) ||
+		strings.ContainsAny(field, "\r\n") || strings.Contains(field, w.recordSep) {
+		return true
+	}
+
+	r, _ := utf8.DecodeRuneInString(field)
+	return unicode.IsSpace(r)
+}
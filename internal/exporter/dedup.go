@@ -0,0 +1,122 @@
+package exporter
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/bits-and-blooms/bloom/v3"
+)
+
+// dedupFalsePositiveRate is the target false-positive rate of the bloom filter
+const dedupFalsePositiveRate = 0.001
+
+// RowDeduplicator skips rows already exported by previous runs
+// Row hashes are tracked in a bloom filter persisted to a sidecar file
+type RowDeduplicator struct {
+	filter  *bloom.BloomFilter
+	path    string
+	mode    os.FileMode
+	key     bytes.Buffer
+	skipped int
+}
+
+// BloomFilePath returns the sidecar bloom filter path for an entity
+func BloomFilePath(exportDir, entityName string) string {
+	return filepath.Join(exportDir, entityName+".bloom")
+}
+
+// LoadRowDeduplicator loads the bloom filter from path or creates an empty one
+// sized for the expected number of rows if the file does not exist yet
+// Save writes the file with mode, zero means 0644.
+func LoadRowDeduplicator(path string, size int, mode os.FileMode) (*RowDeduplicator, error) {
+	if mode == 0 {
+		mode = 0644
+	}
+	d := &RowDeduplicator{path: path, mode: mode}
+
+	file, err := os.Open(path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to open bloom filter %s: %w", path, err)
+		}
+		d.filter = bloom.NewWithEstimates(uint(size), dedupFalsePositiveRate)
+		return d, nil
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	d.filter = &bloom.BloomFilter{}
+	if _, err := d.filter.ReadFrom(file); err != nil {
+		return nil, fmt.Errorf("failed to read bloom filter %s: %w", path, err)
+	}
+
+	return d, nil
+}
+
+// Seen reports whether the scanned row was likely exported before
+// Rows not seen yet are added to the filter
+func (d *RowDeduplicator) Seen(scanTargets []interface{}) bool {
+	d.key.Reset()
+	for _, target := range scanTargets {
+		// Length-prefix values so that NULL, empty and separator-containing
+		// values never collide
+		switch v := target.(type) {
+		case *sql.NullString:
+			if !v.Valid {
+				d.key.WriteString("-1:")
+				continue
+			}
+			fmt.Fprintf(&d.key, "%d:%s", len(v.String), v.String)
+		case *[]byte: // LOB columns, see lobWriter
+			if *v == nil {
+				d.key.WriteString("-1:")
+				continue
+			}
+			fmt.Fprintf(&d.key, "%d:", len(*v))
+			d.key.Write(*v)
+		default:
+			d.key.WriteString("-1:")
+		}
+	}
+
+	if d.filter.TestAndAdd(d.key.Bytes()) {
+		d.skipped++
+		return true
+	}
+	return false
+}
+
+// Skipped returns the number of rows reported as duplicates
+func (d *RowDeduplicator) Skipped() int {
+	return d.skipped
+}
+
+// Save writes the bloom filter to its sidecar file atomically
+func (d *RowDeduplicator) Save() error {
+	tmpPath := d.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, d.mode)
+	if err != nil {
+		return fmt.Errorf("failed to create bloom filter file: %w", err)
+	}
+
+	if _, err := d.filter.WriteTo(file); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write bloom filter: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to close bloom filter file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, d.path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename bloom filter file: %w", err)
+	}
+
+	return nil
+}
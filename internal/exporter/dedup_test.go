@@ -0,0 +1,166 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// failingStateStore fails every timestamp update of the wrapped store
+type failingStateStore struct {
+	state.Store
+}
+
+func (s failingStateStore) UpdateEntityTimestamp(entityName string, timestamp string) error {
+	return errors.New("disk full")
+}
+
+func dedupRow(id int) []interface{} {
+	return []interface{}{
+		&sql.NullString{String: fmt.Sprintf("%d", id), Valid: true},
+		&sql.NullString{String: fmt.Sprintf("name-%d", id), Valid: true},
+		&sql.NullString{},
+	}
+}
+
+func TestBloomFilePath(t *testing.T) {
+	got := BloomFilePath("/export", "crm.orders")
+	want := filepath.Join("/export", "crm.orders.bloom")
+	if got != want {
+		t.Errorf("BloomFilePath() = %q, want %q", got, want)
+	}
+}
+
+func TestRowDeduplicator_SkipsPreviouslyExportedRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.entity.bloom")
+
+	// First run: 1000 unique rows
+	first, err := LoadRowDeduplicator(path, 10000, 0)
+	if err != nil {
+		t.Fatalf("LoadRowDeduplicator() error = %v", err)
+	}
+	for i := 0; i < 1000; i++ {
+		first.Seen(dedupRow(i))
+	}
+	if first.Skipped() != 0 {
+		t.Errorf("first run skipped %d rows, want 0", first.Skipped())
+	}
+	if err := first.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	// Second run: 500 previously exported rows and 500 new ones
+	second, err := LoadRowDeduplicator(path, 10000, 0)
+	if err != nil {
+		t.Fatalf("LoadRowDeduplicator() error = %v", err)
+	}
+	written := 0
+	for i := 500; i < 1500; i++ {
+		if !second.Seen(dedupRow(i)) {
+			written++
+		}
+	}
+
+	// All 500 overlapping rows must be skipped; new rows may only be lost to
+	// bloom filter false positives
+	tolerance := 10
+	if second.Skipped() < 500 || second.Skipped() > 500+tolerance {
+		t.Errorf("second run skipped %d rows, want ~500", second.Skipped())
+	}
+	if written+second.Skipped() != 1000 {
+		t.Errorf("written (%d) + skipped (%d) != 1000", written, second.Skipped())
+	}
+}
+
+func TestRowDeduplicator_SaveFileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.entity.bloom")
+	d, err := LoadRowDeduplicator(path, 100, 0600)
+	if err != nil {
+		t.Fatalf("LoadRowDeduplicator() error = %v", err)
+	}
+	d.Seen(dedupRow(1))
+	if err := d.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error: %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("bloom filter file mode = %o, want 600", got)
+	}
+}
+
+func TestRowDeduplicator_DistinguishesNullFromEmpty(t *testing.T) {
+	d, err := LoadRowDeduplicator(filepath.Join(t.TempDir(), "x.bloom"), 100, 0)
+	if err != nil {
+		t.Fatalf("LoadRowDeduplicator() error = %v", err)
+	}
+
+	if d.Seen([]interface{}{&sql.NullString{}}) {
+		t.Error("NULL row reported as seen on first occurrence")
+	}
+	if d.Seen([]interface{}{&sql.NullString{String: "", Valid: true}}) {
+		t.Error("empty-string row reported as duplicate of NULL row")
+	}
+	if !d.Seen([]interface{}{&sql.NullString{}}) {
+		t.Error("repeated NULL row not reported as seen")
+	}
+}
+
+func TestLoadRowDeduplicator_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.bloom")
+	mustWriteTestFile(t, path, "not a bloom filter")
+
+	if _, err := LoadRowDeduplicator(path, 100, 0); err == nil {
+		t.Error("expected error for corrupt bloom filter file")
+	}
+}
+
+func TestExporter_Run_DedupStateUpdateFails(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		cfg.DeduplicateOutput = true
+		cfg.BloomFilterSize = 1000
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+		defer func() { _ = st.Close() }()
+
+		// The rows are written but the state keeps the old window, so the filter must not track them
+		result, err := New(cfg, database, failingStateStore{st}, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.FailedCount)
+		if _, err := os.Stat(BloomFilePath(cfg.ExportDir, "crm.products")); !os.IsNotExist(err) {
+			t.Fatalf("dedup filter saved despite failed state update: %v", err)
+		}
+
+		// The rerun exports the same rows again and only then persists the filter
+		result, err = New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+		testutil.AssertEqual(t, 2, result.Results[0].RowCount)
+		if _, err := os.Stat(BloomFilePath(cfg.ExportDir, "crm.products")); err != nil {
+			t.Errorf("dedup filter not saved after successful run: %v", err)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// DiscoverSQLFiles returns the entity names of all *.sql files in sqlDir, sorted
+func DiscoverSQLFiles(sqlDir string) ([]string, error) {
+	dirEntries, err := os.ReadDir(sqlDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SQL directory: %w", err)
+	}
+
+	var entities []string
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".sql") {
+			continue
+		}
+		entities = append(entities, strings.TrimSuffix(de.Name(), ".sql"))
+	}
+	sort.Strings(entities)
+
+	return entities, nil
+}
+
+// discoverEntities returns discovered entities that are not in the state file yet
+func (e *Exporter) discoverEntities() ([]types.EntityState, error) {
+	names, err := DiscoverSQLFiles(e.cfg.SQLDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var discovered []types.EntityState
+	for _, name := range names {
+		if !strings.HasPrefix(name, e.cfg.AutoDiscoverPrefix) {
+			continue
+		}
+		if _, ok := e.st.FindEntity(name); ok {
+			continue
+		}
+		discovered = append(discovered, types.EntityState{Entity: name, Active: true})
+	}
+	return discovered, nil
+}
@@ -0,0 +1,80 @@
+package exporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func TestDiscoverSQLFiles(t *testing.T) {
+	sqlDir := t.TempDir()
+	mustWriteTestFile(t, filepath.Join(sqlDir, "crm.orders.sql"), "SELECT 1")
+	mustWriteTestFile(t, filepath.Join(sqlDir, "crm.customers.sql"), "SELECT 1")
+	mustWriteTestFile(t, filepath.Join(sqlDir, "README.md"), "docs")
+	testutil.AssertNoError(t, os.Mkdir(filepath.Join(sqlDir, "nested.sql"), 0755))
+
+	got, err := DiscoverSQLFiles(sqlDir)
+	testutil.AssertNoError(t, err)
+
+	want := []string{"crm.customers", "crm.orders"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverSQLFiles() = %v, want %v", got, want)
+	}
+}
+
+func TestExporter_Run_AutoDiscover_SQLite(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		cfg.AutoDiscover = true
+		cfg.AutoDiscoverPrefix = "crm."
+		cfg.DefaultDaysBack = 3650
+
+		entities := []types.EntityState{
+			{Entity: "crm.known", LastRunTime: "2030-01-01T00:00:00", Active: false},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.known.sql"), integrationSQL)
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "hr.people.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+
+		// Only crm.products is new and matches the prefix
+		testutil.AssertEqual(t, 1, result.ProcessedCount)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+		testutil.AssertEqual(t, "crm.products", result.Results[0].Entity)
+		testutil.AssertEqual(t, 3, result.Results[0].RowCount)
+		testutil.AssertEqual(t, 2, result.TotalEntities)
+
+		testutil.AssertNoError(t, st.Close())
+		reloaded, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+		added, ok := reloaded.FindEntity("crm.products")
+		if !ok {
+			t.Fatal("discovered entity not added to state")
+		}
+		if !added.Active || added.LastRunTime == "" {
+			t.Errorf("unexpected state entry: %+v", added)
+		}
+		if _, ok := reloaded.FindEntity("hr.people"); ok {
+			t.Error("entity outside the prefix was added to state")
+		}
+		known, _ := reloaded.FindEntity("crm.known")
+		testutil.AssertEqual(t, "2030-01-01T00:00:00", known.LastRunTime)
+	})
+}
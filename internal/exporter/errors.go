@@ -0,0 +1,77 @@
+package exporter
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Phases of entity processing reported by EntityError
+const (
+	PhasePrepare   = "prepare"
+	PhaseSQLLoad   = "sql_load"
+	PhaseQueryExec = "query_exec"
+	PhaseRowStream = "row_stream"
+	PhaseFileWrite = "file_write"
+)
+
+// EntityError adds entity context to an error returned while processing an entity
+type EntityError struct {
+	Err          error
+	Entity       string
+	SQLFile      string
+	RunTimestamp time.Time
+	Phase        string
+}
+
+// Error returns the error message prefixed with the entity and phase
+func (e *EntityError) Error() string {
+	return fmt.Sprintf("%s [%s]: %v", e.Entity, e.Phase, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *EntityError) Unwrap() error {
+	return e.Err
+}
+
+// MaxRowsExceededError is returned when an entity exports more rows than allowed
+// Global marks the run-wide max_rows_global limit, otherwise the entity's own limit was hit.
+type MaxRowsExceededError struct {
+	Limit  int64
+	Global bool
+}
+
+func (e *MaxRowsExceededError) Error() string {
+	if e.Global {
+		return fmt.Sprintf("run exceeds the global limit of %d rows", e.Limit)
+	}
+	return fmt.Sprintf("result set exceeds %d rows", e.Limit)
+}
+
+// phaseError tags an error with the processing phase it occurred in
+type phaseError struct {
+	phase string
+	err   error
+}
+
+func (e *phaseError) Error() string {
+	return e.err.Error()
+}
+
+func (e *phaseError) Unwrap() error {
+	return e.err
+}
+
+// withPhase tags err with a processing phase
+func withPhase(phase string, err error) error {
+	return &phaseError{phase: phase, err: err}
+}
+
+// errorPhase returns the phase err was tagged with, or fallback if untagged
+func errorPhase(err error, fallback string) string {
+	var pe *phaseError
+	if errors.As(err, &pe) {
+		return pe.phase
+	}
+	return fallback
+}
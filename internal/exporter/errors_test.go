@@ -0,0 +1,184 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/metrics"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func TestEntityError_As(t *testing.T) {
+	runTime := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	err := fmt.Errorf("export failed: %w", &EntityError{
+		Err:          io.ErrUnexpectedEOF,
+		Entity:       "crm.orders",
+		SQLFile:      "sql/crm.orders.sql",
+		RunTimestamp: runTime,
+		Phase:        PhaseRowStream,
+	})
+
+	var target *EntityError
+	if !errors.As(err, &target) {
+		t.Fatal("errors.As() did not find *EntityError")
+	}
+	if target.Entity != "crm.orders" || target.SQLFile != "sql/crm.orders.sql" {
+		t.Errorf("unexpected context: %+v", target)
+	}
+	if !target.RunTimestamp.Equal(runTime) {
+		t.Errorf("RunTimestamp = %v, want %v", target.RunTimestamp, runTime)
+	}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Error("errors.Is() did not reach the underlying error")
+	}
+}
+
+func TestErrorPhase(t *testing.T) {
+	cause := errors.New("boom")
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"untagged", cause, PhaseQueryExec},
+		{"query_exec", withPhase(PhaseQueryExec, cause), PhaseQueryExec},
+		{"row_stream", withPhase(PhaseRowStream, cause), PhaseRowStream},
+		{"file_write", withPhase(PhaseFileWrite, cause), PhaseFileWrite},
+		{"wrapped", fmt.Errorf("outer: %w", withPhase(PhaseSQLLoad, cause)), PhaseSQLLoad},
+		{"joined keeps first", errors.Join(withPhase(PhaseRowStream, cause), withPhase(PhaseFileWrite, cause)), PhaseRowStream},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorPhase(tt.err, PhaseQueryExec); got != tt.want {
+				t.Errorf("errorPhase() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExporter_Run_QueryRetries(t *testing.T) {
+	tests := []struct {
+		name      string
+		queryErr  string
+		wantCalls int
+	}{
+		{"transient error is retried", "ORA-12541: TNS:no listener", 3},
+		{"non-retryable error fails at once", "ORA-00942: table or view does not exist", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := testutil.NewTestConfig(t)
+			cfg.MaxRetries = 2
+			cfg.RetryBackoff = time.Millisecond
+			testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, []types.EntityState{{Entity: "crm.orders", Active: true}}))
+			testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+			mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.orders.sql"), "SELECT 1 FROM dual")
+			testutil.AssertNoError(t, cfg.EnsureDirs())
+
+			st, err := state.Load(cfg.StateFile, nil, "")
+			testutil.AssertNoError(t, err)
+
+			calls := 0
+			mock := db.NewMockDB()
+			mock.QueryFunc = func(ctx context.Context, query string, args map[string]interface{}) (*sql.Rows, error) {
+				calls++
+				return nil, errors.New(tt.queryErr)
+			}
+
+			result, err := New(cfg, mock, st, logging.New(false), nil).Run(context.Background())
+			testutil.AssertNoError(t, err)
+			testutil.AssertEqual(t, 1, result.FailedCount)
+			testutil.AssertEqual(t, tt.wantCalls, calls)
+		})
+	}
+}
+
+func TestExporter_Run_Metrics(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, []types.EntityState{{Entity: "crm.orders", Active: true}}))
+	testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+	mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.orders.sql"), "SELECT 1 FROM dual")
+	testutil.AssertNoError(t, cfg.EnsureDirs())
+
+	st, err := state.Load(cfg.StateFile, nil, "")
+	testutil.AssertNoError(t, err)
+
+	mock := db.NewMockDB()
+	mock.QueryFunc = func(ctx context.Context, query string, args map[string]interface{}) (*sql.Rows, error) {
+		return nil, errors.New("ORA-00942: table or view does not exist")
+	}
+
+	reg := metrics.NewRegistry()
+	exp := New(cfg, mock, st, logging.New(false), nil)
+	exp.SetMetrics(reg)
+	_, err = exp.Run(context.Background())
+	testutil.AssertNoError(t, err)
+
+	var out strings.Builder
+	_, err = reg.WriteTo(&out)
+	testutil.AssertNoError(t, err)
+	for _, want := range []string{
+		"ora2csv_export_active 0\n",
+		`ora2csv_export_errors_total{entity="crm.orders"} 1`,
+		`ora2csv_entity_duration_seconds_count{entity="crm.orders"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestExporter_Run_EntityErrorPhases(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	entities := []types.EntityState{
+		{Entity: "crm.missing", Active: true},
+		{Entity: "crm.broken", Active: true},
+	}
+	testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+	testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+	mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.broken.sql"), "SELECT 1 FROM dual")
+	testutil.AssertNoError(t, cfg.EnsureDirs())
+
+	st, err := state.Load(cfg.StateFile, nil, "")
+	testutil.AssertNoError(t, err)
+
+	mock := db.NewMockDB()
+	mock.QueryFunc = func(ctx context.Context, query string, args map[string]interface{}) (*sql.Rows, error) {
+		return nil, errors.New("ORA-00942: table or view does not exist")
+	}
+
+	result, err := New(cfg, mock, st, logging.New(false), nil).Run(context.Background())
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 2, result.FailedCount)
+
+	wantPhases := map[string]string{
+		"crm.missing": PhaseSQLLoad,
+		"crm.broken":  PhaseQueryExec,
+	}
+	for _, r := range result.Results {
+		var entityErr *EntityError
+		if !errors.As(r.Error, &entityErr) {
+			t.Fatalf("%s: error %v is not an *EntityError", r.Entity, r.Error)
+		}
+		testutil.AssertEqual(t, r.Entity, entityErr.Entity)
+		testutil.AssertEqual(t, wantPhases[r.Entity], entityErr.Phase)
+		testutil.AssertEqual(t, filepath.Join(cfg.SQLDir, r.Entity+".sql"), entityErr.SQLFile)
+		if entityErr.RunTimestamp.IsZero() {
+			t.Errorf("%s: RunTimestamp not set", r.Entity)
+		}
+	}
+}
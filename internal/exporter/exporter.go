@@ -6,64 +6,197 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/internal/crypto"
 	"github.com/koltyakov/ora2csv/internal/db"
 	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/metrics"
+	"github.com/koltyakov/ora2csv/internal/monitor"
+	"github.com/koltyakov/ora2csv/internal/schedule"
 	"github.com/koltyakov/ora2csv/internal/state"
 	"github.com/koltyakov/ora2csv/internal/storage"
+	"github.com/koltyakov/ora2csv/internal/tracing"
+	apperrors "github.com/koltyakov/ora2csv/pkg/errors"
 	"github.com/koltyakov/ora2csv/pkg/types"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Exporter handles the main export orchestration
 type Exporter struct {
-	cfg    *config.Config
-	db     db.DB
-	st     *state.File
-	logger *logging.Logger
-	s3     *storage.S3Client
+	cfg     *config.Config
+	db      db.DB
+	st      state.Store
+	logger  *logging.Logger
+	store   storage.BlobStore      // nil unless exporting to cloud storage
+	memory  *monitor.MemoryWatcher // nil unless MaxMemoryMB is set
+	schemas *SchemaSemaphorePool
+	version string            // Recorded in run manifests
+	runID   string            // Names the current run like its manifest history copy
+	metrics *metrics.Registry // nil unless metrics are served
+
+	progress *progressLine // nil unless --progress is set and stderr is a terminal
+	rows     *rowBudget    // nil unless MaxRowsGlobal is set
+
+	// lastRowCounts are the rows of each entity's previous export for progress estimates
+	lastRowCounts map[string]int
 }
 
 // New creates a new Exporter
-func New(cfg *config.Config, database db.DB, st *state.File, logger *logging.Logger, s3 *storage.S3Client) *Exporter {
-	return &Exporter{
-		cfg:    cfg,
-		db:     database,
-		st:     st,
-		logger: logger,
-		s3:     s3,
+// A non-nil store uploads the exported files to S3 or Azure instead of the export directory.
+func New(cfg *config.Config, database db.DB, st state.Store, logger *logging.Logger, store storage.BlobStore) *Exporter {
+	e := &Exporter{
+		cfg:     cfg,
+		db:      database,
+		st:      st,
+		logger:  logger,
+		store:   store,
+		schemas: NewSchemaSemaphorePool(cfg.SchemaParallelismLimits),
+	}
+	if cfg.MaxMemoryMB > 0 {
+		e.memory = monitor.NewMemoryWatcher(cfg.MaxMemoryMB)
 	}
+	return e
+}
+
+// SetMetrics records entity results and the active run in reg
+func (e *Exporter) SetMetrics(reg *metrics.Registry) {
+	e.metrics = reg
 }
 
 // Run executes the export process for all active entities
+// Once done, including interrupted runs, the run manifest is written (see ManifestFile).
 func (e *Exporter) Run(ctx context.Context) (*types.ExportResult, error) {
+	if e.metrics != nil {
+		e.metrics.SetActive(true)
+		defer e.metrics.SetActive(false)
+	}
+	result, err := e.run(ctx)
+	if result != nil {
+		// Exports and state are already written, a missing manifest does not fail the run
+		if manifestErr := e.writeManifest(context.WithoutCancel(ctx), result); manifestErr != nil {
+			e.logger.Error("Failed to write manifest: %v", manifestErr)
+		}
+	}
+	return result, err
+}
+
+// run exports all active entities
+func (e *Exporter) run(ctx context.Context) (*types.ExportResult, error) {
 	startTime := time.Now()
 	result := &types.ExportResult{
 		Results: make([]types.EntityResult, 0, e.st.ActiveCount()),
+		RunTime: startTime.UTC(),
+		Version: e.version,
+	}
+	e.runID = result.RunTime.Format(manifestTimeFormat)
+	e.rows = nil
+	if e.cfg.MaxRowsGlobal > 0 {
+		e.rows = &rowBudget{max: e.cfg.MaxRowsGlobal}
 	}
 
 	e.logger.Info("Starting data export process")
 	e.logger.Info("Total entities: %d, Active: %d", e.st.TotalCount(), e.st.ActiveCount())
 
+	entities, err := e.selectEntities()
+	if err != nil {
+		return nil, err
+	}
+
+	// Discovered entities are added to state once exported successfully
+	discovered := map[string]bool{}
+	if e.cfg.AutoDiscover && e.cfg.Entity == "" {
+		found, err := e.discoverEntities()
+		if err != nil {
+			return nil, fmt.Errorf("auto-discovery failed: %w", err)
+		}
+		// Discovered entities have no tags yet, so --include-tags leaves them out
+		found = state.FilterByTags(found, e.cfg.IncludeTags, e.cfg.ExcludeTags)
+		for _, entity := range found {
+			discovered[entity.Entity] = true
+		}
+		if len(found) > 0 {
+			e.logger.Info("Discovered %d new entities in %s", len(found), e.cfg.SQLDir)
+		}
+		entities = append(entities, found...)
+	}
+	// Progress logs estimate the percentage done from the previous export of each entity
+	if e.cfg.ProgressInterval > 0 {
+		counts, err := previousRowCounts(e.cfg.ExportDir)
+		if err != nil {
+			e.logger.Info("Warning: progress estimates unavailable: %v", err)
+		}
+		e.lastRowCounts = counts
+	}
+	if e.cfg.Progress && stderrIsTerminal() {
+		e.progress = startProgress(os.Stderr, progressRedrawInterval)
+		defer func() {
+			e.progress.Stop()
+			e.progress = nil
+		}()
+	}
+	totalCount := func() int {
+		return e.st.TotalCount() + len(discovered)
+	}
+
 	// Capture till date once for all entities (use UTC to avoid timezone issues)
-	tillDateStr := time.Now().UTC().Format("2006-01-02T15:04:05")
+	runTime, err := e.tillDate(time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	tillDateStr := runTime.Format("2006-01-02T15:04:05")
 	e.logger.Info("Using till date for all entities: %s", tillDateStr)
+	if e.cfg.HasDateRange() {
+		e.logger.Info("Date range override set, state will not be updated")
+	}
 
-	// Process each active entity
-	for _, entity := range e.st.GetActiveEntities() {
-		if err := ctx.Err(); err != nil {
-			result.TotalEntities = e.st.TotalCount()
-			result.SkippedCount = result.TotalEntities - result.ProcessedCount
-			result.Duration = time.Since(startTime)
-			return result, fmt.Errorf("export interrupted: %w", err)
-		}
+	// Entities start in entity order, up to Parallelism at once and within the schema
+	// limits. An entity waits for the entities it depends on and is skipped if one failed.
+	var (
+		mu      sync.Mutex          // guards failed, discovered and state updates
+		failed  = map[string]bool{} // entities that failed in this run
+		results = make([]*types.EntityResult, len(entities))
+		done    = make(map[string]chan struct{}, len(entities))
+		workers = make(chan struct{}, e.cfg.ParallelismOrDefault())
+		wg      sync.WaitGroup
+	)
+	for _, entity := range entities {
+		done[entity.Entity] = make(chan struct{})
+	}
 
-		entityResult := e.processEntity(ctx, entity, tillDateStr)
+	// finish updates the state for a processed entity and records its result
+	finish := func(i int, entity types.EntityState, entityResult types.EntityResult, dedup *RowDeduplicator) {
+		mu.Lock()
+		defer mu.Unlock()
+		defer close(done[entity.Entity])
 
-		// Update state only on success
-		if entityResult.Success {
+		// Update state only on success, and never for a date range override
+		if entityResult.Success && e.cfg.HasDateRange() {
+			e.logger.Debug("Date range override, state not updated for %s", entity.Entity)
+		} else if entityResult.Success && discovered[entity.Entity] {
+			entity.LastRunTime = tillDateStr
+			if err := e.st.AddEntity(entity); err != nil {
+				e.logger.Error("Failed to add %s to state: %v", entity.Entity, err)
+				entityResult.Success = false
+				entityResult.Error = fmt.Errorf("failed to add %s to state: %w", entity.Entity, err)
+			} else {
+				delete(discovered, entity.Entity)
+			}
+		} else if entityResult.Success && !entity.Active {
+			e.logger.Info("Inactive entity %s exported, state not updated", entity.Entity)
+		} else if entityResult.Success && entityResult.TillValue != "" {
+			if err := e.st.UpdateEntityValue(entity.Entity, tillDateStr, entityResult.TillValue); err != nil {
+				e.logger.Error("Failed to update state for %s: %v", entity.Entity, err)
+				entityResult.Success = false
+				entityResult.Error = fmt.Errorf("failed to update state for %s: %w", entity.Entity, err)
+			}
+		} else if entityResult.Success {
 			if err := e.st.UpdateEntityTimestamp(entity.Entity, tillDateStr); err != nil {
 				e.logger.Error("Failed to update state for %s: %v", entity.Entity, err)
 				entityResult.Success = false
@@ -71,9 +204,107 @@ func (e *Exporter) Run(ctx context.Context) (*types.ExportResult, error) {
 			}
 		}
 
-		result.Results = append(result.Results, entityResult)
-		result.ProcessedCount++
+		// Persist the dedup filter only after the state update, a failed update leaves the
+		// filter unchanged so the rows are exported again on the next run
+		if entityResult.Success && dedup != nil {
+			if err := dedup.Save(); err != nil {
+				e.logger.Error("Failed to save dedup filter for %s: %v", entity.Entity, err)
+				entityResult.Success = false
+				entityResult.Error = e.entityError(entity.Entity, runTime, PhaseFileWrite, fmt.Errorf("failed to save dedup filter: %w", err))
+			}
+		}
 
+		if e.metrics != nil {
+			e.metrics.ObserveEntity(entity.Entity, entityResult.RowCount, entityResult.Duration, !entityResult.Success)
+		}
+		if !entityResult.Success {
+			failed[entity.Entity] = true
+		}
+		results[i] = &entityResult
+	}
+
+	var interrupted error
+dispatch:
+	for i, entity := range entities {
+		if err := ctx.Err(); err != nil {
+			interrupted = err
+			break
+		}
+
+		if e.outsideSchedule(entity, result.RunTime) {
+			close(done[entity.Entity])
+			continue
+		}
+
+		// Start date errors are reported by processEntity
+		if startDate, err := e.getStartDate(entity); err == nil && e.skipOverlapping(entity.Entity, startDate, tillDateStr) {
+			close(done[entity.Entity])
+			continue
+		}
+
+		for _, dep := range entity.DependsOn {
+			if depDone, ok := done[dep]; ok {
+				select {
+				case <-depDone:
+				case <-ctx.Done():
+					interrupted = ctx.Err()
+					break dispatch
+				}
+			}
+		}
+		mu.Lock()
+		dep := failedDependency(entity, failed)
+		mu.Unlock()
+		if dep != "" {
+			e.logger.Error("Skipping %s, dependency %s failed", entity.Entity, dep)
+			finish(i, entity, types.EntityResult{
+				Entity: entity.Entity,
+				Error:  e.entityError(entity.Entity, runTime, PhasePrepare, fmt.Errorf("dependency %s failed", dep)),
+			}, nil)
+			continue
+		}
+
+		// The schema slot is taken before the global one
+		release, err := e.schemas.Acquire(ctx, entity.Entity)
+		if err != nil {
+			interrupted = err
+			break
+		}
+		select {
+		case workers <- struct{}{}:
+		case <-ctx.Done():
+			release()
+			interrupted = ctx.Err()
+			break dispatch
+		}
+
+		wg.Add(1)
+		go func(i int, entity types.EntityState) {
+			defer wg.Done()
+			spanCtx, span := tracing.Tracer().Start(ctx, "export "+entity.Entity,
+				trace.WithAttributes(attribute.String("ora2csv.entity", entity.Entity)))
+			entityResult, dedup := e.processEntity(spanCtx, entity, runTime)
+			span.SetAttributes(attribute.Int("ora2csv.rows", entityResult.RowCount))
+			tracing.End(span, entityResult.Error)
+			<-workers
+			release()
+
+			// Release memory held by the entity before the next one
+			if e.memory != nil {
+				runtime.GC()
+			}
+
+			finish(i, entity, entityResult, dedup)
+		}(i, entity)
+	}
+	wg.Wait()
+
+	for _, entityResult := range results {
+		if entityResult == nil {
+			continue
+		}
+		result.Results = append(result.Results, *entityResult)
+		result.ProcessedCount++
 		if entityResult.Success {
 			result.SuccessCount++
 		} else {
@@ -81,17 +312,79 @@ func (e *Exporter) Run(ctx context.Context) (*types.ExportResult, error) {
 		}
 	}
 
-	result.TotalEntities = e.st.TotalCount()
+	result.TotalEntities = totalCount()
 	result.SkippedCount = result.TotalEntities - result.ProcessedCount
 	result.Duration = time.Since(startTime)
 
+	if interrupted != nil {
+		return result, fmt.Errorf("export interrupted: %w", interrupted)
+	}
 	return result, nil
 }
 
+// failedDependency returns the first dependency of entity that failed in this run, empty if none did
+func failedDependency(entity types.EntityState, failed map[string]bool) string {
+	for _, dep := range entity.DependsOn {
+		if failed[dep] {
+			return dep
+		}
+	}
+	return ""
+}
+
+// outsideSchedule reports whether entity is skipped because now is outside its schedule
+// Skipped entities keep their state, so the next run inside the window exports the gap.
+func (e *Exporter) outsideSchedule(entity types.EntityState, now time.Time) bool {
+	if entity.Schedule == "" {
+		return false
+	}
+	inWindow, err := schedule.InWindow(entity.Schedule, now)
+	if err != nil {
+		e.logger.Error("Skipping %s: %v", entity.Entity, err)
+		return true
+	}
+	if !inWindow {
+		e.logger.Info("Skipping %s: outside its schedule %q", entity.Entity, entity.Schedule)
+	}
+	return !inWindow
+}
+
+// selectEntities returns the entities to process in this run, ordered by dependsOn
+// With cfg.Entity only that entity is returned; it must be active unless IncludeInactive is set.
+func (e *Exporter) selectEntities() ([]types.EntityState, error) {
+	if e.cfg.Entity != "" {
+		entity, ok := e.st.FindEntity(e.cfg.Entity)
+		if !ok {
+			return nil, fmt.Errorf("entity not found in state: %s", e.cfg.Entity)
+		}
+		if !entity.Active && !e.cfg.IncludeInactive {
+			return nil, fmt.Errorf("entity is not active: %s", e.cfg.Entity)
+		}
+		return []types.EntityState{*entity}, nil
+	}
+
+	entities := e.st.GetActiveEntities()
+	if e.cfg.IncludeInactive {
+		// One-off run: inactive entities are exported but their state is left as is
+		entities = e.st.GetEntities()
+	}
+	entities = state.FilterByTags(entities, e.cfg.IncludeTags, e.cfg.ExcludeTags)
+
+	// Parents are exported before the entities that depend on them
+	sorted, err := state.SortByDependencies(entities)
+	if err != nil {
+		return nil, err
+	}
+	return sorted, nil
+}
+
 // processEntity handles the export of a single entity
-func (e *Exporter) processEntity(ctx context.Context, entity types.EntityState, tillDateStr string) types.EntityResult {
+// Returned errors are wrapped in EntityError. On success the dedup filter tracking the
+// exported rows is returned unsaved, Run persists it once the state is updated.
+func (e *Exporter) processEntity(ctx context.Context, entity types.EntityState, runTime time.Time) (types.EntityResult, *RowDeduplicator) {
 	startTime := time.Now()
-	log := e.logger.WithEntity(entity.Entity)
+	tillDateStr := runTime.Format("2006-01-02T15:04:05")
+	log := e.logger.WithEntity(entity.Entity, entity.DisplayName)
 
 	log.Info("Processing entity: %s (active: %t)", entity.Entity, entity.Active)
 
@@ -102,80 +395,277 @@ func (e *Exporter) processEntity(ctx context.Context, entity types.EntityState,
 		return types.EntityResult{
 			Entity:   entity.Entity,
 			Success:  false,
-			Error:    fmt.Errorf("failed to determine start date: %w", err),
+			Error:    e.entityError(entity.Entity, runTime, PhasePrepare, fmt.Errorf("failed to determine start date: %w", err)),
 			Duration: time.Since(startTime),
-		}
+		}, nil
 	}
 	startDateStr := startDate.Format("2006-01-02T15:04:05")
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.String("ora2csv.start_date", startDateStr),
+		attribute.String("ora2csv.till_date", tillDateStr),
+	)
 
 	log.Info("Start date: %s", startDateStr)
 
 	// Load SQL file
-	sqlContent, err := e.loadSQLFile(entity.Entity)
+	sqlContent, err := e.loadSQLFile(entity, e.sqlVariables(entity, startDateStr, runTime))
 	if err != nil {
 		log.Error("Failed to load SQL file: %v", err)
 		return types.EntityResult{
 			Entity:   entity.Entity,
 			Success:  false,
-			Error:    fmt.Errorf("failed to load SQL file: %w", err),
+			Error:    e.entityError(entity.Entity, runTime, PhaseSQLLoad, fmt.Errorf("failed to load SQL file: %w", err)),
 			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	if e.cfg.UseResultCache(entity.Entity) {
+		sqlContent = InjectHints(sqlContent, "RESULT_CACHE")
+	}
+
+	// Load the enforced column order, if defined for the entity
+	var columnOrder []string
+	if e.cfg.SchemaDir != "" {
+		columnOrder, err = LoadColumnOrder(e.cfg.SchemaDir, entity.Entity)
+		if err != nil {
+			log.Error("Failed to load column order: %v", err)
+			return types.EntityResult{
+				Entity:   entity.Entity,
+				Success:  false,
+				Error:    e.entityError(entity.Entity, runTime, PhaseSQLLoad, err),
+				Duration: time.Since(startTime),
+			}, nil
 		}
 	}
 
+	columnMap, err := e.columnMap(entity.ColumnMap)
+	if err != nil {
+		log.Error("Failed to load column map: %v", err)
+		return types.EntityResult{
+			Entity:   entity.Entity,
+			Success:  false,
+			Error:    e.entityError(entity.Entity, runTime, PhaseSQLLoad, err),
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
 	// Generate output filename
-	outputFile := e.getOutputPath(entity.Entity, startDateStr)
+	outputFile, objectKey, err := e.getOutputPath(entity.Entity, startDateStr, tillDateStr)
+	if err != nil {
+		log.Error("Failed to generate output filename: %v", err)
+		return types.EntityResult{
+			Entity:   entity.Entity,
+			Success:  false,
+			Error:    e.entityError(entity.Entity, runTime, PhasePrepare, err),
+			Duration: time.Since(startTime),
+		}, nil
+	}
 	log.Info("Output file: %s", outputFile)
 
 	// Create export directory
-	if err := os.MkdirAll(filepath.Dir(outputFile), 0755); err != nil {
+	if err := os.MkdirAll(filepath.Dir(outputFile), e.cfg.DirModeOrDefault()); err != nil {
 		log.Error("Failed to create output directory: %v", err)
 		return types.EntityResult{
 			Entity:   entity.Entity,
 			Success:  false,
-			Error:    fmt.Errorf("failed to create output directory: %w", err),
+			Error:    e.entityError(entity.Entity, runTime, PhasePrepare, fmt.Errorf("failed to create output directory: %w", err)),
 			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	// Load the dedup bloom filter persisted by previous runs
+	var dedup *RowDeduplicator
+	if e.cfg.DeduplicateOutput {
+		dedup, err = LoadRowDeduplicator(BloomFilePath(e.cfg.ExportDir, entity.Entity), e.cfg.BloomFilterSize, e.cfg.FileMode)
+		if err != nil {
+			log.Error("Failed to load dedup filter: %v", err)
+			return types.EntityResult{
+				Entity:   entity.Entity,
+				Success:  false,
+				Error:    e.entityError(entity.Entity, runTime, PhasePrepare, fmt.Errorf("failed to load dedup filter: %w", err)),
+				Duration: time.Since(startTime),
+			}, nil
 		}
 	}
 
 	// Execute query and stream to CSV
 	entityCtx, entityCancel := context.WithTimeout(ctx, e.cfg.QueryTimeout)
 	defer entityCancel()
+	if e.memory != nil {
+		var stopWatch context.CancelFunc
+		entityCtx, stopWatch = e.memory.Watch(entityCtx)
+		defer stopWatch()
+	}
 
-	rowCount, err := e.executeQueryToCSV(entityCtx, sqlContent, startDateStr, tillDateStr, outputFile, log)
+	params, tillValue, err := e.queryParams(entityCtx, entity, sqlContent, startDateStr, tillDateStr)
 	if err != nil {
-		log.Error("Failed to execute query: %v", err)
+		log.Error("Failed to determine query window: %v", err)
 		return types.EntityResult{
 			Entity:   entity.Entity,
 			Success:  false,
-			Error:    err,
+			Error:    e.entityError(entity.Entity, runTime, PhaseQueryExec, err),
 			Duration: time.Since(startTime),
+		}, nil
+	}
+	if entity.IsSequence() {
+		log.Info("Sequence window: %v < %s <= %s", params["startValue"], entity.IncrementalColumn, tillValue)
+	}
+
+	store, err := e.entityStore(entity)
+	if err != nil {
+		log.Error("Invalid S3 storage class: %v", err)
+		return types.EntityResult{
+			Entity:   entity.Entity,
+			Success:  false,
+			Error:    e.entityError(entity.Entity, runTime, PhasePrepare, err),
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	limits := streamLimits{maxRows: e.maxRows(entity), maxBytes: e.maxFileSizeBytes(entity), budget: e.rows,
+		reporter: NewProgressReporter(log, time.Now(), e.cfg.ProgressInterval, e.lastRowCounts[entity.Entity])}
+	if e.progress != nil {
+		limits.progress = e.progress.track(entity.Entity)
+		defer e.progress.untrack(entity.Entity)
+	}
+	var rowCount int
+	var exported exportedFile
+	attempts := 0
+	onRetry := func(attempt int, err error, wait time.Duration) {
+		log.Debug("Query attempt %d of %d failed: %v, retrying in %s", attempt, e.cfg.MaxRetries+1, err, wait.Round(time.Millisecond))
+	}
+	err = db.RetryWithBackoff(entityCtx, e.cfg.MaxRetries, e.cfg.RetryBackoff, db.IsRetryableQueryError, onRetry, func() error {
+		attempts++
+		// A failed attempt may have marked rows as seen, start over from the persisted filter
+		if dedup != nil && attempts > 1 {
+			reloaded, err := LoadRowDeduplicator(BloomFilePath(e.cfg.ExportDir, entity.Entity), e.cfg.BloomFilterSize, e.cfg.FileMode)
+			if err != nil {
+				return fmt.Errorf("failed to reload dedup filter: %w", err)
+			}
+			dedup = reloaded
+		}
+		var err error
+		rowCount, exported, err = e.executeQueryToCSV(entityCtx, sqlContent, params, outputFile, store, objectKey, columnOrder, entity.MaskRules, columnMap, dedup, limits, log)
+		return err
+	})
+	if err != nil {
+		if monitor.Exceeded(entityCtx) {
+			err = apperrors.NewMemoryError("processEntity", fmt.Sprintf("heap exceeded %d MB", e.cfg.MaxMemoryMB), err)
 		}
+		log.Error("Failed to execute query: %v", err)
+		return types.EntityResult{
+			Entity:   entity.Entity,
+			Success:  false,
+			Error:    e.entityError(entity.Entity, runTime, PhaseQueryExec, err),
+			Duration: time.Since(startTime),
+		}, nil
+	}
+
+	if dedup != nil && dedup.Skipped() > 0 {
+		log.Info("Skipped %d previously exported rows", dedup.Skipped())
 	}
 
 	if rowCount == 0 {
 		log.Info("No data rows found for entity: %s - skipping CSV creation", entity.Entity)
 		// Still update state since query succeeded
 		return types.EntityResult{
-			Entity:   entity.Entity,
-			Success:  true,
-			RowCount: 0,
-			Duration: time.Since(startTime),
+			Entity:        entity.Entity,
+			Success:       true,
+			RowCount:      0,
+			Duration:      time.Since(startTime),
+			TillValue:     tillValue,
+			RowsScanned:   exported.query.RowsScanned,
+			BytesScanned:  exported.query.BytesScanned,
+			QueryDuration: exported.query.Duration,
+		}, dedup
+	}
+
+	if exported.parts != nil {
+		outputFile = PartPath(outputFile, e.fileExt(), 1)
+		log.Info("Exported %d rows to %d files: %s", rowCount, len(exported.parts), strings.Join(exported.parts, ", "))
+	} else {
+		log.Info("Exported %d rows to: %s", rowCount, outputFile)
+	}
+
+	result := types.EntityResult{
+		Entity:    entity.Entity,
+		Success:   true,
+		RowCount:  rowCount,
+		FilePath:  outputFile,
+		Duration:  time.Since(startTime),
+		ObjectKey: exported.objectKey,
+		FileSize:  exported.size,
+		Checksum:  exported.checksum,
+		Parts:     exported.parts,
+		TillValue: tillValue,
+		StartDate: startDateStr,
+		TillDate:  tillDateStr,
+
+		RowsScanned:   exported.query.RowsScanned,
+		BytesScanned:  exported.query.BytesScanned,
+		QueryDuration: exported.query.Duration,
+	}
+	// The export is complete, a missing checksum file or sidecar does not fail the entity
+	if e.cfg.WriteChecksum {
+		if checksumPath, err := e.writeChecksum(ctx, &result); err != nil {
+			log.Error("Failed to write checksum file: %v", err)
+		} else {
+			log.Info("Wrote checksum file: %s", checksumPath)
+		}
+	}
+	if e.cfg.WriteMeta {
+		if metaPath, err := e.writeMeta(ctx, &result, startDateStr, tillDateStr, exported.columns); err != nil {
+			log.Error("Failed to write export metadata: %v", err)
+		} else {
+			log.Info("Wrote export metadata: %s", metaPath)
 		}
 	}
+	return result, dedup
+}
 
-	log.Info("Exported %d rows to: %s", rowCount, outputFile)
+// exportedFile describes the output file of an entity for the run manifest
+type exportedFile struct {
+	objectKey string // Set when uploaded to cloud storage
+	size      int64
+	checksum  string
+	columns   []MetaColumn // Set with WriteMeta
+	parts     []string     // Set with MaxRowsPerFile: object keys when uploaded, otherwise local paths
+	query     db.QueryMetrics
+}
 
-	return types.EntityResult{
-		Entity:   entity.Entity,
-		Success:  true,
-		RowCount: rowCount,
-		FilePath: outputFile,
-		Duration: time.Since(startTime),
+// entityError wraps err with the entity context, keeping a phase tagged deeper down
+func (e *Exporter) entityError(entity string, runTime time.Time, phase string, err error) *EntityError {
+	return &EntityError{
+		Err:          err,
+		Entity:       entity,
+		SQLFile:      e.st.GetSQLPath(e.cfg.SQLDir, entity),
+		RunTimestamp: runTime,
+		Phase:        errorPhase(err, phase),
 	}
 }
 
-// getStartDate determines the start date for an entity
+// tillDate returns the --until override when set, otherwise now
+func (e *Exporter) tillDate(now time.Time) (time.Time, error) {
+	_, until, err := e.cfg.DateRange()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !until.IsZero() {
+		return until, nil
+	}
+	return now, nil
+}
+
+// getStartDate determines the start date for an entity, preferring the --since override
 func (e *Exporter) getStartDate(entity types.EntityState) (time.Time, error) {
+	since, _, err := e.cfg.DateRange()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if !since.IsZero() {
+		return since, nil
+	}
+
 	lastRunTime, err := entity.GetLastRunTime()
 	if err != nil {
 		return time.Time{}, fmt.Errorf("failed to parse lastRunTime: %w", err)
@@ -183,82 +673,191 @@ func (e *Exporter) getStartDate(entity types.EntityState) (time.Time, error) {
 
 	// If no last run time, use default days back (UTC to avoid timezone issues)
 	if lastRunTime.IsZero() {
-		return time.Now().UTC().AddDate(0, 0, -e.cfg.DefaultDaysBack), nil
+		return time.Now().UTC().AddDate(0, 0, -e.daysBack(entity)), nil
 	}
 
 	return lastRunTime, nil
 }
 
-// loadSQLFile reads the SQL file for an entity
-func (e *Exporter) loadSQLFile(entityName string) (string, error) {
-	sqlPath := e.st.GetSQLPath(e.cfg.SQLDir, entityName)
+// daysBack returns how many days the first export of an entity looks back: its daysBack, else days_back
+func (e *Exporter) daysBack(entity types.EntityState) int {
+	if entity.DaysBack > 0 {
+		return entity.DaysBack
+	}
+	return e.cfg.DefaultDaysBack
+}
+
+// loadSQLFile reads the SQL file for an entity, substitutes ${...} variables from vars and
+// then renders it as a template with the global params and entity's sqlParams when it contains {{
+// Substitution runs first so templates can use the substituted values.
+func (e *Exporter) loadSQLFile(entity types.EntityState, vars map[string]string) (string, error) {
+	sqlPath := e.st.GetSQLPath(e.cfg.SQLDir, entity.Entity)
 
 	content, err := os.ReadFile(sqlPath)
 	if err != nil {
 		return "", fmt.Errorf("failed to read SQL file %s: %w", sqlPath, err)
 	}
 
-	return string(content), nil
+	sql := string(content)
+	if crypto.IsEncrypted(sql) {
+		var key []byte
+		if e.cfg.SQLEncryptionKey != "" {
+			if key, err = crypto.ParseKey(e.cfg.SQLEncryptionKey); err != nil {
+				return "", fmt.Errorf("failed to decrypt SQL file %s: %w", sqlPath, err)
+			}
+		}
+		if sql, err = crypto.DecryptSQL(sql, key); err != nil {
+			return "", fmt.Errorf("failed to decrypt SQL file %s: %w", sqlPath, err)
+		}
+	}
+
+	if sql, err = RenderSQLTemplate(SubstituteVariables(sql, vars), e.templateData(entity)); err != nil {
+		return "", fmt.Errorf("failed to render SQL file %s: %w", sqlPath, err)
+	}
+	return AppendFetchRowsHint(sql, e.cfg.FetchRowsHint), nil
 }
 
-// getOutputPath generates the output file path for an entity
-func (e *Exporter) getOutputPath(entityName, startDate string) string {
+// getOutputPath generates the output file path and cloud object key for an entity from FilenameTemplate
+// A trailing .csv in the template result is replaced by the configured extension (format, compression).
+// Flat file names are uploaded under an <entity>/ prefix, names with directories as they are.
+func (e *Exporter) getOutputPath(entityName, startDate, tillDate string) (path, key string, err error) {
 	// Replace colons with dashes for filename (matches bash script)
-	safeDate := strings.ReplaceAll(startDate, ":", "-")
-	filename := fmt.Sprintf("%s__%s.csv", entityName, safeDate)
-	return filepath.Join(e.cfg.ExportDir, filename)
-}
+	filename, err := config.RenderFilename(e.cfg.FilenameTemplate, config.FilenameData{
+		Entity:    entityName,
+		StartDate: strings.ReplaceAll(startDate, ":", "-"),
+		TillDate:  strings.ReplaceAll(tillDate, ":", "-"),
+		RunID:     e.runID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	if ext := e.fileExt(); ext != ".csv" && strings.HasSuffix(filename, ".csv") {
+		filename = strings.TrimSuffix(filename, ".csv") + ext
+	}
+	if e.cfg.OutputPerEntityDir && filepath.Base(filename) == filename {
+		filename = filepath.Join(entityName, filename)
+	}
 
-// executeQueryToCSV executes a query and streams results to CSV
-func (e *Exporter) executeQueryToCSV(ctx context.Context, sqlContent, startDate, tillDate, outputPath string, log *logging.Logger) (rowCount int, retErr error) {
-	// Prepare query parameters
-	params := map[string]interface{}{
-		"startDate": startDate,
-		"tillDate":  tillDate,
+	key = filepath.ToSlash(filename)
+	if !strings.Contains(key, "/") {
+		key = entityName + "/" + key
 	}
+	return filepath.Join(e.cfg.ExportDir, filename), key, nil
+}
 
-	// Execute query
-	rows, err := e.db.QueryContext(ctx, sqlContent, params)
+// executeQueryToCSV executes a query and streams results to CSV
+// With cloud storage the file is uploaded as objectKey (see getOutputPath) instead of written to outputPath
+// Rows reported as seen by dedup (if non-nil) are not written
+// A non-empty columnOrder writes the columns in that order (see EnforceColumnOrder)
+// Columns named in maskRules are written with their replacement value, columns in columnMap
+// (see applyColumnMap) under their alias
+func (e *Exporter) executeQueryToCSV(ctx context.Context, sqlContent string, params map[string]interface{}, outputPath string, store storage.BlobStore, objectKey string, columnOrder []string, maskRules, columnMap map[string]string, dedup *RowDeduplicator, limits streamLimits, log *logging.Logger) (rowCount int, exported exportedFile, retErr error) {
+	// Execute query, metering the rows read for the throughput log
+	rows, err := db.QueryMetered(ctx, e.db, sqlContent, params)
 	if err != nil {
-		return 0, fmt.Errorf("query execution failed: %w", err)
+		return 0, exportedFile{}, withPhase(PhaseQueryExec, fmt.Errorf("query execution failed: %w", err))
 	}
 	defer func() {
 		if err := rows.Close(); err != nil {
-			retErr = errors.Join(retErr, fmt.Errorf("failed to close rows: %w", err))
+			retErr = errors.Join(retErr, withPhase(PhaseRowStream, fmt.Errorf("failed to close rows: %w", err)))
 		}
 	}()
 
 	// Get column count
 	columns, err := rows.Columns()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get columns: %w", err)
+		return 0, exportedFile{}, withPhase(PhaseQueryExec, fmt.Errorf("failed to get columns: %w", err))
+	}
+
+	// Match the query columns against the defined order
+	var perm []int
+	outputColumns := len(columns)
+	if len(columnOrder) > 0 {
+		perm, err = EnforceColumnOrder(columnOrder, columns, e.cfg.IgnoreColumnAdditions)
+		if err != nil {
+			return 0, exportedFile{}, withPhase(PhaseQueryExec, apperrors.NewValidationError("executeQueryToCSV", "column order mismatch", err))
+		}
+		outputColumns = len(perm)
+	}
+	masks, err := maskColumns(maskRules, columns)
+	if err != nil {
+		return 0, exportedFile{}, withPhase(PhaseQueryExec, apperrors.NewValidationError("executeQueryToCSV", "invalid mask rules", err))
+	}
+	columnTypes, typesErr := rows.ColumnTypes()
+	opts := e.csvOptions()
+	if opts.Format == FormatParquet && typesErr == nil {
+		opts.ColumnTypes = outputColumnTypes(columnTypesOf(columnTypes), perm, masks)
+	}
+	// Aliases are written as they are, so the header case is applied here instead of by the writer
+	headers := columns
+	if len(columnMap) > 0 {
+		headers = applyColumnMap(columns, columnMap, opts.HeaderCase)
+		opts.HeaderCase = ""
+	}
+	if e.cfg.WriteMeta {
+		var metaTypes []ColumnType
+		if typesErr == nil {
+			metaTypes = columnTypesOf(columnTypes)
+		}
+		exported.columns = metaColumns(headers, metaTypes, perm, masks, opts.HeaderCase)
 	}
 
-	// Create the appropriate CSV writer based on S3 configuration
+	// Create the appropriate CSV writer based on cloud storage configuration
 	var writer csvWriter
-	if e.s3 != nil && e.cfg.S3.Bucket != "" {
-		// Generate S3 key from output path
-		safeDate := strings.ReplaceAll(startDate, ":", "-")
-		entityName := filepath.Base(outputPath)
-		entityName = strings.TrimSuffix(entityName, filepath.Ext(entityName))
-		entityName = strings.Split(entityName, "__")[0]
-		s3Key := e.cfg.S3.Key(fmt.Sprintf("%s/%s__%s.csv", entityName, entityName, safeDate))
-
-		log.Info("Streaming to S3: %s", s3Key)
-
-		// Create S3 streaming writer
-		w, err := NewS3StreamingCSVWriter(e.s3, s3Key, outputPath, len(columns))
+	var out *outputFile
+	var split *SplittingCSVWriter
+	if e.cfg.MaxRowsPerFile > 0 {
+		ext := e.fileExt()
+		split, err = NewSplittingCSVWriter(e.cfg.MaxRowsPerFile, func(part int) (*outputFile, error) {
+			return e.newOutputFile(PartPath(outputPath, ext, part), store, PartPath(objectKey, ext, part), outputColumns, opts, log)
+		})
 		if err != nil {
-			return 0, fmt.Errorf("failed to create S3 CSV writer: %w", err)
+			return 0, exportedFile{}, withPhase(PhaseFileWrite, err)
 		}
-		writer = w
+		// A part reaching max_file_size_mb rolls over to the next one instead of failing the entity
+		split.LimitSize(limits.maxBytes, sizeCheckInterval)
+		limits.maxBytes = 0
+		writer = split
 	} else {
-		// Create local file writer
-		w, err := NewStreamingCSVWriter(outputPath, len(columns))
+		out, err = e.newOutputFile(outputPath, store, objectKey, outputColumns, opts, log)
 		if err != nil {
-			return 0, fmt.Errorf("failed to create CSV writer: %w", err)
+			return 0, exportedFile{}, withPhase(PhaseFileWrite, err)
 		}
-		writer = w
+		writer = out
+		if out.cloud != nil {
+			limits.outputSize = out.cloud.OutputSize
+		} else {
+			limits.filePath = outputPath
+		}
+	}
+	if perm != nil {
+		writer = newOrderedWriter(writer, perm, len(columns))
+	}
+	var lobs map[int]lobKind
+	if typesErr == nil {
+		typeNames := make([]string, len(columnTypes))
+		for i, ct := range columnTypes {
+			typeNames[i] = ct.DatabaseTypeName()
+		}
+		if formats := intervalFormats(typeNames); formats != nil {
+			writer = newIntervalWriter(writer, formats)
+		}
+		lobs = lobColumns(typeNames)
+	}
+	if masks != nil {
+		writer = newMaskWriter(writer, masks)
+	}
+	if lobs != nil {
+		writer = newLOBWriter(writer, lobs, e.cfg.BlobEncoding, e.cfg.ClobMaxLength)
+	}
+	// Rows of a failed export do not count against the run budget, this runs after the close below
+	streamed := 0
+	if limits.budget != nil {
+		defer func() {
+			if retErr != nil {
+				limits.budget.release(streamed)
+			}
+		}()
 	}
 	writeComplete := false
 	defer func() {
@@ -267,58 +866,401 @@ func (e *Exporter) executeQueryToCSV(ctx context.Context, sqlContent, startDate,
 		}
 		if !writeComplete {
 			if err := writer.Remove(); err != nil {
-				retErr = errors.Join(retErr, fmt.Errorf("failed to remove incomplete output: %w", err))
+				retErr = errors.Join(retErr, withPhase(PhaseFileWrite, fmt.Errorf("failed to remove incomplete output: %w", err)))
 			}
 		}
-		if err := writer.Close(); err != nil {
-			retErr = errors.Join(retErr, fmt.Errorf("failed to finalize output: %w", err))
+		// Closing a cloud writer completes the upload
+		var closeErr error
+		if store != nil {
+			_, span := tracing.Tracer().Start(ctx, "upload "+store.Name(),
+				trace.WithAttributes(attribute.String("ora2csv.object_key", store.Key(objectKey))))
+			closeErr = writer.Close()
+			tracing.End(span, closeErr)
+		} else {
+			closeErr = writer.Close()
 		}
+		if closeErr != nil {
+			retErr = errors.Join(retErr, withPhase(PhaseFileWrite, fmt.Errorf("failed to finalize output: %w", closeErr)))
+			return
+		}
+		if !writeComplete || rowCount == 0 {
+			return
+		}
+
+		// Describe the finished files for the run manifest
+		if split != nil {
+			parts, err := split.Parts()
+			if err != nil {
+				retErr = errors.Join(retErr, withPhase(PhaseFileWrite, err))
+				return
+			}
+			for _, part := range parts {
+				exported.parts = append(exported.parts, part.Name)
+				exported.size += part.Size
+			}
+			exported.objectKey = parts[0].Key
+			// A checksum describes a single file
+			if len(parts) == 1 {
+				exported.checksum = parts[0].Checksum
+			}
+			return
+		}
+		size, checksum, err := out.digest()
+		if err != nil {
+			retErr = errors.Join(retErr, withPhase(PhaseFileWrite, err))
+			return
+		}
+		exported.objectKey = out.key
+		exported.size, exported.checksum = size, checksum
 	}()
 
 	// Write headers
-	if err := writer.WriteHeaders(columns); err != nil {
-		return 0, fmt.Errorf("failed to write headers: %w", err)
+	if err := writer.WriteHeaders(headers); err != nil {
+		return 0, exportedFile{}, withPhase(PhaseFileWrite, fmt.Errorf("failed to write headers: %w", err))
 	}
 
 	// Stream rows
+	rowCount, err = streamRows(rows, writer, dedup, limits, log)
+	streamed = rowCount
+	if err != nil {
+		return 0, exportedFile{}, err
+	}
+	exported.query = rows.Metrics()
+	log.Info("Query read %d rows (%d bytes) in %s: ~%d rows/sec, ~%d bytes/sec", exported.query.RowsScanned,
+		exported.query.BytesScanned, exported.query.Duration.Round(time.Millisecond),
+		int64(exported.query.RowsPerSec()), int64(exported.query.BytesPerSec()))
+
+	// Final flush
+	if err := writer.Flush(); err != nil {
+		return 0, exportedFile{}, withPhase(PhaseFileWrite, fmt.Errorf("failed to flush writer: %w", err))
+	}
+
+	// If no data rows, remove the file
+	if rowCount == 0 {
+		if err := writer.Remove(); err != nil {
+			return 0, exportedFile{}, withPhase(PhaseFileWrite, fmt.Errorf("failed to remove empty output file: %w", err))
+		}
+	}
+
+	writeComplete = true
+	return rowCount, exported, nil
+}
+
+// outputFile is the writer of an output file, uploaded as key with a cloud store
+type outputFile struct {
+	csvWriter
+	path  string
+	key   string                   // Full object key, empty for local files
+	cloud *CloudStreamingCSVWriter // nil for local files
+	store storage.BlobStore
+}
+
+// newOutputFile creates the writer of outputPath, or of objectKey in store when set
+func (e *Exporter) newOutputFile(outputPath string, store storage.BlobStore, objectKey string, columnCount int, opts CSVWriterOptions, log *logging.Logger) (*outputFile, error) {
+	if store == nil {
+		w, err := newFileWriter(outputPath, columnCount, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &outputFile{csvWriter: w, path: outputPath}, nil
+	}
+
+	key := store.Key(objectKey)
+	log.Info("Streaming to %s: %s", store.Name(), key)
+
+	// As a fallback, buffer the upload in a private temp directory (defaults to the export dir)
+	var tempDir, tempPath string
+	if e.cfg.CloudTempFile {
+		tempBase := e.cfg.TempDir
+		if tempBase == "" {
+			tempBase = e.cfg.ExportDir
+		}
+		var err error
+		tempDir, err = os.MkdirTemp(tempBase, "ora2csv-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temp directory: %w", err)
+		}
+		tempPath = filepath.Join(tempDir, filepath.Base(outputPath))
+	}
+
+	// Create cloud streaming writer
+	w, err := NewCloudStreamingCSVWriter(store, key, tempPath, tempDir, columnCount, opts)
+	if err != nil {
+		if tempDir != "" {
+			_ = os.RemoveAll(tempDir)
+		}
+		return nil, fmt.Errorf("failed to create %s CSV writer: %w", store.Name(), err)
+	}
+	out := &outputFile{csvWriter: w, path: outputPath, key: key, cloud: w, store: store}
+
+	// Keep a local copy next to the upload, e.g. to verify files before archiving
+	if e.cfg.AlsoLocalPath != "" {
+		local, localPath, err := e.newLocalCopyWriter(outputPath, columnCount, opts)
+		if err != nil {
+			_ = w.Remove()
+			_ = w.Close()
+			return nil, err
+		}
+		log.Info("Writing local copy: %s", localPath)
+		out.csvWriter = NewMultiWriter(w, store.Name()+" "+key, local, localPath)
+	}
+	return out, nil
+}
+
+// outputSize returns the bytes written so far, for max_file_size_mb checks
+func (o *outputFile) outputSize() (int64, error) {
+	if o.cloud != nil {
+		return o.cloud.OutputSize()
+	}
+	info, err := statFile(o.path)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// digest returns the size and checksum of the closed file
+func (o *outputFile) digest() (int64, string, error) {
+	if o.cloud != nil {
+		size, checksum := o.cloud.Digest()
+		return size, checksum, nil
+	}
+	return fileDigest(o.path)
+}
+
+// delete removes the closed file, or its uploaded object
+func (o *outputFile) delete(ctx context.Context) error {
+	if o.store != nil {
+		return o.store.Delete(ctx, o.key)
+	}
+	if err := os.Remove(o.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// newFileWriter creates a local file writer for the output format
+func newFileWriter(path string, columnCount int, opts CSVWriterOptions) (csvWriter, error) {
+	switch opts.Format {
+	case FormatJSONLines:
+		w, err := NewJSONLinesWriter(path, columnCount, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create JSON Lines writer: %w", err)
+		}
+		return w, nil
+	case FormatParquet:
+		w, err := NewParquetWriter(path, columnCount, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create Parquet writer: %w", err)
+		}
+		return w, nil
+	default:
+		w, err := NewStreamingCSVWriterWithOptions(path, columnCount, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create CSV writer: %w", err)
+		}
+		return w, nil
+	}
+}
+
+// newLocalCopyWriter creates the --also-local-path copy of outputPath
+// The copy has the same path relative to AlsoLocalPath as outputPath has to ExportDir.
+func (e *Exporter) newLocalCopyWriter(outputPath string, columnCount int, opts CSVWriterOptions) (csvWriter, string, error) {
+	rel, err := filepath.Rel(e.cfg.ExportDir, outputPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to resolve local copy path: %w", err)
+	}
+	path := filepath.Join(e.cfg.AlsoLocalPath, rel)
+	if err := os.MkdirAll(filepath.Dir(path), e.cfg.DirModeOrDefault()); err != nil {
+		return nil, "", fmt.Errorf("failed to create local copy directory: %w", err)
+	}
+	w, err := newFileWriter(path, columnCount, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	return w, path, nil
+}
+
+// sizeCheckInterval is the number of rows written between output file size checks
+const sizeCheckInterval = 10000
+
+// statFile is os.Stat, replaced in tests
+var statFile = os.Stat
+
+// streamLimits bounds an entity export, zero values mean unlimited
+type streamLimits struct {
+	maxRows  int64
+	maxBytes int64
+	filePath string // Local file checked against maxBytes
+	// outputSize, if set, is checked instead of filePath, e.g. for streamed uploads
+	outputSize func() (int64, error)
+	// progress, if set, receives the number of rows written so far for --progress
+	progress *atomic.Int64
+	// reporter, if set, logs the row count every progress_interval rows
+	reporter *ProgressReporter
+	// budget, if set, is the row limit shared by all entities of the run
+	budget *rowBudget
+}
+
+// rowBudget counts the rows exported by all entities of a run against max_rows_global
+// Rows of failed exports are released, their files are removed.
+type rowBudget struct {
+	max  int64
+	used atomic.Int64
+}
+
+// take counts one row and reports whether it is within the budget
+func (b *rowBudget) take() bool {
+	if b.used.Add(1) > b.max {
+		b.used.Add(-1)
+		return false
+	}
+	return true
+}
+
+// release returns n rows taken by an export that failed
+func (b *rowBudget) release(n int) {
+	b.used.Add(-int64(n))
+}
+
+// size returns the current output size, see outputSize
+func (l streamLimits) size() (int64, error) {
+	if l.outputSize != nil {
+		return l.outputSize()
+	}
+	info, err := statFile(l.filePath)
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// storageClassStore is a store whose uploads can use a different storage class
+type storageClassStore interface {
+	WithStorageClass(class string) storage.BlobStore
+}
+
+// entityStore returns the cloud store for an entity's export files, applying its S3 storage class
+// Stores without storage classes ignore the override.
+func (e *Exporter) entityStore(entity types.EntityState) (storage.BlobStore, error) {
+	if e.store == nil || entity.S3StorageClass == "" {
+		return e.store, nil
+	}
+	if err := config.ValidateS3StorageClass(entity.S3StorageClass); err != nil {
+		return nil, err
+	}
+	if s, ok := e.store.(storageClassStore); ok {
+		return s.WithStorageClass(entity.S3StorageClass), nil
+	}
+	return e.store, nil
+}
+
+// maxRows returns the row limit for an entity
+func (e *Exporter) maxRows(entity types.EntityState) int64 {
+	if entity.MaxRows > 0 {
+		return entity.MaxRows
+	}
+	return e.cfg.MaxResultSetRows
+}
+
+// maxFileSizeBytes returns the output file size limit for an entity
+func (e *Exporter) maxFileSizeBytes(entity types.EntityState) int64 {
+	sizeMB := e.cfg.DefaultMaxFileSizeMB
+	if entity.MaxFileSizeMB > 0 {
+		sizeMB = entity.MaxFileSizeMB
+	}
+	return int64(sizeMB) * 1024 * 1024
+}
+
+// streamRows writes scanned rows to writer and returns the number written
+// A positive maxRows, or an exhausted run budget, aborts with a validation error wrapping
+// MaxRowsExceededError instead of writing more rows, a positive maxBytes aborts with an
+// export error once the output file grows larger
+func streamRows(rows RowScanner, writer csvWriter, dedup *RowDeduplicator, limits streamLimits, log *logging.Logger) (int, error) {
+	maxRows := limits.maxRows
+	rowCount := 0
 	scanTargets := writer.GetScanTargets()
 	for rows.Next() {
 		if err := rows.Scan(scanTargets...); err != nil {
-			return 0, fmt.Errorf("failed to scan row: %w", err)
+			return rowCount, withPhase(PhaseRowStream, fmt.Errorf("failed to scan row: %w", err))
+		}
+		if dedup != nil && dedup.Seen(scanTargets) {
+			continue
+		}
+		if maxRows > 0 && int64(rowCount) >= maxRows {
+			log.Error("Result set exceeds the row limit (%d), check the SQL file for a missing WHERE clause", maxRows)
+			return rowCount, withPhase(PhaseRowStream, apperrors.NewValidationError(
+				"streamRows", "row limit exceeded", &MaxRowsExceededError{Limit: maxRows}))
+		}
+		if limits.budget != nil && !limits.budget.take() {
+			log.Error("Run exceeds max_rows_global (%d)", limits.budget.max)
+			return rowCount, withPhase(PhaseRowStream, apperrors.NewValidationError(
+				"streamRows", "row limit exceeded", &MaxRowsExceededError{Limit: limits.budget.max, Global: true}))
 		}
 		if err := writer.WriteScannedRow(); err != nil {
-			return 0, fmt.Errorf("failed to write row: %w", err)
+			return rowCount, withPhase(PhaseFileWrite, fmt.Errorf("failed to write row: %w", err))
 		}
 		rowCount++
-
-		// Log progress for large exports
-		if rowCount%10000 == 0 {
-			log.Debug("Progress: %d rows", rowCount)
+		if limits.progress != nil {
+			limits.progress.Store(int64(rowCount))
+		}
+		limits.reporter.Report(rowCount)
+		if limits.maxBytes > 0 && rowCount%sizeCheckInterval == 0 {
+			if err := checkFileSize(writer, limits, log); err != nil {
+				return rowCount, err
+			}
 		}
 	}
 
 	// Check for iteration errors
 	if err := rows.Err(); err != nil {
-		return 0, fmt.Errorf("row iteration error: %w", err)
+		return rowCount, withPhase(PhaseRowStream, fmt.Errorf("row iteration error: %w", err))
 	}
 
-	// Final flush
+	return rowCount, nil
+}
+
+// checkFileSize flushes the writer and fails if the output file exceeds limits.maxBytes
+func checkFileSize(writer csvWriter, limits streamLimits, log *logging.Logger) error {
 	if err := writer.Flush(); err != nil {
-		return 0, fmt.Errorf("failed to flush writer: %w", err)
+		return withPhase(PhaseFileWrite, fmt.Errorf("failed to flush writer: %w", err))
+	}
+	size, err := limits.size()
+	if err != nil {
+		return withPhase(PhaseFileWrite, fmt.Errorf("failed to check output file size: %w", err))
+	}
+	if size <= limits.maxBytes {
+		return nil
 	}
 
-	// If no data rows, remove the file
-	if rowCount == 0 {
-		if err := writer.Remove(); err != nil {
-			return 0, fmt.Errorf("failed to remove empty output file: %w", err)
-		}
+	log.Error("Output file size %d bytes exceeds max_file_size_mb limit of %d bytes", size, limits.maxBytes)
+	return withPhase(PhaseFileWrite, apperrors.NewExportError(
+		"streamRows", fmt.Sprintf("output file exceeds %d bytes", limits.maxBytes), nil))
+}
+
+// csvOptions returns the CSV output options from configuration
+func (e *Exporter) csvOptions() CSVWriterOptions {
+	return CSVWriterOptions{
+		QuoteChar:       e.cfg.CSVQuoteChar,
+		FileMode:        e.cfg.FileMode,
+		HeaderCase:      e.cfg.HeaderCase,
+		RecordSeparator: e.cfg.RecordSeparator,
+		Compress:        e.cfg.Compress,
+		Format:          e.cfg.FileFormat,
+		Delimiter:       e.cfg.Delimiter,
+		RowGroupBytes:   int64(e.cfg.ParquetRowGroupMB) * 1024 * 1024,
 	}
+}
 
-	writeComplete = true
-	return rowCount, nil
+// fileExt returns the output file name suffix, FileExtension overrides the format default
+func (e *Exporter) fileExt() string {
+	if e.cfg.FileExtension != "" {
+		return "." + strings.TrimPrefix(e.cfg.FileExtension, ".") + CompressedExt(e.cfg.Compress)
+	}
+	return FileExt(e.csvOptions())
 }
 
-// csvWriter is the interface for both StreamingCSVWriter and S3StreamingCSVWriter
+// csvWriter is the interface for StreamingCSVWriter, JSONLinesWriter, ParquetWriter and CloudStreamingCSVWriter
 type csvWriter interface {
 	WriteHeaders(columns []string) error
 	GetScanTargets() []interface{}
@@ -329,7 +1271,7 @@ type csvWriter interface {
 }
 
 // Validate validates configuration and SQL files
-func Validate(cfg *config.Config, st *state.File, testDB bool) error {
+func Validate(cfg *config.Config, st state.Store, testDB bool) error {
 	// Validate config
 	if err := cfg.Validate(); err != nil {
 		return fmt.Errorf("config validation failed: %w", err)
@@ -340,6 +1282,32 @@ func Validate(cfg *config.Config, st *state.File, testDB bool) error {
 		return fmt.Errorf("SQL file validation failed: %w", err)
 	}
 
+	if err := st.ValidateDependencies(); err != nil {
+		return fmt.Errorf("dependency validation failed: %w", err)
+	}
+
+	var fileMap map[string]string
+	if cfg.ColumnMapFile != "" {
+		var err error
+		if fileMap, err = LoadColumnMap(cfg.ColumnMapFile); err != nil {
+			return err
+		}
+	}
+	for _, entity := range st.GetEntities() {
+		if err := config.ValidateS3StorageClass(entity.S3StorageClass); err != nil {
+			return fmt.Errorf("entity %s: s3StorageClass: %w", entity.Entity, err)
+		}
+		if _, err := mergeColumnMaps(fileMap, entity.ColumnMap); err != nil {
+			return fmt.Errorf("entity %s: columnMap: %w", entity.Entity, err)
+		}
+		// The column map file is shared by all entities, only the columns of an entity's own map must exist
+		if entity.Active {
+			if err := validateColumnMap(st.GetSQLPath(cfg.SQLDir, entity.Entity), entity.ColumnMap); err != nil {
+				return fmt.Errorf("entity %s: %w", entity.Entity, err)
+			}
+		}
+	}
+
 	// Test database connection if requested
 	if testDB {
 		connStr := cfg.ConnectionString()
@@ -0,0 +1,601 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/crypto"
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	"github.com/koltyakov/ora2csv/internal/state"
+	apperrors "github.com/koltyakov/ora2csv/pkg/errors"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+const integrationSchema = `
+CREATE TABLE products (
+	id INTEGER PRIMARY KEY,
+	name TEXT,
+	sku TEXT,
+	updated TEXT NOT NULL
+);
+INSERT INTO products (id, name, sku, updated) VALUES
+	(1, 'Widget', 'W-1', '2025-01-01T10:00:00'),
+	(2, 'Gadget, large', NULL, '2025-01-02T10:00:00'),
+	(3, 'Old', 'O-1', '2024-12-01T10:00:00');
+`
+
+const integrationSQL = `SELECT id, name, sku, updated
+FROM products
+WHERE updated >= ? AND updated < ?
+ORDER BY updated ASC`
+
+func TestExporter_Run_SQLite(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		exp := New(cfg, database, st, logging.New(false), nil)
+		result, err := exp.Run(context.Background())
+		testutil.AssertNoError(t, err)
+
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+		testutil.AssertEqual(t, 0, result.FailedCount)
+		testutil.AssertEqual(t, 2, result.Results[0].RowCount)
+		testutil.AssertEqual(t, int64(2), result.Results[0].RowsScanned)
+		testutil.AssertEqual(t, int64(62), result.Results[0].BytesScanned)
+		if result.Results[0].QueryDuration <= 0 {
+			t.Errorf("QueryDuration = %s, want > 0", result.Results[0].QueryDuration)
+		}
+
+		data, err := os.ReadFile(result.Results[0].FilePath)
+		testutil.AssertNoError(t, err)
+		want := "id,name,sku,updated\n" +
+			"1,Widget,W-1,2025-01-01T10:00:00\n" +
+			"2,\"Gadget, large\",,2025-01-02T10:00:00\n"
+		testutil.AssertEqual(t, want, string(data))
+
+		// State must advance past the exported window
+		entity, ok := st.FindEntity("crm.products")
+		if !ok {
+			t.Fatal("entity not found in state")
+		}
+		if entity.LastRunTime <= "2025-01-01T00:00:00" {
+			t.Errorf("lastRunTime not advanced: %s", entity.LastRunTime)
+		}
+	})
+}
+
+func TestExporter_Run_IncludeInactive(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		cfg.IncludeInactive = true
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: false},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+		testutil.AssertEqual(t, 2, result.Results[0].RowCount)
+
+		// The state file keeps the entity inactive with its previous window
+		testutil.AssertNoError(t, st.Close())
+		reloaded, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+		entity, ok := reloaded.FindEntity("crm.products")
+		if !ok {
+			t.Fatal("entity not found in state")
+		}
+		testutil.AssertEqual(t, false, entity.Active)
+		testutil.AssertEqual(t, "2025-01-01T00:00:00", entity.LastRunTime)
+	})
+}
+
+func TestExporter_Run_Schedule(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		// Only scheduled tomorrow, so this run is outside the window
+		tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format("Mon")
+		mustWriteTestFile(t, cfg.StateFile, `[
+  {"entity":"crm.products","lastRunTime":"2025-01-01T00:00:00","active":true,"schedule":"`+tomorrow+`"}
+]`)
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+		defer func() { _ = st.Close() }()
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 0, result.ProcessedCount)
+		testutil.AssertEqual(t, 1, result.SkippedCount)
+
+		entity, ok := st.FindEntity("crm.products")
+		if !ok {
+			t.Fatal("entity not found in state")
+		}
+		testutil.AssertEqual(t, "2025-01-01T00:00:00", entity.LastRunTime)
+	})
+}
+
+func TestExporter_Run_DateRange(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		cfg.Since = "2024-12-01T00:00:00"
+		cfg.Until = "2025-01-02T00:00:00"
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+		testutil.AssertEqual(t, 2, result.Results[0].RowCount)
+
+		data, err := os.ReadFile(result.Results[0].FilePath)
+		testutil.AssertNoError(t, err)
+		want := "id,name,sku,updated\n" +
+			"3,Old,O-1,2024-12-01T10:00:00\n" +
+			"1,Widget,W-1,2025-01-01T10:00:00\n"
+		testutil.AssertEqual(t, want, string(data))
+
+		// A targeted re-export leaves the state file untouched
+		testutil.AssertNoError(t, st.Close())
+		reloaded, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+		entity, ok := reloaded.FindEntity("crm.products")
+		if !ok {
+			t.Fatal("entity not found in state")
+		}
+		testutil.AssertEqual(t, "2025-01-01T00:00:00", entity.LastRunTime)
+	})
+}
+
+func TestExporter_Run_Sequence(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		mustWriteTestFile(t, cfg.StateFile, `[{"entity":"crm.products","lastRunTime":"","active":true,"incrementalColumn":"id","incrementalType":"sequence","lastRunValue":"1"}]`)
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), `SELECT id, name
+FROM products
+WHERE id > ? AND id <= ?
+ORDER BY id ASC`)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+		testutil.AssertEqual(t, 2, result.Results[0].RowCount)
+
+		data, err := os.ReadFile(result.Results[0].FilePath)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, "id,name\n2,\"Gadget, large\"\n3,Old\n", string(data))
+
+		// The watermark advances to the highest exported id
+		entity, ok := st.FindEntity("crm.products")
+		if !ok {
+			t.Fatal("entity not found in state")
+		}
+		testutil.AssertEqual(t, "3", entity.LastRunValue)
+
+		// Nothing new on the next run keeps the watermark
+		result, err = New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 0, result.Results[0].RowCount)
+		entity, _ = st.FindEntity("crm.products")
+		testutil.AssertEqual(t, "3", entity.LastRunValue)
+	})
+}
+
+func TestExporter_GetOutputPath(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	e := New(cfg, nil, nil, logging.New(false), nil)
+	e.runID = "2025-01-02T00-00-05"
+
+	path, key, err := e.getOutputPath("crm.products", "2025-01-01T00:00:00", "2025-01-02T00:00:00")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, filepath.Join(cfg.ExportDir, "crm.products__2025-01-01T00-00-00.csv"), path)
+	testutil.AssertEqual(t, "crm.products/crm.products__2025-01-01T00-00-00.csv", key)
+
+	// Directories in the template are kept in the object key, .csv follows the output format
+	cfg.FilenameTemplate = "{{.Entity}}/{{.TillDate}}_{{.RunID}}.csv"
+	cfg.Compress = "gzip"
+	path, key, err = e.getOutputPath("crm.products", "2025-01-01T00:00:00", "2025-01-02T00:00:00")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, filepath.Join(cfg.ExportDir, "crm.products", "2025-01-02T00-00-00_2025-01-02T00-00-05.csv.gz"), path)
+	testutil.AssertEqual(t, "crm.products/2025-01-02T00-00-00_2025-01-02T00-00-05.csv.gz", key)
+
+	// Per-entity directories only apply to templates without a directory
+	cfg.OutputPerEntityDir = true
+	path, _, err = e.getOutputPath("crm.products", "2025-01-01T00:00:00", "2025-01-02T00:00:00")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, filepath.Join(cfg.ExportDir, "crm.products", "2025-01-02T00-00-00_2025-01-02T00-00-05.csv.gz"), path)
+	cfg.FilenameTemplate = ""
+	path, key, err = e.getOutputPath("crm.products", "2025-01-01T00:00:00", "2025-01-02T00:00:00")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, filepath.Join(cfg.ExportDir, "crm.products", "crm.products__2025-01-01T00-00-00.csv.gz"), path)
+	testutil.AssertEqual(t, "crm.products/crm.products__2025-01-01T00-00-00.csv.gz", key)
+}
+
+func TestExporter_Run_DependsOn(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		mustWriteTestFile(t, cfg.StateFile, `[
+  {"entity":"crm.lines","lastRunTime":"2025-01-01T00:00:00","active":true,"dependsOn":["crm.orders"]},
+  {"entity":"crm.orders","lastRunTime":"2025-01-01T00:00:00","active":true},
+  {"entity":"crm.items","lastRunTime":"2025-01-01T00:00:00","active":true,"dependsOn":["crm.products"]},
+  {"entity":"crm.products","lastRunTime":"2025-01-01T00:00:00","active":true}
+]`)
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		for _, name := range []string{"crm.lines", "crm.items", "crm.products"} {
+			mustWriteTestFile(t, filepath.Join(cfg.SQLDir, name+".sql"), integrationSQL)
+		}
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.orders.sql"), "SELECT id FROM missing_table WHERE ? < ?")
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 4, result.ProcessedCount)
+
+		// Parents run first, crm.lines is not exported once crm.orders fails
+		order := make([]string, len(result.Results))
+		for i, r := range result.Results {
+			order[i] = r.Entity
+		}
+		testutil.AssertEqual(t, "crm.orders,crm.lines,crm.products,crm.items", strings.Join(order, ","))
+		testutil.AssertEqual(t, 2, result.SuccessCount)
+		if err := result.Results[1].Error; err == nil || !strings.Contains(err.Error(), "dependency crm.orders failed") {
+			t.Errorf("crm.lines error = %v, want dependency failure", err)
+		}
+		entity, _ := st.FindEntity("crm.lines")
+		testutil.AssertEqual(t, "2025-01-01T00:00:00", entity.LastRunTime)
+	})
+}
+
+func TestExporter_Run_SingleEntity(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+			{Entity: "crm.items", LastRunTime: "2025-01-01T00:00:00", Active: true},
+			{Entity: "crm.legacy", LastRunTime: "2025-01-01T00:00:00", Active: false},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		for _, entity := range entities {
+			mustWriteTestFile(t, filepath.Join(cfg.SQLDir, entity.Entity+".sql"), integrationSQL)
+		}
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		cfg.Entity = "crm.items"
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.ProcessedCount)
+		testutil.AssertEqual(t, "crm.items", result.Results[0].Entity)
+
+		// Only the named entity is exported and advanced
+		files, err := filepath.Glob(filepath.Join(cfg.ExportDir, "*.csv"))
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, len(files))
+		if !strings.HasPrefix(filepath.Base(files[0]), "crm.items__") {
+			t.Errorf("exported %s, want crm.items", files[0])
+		}
+		products, _ := st.FindEntity("crm.products")
+		testutil.AssertEqual(t, "2025-01-01T00:00:00", products.LastRunTime)
+
+		for _, name := range []string{"crm.missing", "crm.legacy"} {
+			cfg.Entity = name
+			if _, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background()); err == nil {
+				t.Errorf("Run() with entity %s should fail", name)
+			}
+		}
+	})
+}
+
+func TestExporter_LoadSQLFile_Encrypted(t *testing.T) {
+	const keyHex = "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"
+	key, err := crypto.ParseKey(keyHex)
+	testutil.AssertNoError(t, err)
+
+	cfg := testutil.NewTestConfig(t)
+	testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+	encrypted, err := crypto.EncryptSQL(integrationSQL, key)
+	testutil.AssertNoError(t, err)
+	mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.secret.sql"), encrypted)
+	mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.plain.sql"), integrationSQL)
+
+	st, err := state.NewMultiFile()
+	testutil.AssertNoError(t, err)
+
+	t.Run("decrypts with key", func(t *testing.T) {
+		cfg.SQLEncryptionKey = keyHex
+		exp := New(cfg, nil, st, logging.New(false), nil)
+
+		sql, err := exp.loadSQLFile(types.EntityState{Entity: "crm.secret"}, nil)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, integrationSQL, sql)
+
+		sql, err = exp.loadSQLFile(types.EntityState{Entity: "crm.plain"}, nil)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, integrationSQL, sql)
+	})
+
+	t.Run("fails without key", func(t *testing.T) {
+		cfg.SQLEncryptionKey = ""
+		exp := New(cfg, nil, st, logging.New(false), nil)
+
+		if _, err := exp.loadSQLFile(types.EntityState{Entity: "crm.secret"}, nil); !errors.Is(err, crypto.ErrNoKey) {
+			t.Errorf("loadSQLFile() error = %v, want ErrNoKey", err)
+		}
+	})
+}
+
+func TestExporter_LoadSQLFile_Template(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+	mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "sales.orders.sql"), "SELECT * FROM {{.Table}} WHERE updated >= :startDate")
+	st, err := state.NewMultiFile()
+	testutil.AssertNoError(t, err)
+	exp := New(cfg, nil, st, logging.New(false), nil)
+
+	sql, err := exp.loadSQLFile(types.EntityState{Entity: "sales.orders", SQLParams: map[string]string{"Table": "ORDERS_2024"}}, nil)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "SELECT * FROM ORDERS_2024 WHERE updated >= :startDate", sql)
+
+	if _, err := exp.loadSQLFile(types.EntityState{Entity: "sales.orders"}, nil); err == nil {
+		t.Error("loadSQLFile() without sqlParams should fail")
+	}
+}
+
+func TestExporter_LoadSQLFile_TemplateAfterVariables(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+	mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "sales.orders.sql"),
+		`SELECT * FROM {{.Table}}{{ if eq "${DAYS_BACK}" "7" }} WHERE region = 'EU'{{ end }}`)
+	st, err := state.NewMultiFile()
+	testutil.AssertNoError(t, err)
+	exp := New(cfg, nil, st, logging.New(false), nil)
+	entity := types.EntityState{Entity: "sales.orders", SQLParams: map[string]string{"Table": "ORDERS"}}
+
+	// ${...} is substituted before the template is rendered
+	sql, err := exp.loadSQLFile(entity, map[string]string{"DAYS_BACK": "7"})
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "SELECT * FROM ORDERS WHERE region = 'EU'", sql)
+
+	sql, err = exp.loadSQLFile(entity, map[string]string{"DAYS_BACK": "30"})
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "SELECT * FROM ORDERS", sql)
+}
+
+func TestStreamFromRows_SQLite(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		rows, err := database.QueryContext(context.Background(), integrationSQL, map[string]interface{}{
+			"startDate": "2024-01-01T00:00:00",
+			"tillDate":  "2026-01-01T00:00:00",
+		})
+		testutil.AssertNoError(t, err)
+
+		filePath := filepath.Join(t.TempDir(), "products.csv")
+		writer, err := NewStreamingCSVWriter(filePath, 4)
+		testutil.AssertNoError(t, err)
+		defer mustCloseStreamingCSVWriter(t, writer)
+
+		testutil.AssertNoError(t, StreamFromRows(writer, rows))
+		testutil.AssertEqual(t, 3, writer.RowCount())
+
+		data, err := os.ReadFile(filePath)
+		testutil.AssertNoError(t, err)
+		if !strings.HasPrefix(string(data), "id,name,sku,updated\n3,Old,O-1,") {
+			t.Errorf("unexpected CSV content:\n%s", data)
+		}
+	})
+}
+
+func TestStreamRows_MaxResultSetRows(t *testing.T) {
+	scanner := db.NewMockRowScanner([]string{"ID", "NAME"}, nil)
+	for i := 0; i < 10; i++ {
+		scanner.AddRow(fmt.Sprintf("%d", i), fmt.Sprintf("row-%d", i))
+	}
+
+	writer, err := NewStreamingCSVWriter(filepath.Join(t.TempDir(), "out.csv"), 2)
+	testutil.AssertNoError(t, err)
+	defer mustCloseStreamingCSVWriter(t, writer)
+
+	rowCount, err := streamRows(scanner, writer, nil, streamLimits{maxRows: 5}, logging.New(false))
+	if err == nil {
+		t.Fatal("expected error when result set exceeds the limit")
+	}
+	if !apperrors.IsType(err, apperrors.ErrorTypeValidation) {
+		t.Errorf("error %v is not a validation error", err)
+	}
+	var exceeded *MaxRowsExceededError
+	if !errors.As(err, &exceeded) || exceeded.Limit != 5 || exceeded.Global {
+		t.Errorf("error %v is not an entity MaxRowsExceededError", err)
+	}
+	testutil.AssertEqual(t, 5, rowCount)
+	testutil.AssertEqual(t, 5, writer.RowCount())
+}
+
+func TestStreamRows_MaxRowsGlobal(t *testing.T) {
+	budget := &rowBudget{max: 7}
+	stream := func() (int, error) {
+		scanner := db.NewMockRowScanner([]string{"ID"}, nil)
+		for i := 0; i < 5; i++ {
+			scanner.AddRow(fmt.Sprintf("%d", i))
+		}
+		writer, err := NewStreamingCSVWriter(filepath.Join(t.TempDir(), "out.csv"), 1)
+		testutil.AssertNoError(t, err)
+		defer mustCloseStreamingCSVWriter(t, writer)
+		return streamRows(scanner, writer, nil, streamLimits{budget: budget}, logging.New(false))
+	}
+
+	rowCount, err := stream()
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 5, rowCount)
+
+	// The second entity runs out of the budget shared with the first
+	rowCount, err = stream()
+	var exceeded *MaxRowsExceededError
+	if !errors.As(err, &exceeded) || !exceeded.Global || exceeded.Limit != 7 {
+		t.Fatalf("streamRows() error = %v, want a global MaxRowsExceededError", err)
+	}
+	testutil.AssertEqual(t, 2, rowCount)
+
+	// A failed export returns its rows to the budget
+	budget.release(rowCount)
+	testutil.AssertEqual(t, int64(5), budget.used.Load())
+}
+
+func TestExporter_MaxRows(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	cfg.MaxResultSetRows = 1000
+	e := New(cfg, nil, nil, logging.New(false), nil)
+	testutil.AssertEqual(t, int64(1000), e.maxRows(types.EntityState{Entity: "crm.orders"}))
+	testutil.AssertEqual(t, int64(50), e.maxRows(types.EntityState{Entity: "crm.orders", MaxRows: 50}))
+}
+
+func TestExporter_DaysBack(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	cfg.DefaultDaysBack = 30
+	e := New(cfg, nil, nil, logging.New(false), nil)
+	testutil.AssertEqual(t, 30, e.daysBack(types.EntityState{Entity: "crm.orders"}))
+	testutil.AssertEqual(t, 730, e.daysBack(types.EntityState{Entity: "crm.orders", DaysBack: 730}))
+
+	startDate, err := e.getStartDate(types.EntityState{Entity: "crm.orders", DaysBack: 7})
+	testutil.AssertNoError(t, err)
+	if age := time.Since(startDate); age < 7*24*time.Hour-time.Minute || age > 7*24*time.Hour+time.Minute {
+		t.Errorf("startDate = %s, want 7 days ago", startDate)
+	}
+
+	// lastRunTime takes precedence over daysBack
+	startDate, err = e.getStartDate(types.EntityState{Entity: "crm.orders", LastRunTime: "2025-01-14T00:00:00", DaysBack: 7})
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "2025-01-14T00:00:00", startDate.Format("2006-01-02T15:04:05"))
+}
+
+func TestStreamRows_WithinLimit(t *testing.T) {
+	scanner := db.NewMockRowScanner([]string{"ID"}, [][]string{{"1"}, {"2"}, {"3"}, {"4"}, {"5"}})
+
+	writer, err := NewStreamingCSVWriter(filepath.Join(t.TempDir(), "out.csv"), 1)
+	testutil.AssertNoError(t, err)
+	defer mustCloseStreamingCSVWriter(t, writer)
+
+	rowCount, err := streamRows(scanner, writer, nil, streamLimits{maxRows: 5}, logging.New(false))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 5, rowCount)
+}
+
+// sizedFileInfo reports a fixed file size
+type sizedFileInfo struct {
+	os.FileInfo
+	size int64
+}
+
+func (f sizedFileInfo) Size() int64 { return f.size }
+
+func TestStreamRows_MaxFileSize(t *testing.T) {
+	const limit = 1024 * 1024
+	tests := []struct {
+		name     string
+		size     int64
+		wantRows int
+		wantErr  bool
+	}{
+		{"aborts at the first check over the limit", limit + 1, sizeCheckInterval, true},
+		{"within limit", limit, 2*sizeCheckInterval + 5, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stat := statFile
+			statFile = func(name string) (os.FileInfo, error) { return sizedFileInfo{size: tt.size}, nil }
+			defer func() { statFile = stat }()
+
+			scanner := db.NewMockRowScanner([]string{"ID"}, nil)
+			for i := 0; i < 2*sizeCheckInterval+5; i++ {
+				scanner.AddRow(fmt.Sprintf("%d", i))
+			}
+			filePath := filepath.Join(t.TempDir(), "out.csv")
+			writer, err := NewStreamingCSVWriter(filePath, 1)
+			testutil.AssertNoError(t, err)
+			defer mustCloseStreamingCSVWriter(t, writer)
+
+			limits := streamLimits{maxBytes: limit, filePath: filePath}
+			rowCount, err := streamRows(scanner, writer, nil, limits, logging.New(false))
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("streamRows() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr && !apperrors.IsType(err, apperrors.ErrorTypeExport) {
+				t.Errorf("error %v is not an export error", err)
+			}
+			testutil.AssertEqual(t, tt.wantRows, rowCount)
+		})
+	}
+}
+
+func TestExporter_SelectEntities_Tags(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	cfg.IncludeTags = []string{"daily"}
+	cfg.ExcludeTags = []string{"experimental"}
+	mustWriteTestFile(t, cfg.StateFile, `[
+  {"entity":"crm.orders","lastRunTime":"","active":true,"tags":["daily"],"dependsOn":["crm.customers"]},
+  {"entity":"crm.customers","lastRunTime":"","active":true,"tags":["weekly"]},
+  {"entity":"crm.sandbox","lastRunTime":"","active":true,"tags":["daily","experimental"]},
+  {"entity":"crm.invoices","lastRunTime":"","active":true,"tags":["billing","daily"]}
+]`)
+	st, err := state.Load(cfg.StateFile, nil, "")
+	testutil.AssertNoError(t, err)
+	defer func() { _ = st.Close() }()
+
+	selected, err := New(cfg, nil, st, logging.New(false), nil).selectEntities()
+	testutil.AssertNoError(t, err)
+	var names []string
+	for _, entity := range selected {
+		names = append(names, entity.Entity)
+	}
+	// A dependency outside the selection is not waited for
+	testutil.AssertEqual(t, "crm.orders,crm.invoices", strings.Join(names, ","))
+}
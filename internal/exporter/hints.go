@@ -0,0 +1,89 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	selectKeyword = regexp.MustCompile(`(?i)\bSELECT\b`)
+	hintBlock     = regexp.MustCompile(`^\s*/\*\+((?s).*?)\*/`)
+)
+
+// InjectHints adds optimizer hints to the first SELECT of a query
+// Hints are merged into an existing /*+ ... */ block and ones already present are skipped
+func InjectHints(sqlContent string, hints ...string) string {
+	start := leadingCommentsEnd(sqlContent)
+	loc := selectKeyword.FindStringIndex(sqlContent[start:])
+	if loc == nil {
+		return sqlContent
+	}
+	head, rest := sqlContent[:start+loc[1]], sqlContent[start+loc[1]:]
+
+	if m := hintBlock.FindStringSubmatchIndex(rest); m != nil {
+		existing := rest[m[2]:m[3]]
+		missing := missingHints(existing, hints)
+		if len(missing) == 0 {
+			return sqlContent
+		}
+		merged := strings.TrimSpace(existing) + " " + strings.Join(missing, " ")
+		return head + rest[:m[2]] + " " + merged + " " + rest[m[3]:]
+	}
+
+	missing := missingHints("", hints)
+	if len(missing) == 0 {
+		return sqlContent
+	}
+	return head + " /*+ " + strings.Join(missing, " ") + " */" + rest
+}
+
+// AppendFetchRowsHint appends a "-- ROWS=<n>" comment with the rows to fetch per round-trip
+// The comment goes on its own line so a trailing line comment in the query is not extended
+// A non-positive rows value returns the query unchanged
+func AppendFetchRowsHint(sqlContent string, rows int) string {
+	if rows <= 0 {
+		return sqlContent
+	}
+	return strings.TrimRight(sqlContent, " \t\r\n") + fmt.Sprintf("\n-- ROWS=%d\n", rows)
+}
+
+// missingHints returns the hints not yet present in an existing hint block
+func missingHints(existing string, hints []string) []string {
+	upper := strings.ToUpper(existing)
+	var missing []string
+	for _, hint := range hints {
+		if hint == "" || strings.Contains(upper, strings.ToUpper(hint)) {
+			continue
+		}
+		missing = append(missing, hint)
+		upper += " " + strings.ToUpper(hint)
+	}
+	return missing
+}
+
+// leadingCommentsEnd returns the offset of the first token after leading
+// whitespace and comments, so SELECT in a header comment is not matched
+func leadingCommentsEnd(sqlContent string) int {
+	i := 0
+	for {
+		trimmed := strings.TrimLeft(sqlContent[i:], " \t\r\n")
+		i = len(sqlContent) - len(trimmed)
+		switch {
+		case strings.HasPrefix(trimmed, "--"):
+			end := strings.IndexByte(trimmed, '\n')
+			if end < 0 {
+				return len(sqlContent)
+			}
+			i += end + 1
+		case strings.HasPrefix(trimmed, "/*") && !strings.HasPrefix(trimmed, "/*+"):
+			end := strings.Index(trimmed[2:], "*/")
+			if end < 0 {
+				return len(sqlContent)
+			}
+			i += end + 4
+		default:
+			return i
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package exporter
+
+import "testing"
+
+func TestInjectHints(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "no hint block",
+			sql:  "SELECT id FROM t",
+			want: "SELECT /*+ RESULT_CACHE */ id FROM t",
+		},
+		{
+			name: "merges existing hint block",
+			sql:  "SELECT /*+ FULL(t) PARALLEL(4) */ id FROM t",
+			want: "SELECT /*+ FULL(t) PARALLEL(4) RESULT_CACHE */ id FROM t",
+		},
+		{
+			name: "hint already present",
+			sql:  "select /*+ result_cache */ id from t",
+			want: "select /*+ result_cache */ id from t",
+		},
+		{
+			name: "skips header comments",
+			sql:  "-- Select all rows\n/* select */\nSELECT\n    id\nFROM t",
+			want: "-- Select all rows\n/* select */\nSELECT /*+ RESULT_CACHE */\n    id\nFROM t",
+		},
+		{
+			name: "no select",
+			sql:  "-- nothing here",
+			want: "-- nothing here",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := InjectHints(tt.sql, "RESULT_CACHE"); got != tt.want {
+				t.Errorf("InjectHints() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppendFetchRowsHint(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		rows int
+		want string
+	}{
+		{
+			name: "appends comment",
+			sql:  "SELECT id FROM t",
+			rows: 1000,
+			want: "SELECT id FROM t\n-- ROWS=1000\n",
+		},
+		{
+			name: "trailing newline and line comment",
+			sql:  "SELECT id FROM t -- all rows\n",
+			rows: 500,
+			want: "SELECT id FROM t -- all rows\n-- ROWS=500\n",
+		},
+		{
+			name: "zero leaves query unchanged",
+			sql:  "SELECT id FROM t\n",
+			rows: 0,
+			want: "SELECT id FROM t\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AppendFetchRowsHint(tt.sql, tt.rows); got != tt.want {
+				t.Errorf("AppendFetchRowsHint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package exporter
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// FormatIntervalYearToMonth formats an INTERVAL YEAR TO MONTH value as an ISO 8601 duration
+// go-ora returns the value as "+YY-MM" (e.g. "+01-02" becomes "P1Y2M"). Values in
+// another format are returned unchanged.
+func FormatIntervalYearToMonth(raw interface{}) string {
+	s := rawString(raw)
+	sign, body := splitSign(s)
+	years, months, ok := strings.Cut(body, "-")
+	if !ok {
+		return s
+	}
+	y, errY := strconv.ParseInt(years, 10, 64)
+	m, errM := strconv.ParseInt(months, 10, 64)
+	if errY != nil || errM != nil {
+		return s
+	}
+
+	var b strings.Builder
+	b.WriteString(sign + "P")
+	if y != 0 {
+		fmt.Fprintf(&b, "%dY", y)
+	}
+	if m != 0 || y == 0 {
+		fmt.Fprintf(&b, "%dM", m)
+	}
+	return b.String()
+}
+
+// FormatIntervalDayToSec formats an INTERVAL DAY TO SECOND value as an ISO 8601 duration
+// go-ora returns the value as "+DD HH:MM:SS.FFFFFF" (e.g. "+00 03:04:05.000000" becomes
+// "PT3H4M5S"). Values in another format are returned unchanged.
+func FormatIntervalDayToSec(raw interface{}) string {
+	s := rawString(raw)
+	sign, body := splitSign(s)
+	days, clock, ok := strings.Cut(body, " ")
+	if !ok {
+		return s
+	}
+	parts := strings.Split(clock, ":")
+	if len(parts) != 3 {
+		return s
+	}
+	d, errD := strconv.ParseInt(days, 10, 64)
+	h, errH := strconv.ParseInt(parts[0], 10, 64)
+	m, errM := strconv.ParseInt(parts[1], 10, 64)
+	secs := parts[2]
+	if errD != nil || errH != nil || errM != nil {
+		return s
+	}
+	if _, err := strconv.ParseFloat(secs, 64); err != nil {
+		return s
+	}
+	if strings.Contains(secs, ".") {
+		secs = strings.TrimRight(strings.TrimRight(secs, "0"), ".")
+	}
+	secs = strings.TrimLeft(secs, "0")
+	if secs == "" || strings.HasPrefix(secs, ".") {
+		secs = "0" + secs
+	}
+
+	var b strings.Builder
+	b.WriteString(sign + "P")
+	if d != 0 {
+		fmt.Fprintf(&b, "%dD", d)
+	}
+	if h != 0 || m != 0 || secs != "0" || d == 0 {
+		b.WriteString("T")
+		if h != 0 {
+			fmt.Fprintf(&b, "%dH", h)
+		}
+		if m != 0 {
+			fmt.Fprintf(&b, "%dM", m)
+		}
+		if secs != "0" || (h == 0 && m == 0) {
+			b.WriteString(secs + "S")
+		}
+	}
+	return b.String()
+}
+
+// rawString converts a scanned value to string
+func rawString(raw interface{}) string {
+	switch v := raw.(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case []byte:
+		return strings.TrimSpace(string(v))
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// splitSign returns "-" for negative values and the value without its sign
+func splitSign(s string) (string, string) {
+	switch {
+	case strings.HasPrefix(s, "-"):
+		return "-", s[1:]
+	case strings.HasPrefix(s, "+"):
+		return "", s[1:]
+	}
+	return "", s
+}
+
+// intervalFormatter returns the formatter for an interval database type name, nil otherwise
+// Both Oracle names (INTERVAL DAY TO SECOND) and go-ora names (IntervalDS_DTY) are matched.
+func intervalFormatter(typeName string) func(interface{}) string {
+	name := strings.ToUpper(strings.ReplaceAll(typeName, "_", " "))
+	switch {
+	case strings.HasPrefix(name, "INTERVAL DAY"), strings.HasPrefix(name, "INTERVALDS"):
+		return FormatIntervalDayToSec
+	case strings.HasPrefix(name, "INTERVAL YEAR"), strings.HasPrefix(name, "INTERVALYM"):
+		return FormatIntervalYearToMonth
+	}
+	return nil
+}
+
+// intervalFormats maps query column indexes to interval formatters
+func intervalFormats(typeNames []string) map[int]func(interface{}) string {
+	var formats map[int]func(interface{}) string
+	for i, name := range typeNames {
+		if f := intervalFormatter(name); f != nil {
+			if formats == nil {
+				formats = make(map[int]func(interface{}) string)
+			}
+			formats[i] = f
+		}
+	}
+	return formats
+}
+
+// intervalWriter rewrites interval columns as ISO 8601 durations before writing a row
+// It wraps the outermost writer so formats are keyed by query column index; the scanned
+// values are formatted after dedup has seen the raw driver values.
+type intervalWriter struct {
+	csvWriter
+	formats map[int]func(interface{}) string
+	targets []interface{}
+}
+
+// newIntervalWriter wraps w to format the interval columns in formats
+func newIntervalWriter(w csvWriter, formats map[int]func(interface{}) string) *intervalWriter {
+	return &intervalWriter{csvWriter: w, formats: formats}
+}
+
+// GetScanTargets returns the wrapped scan targets and keeps them for formatting
+func (w *intervalWriter) GetScanTargets() []interface{} {
+	w.targets = w.csvWriter.GetScanTargets()
+	return w.targets
+}
+
+// WriteScannedRow formats interval values and writes the row
+func (w *intervalWriter) WriteScannedRow() error {
+	for i, format := range w.formats {
+		if v, ok := w.targets[i].(*sql.NullString); ok && v.Valid {
+			v.String = format(v.String)
+		}
+	}
+	return w.csvWriter.WriteScannedRow()
+}
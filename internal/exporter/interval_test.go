@@ -0,0 +1,90 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestFormatIntervalYearToMonth(t *testing.T) {
+	tests := []struct {
+		raw  interface{}
+		want string
+	}{
+		{"+01-02", "P1Y2M"},
+		{"+00-07", "P7M"},
+		{"+05-00", "P5Y"},
+		{"+00-00", "P0M"},
+		{"-02-03", "-P2Y3M"},
+		{[]byte("+10-11"), "P10Y11M"},
+		{"not an interval", "not an interval"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatIntervalYearToMonth(tt.raw); got != tt.want {
+			t.Errorf("FormatIntervalYearToMonth(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestFormatIntervalDayToSec(t *testing.T) {
+	tests := []struct {
+		raw  interface{}
+		want string
+	}{
+		{"+00 03:04:05.000000", "PT3H4M5S"},
+		{"+02 00:00:00.000000", "P2D"},
+		{"+01 12:00:00.000000", "P1DT12H"},
+		{"+00 00:00:00.500000", "PT0.5S"},
+		{"+00 00:00:10.000000", "PT10S"},
+		{"+00 00:00:00.000000", "PT0S"},
+		{"-01 02:03:04.250000", "-P1DT2H3M4.25S"},
+		{"garbage", "garbage"},
+	}
+
+	for _, tt := range tests {
+		if got := FormatIntervalDayToSec(tt.raw); got != tt.want {
+			t.Errorf("FormatIntervalDayToSec(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestIntervalFormats(t *testing.T) {
+	formats := intervalFormats([]string{"NUMBER", "IntervalDS_DTY", "INTERVAL YEAR TO MONTH", "VARCHAR2"})
+	if len(formats) != 2 || formats[1] == nil || formats[2] == nil {
+		t.Fatalf("intervalFormats() = %v, want columns 1 and 2", formats)
+	}
+	testutil.AssertEqual(t, "PT1H", formats[1]("+00 01:00:00.000000"))
+	testutil.AssertEqual(t, "P1Y", formats[2]("+01-00"))
+
+	if formats := intervalFormats([]string{"NUMBER", "DATE"}); formats != nil {
+		t.Errorf("intervalFormats() = %v, want nil", formats)
+	}
+}
+
+func TestIntervalWriter(t *testing.T) {
+	columns := []string{"ID", "ELAPSED", "TENURE"}
+	scanner := db.NewMockRowScanner(columns, [][]string{
+		{"1", "+00 03:04:05.000000", "+01-02"},
+		{"2", "+01 00:00:30.000000", "-00-06"},
+	})
+
+	filePath := filepath.Join(t.TempDir(), "out.csv")
+	inner, err := NewStreamingCSVWriter(filePath, len(columns))
+	testutil.AssertNoError(t, err)
+	writer := newIntervalWriter(inner, intervalFormats([]string{"NUMBER", "IntervalDS_DTY", "IntervalYM_DTY"}))
+
+	testutil.AssertNoError(t, writer.WriteHeaders(columns))
+	rowCount, err := streamRows(scanner, writer, nil, streamLimits{}, logging.New(false))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 2, rowCount)
+	testutil.AssertNoError(t, writer.Close())
+
+	data, err := os.ReadFile(filePath)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "ID,ELAPSED,TENURE\n1,PT3H4M5S,P1Y2M\n2,P1DT30S,-P6M\n", string(data))
+}
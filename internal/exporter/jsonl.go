@@ -0,0 +1,184 @@
+package exporter
+
+import (
+	"bufio"
+	"compress/gzip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FormatJSONLines writes one JSON object per row instead of CSV
+const FormatJSONLines = "jsonl"
+
+// isJSONLinesPath reports whether path names a JSON Lines file, optionally compressed
+func isJSONLinesPath(path string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(path, ".gz"), ".jsonl")
+}
+
+// JSONLinesWriter writes rows as JSON objects keyed by column name, one per line
+// Values are written as JSON strings and NULLs as null. Headers are only used as keys.
+type JSONLinesWriter struct {
+	w          *bufio.Writer
+	file       outputSink
+	gz         *gzip.Writer // nil unless compressing
+	keys       [][]byte     // JSON-encoded column names
+	headerCase string
+	dest       []interface{}
+	rowValues  []sql.NullString
+	rowCount   int
+}
+
+// NewJSONLinesWriter creates a JSON Lines writer for columnCount columns
+// FileMode, HeaderCase and Compress are taken from opts, CSV-only options are ignored.
+func NewJSONLinesWriter(filePath string, columnCount int, opts CSVWriterOptions) (*JSONLinesWriter, error) {
+	file, err := createFileSink(filePath, opts.FileMode)
+	if err != nil {
+		return nil, err
+	}
+	return newJSONLinesWriter(file, columnCount, opts), nil
+}
+
+// newJSONLinesWriter creates a JSONLinesWriter writing to file, FileMode is not used
+func newJSONLinesWriter(file outputSink, columnCount int, opts CSVWriterOptions) *JSONLinesWriter {
+	var out io.Writer = file
+	var gz *gzip.Writer
+	if opts.Compress == CompressGzip {
+		gz = gzip.NewWriter(file)
+		out = gz
+	}
+
+	return &JSONLinesWriter{
+		w:          bufio.NewWriter(out),
+		file:       file,
+		gz:         gz,
+		headerCase: opts.HeaderCase,
+		dest:       make([]interface{}, columnCount),
+		rowValues:  make([]sql.NullString, columnCount),
+	}
+}
+
+// WriteHeaders sets the object keys, nothing is written to the file
+func (w *JSONLinesWriter) WriteHeaders(columns []string) error {
+	w.keys = make([][]byte, len(columns))
+	for i, col := range columns {
+		if w.headerCase != "" {
+			col = ConvertCase(col, w.headerCase)
+		}
+		key, err := json.Marshal(col)
+		if err != nil {
+			return fmt.Errorf("failed to encode column name %q: %w", col, err)
+		}
+		w.keys[i] = key
+	}
+	return nil
+}
+
+// GetScanTargets returns a slice of interface{} pointers for sql.Rows.Scan
+func (w *JSONLinesWriter) GetScanTargets() []interface{} {
+	for i := range w.dest {
+		w.rowValues[i] = sql.NullString{}
+		w.dest[i] = &w.rowValues[i]
+	}
+	return w.dest
+}
+
+// WriteScannedRow writes the most recently scanned row
+func (w *JSONLinesWriter) WriteScannedRow() error {
+	values := make([]interface{}, len(w.rowValues))
+	for i, v := range w.rowValues {
+		if v.Valid {
+			values[i] = v.String
+		}
+	}
+	return w.WriteRow(values)
+}
+
+// WriteRow writes values as a single JSON object
+// nil values become null, other values are formatted like CSV fields
+func (w *JSONLinesWriter) WriteRow(values []interface{}) error {
+	if len(values) != len(w.keys) {
+		return fmt.Errorf("failed to write row: %d values for %d columns", len(values), len(w.keys))
+	}
+
+	if err := w.w.WriteByte('{'); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
+	}
+	for i, v := range values {
+		if i > 0 {
+			_ = w.w.WriteByte(',')
+		}
+		_, _ = w.w.Write(w.keys[i])
+		_ = w.w.WriteByte(':')
+		if v == nil {
+			_, _ = w.w.WriteString("null")
+			continue
+		}
+		encoded, err := json.Marshal(formatValue(v))
+		if err != nil {
+			return fmt.Errorf("failed to encode value: %w", err)
+		}
+		_, _ = w.w.Write(encoded)
+	}
+	// bufio.Writer keeps the first write error, reported here
+	if _, err := w.w.WriteString("}\n"); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
+	}
+
+	w.rowCount++
+	return nil
+}
+
+// Flush flushes any buffered data, including pending compressed output
+func (w *JSONLinesWriter) Flush() error {
+	if err := w.w.Flush(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Flush()
+	}
+	return nil
+}
+
+// Close flushes and closes the file
+func (w *JSONLinesWriter) Close() error {
+	if w.w != nil {
+		if err := w.w.Flush(); err != nil {
+			return err
+		}
+		w.w = nil
+	}
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			return fmt.Errorf("failed to finish gzip stream: %w", err)
+		}
+		w.gz = nil
+	}
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	return nil
+}
+
+// RowCount returns the number of rows written
+func (w *JSONLinesWriter) RowCount() int {
+	return w.rowCount
+}
+
+// Remove discards the output: the file is deleted, an upload is aborted
+func (w *JSONLinesWriter) Remove() error {
+	w.w = nil
+	w.gz = nil
+	if w.file != nil {
+		if err := w.file.Discard(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	return nil
+}
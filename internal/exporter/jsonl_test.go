@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestJSONLinesWriter(t *testing.T) {
+	columns := []string{"ID", "NAME", "NOTE"}
+	scanner := db.NewMockRowScanner(columns, [][]string{
+		{"1", "Alice", "say \"hi\"\n"},
+	})
+
+	filePath := filepath.Join(t.TempDir(), "out.jsonl")
+	writer, err := NewJSONLinesWriter(filePath, len(columns), CSVWriterOptions{HeaderCase: "lower"})
+	testutil.AssertNoError(t, err)
+
+	testutil.AssertNoError(t, writer.WriteHeaders(columns))
+	rowCount, err := streamRows(scanner, writer, nil, streamLimits{}, logging.New(false))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 1, rowCount)
+	testutil.AssertNoError(t, writer.WriteRow([]interface{}{2, "", nil}))
+	// Scan targets are reset to NULL
+	writer.GetScanTargets()
+	testutil.AssertNoError(t, writer.WriteScannedRow())
+	testutil.AssertEqual(t, 3, writer.RowCount())
+	testutil.AssertNoError(t, writer.Close())
+
+	data, err := os.ReadFile(filePath)
+	testutil.AssertNoError(t, err)
+	want := `{"id":"1","name":"Alice","note":"say \"hi\"\n"}` + "\n" +
+		`{"id":"2","name":"","note":null}` + "\n" +
+		`{"id":null,"name":null,"note":null}` + "\n"
+	testutil.AssertEqual(t, want, string(data))
+	testutil.AssertEqual(t, false, IsEmpty(filePath))
+}
+
+func TestJSONLinesWriter_Remove(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "out.jsonl.gz")
+	writer, err := NewJSONLinesWriter(filePath, 1, CSVWriterOptions{Compress: CompressGzip})
+	testutil.AssertNoError(t, err)
+	testutil.AssertNoError(t, writer.WriteHeaders([]string{"ID"}))
+	testutil.AssertNoError(t, writer.Remove())
+	testutil.AssertNoError(t, writer.Close())
+
+	if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+		t.Errorf("file not removed: %v", err)
+	}
+}
+
+func TestIsEmpty_JSONLines(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		content string
+		want    bool
+	}{
+		{"", true},
+		{"{}\n", true},
+		{`{"ID":"1"}` + "\n", false},
+	}
+	for _, tt := range tests {
+		path := filepath.Join(dir, "data.jsonl")
+		mustWriteTestFile(t, path, tt.content)
+		if got := IsEmpty(path); got != tt.want {
+			t.Errorf("IsEmpty(%q) = %v, want %v", tt.content, got, tt.want)
+		}
+	}
+}
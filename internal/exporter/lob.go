@@ -0,0 +1,120 @@
+package exporter
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+)
+
+// lobKind classifies LOB columns by how their values are written
+type lobKind int
+
+const (
+	lobText   lobKind = iota + 1 // CLOB and NCLOB, written as UTF-8 text
+	lobBinary                    // BLOB and LONG RAW, written encoded
+)
+
+// lobColumnKind returns the kind of a LOB database type name, 0 for other types
+// Both Oracle names (CLOB, LONG RAW) and go-ora names (OCIClobLocator, LongRaw) are matched.
+func lobColumnKind(typeName string) lobKind {
+	switch strings.ToUpper(strings.ReplaceAll(typeName, "_", " ")) {
+	case "CLOB", "NCLOB", "OCICLOBLOCATOR":
+		return lobText
+	case "BLOB", "LONG RAW", "LONGRAW", "LONGVARRAW", "OCIBLOBLOCATOR":
+		return lobBinary
+	}
+	return 0
+}
+
+// lobColumns maps query column indexes to LOB kinds
+func lobColumns(typeNames []string) map[int]lobKind {
+	var kinds map[int]lobKind
+	for i, name := range typeNames {
+		if kind := lobColumnKind(name); kind != 0 {
+			if kinds == nil {
+				kinds = make(map[int]lobKind)
+			}
+			kinds[i] = kind
+		}
+	}
+	return kinds
+}
+
+// lobWriter scans LOB columns into []byte instead of sql.NullString
+// Binary values are hex or base64 encoded (or written as NULL with skip) and text values
+// are truncated to maxLength characters. It wraps the outermost writer and converts the
+// values into the wrapped scan targets before masks and the output writer see them.
+type lobWriter struct {
+	csvWriter
+	kinds     map[int]lobKind
+	encoding  string
+	maxLength int
+	inner     []interface{}
+	values    map[int]*[]byte
+}
+
+// newLOBWriter wraps w to scan and convert the columns in kinds
+func newLOBWriter(w csvWriter, kinds map[int]lobKind, encoding string, maxLength int) *lobWriter {
+	return &lobWriter{csvWriter: w, kinds: kinds, encoding: encoding, maxLength: maxLength}
+}
+
+// GetScanTargets returns the wrapped scan targets with []byte targets for LOB columns
+func (w *lobWriter) GetScanTargets() []interface{} {
+	w.inner = w.csvWriter.GetScanTargets()
+	targets := make([]interface{}, len(w.inner))
+	copy(targets, w.inner)
+	w.values = make(map[int]*[]byte, len(w.kinds))
+	for i := range w.kinds {
+		w.values[i] = new([]byte)
+		targets[i] = w.values[i]
+	}
+	return targets
+}
+
+// WriteScannedRow converts the LOB values and writes the row
+func (w *lobWriter) WriteScannedRow() error {
+	for i, kind := range w.kinds {
+		v, ok := w.inner[i].(*sql.NullString)
+		if !ok {
+			continue
+		}
+		*v = w.convert(kind, *w.values[i])
+	}
+	return w.csvWriter.WriteScannedRow()
+}
+
+// convert returns the output value of a scanned LOB, NULL for a nil value
+func (w *lobWriter) convert(kind lobKind, b []byte) sql.NullString {
+	if b == nil {
+		return sql.NullString{}
+	}
+	if kind == lobText {
+		return sql.NullString{String: truncateChars(string(b), w.maxLength), Valid: true}
+	}
+	switch w.encoding {
+	case config.BlobEncodingSkip:
+		return sql.NullString{}
+	case config.BlobEncodingBase64:
+		return sql.NullString{String: base64.StdEncoding.EncodeToString(b), Valid: true}
+	default:
+		return sql.NullString{String: hex.EncodeToString(b), Valid: true}
+	}
+}
+
+// truncateChars returns s cut to at most n characters, s itself when n is 0
+func truncateChars(s string, n int) string {
+	if n <= 0 || len(s) <= n {
+		return s
+	}
+	chars := 0
+	for i := range s {
+		if chars == n {
+			return s[:i]
+		}
+		chars++
+	}
+	return s
+}
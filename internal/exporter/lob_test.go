@@ -0,0 +1,74 @@
+package exporter
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestLOBColumns(t *testing.T) {
+	kinds := lobColumns([]string{"NUMBER", "OCIClobLocator", "OCIBlobLocator", "LongRaw", "CLOB", "BLOB", "LONG RAW", "RAW"})
+	want := map[int]lobKind{1: lobText, 2: lobBinary, 3: lobBinary, 4: lobText, 5: lobBinary, 6: lobBinary}
+	testutil.AssertEqual(t, len(want), len(kinds))
+	for i, kind := range want {
+		if kinds[i] != kind {
+			t.Errorf("column %d kind = %d, want %d", i, kinds[i], kind)
+		}
+	}
+
+	if lobColumns([]string{"NUMBER", "VARCHAR2"}) != nil {
+		t.Error("expected nil without LOB columns")
+	}
+}
+
+func TestLOBWriter(t *testing.T) {
+	columns := []string{"ID", "NOTES", "PHOTO"}
+	scanner := db.NewMockRowScanner(columns, [][]string{
+		{"1", "Привет, мир", "\x00\xff\x10"},
+		{"2", "short", ""},
+	})
+
+	filePath := filepath.Join(t.TempDir(), "out.csv")
+	inner, err := NewStreamingCSVWriter(filePath, len(columns))
+	testutil.AssertNoError(t, err)
+	writer := newLOBWriter(inner, map[int]lobKind{1: lobText, 2: lobBinary}, config.BlobEncodingHex, 6)
+
+	testutil.AssertNoError(t, writer.WriteHeaders(columns))
+	rowCount, err := streamRows(scanner, writer, nil, streamLimits{}, logging.New(false))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 2, rowCount)
+	testutil.AssertNoError(t, writer.Close())
+
+	data, err := os.ReadFile(filePath)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "ID,NOTES,PHOTO\n1,Привет,00ff10\n2,short,\n", string(data))
+}
+
+func TestLOBWriter_Convert(t *testing.T) {
+	blob := []byte{0x00, 0xff, 0x10}
+	tests := []struct {
+		encoding string
+		kind     lobKind
+		value    []byte
+		want     sql.NullString
+	}{
+		{config.BlobEncodingHex, lobBinary, blob, sql.NullString{String: "00ff10", Valid: true}},
+		{config.BlobEncodingBase64, lobBinary, blob, sql.NullString{String: "AP8Q", Valid: true}},
+		{config.BlobEncodingSkip, lobBinary, blob, sql.NullString{}},
+		{config.BlobEncodingHex, lobBinary, nil, sql.NullString{}},
+		{config.BlobEncodingHex, lobText, []byte("abcdef"), sql.NullString{String: "abcd", Valid: true}},
+		{config.BlobEncodingHex, lobText, nil, sql.NullString{}},
+	}
+	for _, tt := range tests {
+		w := newLOBWriter(nil, nil, tt.encoding, 4)
+		if got := w.convert(tt.kind, tt.value); got != tt.want {
+			t.Errorf("convert(%s, %q) = %+v, want %+v", tt.encoding, tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package exporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+const (
+	// ManifestFile is the manifest of the latest run, written to the export directory
+	ManifestFile = "manifest.json"
+	// DryRunManifestFile holds the row count estimates of a dry run, kept apart from ManifestFile
+	DryRunManifestFile = "manifest.dry-run.json"
+)
+
+// manifestName returns the name of a run's manifest copy in ManifestDir
+func manifestName(runTime time.Time) string {
+	return manifestPrefix + runTime.UTC().Format(manifestTimeFormat) + ".json"
+}
+
+// fileDigest returns the size and hex SHA-256 checksum of a file
+func fileDigest(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to open file for checksum: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to checksum file: %w", err)
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// SetVersion sets the tool version recorded in run manifests
+func (e *Exporter) SetVersion(version string) {
+	e.version = version
+}
+
+// writeManifest writes ManifestFile and its timestamped copy in ManifestDir
+// With a cloud store both are also uploaded next to the exports and the state file.
+// A dry run only writes DryRunManifestFile locally.
+func (e *Exporter) writeManifest(ctx context.Context, result *types.ExportResult) error {
+	if result.DryRun {
+		path := filepath.Join(e.cfg.ExportDir, DryRunManifestFile)
+		if err := types.WriteManifest(path, result); err != nil {
+			return err
+		}
+		e.logger.Info("Wrote dry run manifest: %s", path)
+		return nil
+	}
+
+	latest := filepath.Join(e.cfg.ExportDir, ManifestFile)
+	if err := types.WriteManifest(latest, result); err != nil {
+		return err
+	}
+	historyDir := filepath.Join(e.cfg.ExportDir, ManifestDir)
+	if err := os.MkdirAll(historyDir, e.cfg.DirModeOrDefault()); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+	history := filepath.Join(historyDir, manifestName(result.RunTime))
+	if err := types.WriteManifest(history, result); err != nil {
+		return err
+	}
+	e.logger.Info("Wrote manifest: %s", latest)
+
+	if e.store == nil {
+		return nil
+	}
+	uploads := []struct{ key, path string }{
+		{e.store.Key(ManifestFile), latest},
+		{e.store.Key(ManifestDir + "/" + filepath.Base(history)), history},
+	}
+	for _, u := range uploads {
+		if err := e.uploadFile(ctx, u.key, u.path); err != nil {
+			return err
+		}
+	}
+	e.logger.Info("Uploaded manifest to %s: %s", e.store.Name(), uploads[0].key)
+	return nil
+}
+
+// uploadFile uploads a local file to the cloud store
+func (e *Exporter) uploadFile(ctx context.Context, key, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	if err := e.store.UploadStream(ctx, key, file); err != nil {
+		return fmt.Errorf("failed to upload %s to %s: %w", filepath.Base(path), e.store.Name(), err)
+	}
+	return nil
+}
+
+// Estimate counts the rows each entity would export, without writing output or state
+// Entity queries are wrapped in SELECT COUNT(*) over the same window a run would use,
+// and the result, marked DryRun, is written to DryRunManifestFile.
+func (e *Exporter) Estimate(ctx context.Context) (*types.ExportResult, error) {
+	startTime := time.Now()
+	runTime, err := e.tillDate(startTime.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := e.selectEntities()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &types.ExportResult{
+		Results: make([]types.EntityResult, 0, len(entities)),
+		RunTime: runTime,
+		Version: e.version,
+		DryRun:  true,
+	}
+	for _, entity := range entities {
+		if err := ctx.Err(); err != nil {
+			return result, fmt.Errorf("estimate interrupted: %w", err)
+		}
+
+		entityResult := e.estimateEntity(ctx, entity, runTime)
+		result.Results = append(result.Results, entityResult)
+		result.ProcessedCount++
+		if entityResult.Success {
+			result.SuccessCount++
+		} else {
+			result.FailedCount++
+		}
+	}
+	result.TotalEntities = e.st.TotalCount()
+	result.SkippedCount = result.TotalEntities - result.ProcessedCount
+	result.Duration = time.Since(startTime)
+
+	if err := e.writeManifest(ctx, result); err != nil {
+		return result, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	return result, nil
+}
+
+// estimateEntity counts the rows of a single entity
+func (e *Exporter) estimateEntity(ctx context.Context, entity types.EntityState, runTime time.Time) types.EntityResult {
+	startTime := time.Now()
+	log := e.logger.WithEntity(entity.Entity, entity.DisplayName)
+	result := types.EntityResult{Entity: entity.Entity}
+
+	startDate, err := e.getStartDate(entity)
+	if err != nil {
+		result.Error = fmt.Errorf("failed to determine start date: %w", err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	startDateStr := startDate.Format("2006-01-02T15:04:05")
+
+	sqlContent, err := e.loadSQLFile(entity, e.sqlVariables(entity, startDateStr, runTime))
+	if err != nil {
+		result.Error = fmt.Errorf("failed to load SQL file: %w", err)
+		result.Duration = time.Since(startTime)
+		return result
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, e.cfg.QueryTimeout)
+	defer cancel()
+	params, _, err := e.queryParams(queryCtx, entity, sqlContent, startDateStr, runTime.Format("2006-01-02T15:04:05"))
+	if err != nil {
+		log.Error("Failed to determine query window: %v", err)
+		result.Error = err
+		result.Duration = time.Since(startTime)
+		return result
+	}
+	count, err := e.countRows(queryCtx, sqlContent, params)
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		log.Error("Failed to estimate rows: %v", err)
+		result.Error = err
+		return result
+	}
+
+	log.Info("Estimated %d rows since %s", count, startDateStr)
+	result.Success = true
+	result.RowCount = count
+	return result
+}
+
+// countRows runs SELECT COUNT(*) over the entity query
+func (e *Exporter) countRows(ctx context.Context, sqlContent string, params map[string]interface{}) (count int, retErr error) {
+	rows, err := e.db.QueryContext(ctx, countQuery(sqlContent), params)
+	if err != nil {
+		return 0, fmt.Errorf("count query failed: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("failed to close rows: %w", err)
+		}
+	}()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("count query failed: %w", err)
+		}
+		return 0, fmt.Errorf("count query returned no rows")
+	}
+	if err := rows.Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to read row count: %w", err)
+	}
+	return count, nil
+}
+
+// countQuery wraps a query in SELECT COUNT(*)
+// The closing parenthesis goes on its own line so a trailing line comment is not extended.
+func countQuery(sqlContent string) string {
+	inner := strings.TrimRight(strings.TrimSpace(sqlContent), ";")
+	return "SELECT COUNT(*) FROM (\n" + inner + "\n)"
+}
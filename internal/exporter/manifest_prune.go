@@ -0,0 +1,93 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ManifestDir is the export subdirectory (and S3 key prefix) holding run manifests
+	ManifestDir = "manifests"
+
+	// manifestPrefix and manifestTimeFormat name manifests manifest__2006-01-02T15-04-05.json
+	manifestPrefix     = "manifest__"
+	manifestTimeFormat = "2006-01-02T15-04-05"
+)
+
+// manifestFile is a manifest with the timestamp parsed from its name
+type manifestFile struct {
+	path string
+	time time.Time
+}
+
+// PruneManifests deletes manifests in dir outside the retention policy and returns their paths
+// keepN keeps the newest N manifests and olderThan keeps manifests newer than that age;
+// zero disables a policy and when both are set only manifests outside both are deleted.
+// Files not named like a manifest are never touched. With dryRun nothing is deleted.
+func PruneManifests(dir string, keepN int, olderThan time.Duration, dryRun bool) ([]string, error) {
+	if keepN < 0 || olderThan < 0 {
+		return nil, fmt.Errorf("retention values cannot be negative")
+	}
+	if keepN == 0 && olderThan == 0 {
+		return nil, nil
+	}
+
+	manifests, err := listManifests(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Newest first so the index is the number of newer manifests
+	sort.Slice(manifests, func(i, j int) bool {
+		return manifests[i].time.After(manifests[j].time)
+	})
+
+	cutoff := time.Now().UTC().Add(-olderThan)
+	var pruned []string
+	for i, m := range manifests {
+		if keepN > 0 && i < keepN {
+			continue
+		}
+		if olderThan > 0 && !m.time.Before(cutoff) {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(m.path); err != nil && !os.IsNotExist(err) {
+				return pruned, fmt.Errorf("failed to remove manifest %s: %w", m.path, err)
+			}
+		}
+		pruned = append(pruned, m.path)
+	}
+
+	return pruned, nil
+}
+
+// listManifests returns the manifests in dir; a missing directory has none
+func listManifests(dir string) ([]manifestFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read manifest directory: %w", err)
+	}
+
+	var manifests []manifestFile
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, manifestPrefix) || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, manifestPrefix), ".json")
+		t, err := time.ParseInLocation(manifestTimeFormat, stamp, time.UTC)
+		if err != nil {
+			continue
+		}
+		manifests = append(manifests, manifestFile{path: filepath.Join(dir, name), time: t})
+	}
+	return manifests, nil
+}
@@ -0,0 +1,98 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeManifests creates one manifest per day ending today and returns the directory
+func writeManifests(t *testing.T, days int) string {
+	t.Helper()
+	dir := filepath.Join(t.TempDir(), ManifestDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	now := time.Now().UTC()
+	for i := 0; i < days; i++ {
+		name := manifestPrefix + now.AddDate(0, 0, -i).Format(manifestTimeFormat) + ".json"
+		mustWriteTestFile(t, filepath.Join(dir, name), "{}")
+	}
+	mustWriteTestFile(t, filepath.Join(dir, "notes.txt"), "keep me")
+	return dir
+}
+
+// countManifests returns the number of manifests left in dir
+func countManifests(t *testing.T, dir string) int {
+	t.Helper()
+	manifests, err := listManifests(dir)
+	if err != nil {
+		t.Fatalf("listManifests() error = %v", err)
+	}
+	return len(manifests)
+}
+
+func TestPruneManifests(t *testing.T) {
+	t.Run("keep last n", func(t *testing.T) {
+		dir := writeManifests(t, 10)
+
+		pruned, err := PruneManifests(dir, 3, 0, false)
+		if err != nil {
+			t.Fatalf("PruneManifests() error = %v", err)
+		}
+		if len(pruned) != 7 {
+			t.Errorf("pruned %d manifests, want 7", len(pruned))
+		}
+		if got := countManifests(t, dir); got != 3 {
+			t.Errorf("%d manifests remain, want 3", got)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "notes.txt")); err != nil {
+			t.Errorf("non-manifest file removed: %v", err)
+		}
+	})
+
+	t.Run("older than", func(t *testing.T) {
+		dir := writeManifests(t, 10)
+
+		if _, err := PruneManifests(dir, 0, 84*time.Hour, false); err != nil {
+			t.Fatalf("PruneManifests() error = %v", err)
+		}
+		if got := countManifests(t, dir); got != 4 {
+			t.Errorf("%d manifests remain, want 4", got)
+		}
+	})
+
+	t.Run("both policies keep the union", func(t *testing.T) {
+		dir := writeManifests(t, 10)
+
+		if _, err := PruneManifests(dir, 6, 84*time.Hour, false); err != nil {
+			t.Fatalf("PruneManifests() error = %v", err)
+		}
+		if got := countManifests(t, dir); got != 6 {
+			t.Errorf("%d manifests remain, want 6", got)
+		}
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		dir := writeManifests(t, 5)
+
+		pruned, err := PruneManifests(dir, 3, 0, true)
+		if err != nil {
+			t.Fatalf("PruneManifests() error = %v", err)
+		}
+		if len(pruned) != 2 {
+			t.Errorf("pruned %d manifests, want 2", len(pruned))
+		}
+		if got := countManifests(t, dir); got != 5 {
+			t.Errorf("%d manifests remain, want 5", got)
+		}
+	})
+
+	t.Run("missing directory", func(t *testing.T) {
+		pruned, err := PruneManifests(filepath.Join(t.TempDir(), "missing"), 3, 0, false)
+		if err != nil || len(pruned) != 0 {
+			t.Errorf("PruneManifests() = %v, %v; want nothing pruned", pruned, err)
+		}
+	})
+}
@@ -0,0 +1,106 @@
+package exporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// readManifest decodes a manifest file
+func readManifest(t *testing.T, path string) types.ExportManifest {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	testutil.AssertNoError(t, err)
+	var manifest types.ExportManifest
+	testutil.AssertNoError(t, json.Unmarshal(data, &manifest))
+	return manifest
+}
+
+func TestExporter_Run_Manifest(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		exp := New(cfg, database, st, logging.New(false), nil)
+		exp.SetVersion("1.2.3")
+		result, err := exp.Run(context.Background())
+		testutil.AssertNoError(t, err)
+
+		manifest := readManifest(t, filepath.Join(cfg.ExportDir, ManifestFile))
+		testutil.AssertEqual(t, "1.2.3", manifest.Version)
+		testutil.AssertEqual(t, 1, len(manifest.Entities))
+
+		entity := manifest.Entities[0]
+		data, err := os.ReadFile(result.Results[0].FilePath)
+		testutil.AssertNoError(t, err)
+		sum := sha256.Sum256(data)
+		testutil.AssertEqual(t, hex.EncodeToString(sum[:]), entity.SHA256)
+		testutil.AssertEqual(t, int64(len(data)), entity.FileSize)
+		testutil.AssertEqual(t, 2, entity.RowCount)
+
+		// The timestamped copy is kept for prune-manifests
+		history := filepath.Join(cfg.ExportDir, ManifestDir, manifestName(result.RunTime))
+		testutil.AssertEqual(t, manifest.RunTimestamp, readManifest(t, history).RunTimestamp)
+	})
+}
+
+func TestExporter_Estimate(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL+";\n")
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Estimate(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+		testutil.AssertEqual(t, 2, result.Results[0].RowCount)
+
+		manifest := readManifest(t, filepath.Join(cfg.ExportDir, DryRunManifestFile))
+		testutil.AssertEqual(t, true, manifest.DryRun)
+		testutil.AssertEqual(t, 2, manifest.Entities[0].RowCount)
+
+		// Nothing is exported and the state is unchanged
+		if _, err := os.Stat(filepath.Join(cfg.ExportDir, ManifestFile)); !os.IsNotExist(err) {
+			t.Errorf("dry run wrote %s: %v", ManifestFile, err)
+		}
+		entity, _ := st.FindEntity("crm.products")
+		testutil.AssertEqual(t, "2025-01-01T00:00:00", entity.LastRunTime)
+	})
+}
+
+func TestCountQuery(t *testing.T) {
+	got := countQuery("SELECT id FROM t;\n")
+	testutil.AssertEqual(t, "SELECT COUNT(*) FROM (\nSELECT id FROM t\n)", got)
+
+	// A trailing line comment must not swallow the closing parenthesis
+	got = countQuery(AppendFetchRowsHint("SELECT id FROM t", 500))
+	testutil.AssertEqual(t, "SELECT COUNT(*) FROM (\nSELECT id FROM t\n-- ROWS=500\n)", got)
+}
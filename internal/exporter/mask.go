@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maskColumns maps query column indexes to the replacements of an entity's mask rules
+// Column names match case-insensitively. A rule naming a column the query does not
+// return is an error, so a typo cannot silently leak the column it meant to mask.
+func maskColumns(rules map[string]string, columns []string) (map[int]string, error) {
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	masks := make(map[int]string, len(rules))
+	var missing []string
+	for column, replacement := range rules {
+		found := false
+		for i, name := range columns {
+			if strings.EqualFold(name, column) {
+				masks[i] = replacement
+				found = true
+			}
+		}
+		if !found {
+			missing = append(missing, column)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("mask columns not in query result: %s", strings.Join(missing, ", "))
+	}
+	return masks, nil
+}
+
+// maskWriter replaces masked column values before writing a row
+// Like intervalWriter it wraps the outermost writer so masks are keyed by query column
+// index; dedup still sees the original values. NULL values stay NULL.
+type maskWriter struct {
+	csvWriter
+	masks   map[int]string
+	targets []interface{}
+}
+
+// newMaskWriter wraps w to replace the columns in masks
+func newMaskWriter(w csvWriter, masks map[int]string) *maskWriter {
+	return &maskWriter{csvWriter: w, masks: masks}
+}
+
+// GetScanTargets returns the wrapped scan targets and keeps them for masking
+func (w *maskWriter) GetScanTargets() []interface{} {
+	w.targets = w.csvWriter.GetScanTargets()
+	return w.targets
+}
+
+// WriteScannedRow masks column values and writes the row
+func (w *maskWriter) WriteScannedRow() error {
+	for i, replacement := range w.masks {
+		if v, ok := w.targets[i].(*sql.NullString); ok && v.Valid {
+			v.String = replacement
+		}
+	}
+	return w.csvWriter.WriteScannedRow()
+}
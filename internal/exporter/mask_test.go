@@ -0,0 +1,56 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestMaskColumns(t *testing.T) {
+	columns := []string{"ID", "EMAIL", "SSN"}
+
+	masks, err := maskColumns(map[string]string{"email": "***", "SSN": ""}, columns)
+	testutil.AssertNoError(t, err)
+	if len(masks) != 2 || masks[1] != "***" || masks[2] != "" {
+		t.Errorf("maskColumns() = %v, want columns 1 and 2", masks)
+	}
+
+	if masks, err := maskColumns(nil, columns); err != nil || masks != nil {
+		t.Errorf("maskColumns(nil) = %v, %v, want nil", masks, err)
+	}
+
+	_, err = maskColumns(map[string]string{"PHONE": "***", "EMAIL": "***"}, columns)
+	if err == nil || !strings.Contains(err.Error(), "PHONE") {
+		t.Errorf("maskColumns() with unknown column error = %v", err)
+	}
+}
+
+func TestMaskWriter(t *testing.T) {
+	columns := []string{"ID", "EMAIL", "NAME"}
+	scanner := db.NewMockRowScanner(columns, [][]string{
+		{"1", "alice@example.com", "Alice"},
+		{"2", "bob@example.com", "Bob"},
+	})
+
+	filePath := filepath.Join(t.TempDir(), "out.csv")
+	inner, err := NewStreamingCSVWriter(filePath, len(columns))
+	testutil.AssertNoError(t, err)
+	masks, err := maskColumns(map[string]string{"EMAIL": "REDACTED"}, columns)
+	testutil.AssertNoError(t, err)
+	writer := newMaskWriter(inner, masks)
+
+	testutil.AssertNoError(t, writer.WriteHeaders(columns))
+	rowCount, err := streamRows(scanner, writer, nil, streamLimits{}, logging.New(false))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 2, rowCount)
+	testutil.AssertNoError(t, writer.Close())
+
+	data, err := os.ReadFile(filePath)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "ID,EMAIL,NAME\n1,REDACTED,Alice\n2,REDACTED,Bob\n", string(data))
+}
@@ -0,0 +1,101 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// metaExtension replaces the export file extension in the name of its sidecar, see MetaPath
+const metaExtension = ".meta.json"
+
+// EntityMeta describes an export file in its sidecar JSON, written with --write-meta
+type EntityMeta struct {
+	Entity          string       `json:"entity"`
+	StartDate       string       `json:"start_date"`
+	TillDate        string       `json:"till_date"`
+	RowCount        int          `json:"row_count"`
+	FileSize        int64        `json:"file_size_bytes"`
+	SHA256          string       `json:"sha256"`
+	DurationSeconds float64      `json:"duration_seconds"`
+	Columns         []MetaColumn `json:"columns"`
+}
+
+// MetaColumn is an output column, Type is the database type name (empty for masked columns)
+type MetaColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+}
+
+// MetaPath returns the sidecar path of an export file, e.g. crm.orders__2025-01-01T00-00-00.meta.json
+// for crm.orders__2025-01-01T00-00-00.csv.gz. It works for object keys as well as local paths.
+func MetaPath(exportPath string) string {
+	base := strings.TrimSuffix(exportPath, ".gz")
+	return strings.TrimSuffix(base, path.Ext(base)) + metaExtension
+}
+
+// WriteEntityMeta writes the sidecar JSON of a successfully exported entity to path
+func WriteEntityMeta(path string, result *types.EntityResult, startDate, tillDate string, columns []MetaColumn, mode os.FileMode) error {
+	meta := EntityMeta{
+		Entity:          result.Entity,
+		StartDate:       startDate,
+		TillDate:        tillDate,
+		RowCount:        result.RowCount,
+		FileSize:        result.FileSize,
+		SHA256:          result.Checksum,
+		DurationSeconds: result.Duration.Seconds(),
+		Columns:         columns,
+	}
+	data, err := json.MarshalIndent(meta, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode export metadata: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), mode); err != nil {
+		return fmt.Errorf("failed to write export metadata: %w", err)
+	}
+	return nil
+}
+
+// metaColumns returns the written columns with their database types
+// perm and masks select and replace columns like outputColumnTypes; types may be nil.
+func metaColumns(names []string, types []ColumnType, perm []int, masks map[int]string, headerCase string) []MetaColumn {
+	columns := make([]MetaColumn, len(names))
+	for i, name := range names {
+		columns[i].Name = ConvertCase(name, headerCase)
+		if len(types) == len(names) {
+			if _, masked := masks[i]; !masked {
+				columns[i].Type = types[i].DatabaseType
+			}
+		}
+	}
+	if perm == nil {
+		return columns
+	}
+	ordered := make([]MetaColumn, len(perm))
+	for i, j := range perm {
+		ordered[i] = columns[j]
+	}
+	return ordered
+}
+
+// writeMeta writes the sidecar of an exported entity next to its file
+// Cloud exports keep the sidecar in the export directory and upload it next to the object.
+func (e *Exporter) writeMeta(ctx context.Context, result *types.EntityResult, startDate, tillDate string, columns []MetaColumn) (string, error) {
+	metaPath := MetaPath(result.FilePath)
+	if err := WriteEntityMeta(metaPath, result, startDate, tillDate, columns, e.cfg.FileModeOrDefault()); err != nil {
+		return "", err
+	}
+	if e.store == nil || result.ObjectKey == "" {
+		return metaPath, nil
+	}
+	key := MetaPath(result.ObjectKey)
+	if err := e.uploadFile(ctx, key, metaPath); err != nil {
+		return "", err
+	}
+	return key, nil
+}
@@ -0,0 +1,117 @@
+package exporter
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func TestMetaPath(t *testing.T) {
+	tests := map[string]string{
+		"export/crm.orders__2025-01-01T00-00-00.csv":        "export/crm.orders__2025-01-01T00-00-00.meta.json",
+		"export/crm.orders__2025-01-01T00-00-00.jsonl.gz":   "export/crm.orders__2025-01-01T00-00-00.meta.json",
+		"exports/crm.orders/crm.orders__2025-01-01.parquet": "exports/crm.orders/crm.orders__2025-01-01.meta.json",
+	}
+	for exportPath, want := range tests {
+		testutil.AssertEqual(t, want, MetaPath(exportPath))
+	}
+
+	// Sidecars are not export files, purge leaves them in place
+	if _, ok := ExportFileTime("crm.orders__2025-01-01T00-00-00.meta.json", ""); ok {
+		t.Error("ExportFileTime() recognized a sidecar as an export file")
+	}
+}
+
+func TestWriteEntityMeta(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crm.orders__2025-01-01T00-00-00.meta.json")
+	result := &types.EntityResult{
+		Entity:   "crm.orders",
+		RowCount: 2,
+		FileSize: 42,
+		Checksum: "abc123",
+		Duration: 1500 * time.Millisecond,
+	}
+	columns := []MetaColumn{{Name: "ID", Type: "NUMBER"}, {Name: "EMAIL"}}
+	testutil.AssertNoError(t, WriteEntityMeta(path, result, "2025-01-01T00:00:00", "2025-01-02T00:00:00", columns, 0644))
+
+	data, err := os.ReadFile(path)
+	testutil.AssertNoError(t, err)
+	var meta EntityMeta
+	testutil.AssertNoError(t, json.Unmarshal(data, &meta))
+	want := EntityMeta{
+		Entity:          "crm.orders",
+		StartDate:       "2025-01-01T00:00:00",
+		TillDate:        "2025-01-02T00:00:00",
+		RowCount:        2,
+		FileSize:        42,
+		SHA256:          "abc123",
+		DurationSeconds: 1.5,
+		Columns:         columns,
+	}
+	if !reflect.DeepEqual(meta, want) {
+		t.Errorf("meta = %+v, want %+v", meta, want)
+	}
+}
+
+func TestMetaColumns(t *testing.T) {
+	names := []string{"ID", "EMAIL", "CREATED_AT"}
+	colTypes := []ColumnType{{DatabaseType: "NUMBER"}, {DatabaseType: "VARCHAR2"}, {DatabaseType: "DATE"}}
+	masks := map[int]string{1: "***"}
+
+	got := metaColumns(names, colTypes, []int{2, 0, 1}, masks, CaseLower)
+	want := []MetaColumn{{Name: "created_at", Type: "DATE"}, {Name: "id", Type: "NUMBER"}, {Name: "email"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("metaColumns() = %+v, want %+v", got, want)
+	}
+
+	// Without column types only the names are listed
+	got = metaColumns(names[:1], nil, nil, nil, "")
+	if !reflect.DeepEqual(got, []MetaColumn{{Name: "ID"}}) {
+		t.Errorf("metaColumns() = %+v", got)
+	}
+}
+
+func TestExporter_Run_WriteMeta(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		cfg.WriteMeta = true
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.products.sql"), integrationSQL)
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+		defer func() { _ = st.Close() }()
+
+		result, err := New(cfg, database, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+
+		exported := result.Results[0]
+		data, err := os.ReadFile(MetaPath(exported.FilePath))
+		testutil.AssertNoError(t, err)
+		var meta EntityMeta
+		testutil.AssertNoError(t, json.Unmarshal(data, &meta))
+		testutil.AssertEqual(t, 2, meta.RowCount)
+		testutil.AssertEqual(t, exported.Checksum, meta.SHA256)
+		testutil.AssertEqual(t, exported.FileSize, meta.FileSize)
+		testutil.AssertEqual(t, "2025-01-01T00:00:00", meta.StartDate)
+		testutil.AssertEqual(t, 4, len(meta.Columns))
+		testutil.AssertEqual(t, "id", meta.Columns[0].Name)
+	})
+}
@@ -0,0 +1,84 @@
+package exporter
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// MultiWriter fans every call out to a primary and a secondary writer, e.g. a cloud
+// upload and a local copy. Rows are scanned into the primary writer's targets and
+// copied to the secondary. Each writer is called even if the other one fails, so a
+// failure mid-stream still flushes, removes and closes both outputs; errors name the
+// output they belong to.
+type MultiWriter struct {
+	primary, secondary         csvWriter
+	primaryName, secondaryName string
+	targets                    []interface{}
+}
+
+// NewMultiWriter returns a writer writing to both primary and secondary
+// The names, e.g. the object key and the local path, identify the outputs in errors.
+func NewMultiWriter(primary csvWriter, primaryName string, secondary csvWriter, secondaryName string) *MultiWriter {
+	return &MultiWriter{
+		primary:       primary,
+		secondary:     secondary,
+		primaryName:   primaryName,
+		secondaryName: secondaryName,
+	}
+}
+
+// WriteHeaders writes the header row to both writers
+func (w *MultiWriter) WriteHeaders(columns []string) error {
+	return w.both(func(cw csvWriter) error { return cw.WriteHeaders(columns) })
+}
+
+// GetScanTargets returns the primary writer's scan targets
+func (w *MultiWriter) GetScanTargets() []interface{} {
+	w.targets = w.primary.GetScanTargets()
+	return w.targets
+}
+
+// WriteScannedRow copies the scanned row to the secondary writer and writes it to both
+func (w *MultiWriter) WriteScannedRow() error {
+	secondary := w.secondary.GetScanTargets()
+	if len(secondary) != len(w.targets) {
+		return fmt.Errorf("%s: got %d scan targets, want %d", w.secondaryName, len(secondary), len(w.targets))
+	}
+	for i, target := range w.targets {
+		src, ok := target.(*sql.NullString)
+		dst, ok2 := secondary[i].(*sql.NullString)
+		if !ok || !ok2 {
+			return fmt.Errorf("%s: unsupported scan target %T for column %d", w.secondaryName, secondary[i], i+1)
+		}
+		*dst = *src
+	}
+	return w.both(csvWriter.WriteScannedRow)
+}
+
+// Flush flushes both writers
+func (w *MultiWriter) Flush() error {
+	return w.both(csvWriter.Flush)
+}
+
+// Remove removes both outputs
+func (w *MultiWriter) Remove() error {
+	return w.both(csvWriter.Remove)
+}
+
+// Close closes both writers
+func (w *MultiWriter) Close() error {
+	return w.both(csvWriter.Close)
+}
+
+// both calls fn on the primary and then the secondary writer and joins their errors
+func (w *MultiWriter) both(fn func(csvWriter) error) error {
+	var errs []error
+	if err := fn(w.primary); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", w.primaryName, err))
+	}
+	if err := fn(w.secondary); err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", w.secondaryName, err))
+	}
+	return errors.Join(errs...)
+}
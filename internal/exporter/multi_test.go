@@ -0,0 +1,89 @@
+package exporter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+// failingWriter fails WriteScannedRow and records the calls made after the failure
+type failingWriter struct {
+	csvWriter
+	removed, closed bool
+}
+
+func (w *failingWriter) WriteScannedRow() error { return errors.New("connection reset") }
+
+func (w *failingWriter) Remove() error {
+	w.removed = true
+	return w.csvWriter.Remove()
+}
+
+func (w *failingWriter) Close() error {
+	w.closed = true
+	return w.csvWriter.Close()
+}
+
+func TestMultiWriter(t *testing.T) {
+	columns := []string{"ID", "NAME"}
+	scanner := db.NewMockRowScanner(columns, [][]string{{"1", "Alice"}, {"2", "Bob"}})
+
+	dir := t.TempDir()
+	primaryPath, secondaryPath := filepath.Join(dir, "primary.csv"), filepath.Join(dir, "copy.csv")
+	primary, err := NewStreamingCSVWriter(primaryPath, len(columns))
+	testutil.AssertNoError(t, err)
+	secondary, err := NewStreamingCSVWriter(secondaryPath, len(columns))
+	testutil.AssertNoError(t, err)
+	writer := NewMultiWriter(primary, primaryPath, secondary, secondaryPath)
+
+	testutil.AssertNoError(t, writer.WriteHeaders(columns))
+	rowCount, err := streamRows(scanner, writer, nil, streamLimits{}, logging.New(false))
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 2, rowCount)
+	testutil.AssertNoError(t, writer.Flush())
+	testutil.AssertNoError(t, writer.Close())
+
+	for _, path := range []string{primaryPath, secondaryPath} {
+		data, err := os.ReadFile(path)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, "ID,NAME\n1,Alice\n2,Bob\n", string(data))
+	}
+}
+
+func TestMultiWriter_Failure(t *testing.T) {
+	columns := []string{"ID"}
+	scanner := db.NewMockRowScanner(columns, [][]string{{"1"}})
+
+	dir := t.TempDir()
+	primaryPath, secondaryPath := filepath.Join(dir, "primary.csv"), filepath.Join(dir, "copy.csv")
+	inner, err := NewStreamingCSVWriter(primaryPath, len(columns))
+	testutil.AssertNoError(t, err)
+	primary := &failingWriter{csvWriter: inner}
+	secondary, err := NewStreamingCSVWriter(secondaryPath, len(columns))
+	testutil.AssertNoError(t, err)
+	writer := NewMultiWriter(primary, "s3://exports/crm.orders.csv", secondary, secondaryPath)
+
+	testutil.AssertNoError(t, writer.WriteHeaders(columns))
+	_, err = streamRows(scanner, writer, nil, streamLimits{}, logging.New(false))
+	if err == nil || !strings.Contains(err.Error(), "s3://exports/crm.orders.csv") {
+		t.Fatalf("streamRows() error = %v, want the failed output named", err)
+	}
+
+	// executeQueryToCSV removes and closes the outputs of a failed export
+	testutil.AssertNoError(t, writer.Remove())
+	testutil.AssertNoError(t, writer.Close())
+	if !primary.removed || !primary.closed {
+		t.Error("expected the failed writer to be removed and closed")
+	}
+	for _, path := range []string{primaryPath, secondaryPath} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after Remove(), stat error = %v", path, err)
+		}
+	}
+}
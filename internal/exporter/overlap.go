@@ -0,0 +1,73 @@
+package exporter
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// exportFileTimeLayout is the start date layout embedded in export file names
+const exportFileTimeLayout = "2006-01-02T15-04-05"
+
+// DetectOverlappingExport looks for an export file of entity whose start date falls in [startDate, tillDate)
+// File names only embed the start of their window, so a file starting inside the current
+// window means part of it was already exported. It returns the first such file path.
+// Dates use the 2006-01-02T15:04:05 layout; files with unparseable names are ignored.
+func DetectOverlappingExport(exportDir, entity, startDate, tillDate string) (bool, string, error) {
+	start, err := time.Parse("2006-01-02T15:04:05", startDate)
+	if err != nil {
+		return false, "", fmt.Errorf("invalid start date: %w", err)
+	}
+	till, err := time.Parse("2006-01-02T15:04:05", tillDate)
+	if err != nil {
+		return false, "", fmt.Errorf("invalid till date: %w", err)
+	}
+
+	prefix := entity + "__"
+	matches, err := filepath.Glob(filepath.Join(exportDir, prefix+"*"))
+	if err != nil {
+		return false, "", fmt.Errorf("failed to list export files: %w", err)
+	}
+	sort.Strings(matches)
+
+	for _, path := range matches {
+		// The file extension (.csv, .tsv, .jsonl, optionally .gz) starts at the first dot
+		stamp := strings.TrimPrefix(trimPartNumber(filepath.Base(path)), prefix)
+		if i := strings.IndexByte(stamp, '.'); i >= 0 {
+			stamp = stamp[:i]
+		}
+		fileStart, err := time.Parse(exportFileTimeLayout, stamp)
+		if err != nil {
+			continue
+		}
+		if !fileStart.Before(start) && fileStart.Before(till) {
+			return true, path, nil
+		}
+	}
+	return false, "", nil
+}
+
+// skipOverlapping reports whether an entity is skipped because its window was already exported
+// Overlaps are logged as warnings and only skip the entity with SkipIfOverlapping.
+func (e *Exporter) skipOverlapping(entity string, startDate time.Time, tillDate string) bool {
+	dir := e.cfg.ExportDir
+	if e.cfg.OutputPerEntityDir {
+		dir = filepath.Join(dir, entity)
+	}
+	overlapping, path, err := DetectOverlappingExport(dir, entity, startDate.Format("2006-01-02T15:04:05"), tillDate)
+	if err != nil {
+		e.logger.Error("Failed to check overlapping exports for %s: %v", entity, err)
+		return false
+	}
+	if !overlapping {
+		return false
+	}
+	if e.cfg.SkipIfOverlapping {
+		e.logger.Info("Skipping %s: export window overlaps existing file %s", entity, path)
+		return true
+	}
+	e.logger.Info("Warning: export window of %s overlaps existing file %s", entity, path)
+	return false
+}
@@ -0,0 +1,45 @@
+package exporter
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestDetectOverlappingExport(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "crm.products__2025-01-02T00-00-00.csv")
+	mustWriteTestFile(t, existing, "ID\n1\n")
+	mustWriteTestFile(t, filepath.Join(dir, "crm.products__latest.csv"), "ID\n")
+	mustWriteTestFile(t, filepath.Join(dir, "crm.orders__2025-01-02T00-00-00.csv"), "ID\n")
+
+	tests := []struct {
+		name     string
+		start    string
+		till     string
+		want     bool
+		wantPath string
+	}{
+		{"file inside window", "2025-01-01T00:00:00", "2025-01-03T00:00:00", true, existing},
+		{"file at window start", "2025-01-02T00:00:00", "2025-01-03T00:00:00", true, existing},
+		{"file at window end", "2025-01-01T00:00:00", "2025-01-02T00:00:00", false, ""},
+		{"file before window", "2025-01-02T00:00:01", "2025-01-03T00:00:00", false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, path, err := DetectOverlappingExport(dir, "crm.products", tt.start, tt.till)
+			if err != nil {
+				t.Fatalf("DetectOverlappingExport() error = %v", err)
+			}
+			if got != tt.want || path != tt.wantPath {
+				t.Errorf("DetectOverlappingExport() = %v, %q, want %v, %q", got, path, tt.want, tt.wantPath)
+			}
+		})
+	}
+
+	t.Run("invalid date", func(t *testing.T) {
+		if _, _, err := DetectOverlappingExport(dir, "crm.products", "yesterday", "2025-01-03T00:00:00"); err == nil {
+			t.Error("expected error for invalid start date")
+		}
+	})
+}
@@ -0,0 +1,343 @@
+package exporter
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// FormatParquet writes rows as a Parquet file with typed columns
+const FormatParquet = "parquet"
+
+// DefaultParquetRowGroupBytes is the uncompressed data size of a row group
+const DefaultParquetRowGroupBytes = 128 * 1024 * 1024
+
+// ColumnType describes a query column for typed output formats
+type ColumnType struct {
+	// DatabaseType is the driver type name, e.g. NUMBER, DATE or TimeStampDTY
+	DatabaseType string
+	// Precision and Scale are set for NUMBER columns, zero when unknown
+	Precision int64
+	Scale     int64
+}
+
+// columnTypesOf converts driver column types
+func columnTypesOf(columnTypes []*sql.ColumnType) []ColumnType {
+	types := make([]ColumnType, len(columnTypes))
+	for i, ct := range columnTypes {
+		types[i].DatabaseType = ct.DatabaseTypeName()
+		if precision, scale, ok := ct.DecimalSize(); ok {
+			types[i].Precision, types[i].Scale = precision, scale
+		}
+	}
+	return types
+}
+
+// outputColumnTypes returns the types of the written columns
+// Masked columns hold replacement text and are typed as strings, perm (if non-nil)
+// selects and orders the query columns like EnforceColumnOrder.
+func outputColumnTypes(types []ColumnType, perm []int, masks map[int]string) []ColumnType {
+	for i := range masks {
+		types[i] = ColumnType{}
+	}
+	if perm == nil {
+		return types
+	}
+	ordered := make([]ColumnType, len(perm))
+	for i, j := range perm {
+		ordered[i] = types[j]
+	}
+	return ordered
+}
+
+// parquetKind is the Parquet column type an Oracle column is written as
+type parquetKind int
+
+const (
+	parquetString parquetKind = iota
+	parquetInt64
+	parquetDouble
+	parquetTimestamp
+)
+
+// parquetKindOf maps a column type to a Parquet type
+// NUMBER columns with scale 0 and at most 18 digits are INT64, other numbers DOUBLE.
+// DATE and TIMESTAMP columns are timestamp_millis, everything else is a UTF-8 string.
+func parquetKindOf(ct ColumnType) parquetKind {
+	name := strings.ToUpper(ct.DatabaseType)
+	switch {
+	case name == "NUMBER":
+		if ct.Scale == 0 && ct.Precision > 0 && ct.Precision <= 18 {
+			return parquetInt64
+		}
+		return parquetDouble
+	case name == "FLOAT", strings.Contains(name, "BFLOAT"), strings.Contains(name, "BDOUBLE"),
+		strings.HasPrefix(name, "BINARY_"):
+		return parquetDouble
+	case name == "DATE", strings.HasPrefix(name, "TIMESTAMP"):
+		return parquetTimestamp
+	}
+	return parquetString
+}
+
+// parquetNode returns the optional schema node for a kind
+func parquetNode(kind parquetKind) parquet.Node {
+	switch kind {
+	case parquetInt64:
+		return parquet.Optional(parquet.Int(64))
+	case parquetDouble:
+		return parquet.Optional(parquet.Leaf(parquet.DoubleType))
+	case parquetTimestamp:
+		return parquet.Optional(parquet.Timestamp(parquet.Millisecond))
+	}
+	return parquet.Optional(parquet.String())
+}
+
+// orderedGroup is a schema group whose fields keep the query column order
+// parquet.Group on its own sorts fields by name.
+type orderedGroup struct {
+	parquet.Group
+	names []string
+}
+
+// Fields returns the group fields in column order
+func (g orderedGroup) Fields() []parquet.Field {
+	byName := make(map[string]parquet.Field, len(g.names))
+	for _, f := range g.Group.Fields() {
+		byName[f.Name()] = f
+	}
+	fields := make([]parquet.Field, len(g.names))
+	for i, name := range g.names {
+		fields[i] = byName[name]
+	}
+	return fields
+}
+
+// ParquetWriter writes rows to a Parquet file, typed by the query column types
+// Every column is optional so NULLs stay distinct from empty strings. Rows are
+// buffered in memory and written as a row group once RowGroupBytes of data are
+// pending, and the footer is written on Close.
+type ParquetWriter struct {
+	file          outputSink
+	pw            *parquet.Writer
+	kinds         []parquetKind
+	headerCase    string
+	rowGroupBytes int64
+	pending       int64 // Uncompressed bytes in the current row group
+	row           parquet.Row
+	dest          []interface{}
+	rowValues     []sql.NullString
+	rowCount      int
+}
+
+// NewParquetWriter creates a Parquet writer for columnCount columns
+// FileMode, HeaderCase, ColumnTypes and RowGroupBytes are taken from opts,
+// CSV-only options are ignored.
+func NewParquetWriter(filePath string, columnCount int, opts CSVWriterOptions) (*ParquetWriter, error) {
+	file, err := createFileSink(filePath, opts.FileMode)
+	if err != nil {
+		return nil, err
+	}
+	return newParquetWriter(file, columnCount, opts), nil
+}
+
+// newParquetWriter creates a ParquetWriter writing to file, FileMode is not used
+// Columns without a type in opts.ColumnTypes are written as strings.
+func newParquetWriter(file outputSink, columnCount int, opts CSVWriterOptions) *ParquetWriter {
+	kinds := make([]parquetKind, columnCount)
+	if len(opts.ColumnTypes) == columnCount {
+		for i, ct := range opts.ColumnTypes {
+			kinds[i] = parquetKindOf(ct)
+		}
+	}
+	rowGroupBytes := opts.RowGroupBytes
+	if rowGroupBytes <= 0 {
+		rowGroupBytes = DefaultParquetRowGroupBytes
+	}
+
+	return &ParquetWriter{
+		file:          file,
+		kinds:         kinds,
+		headerCase:    opts.HeaderCase,
+		rowGroupBytes: rowGroupBytes,
+		row:           make(parquet.Row, columnCount),
+		dest:          make([]interface{}, columnCount),
+		rowValues:     make([]sql.NullString, columnCount),
+	}
+}
+
+// WriteHeaders builds the file schema from the column names, nothing is written yet
+func (w *ParquetWriter) WriteHeaders(columns []string) error {
+	if len(columns) != len(w.kinds) {
+		return fmt.Errorf("failed to write headers: %d columns for %d types", len(columns), len(w.kinds))
+	}
+
+	group := make(parquet.Group, len(columns))
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		if w.headerCase != "" {
+			col = ConvertCase(col, w.headerCase)
+		}
+		if _, ok := group[col]; ok {
+			return fmt.Errorf("duplicate column name %q", col)
+		}
+		group[col] = parquetNode(w.kinds[i])
+		names[i] = col
+	}
+
+	schema := parquet.NewSchema("ora2csv", orderedGroup{Group: group, names: names})
+	w.pw = parquet.NewWriter(w.file, schema, parquet.Compression(&parquet.Snappy))
+	return nil
+}
+
+// GetScanTargets returns a slice of interface{} pointers for sql.Rows.Scan
+func (w *ParquetWriter) GetScanTargets() []interface{} {
+	for i := range w.dest {
+		w.rowValues[i] = sql.NullString{}
+		w.dest[i] = &w.rowValues[i]
+	}
+	return w.dest
+}
+
+// WriteScannedRow writes the most recently scanned row
+func (w *ParquetWriter) WriteScannedRow() error {
+	values := make([]interface{}, len(w.rowValues))
+	for i, v := range w.rowValues {
+		if v.Valid {
+			values[i] = v.String
+		}
+	}
+	return w.WriteRow(values)
+}
+
+// WriteRow converts values to the column types and buffers the row
+// nil values become nulls, strings are parsed for numeric and timestamp columns.
+func (w *ParquetWriter) WriteRow(values []interface{}) error {
+	if w.pw == nil {
+		return fmt.Errorf("failed to write row: headers not written")
+	}
+	if len(values) != len(w.kinds) {
+		return fmt.Errorf("failed to write row: %d values for %d columns", len(values), len(w.kinds))
+	}
+
+	for i, v := range values {
+		value, size, err := parquetValue(w.kinds[i], v)
+		if err != nil {
+			return fmt.Errorf("failed to convert column %d: %w", i+1, err)
+		}
+		if v == nil {
+			w.row[i] = value.Level(0, 0, i)
+		} else {
+			w.row[i] = value.Level(0, 1, i)
+		}
+		w.pending += size
+	}
+	if _, err := w.pw.WriteRows([]parquet.Row{w.row}); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
+	}
+	w.rowCount++
+
+	if w.pending >= w.rowGroupBytes {
+		if err := w.pw.Flush(); err != nil {
+			return fmt.Errorf("failed to write row group: %w", err)
+		}
+		w.pending = 0
+	}
+	return nil
+}
+
+// parquetValue converts v to a Parquet value of kind and returns its approximate size
+func parquetValue(kind parquetKind, v interface{}) (parquet.Value, int64, error) {
+	if v == nil {
+		return parquet.NullValue(), 0, nil
+	}
+	switch kind {
+	case parquetInt64:
+		n, err := parseInt64(v)
+		return parquet.Int64Value(n), 8, err
+	case parquetDouble:
+		f, err := strconv.ParseFloat(formatValue(v), 64)
+		return parquet.DoubleValue(f), 8, err
+	case parquetTimestamp:
+		t, err := parseTimestamp(v)
+		return parquet.Int64Value(t.UnixMilli()), 8, err
+	}
+	s := formatValue(v)
+	return parquet.ByteArrayValue([]byte(s)), int64(len(s)), nil
+}
+
+// parseInt64 parses an integer, accepting integral floats such as "1e+06"
+func parseInt64(v interface{}) (int64, error) {
+	s := formatValue(v)
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f != math.Trunc(f) || math.Abs(f) > math.MaxInt64 {
+		return 0, fmt.Errorf("invalid integer %q", s)
+	}
+	return int64(f), nil
+}
+
+// timestampLayouts are tried in order for timestamp strings, RFC 3339 is what
+// database/sql produces when scanning a time.Time into a string
+var timestampLayouts = []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999", "2006-01-02 15:04:05.999999999", "2006-01-02"}
+
+// parseTimestamp parses a time.Time or timestamp string
+func parseTimestamp(v interface{}) (time.Time, error) {
+	if t, ok := v.(time.Time); ok {
+		return t, nil
+	}
+	s := formatValue(v)
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// Flush is a no-op, rows are written in whole row groups
+// Size checks therefore only see completed row groups.
+func (w *ParquetWriter) Flush() error {
+	return nil
+}
+
+// Close writes the pending row group and the footer, then closes the file
+func (w *ParquetWriter) Close() error {
+	if w.pw != nil {
+		if err := w.pw.Close(); err != nil {
+			return fmt.Errorf("failed to finish parquet file: %w", err)
+		}
+		w.pw = nil
+	}
+	if w.file != nil {
+		if err := w.file.Close(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	return nil
+}
+
+// RowCount returns the number of rows written
+func (w *ParquetWriter) RowCount() int {
+	return w.rowCount
+}
+
+// Remove discards the output: the file is deleted, an upload is aborted
+func (w *ParquetWriter) Remove() error {
+	w.pw = nil
+	if w.file != nil {
+		if err := w.file.Discard(); err != nil {
+			return err
+		}
+		w.file = nil
+	}
+	return nil
+}
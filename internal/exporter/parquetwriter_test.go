@@ -0,0 +1,196 @@
+package exporter
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// readParquet returns the column names and rows of a Parquet file
+func readParquet(t *testing.T, path string) ([]string, []parquet.Row) {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		t.Fatalf("OpenFile() error = %v", err)
+	}
+
+	var names []string
+	for _, field := range file.Schema().Fields() {
+		names = append(names, field.Name())
+	}
+	reader := parquet.NewReader(file)
+	defer func() { _ = reader.Close() }()
+	var rows []parquet.Row
+	buf := make([]parquet.Row, 10)
+	for {
+		n, err := reader.ReadRows(buf)
+		for _, row := range buf[:n] {
+			rows = append(rows, row.Clone())
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("ReadRows() error = %v", err)
+		}
+	}
+	return names, rows
+}
+
+func TestParquetWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "users.parquet")
+	opts := CSVWriterOptions{
+		HeaderCase: "lower",
+		ColumnTypes: []ColumnType{
+			{DatabaseType: "VARCHAR"},
+			{DatabaseType: "NUMBER", Precision: 10},
+			{DatabaseType: "NUMBER", Precision: 10, Scale: 2},
+			{DatabaseType: "DATE"},
+		},
+	}
+	w, err := NewParquetWriter(path, 4, opts)
+	if err != nil {
+		t.Fatalf("NewParquetWriter() error = %v", err)
+	}
+	if err := w.WriteHeaders([]string{"NAME", "ID", "BALANCE", "CREATED"}); err != nil {
+		t.Fatalf("WriteHeaders() error = %v", err)
+	}
+	if err := w.WriteRow([]interface{}{"alice", "1", "10.5", "2025-01-02T03:04:05Z"}); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := w.WriteRow([]interface{}{"", "2e+00", nil, nil}); err != nil {
+		t.Fatalf("WriteRow() error = %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if w.RowCount() != 2 {
+		t.Errorf("RowCount() = %d, want 2", w.RowCount())
+	}
+
+	names, rows := readParquet(t, path)
+	if want := []string{"name", "id", "balance", "created"}; !slices.Equal(names, want) {
+		t.Errorf("columns = %v, want %v", names, want)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows = %d, want 2", len(rows))
+	}
+
+	first := rows[0]
+	if first[0].String() != "alice" || first[1].Int64() != 1 || first[2].Double() != 10.5 {
+		t.Errorf("first row = %v", first)
+	}
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC).UnixMilli()
+	if first[3].Int64() != created {
+		t.Errorf("created = %d, want %d", first[3].Int64(), created)
+	}
+
+	second := rows[1]
+	if second[0].IsNull() || second[0].String() != "" {
+		t.Errorf("empty string should not be null: %v", second[0])
+	}
+	if second[1].Int64() != 2 {
+		t.Errorf("id = %v, want 2", second[1])
+	}
+	if !second[2].IsNull() || !second[3].IsNull() {
+		t.Errorf("NULLs should be null: %v", second)
+	}
+}
+
+func TestParquetWriter_InvalidValue(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.parquet")
+	w, err := NewParquetWriter(path, 1, CSVWriterOptions{ColumnTypes: []ColumnType{{DatabaseType: "NUMBER", Precision: 5}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeaders([]string{"ID"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]interface{}{"1.5"}); err == nil {
+		t.Error("WriteRow() should reject a fraction in an INT64 column")
+	}
+	if err := w.Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("file should be removed, stat error = %v", err)
+	}
+}
+
+func TestParquetWriter_RowGroups(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "groups.parquet")
+	w, err := NewParquetWriter(path, 1, CSVWriterOptions{RowGroupBytes: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeaders([]string{"V"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := w.WriteRow([]interface{}{"0123456789"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = f.Close() }()
+	info, _ := f.Stat()
+	file, err := parquet.OpenFile(f, info.Size())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := len(file.RowGroups()); got != 5 {
+		t.Errorf("row groups = %d, want 5", got)
+	}
+}
+
+func TestParquetKindOf(t *testing.T) {
+	tests := []struct {
+		ct   ColumnType
+		want parquetKind
+	}{
+		{ColumnType{DatabaseType: "NUMBER", Precision: 10}, parquetInt64},
+		{ColumnType{DatabaseType: "NUMBER", Precision: 38}, parquetDouble},
+		{ColumnType{DatabaseType: "NUMBER", Precision: 10, Scale: 2}, parquetDouble},
+		{ColumnType{DatabaseType: "NUMBER"}, parquetDouble},
+		{ColumnType{DatabaseType: "IBDouble"}, parquetDouble},
+		{ColumnType{DatabaseType: "DATE"}, parquetTimestamp},
+		{ColumnType{DatabaseType: "TimeStampTZ_DTY"}, parquetTimestamp},
+		{ColumnType{DatabaseType: "NCHAR"}, parquetString},
+		{ColumnType{DatabaseType: "IntervalDS_DTY"}, parquetString},
+		{ColumnType{}, parquetString},
+	}
+	for _, tt := range tests {
+		if got := parquetKindOf(tt.ct); got != tt.want {
+			t.Errorf("parquetKindOf(%+v) = %d, want %d", tt.ct, got, tt.want)
+		}
+	}
+}
+
+func TestOutputColumnTypes(t *testing.T) {
+	types := []ColumnType{{DatabaseType: "NUMBER"}, {DatabaseType: "DATE"}, {DatabaseType: "VARCHAR"}}
+	got := outputColumnTypes(types, []int{2, 0}, map[int]string{0: "***"})
+	if len(got) != 2 || got[0].DatabaseType != "VARCHAR" || got[1].DatabaseType != "" {
+		t.Errorf("outputColumnTypes() = %+v", got)
+	}
+}
@@ -0,0 +1,170 @@
+package exporter
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/term"
+
+	"github.com/koltyakov/ora2csv/internal/logging"
+)
+
+// progressRedrawInterval is how often the progress line is redrawn
+const progressRedrawInterval = time.Second
+
+// progressLine redraws a single line with the row counts of the running entities
+// The line is written to a terminal with \r; entities exported in parallel share it.
+type progressLine struct {
+	out  io.Writer
+	stop chan struct{}
+	done chan struct{}
+
+	mu       sync.Mutex
+	entities []*entityProgress
+	width    int // length of the last line, cleared by the next one
+}
+
+// entityProgress counts the rows written by one entity
+type entityProgress struct {
+	name  string
+	start time.Time
+	rows  atomic.Int64
+}
+
+// stderrIsTerminal reports whether stderr is a terminal, progress is not shown otherwise
+func stderrIsTerminal() bool {
+	return term.IsTerminal(int(os.Stderr.Fd()))
+}
+
+// startProgress starts redrawing the progress line on out every interval until Stop
+func startProgress(out io.Writer, interval time.Duration) *progressLine {
+	p := &progressLine{
+		out:  out,
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	go func() {
+		defer close(p.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case now := <-ticker.C:
+				p.draw(p.line(now))
+			}
+		}
+	}()
+	return p
+}
+
+// track starts counting rows for entity and returns the counter the scan loop updates
+func (p *progressLine) track(entity string) *atomic.Int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ep := &entityProgress{name: entity, start: time.Now()}
+	p.entities = append(p.entities, ep)
+	return &ep.rows
+}
+
+// untrack removes entity from the progress line
+func (p *progressLine) untrack(entity string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, ep := range p.entities {
+		if ep.name == entity {
+			p.entities = append(p.entities[:i], p.entities[i+1:]...)
+			return
+		}
+	}
+}
+
+// line formats the progress of the running entities as "entity: N rows (M rows/sec)"
+func (p *progressLine) line(now time.Time) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	parts := make([]string, 0, len(p.entities))
+	for _, ep := range p.entities {
+		rows := ep.rows.Load()
+		var rate int64
+		if elapsed := now.Sub(ep.start).Seconds(); elapsed > 0 {
+			rate = int64(float64(rows) / elapsed)
+		}
+		parts = append(parts, fmt.Sprintf("%s: %d rows (%d rows/sec)", ep.name, rows, rate))
+	}
+	return strings.Join(parts, " | ")
+}
+
+// draw overwrites the current line with line, padding over a longer previous line
+func (p *progressLine) draw(line string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	padding := ""
+	if p.width > len(line) {
+		padding = strings.Repeat(" ", p.width-len(line))
+	}
+	_, _ = fmt.Fprintf(p.out, "\r%s%s", line, padding)
+	p.width = len(line)
+}
+
+// Stop stops redrawing and clears the progress line
+func (p *progressLine) Stop() {
+	close(p.stop)
+	<-p.done
+	p.draw("")
+	_, _ = fmt.Fprint(p.out, "\r")
+}
+
+// ProgressReporter logs the row count of an entity export every interval rows
+// With the row count of the entity's previous export it also estimates the percentage done
+// and the time left, assuming the export is about as large as the last one.
+type ProgressReporter struct {
+	log      *logging.Logger
+	start    time.Time
+	interval int
+	expected int // Rows of the previous export, 0 when unknown
+
+	now func() time.Time // time.Now, replaced in tests
+}
+
+// NewProgressReporter returns a reporter for an export started at start
+// An interval of 0 or less disables it, Report is then a no-op.
+func NewProgressReporter(log *logging.Logger, start time.Time, interval, expected int) *ProgressReporter {
+	if interval <= 0 {
+		return nil
+	}
+	return &ProgressReporter{log: log, start: start, interval: interval, expected: expected, now: time.Now}
+}
+
+// Report logs the progress when rows is a multiple of the interval, a nil reporter does nothing
+func (p *ProgressReporter) Report(rows int) {
+	if p == nil || rows == 0 || rows%p.interval != 0 {
+		return
+	}
+	p.log.Info("%s", p.message(rows, p.now()))
+}
+
+// message formats the progress after rows, e.g.
+// "Progress: 50000 rows (elapsed 30s, ~1666 rows/sec, ETA unknown)"
+func (p *ProgressReporter) message(rows int, now time.Time) string {
+	elapsed := now.Sub(p.start)
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(rows) / elapsed.Seconds()
+	}
+
+	// Past the previous row count the size of the export is unknown
+	if p.expected <= 0 || rows >= p.expected || rate <= 0 {
+		return fmt.Sprintf("Progress: %d rows (elapsed %s, ~%d rows/sec, ETA unknown)",
+			rows, elapsed.Round(time.Second), int64(rate))
+	}
+	eta := time.Duration(float64(p.expected-rows) / rate * float64(time.Second))
+	return fmt.Sprintf("Progress: %d rows, ~%d%% of %d in the last run (elapsed %s, ~%d rows/sec, ETA %s)",
+		rows, rows*100/p.expected, p.expected, elapsed.Round(time.Second), int64(rate), eta.Round(time.Second))
+}
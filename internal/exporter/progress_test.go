@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/logging"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestProgressLine_Line(t *testing.T) {
+	var out bytes.Buffer
+	p := &progressLine{out: &out}
+	orders := p.track("crm.orders")
+	customers := p.track("crm.customers")
+	orders.Store(3000)
+	customers.Store(50)
+
+	// Rates are averaged over the time since each entity started
+	start := time.Now()
+	for _, ep := range p.entities {
+		ep.start = start
+	}
+	line := p.line(start.Add(2 * time.Second))
+	testutil.AssertEqual(t, "crm.orders: 3000 rows (1500 rows/sec) | crm.customers: 50 rows (25 rows/sec)", line)
+
+	p.untrack("crm.orders")
+	testutil.AssertEqual(t, "crm.customers: 50 rows (25 rows/sec)", p.line(start.Add(2*time.Second)))
+
+	// A shorter line is padded to clear the previous one
+	p.draw("crm.orders: 3000 rows")
+	p.draw("done")
+	testutil.AssertEqual(t, "\rcrm.orders: 3000 rows\rdone"+strings.Repeat(" ", 17), out.String())
+}
+
+func TestProgressLine_StartStop(t *testing.T) {
+	var out bytes.Buffer
+	p := startProgress(&out, time.Millisecond)
+	rows := p.track("crm.orders")
+	rows.Store(42)
+	time.Sleep(20 * time.Millisecond)
+	p.Stop()
+
+	got := out.String()
+	if !strings.Contains(got, "\rcrm.orders: 42 rows (") {
+		t.Errorf("progress output = %q, want the entity row count", got)
+	}
+	// Stop leaves an empty line for the output that follows
+	if !strings.HasSuffix(got, "\r") {
+		t.Errorf("progress output = %q, want it to end by returning to the line start", got)
+	}
+}
+
+func TestProgressReporter(t *testing.T) {
+	start := time.Date(2025, 1, 15, 8, 0, 0, 0, time.UTC)
+
+	p := NewProgressReporter(logging.New(false), start, 5000, 0)
+	testutil.AssertEqual(t, "Progress: 50000 rows (elapsed 30s, ~1666 rows/sec, ETA unknown)",
+		p.message(50000, start.Add(30*time.Second)))
+
+	p = NewProgressReporter(logging.New(false), start, 5000, 200000)
+	testutil.AssertEqual(t, "Progress: 50000 rows, ~25% of 200000 in the last run (elapsed 30s, ~1666 rows/sec, ETA 1m30s)",
+		p.message(50000, start.Add(30*time.Second)))
+	// Past the previous row count there is no estimate
+	testutil.AssertEqual(t, "Progress: 250000 rows (elapsed 1m0s, ~4166 rows/sec, ETA unknown)",
+		p.message(250000, start.Add(time.Minute)))
+
+	if NewProgressReporter(logging.New(false), start, 0, 0) != nil {
+		t.Error("expected no reporter for interval 0")
+	}
+	var disabled *ProgressReporter
+	disabled.Report(10000) // no-op
+}
+
+func TestProgressReporter_Report(t *testing.T) {
+	var out bytes.Buffer
+	start := time.Now()
+	p := NewProgressReporter(logging.NewWithWriter(&out, false), start, 100, 0)
+	p.now = func() time.Time { return start.Add(10 * time.Second) }
+
+	for rows := 1; rows <= 250; rows++ {
+		p.Report(rows)
+	}
+	if got := strings.Count(out.String(), "Progress:"); got != 2 {
+		t.Errorf("got %d progress lines, want 2:\n%s", got, out.String())
+	}
+	if !strings.Contains(out.String(), "Progress: 200 rows (elapsed 10s, ~20 rows/sec, ETA unknown)") {
+		t.Errorf("unexpected progress output:\n%s", out.String())
+	}
+}
@@ -0,0 +1,96 @@
+package exporter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exportExtensions are the export file extensions recognized by ExportFileTime, each optionally gzipped
+var exportExtensions = []string{".csv", ".tsv", ".jsonl"}
+
+// PurgedFile is an export file deleted (or listed with dryRun) by PurgeExports
+type PurgedFile struct {
+	Path string
+	Size int64
+}
+
+// ExportFileTime returns the start date embedded in an export file name
+// Names look like <entity>__2006-01-02T15-04-05.csv, with a .csv, .tsv, .jsonl or extraExt
+// extension, optionally followed by .gz. Other names report false. Part files of split
+// exports, e.g. <entity>__2006-01-02T15-04-05__part002.csv, are recognized as well.
+func ExportFileTime(name, extraExt string) (time.Time, bool) {
+	name = trimPartNumber(name)
+	i := strings.LastIndex(name, "__")
+	if i <= 0 {
+		return time.Time{}, false
+	}
+	rest := name[i+2:]
+	if len(rest) <= len(exportFileTimeLayout) {
+		return time.Time{}, false
+	}
+	stamp, ext := rest[:len(exportFileTimeLayout)], strings.TrimSuffix(rest[len(exportFileTimeLayout):], ".gz")
+	if !isExportExtension(ext, extraExt) {
+		return time.Time{}, false
+	}
+	t, err := time.ParseInLocation(exportFileTimeLayout, stamp, time.UTC)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// isExportExtension reports whether ext is one of exportExtensions or extraExt
+func isExportExtension(ext, extraExt string) bool {
+	if extraExt != "" && ext == "."+strings.TrimPrefix(extraExt, ".") {
+		return true
+	}
+	for _, known := range exportExtensions {
+		if ext == known {
+			return true
+		}
+	}
+	return false
+}
+
+// PurgeExports deletes export files in dir whose embedded start date is before cutoff
+// Only files named like exports (see ExportFileTime) directly in dir are considered,
+// state, manifests and dedup filters are never touched. With dryRun nothing is deleted.
+func PurgeExports(dir, extraExt string, cutoff time.Time, dryRun bool) ([]PurgedFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read export directory: %w", err)
+	}
+
+	var purged []PurgedFile
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		t, ok := ExportFileTime(entry.Name(), extraExt)
+		if !ok || !t.Before(cutoff) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return purged, fmt.Errorf("failed to stat %s: %w", entry.Name(), err)
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		if !dryRun {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return purged, fmt.Errorf("failed to remove export file %s: %w", path, err)
+			}
+		}
+		purged = append(purged, PurgedFile{Path: path, Size: info.Size()})
+	}
+	return purged, nil
+}
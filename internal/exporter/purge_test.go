@@ -0,0 +1,83 @@
+package exporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestExportFileTime(t *testing.T) {
+	want := time.Date(2025, 1, 14, 10, 30, 0, 0, time.UTC)
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"crm.products__2025-01-14T10-30-00.csv", true},
+		{"crm.products__2025-01-14T10-30-00.csv.gz", true},
+		{"crm.products__2025-01-14T10-30-00.jsonl", true},
+		{"crm.products__2025-01-14T10-30-00__part002.csv.gz", true},
+		{"crm.products__2025-01-14T10-30-00__part.csv", false},
+		{"crm.order__lines__2025-01-14T10-30-00.tsv", true},
+		{"crm.products__2025-01-14T10-30-00.dat", true}, // extraExt
+		{"crm.products__2025-01-14T10-30-00.json", false},
+		{"crm.products__2025-01-14.csv", false},
+		{"manifest__2025-01-14T10-30-00.json", false},
+		{"crm.products.bloom", false},
+		{"__2025-01-14T10-30-00.csv", false},
+	}
+	for _, tt := range tests {
+		got, ok := ExportFileTime(tt.name, "dat")
+		if ok != tt.ok {
+			t.Errorf("ExportFileTime(%q) ok = %t, want %t", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && !got.Equal(want) {
+			t.Errorf("ExportFileTime(%q) = %s, want %s", tt.name, got, want)
+		}
+	}
+}
+
+func TestPurgeExports(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"crm.products__2025-01-01T00-00-00.csv":    "old",
+		"crm.products__2025-01-01T00-00-00.csv.gz": "old gz",
+		"crm.products__2025-03-01T00-00-00.csv":    "new",
+		"crm.products.bloom":                       "filter",
+		"manifest.json":                            "{}",
+		"state.json":                               "[]",
+	}
+	for name, content := range files {
+		mustWriteTestFile(t, filepath.Join(dir, name), content)
+	}
+	testutil.AssertNoError(t, os.MkdirAll(filepath.Join(dir, ManifestDir), 0755))
+	cutoff := time.Date(2025, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	purged, err := PurgeExports(dir, "", cutoff, true)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 2, len(purged))
+	if _, err := os.Stat(purged[0].Path); err != nil {
+		t.Errorf("dry run removed %s", purged[0].Path)
+	}
+
+	purged, err = PurgeExports(dir, "", cutoff, false)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 2, len(purged))
+	testutil.AssertEqual(t, int64(len("old")+len("old gz")), purged[0].Size+purged[1].Size)
+
+	remaining, err := os.ReadDir(dir)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 5, len(remaining))
+	for _, f := range purged {
+		if _, err := os.Stat(f.Path); !os.IsNotExist(err) {
+			t.Errorf("%s not removed", f.Path)
+		}
+	}
+
+	purged, err = PurgeExports(filepath.Join(dir, "missing"), "", cutoff, false)
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 0, len(purged))
+}
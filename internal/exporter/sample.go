@@ -0,0 +1,88 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SampleRows runs the SQL of an entity over its next export window and returns at most limit rows
+// The window and bind variables are the ones an export would use now; the query is wrapped in
+// a ROWNUM filter so Oracle stops early. Nil values are NULL. State is not changed.
+func (e *Exporter) SampleRows(ctx context.Context, entityName string, limit int) (columns []string, rows [][]*string, retErr error) {
+	if limit <= 0 {
+		return nil, nil, fmt.Errorf("limit must be positive")
+	}
+	entity, ok := e.st.FindEntity(entityName)
+	if !ok {
+		return nil, nil, fmt.Errorf("entity not found in state: %s", entityName)
+	}
+
+	runTime, err := e.tillDate(time.Now().UTC())
+	if err != nil {
+		return nil, nil, err
+	}
+	startDate, err := e.getStartDate(*entity)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to determine start date: %w", err)
+	}
+	startDateStr := startDate.Format("2006-01-02T15:04:05")
+	tillDateStr := runTime.Format("2006-01-02T15:04:05")
+
+	sqlContent, err := e.loadSQLFile(*entity, e.sqlVariables(*entity, startDateStr, runTime))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load SQL file: %w", err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, e.cfg.QueryTimeout)
+	defer cancel()
+	params, _, err := e.queryParams(queryCtx, *entity, sqlContent, startDateStr, tillDateStr)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := e.db.QueryContext(queryCtx, limitQuery(sqlContent, limit), params)
+	if err != nil {
+		return nil, nil, fmt.Errorf("query execution failed: %w", err)
+	}
+	defer func() {
+		if err := result.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("failed to close rows: %w", err)
+		}
+	}()
+
+	columns, err = result.Columns()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get columns: %w", err)
+	}
+	values := make([]sql.NullString, len(columns))
+	targets := make([]interface{}, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	for len(rows) < limit && result.Next() {
+		if err := result.Scan(targets...); err != nil {
+			return nil, nil, fmt.Errorf("failed to scan row: %w", err)
+		}
+		row := make([]*string, len(values))
+		for i, v := range values {
+			if v.Valid {
+				s := v.String
+				row[i] = &s
+			}
+		}
+		rows = append(rows, row)
+	}
+	if err := result.Err(); err != nil {
+		return nil, nil, fmt.Errorf("row iteration error: %w", err)
+	}
+	return columns, rows, nil
+}
+
+// limitQuery wraps the entity query in a ROWNUM filter returning at most limit rows
+func limitQuery(sqlContent string, limit int) string {
+	inner := strings.TrimRight(strings.TrimSpace(sqlContent), ";")
+	return fmt.Sprintf("SELECT * FROM (\n%s\n) WHERE ROWNUM <= %d", inner, limit)
+}
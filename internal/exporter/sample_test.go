@@ -0,0 +1,61 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func TestLimitQuery(t *testing.T) {
+	got := limitQuery("  SELECT id FROM orders WHERE updated >= :startDate;\n", 5)
+	want := "SELECT * FROM (\nSELECT id FROM orders WHERE updated >= :startDate\n) WHERE ROWNUM <= 5"
+	testutil.AssertEqual(t, want, got)
+}
+
+func TestExporter_SampleRows(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, []types.EntityState{
+		{Entity: "crm.orders", LastRunTime: "2025-01-01T00:00:00", Active: true},
+	}))
+	testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+	mustWriteTestFile(t, filepath.Join(cfg.SQLDir, "crm.orders.sql"), "SELECT id FROM orders WHERE updated >= :startDate")
+
+	st, err := state.Load(cfg.StateFile, nil, "")
+	testutil.AssertNoError(t, err)
+
+	var gotQuery string
+	var gotArgs map[string]interface{}
+	mock := db.NewMockDB()
+	mock.QueryFunc = func(ctx context.Context, query string, args map[string]interface{}) (*sql.Rows, error) {
+		gotQuery, gotArgs = query, args
+		return nil, errors.New("ORA-00942: table or view does not exist")
+	}
+
+	exp := New(cfg, mock, st, logging.New(false), nil)
+	_, _, err = exp.SampleRows(context.Background(), "crm.orders", 3)
+	if err == nil || !strings.Contains(err.Error(), "ORA-00942") {
+		t.Fatalf("expected query error, got %v", err)
+	}
+	if !strings.HasPrefix(gotQuery, "SELECT * FROM (\nSELECT id FROM orders") || !strings.HasSuffix(gotQuery, "WHERE ROWNUM <= 3") {
+		t.Errorf("query not limited: %q", gotQuery)
+	}
+	testutil.AssertEqual(t, "2025-01-01T00:00:00", gotArgs["startDate"])
+
+	// State is never touched by a preview
+	entity, _ := st.FindEntity("crm.orders")
+	testutil.AssertEqual(t, "2025-01-01T00:00:00", entity.LastRunTime)
+
+	if _, _, err := exp.SampleRows(context.Background(), "crm.missing", 3); err == nil {
+		t.Error("expected error for unknown entity")
+	}
+}
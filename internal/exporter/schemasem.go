@@ -0,0 +1,49 @@
+package exporter
+
+import (
+	"context"
+	"strings"
+)
+
+// SchemaSemaphorePool limits how many entities of the same Oracle schema export at once
+// The schema is the entity name prefix before the first dot, compared case-insensitively.
+// Schemas without a limit are not restricted.
+type SchemaSemaphorePool struct {
+	sems map[string]chan struct{}
+}
+
+// NewSchemaSemaphorePool creates a pool from schema limits; limits below 1 are ignored
+func NewSchemaSemaphorePool(limits map[string]int) *SchemaSemaphorePool {
+	p := &SchemaSemaphorePool{sems: make(map[string]chan struct{}, len(limits))}
+	for schema, n := range limits {
+		if n > 0 {
+			p.sems[strings.ToUpper(schema)] = make(chan struct{}, n)
+		}
+	}
+	return p
+}
+
+// entitySchema returns the schema prefix of an entity name, empty without a dot
+func entitySchema(entity string) string {
+	schema, _, ok := strings.Cut(entity, ".")
+	if !ok {
+		return ""
+	}
+	return strings.ToUpper(schema)
+}
+
+// Acquire blocks until the entity's schema has a free slot or ctx is done
+// The returned function releases the slot and must be called once.
+func (p *SchemaSemaphorePool) Acquire(ctx context.Context, entity string) (func(), error) {
+	sem, ok := p.sems[entitySchema(entity)]
+	if !ok {
+		return func() {}, nil
+	}
+
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
@@ -0,0 +1,147 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	"github.com/koltyakov/ora2csv/internal/state"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// schemaTrackingDB records the peak number of concurrent queries per schema,
+// taken from a /* SCHEMA */ comment in the query
+type schemaTrackingDB struct {
+	db.DB
+	mu      sync.Mutex
+	running map[string]int
+	peak    map[string]int
+}
+
+func (d *schemaTrackingDB) QueryContext(ctx context.Context, query string, args map[string]interface{}) (*sql.Rows, error) {
+	schema := query[strings.Index(query, "/* ")+3 : strings.Index(query, " */")]
+	d.mu.Lock()
+	d.running[schema]++
+	d.peak[schema] = max(d.peak[schema], d.running[schema])
+	d.mu.Unlock()
+
+	time.Sleep(50 * time.Millisecond)
+
+	d.mu.Lock()
+	d.running[schema]--
+	d.mu.Unlock()
+	return d.DB.QueryContext(ctx, query, args)
+}
+
+func TestEntitySchema(t *testing.T) {
+	tests := map[string]string{
+		"hr.employees":  "HR",
+		"HR.DEPTS":      "HR",
+		"crm.a.b":       "CRM",
+		"no_schema_sql": "",
+	}
+	for entity, want := range tests {
+		if got := entitySchema(entity); got != want {
+			t.Errorf("entitySchema(%q) = %q, want %q", entity, got, want)
+		}
+	}
+}
+
+func TestSchemaSemaphorePool_Limit(t *testing.T) {
+	pool := NewSchemaSemaphorePool(map[string]int{"hr": 1, "crm": 0})
+
+	// Higher global parallelism than the HR limit
+	entities := []string{"HR.EMPLOYEES", "hr.departments", "HR.JOBS", "crm.products", "crm.orders"}
+
+	var mu sync.Mutex
+	running := map[string]int{}
+	peak := map[string]int{}
+	var wg sync.WaitGroup
+	for _, entity := range entities {
+		wg.Add(1)
+		go func(entity string) {
+			defer wg.Done()
+			release, err := pool.Acquire(context.Background(), entity)
+			if err != nil {
+				t.Errorf("Acquire(%s) error = %v", entity, err)
+				return
+			}
+			defer release()
+
+			schema := entitySchema(entity)
+			mu.Lock()
+			running[schema]++
+			peak[schema] = max(peak[schema], running[schema])
+			mu.Unlock()
+
+			time.Sleep(20 * time.Millisecond)
+
+			mu.Lock()
+			running[schema]--
+			mu.Unlock()
+		}(entity)
+	}
+	wg.Wait()
+
+	if peak["HR"] != 1 {
+		t.Errorf("peak HR concurrency = %d, want 1", peak["HR"])
+	}
+}
+
+func TestSchemaSemaphorePool_ContextCancelled(t *testing.T) {
+	pool := NewSchemaSemaphorePool(map[string]int{"HR": 1})
+	release, err := pool.Acquire(context.Background(), "HR.EMPLOYEES")
+	if err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := pool.Acquire(ctx, "HR.DEPARTMENTS"); err == nil {
+		t.Error("expected Acquire to wait for the held slot until the context expired")
+	}
+}
+
+func TestExporter_Run_SchemaLimit(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		cfg.Parallelism = 4
+		cfg.SchemaParallelismLimits = map[string]int{"HR": 1}
+		entities := []types.EntityState{
+			{Entity: "hr.employees", LastRunTime: "2025-01-01T00:00:00", Active: true},
+			{Entity: "hr.departments", LastRunTime: "2025-01-01T00:00:00", Active: true},
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+			{Entity: "crm.orders", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		}
+		testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+		testutil.AssertNoError(t, os.MkdirAll(cfg.SQLDir, 0755))
+		for _, entity := range entities {
+			schema := strings.ToUpper(entitySchema(entity.Entity))
+			mustWriteTestFile(t, filepath.Join(cfg.SQLDir, entity.Entity+".sql"), "/* "+schema+" */ "+integrationSQL)
+		}
+		testutil.AssertNoError(t, cfg.EnsureDirs())
+
+		st, err := state.Load(cfg.StateFile, nil, "")
+		testutil.AssertNoError(t, err)
+		defer func() { _ = st.Close() }()
+
+		tracking := &schemaTrackingDB{DB: database, running: map[string]int{}, peak: map[string]int{}}
+		result, err := New(cfg, tracking, st, logging.New(false), nil).Run(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 4, result.SuccessCount)
+
+		// HR is capped at one export while the unlimited CRM entities run side by side
+		testutil.AssertEqual(t, 1, tracking.peak["HR"])
+		testutil.AssertEqual(t, 2, tracking.peak["CRM"])
+	})
+}
@@ -0,0 +1,92 @@
+package exporter
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// identifierPattern matches a plain (unquoted) Oracle column name
+var identifierPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_$#]*$`)
+
+// queryParams returns the bind parameters of an entity query
+// Timestamp entities bind :startDate and :tillDate. Sequence entities bind :startValue
+// (lastRunValue) and :tillValue, the current MAX of incrementalColumn, which is also
+// returned as the new watermark so rows committed during the export wait for the next run.
+func (e *Exporter) queryParams(ctx context.Context, entity types.EntityState, sqlContent, startDate, tillDate string) (map[string]interface{}, string, error) {
+	params := e.paramOverrides(entity)
+	switch entity.IncrementalType {
+	case "", types.IncrementalTimestamp:
+		params["startDate"] = startDate
+		params["tillDate"] = tillDate
+		return params, "", nil
+	case types.IncrementalSequence:
+	default:
+		return nil, "", fmt.Errorf("unknown incrementalType %q, want %s or %s", entity.IncrementalType, types.IncrementalTimestamp, types.IncrementalSequence)
+	}
+
+	if !identifierPattern.MatchString(entity.IncrementalColumn) {
+		return nil, "", fmt.Errorf("invalid incrementalColumn %q for sequence entity", entity.IncrementalColumn)
+	}
+	startValue, err := entity.GetLastRunValue()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to parse lastRunValue: %w", err)
+	}
+
+	tillValue, err := e.maxValue(ctx, sqlContent, entity.IncrementalColumn, startValue, params)
+	if err != nil {
+		return nil, "", err
+	}
+	params["startValue"] = startValue
+	params["tillValue"] = tillValue
+	return params, strconv.FormatInt(tillValue, 10), nil
+}
+
+// maxValue returns the highest column value the entity query returns above startValue,
+// or startValue when there are no new rows
+// binds are the other bind variables of the query, e.g. sqlParams overriding global params
+func (e *Exporter) maxValue(ctx context.Context, sqlContent, column string, startValue int64, binds map[string]interface{}) (value int64, retErr error) {
+	params := map[string]interface{}{
+		"startValue": startValue,
+		"tillValue":  int64(math.MaxInt64),
+	}
+	for k, v := range binds {
+		params[k] = v
+	}
+	rows, err := e.db.QueryContext(ctx, maxValueQuery(sqlContent, column), params)
+	if err != nil {
+		return 0, fmt.Errorf("max value query failed: %w", err)
+	}
+	defer func() {
+		if err := rows.Close(); err != nil && retErr == nil {
+			retErr = fmt.Errorf("failed to close rows: %w", err)
+		}
+	}()
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, fmt.Errorf("max value query failed: %w", err)
+		}
+		return 0, fmt.Errorf("max value query returned no rows")
+	}
+	var maxValue sql.NullInt64
+	if err := rows.Scan(&maxValue); err != nil {
+		return 0, fmt.Errorf("failed to read max value: %w", err)
+	}
+	if !maxValue.Valid || maxValue.Int64 < startValue {
+		return startValue, nil
+	}
+	return maxValue.Int64, nil
+}
+
+// maxValueQuery wraps the entity query in SELECT MAX(column)
+func maxValueQuery(sqlContent, column string) string {
+	inner := strings.TrimRight(strings.TrimSpace(sqlContent), ";")
+	return "SELECT MAX(" + column + ") FROM (\n" + inner + "\n)"
+}
@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func TestExporter_QueryParams_GlobalParams(t *testing.T) {
+	cfg := testutil.NewTestConfig(t)
+	cfg.GlobalParams = map[string]string{"tenantId": "acme", "env": "prod"}
+	e := New(cfg, nil, nil, logging.New(false), nil)
+
+	entity := types.EntityState{
+		Entity:    "crm.orders",
+		SQLParams: map[string]string{"tenantId": "globex", "Table": "ORDERS_2024"},
+	}
+	params, watermark, err := e.queryParams(context.Background(), entity, "", "2025-01-01T00:00:00", "2025-01-02T00:00:00")
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, "", watermark)
+
+	// Only sqlParams overriding a global param become binds, the rest are bound by the db layer
+	want := map[string]interface{}{
+		"startDate": "2025-01-01T00:00:00",
+		"tillDate":  "2025-01-02T00:00:00",
+		"tenantId":  "globex",
+	}
+	if !reflect.DeepEqual(params, want) {
+		t.Errorf("queryParams() = %v, want %v", params, want)
+	}
+}
+
+func TestExporter_QueryParams_SequenceGlobalParams(t *testing.T) {
+	sqlitetest.Run(t, integrationSchema, func(database db.DB) {
+		cfg := testutil.NewTestConfig(t)
+		cfg.GlobalParams = map[string]string{"name": "Widget"}
+		e := New(cfg, database, nil, logging.New(false), nil)
+
+		entity := types.EntityState{
+			Entity:            "crm.products",
+			IncrementalType:   types.IncrementalSequence,
+			IncrementalColumn: "id",
+			LastRunValue:      "0",
+			SQLParams:         map[string]string{"name": "Old"},
+		}
+		sql := "SELECT id, name FROM products WHERE id > ? AND id <= ? AND name <> ? ORDER BY id"
+		params, watermark, err := e.queryParams(context.Background(), entity, sql, "", "")
+		testutil.AssertNoError(t, err)
+
+		// The max value query binds the entity override, so id 3 (Old) is excluded
+		testutil.AssertEqual(t, "2", watermark)
+		want := map[string]interface{}{
+			"startValue": int64(0),
+			"tillValue":  int64(2),
+			"name":       "Old",
+		}
+		if !reflect.DeepEqual(params, want) {
+			t.Errorf("queryParams() = %v, want %v", params, want)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package exporter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+
+	"github.com/koltyakov/ora2csv/internal/storage"
+)
+
+// outputSink receives the encoded output of a row writer
+type outputSink interface {
+	io.Writer
+	// Close finishes the output
+	Close() error
+	// Discard abandons the output, nothing is left behind
+	Discard() error
+}
+
+// fileSink writes output to a local file
+type fileSink struct {
+	*os.File
+}
+
+// createFileSink creates or truncates the file at path
+func createFileSink(path string, mode os.FileMode) (*fileSink, error) {
+	if mode == 0 {
+		mode = 0644
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+	return &fileSink{File: file}, nil
+}
+
+// Close closes the file, closing it twice is not an error
+func (s *fileSink) Close() error {
+	if err := s.File.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+// Discard closes and deletes the file
+func (s *fileSink) Discard() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+	if err := os.Remove(s.Name()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// errUploadDiscarded aborts an upload whose output was discarded
+var errUploadDiscarded = errors.New("output discarded")
+
+// uploadSink streams output to cloud storage through a pipe
+// The upload runs in its own goroutine reading the pipe while rows are written, so no
+// local copy is kept. If the upload fails the pipe is closed with its error, failing
+// the next write instead of blocking it.
+type uploadSink struct {
+	pw     *io.PipeWriter
+	cancel context.CancelFunc
+	done   chan error
+	err    error // Upload result, set once done is received
+	closed bool
+	size   int64
+	hash   hash.Hash
+}
+
+// newUploadSink starts uploading to store under key, contentEncoding may be empty
+func newUploadSink(store storage.BlobStore, key, contentEncoding string) *uploadSink {
+	pr, pw := io.Pipe()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &uploadSink{pw: pw, cancel: cancel, done: make(chan error, 1), hash: sha256.New()}
+
+	go func() {
+		var err error
+		if contentEncoding != "" {
+			err = store.UploadStreamWithEncoding(ctx, key, contentEncoding, pr)
+		} else {
+			err = store.UploadStream(ctx, key, pr)
+		}
+		// Unblock the writer if the upload stopped before reading everything
+		if err != nil {
+			_ = pr.CloseWithError(err)
+		} else {
+			_ = pr.Close()
+		}
+		s.done <- err
+	}()
+	return s
+}
+
+// Write sends p to the upload, counting and hashing what was sent
+func (s *uploadSink) Write(p []byte) (int, error) {
+	n, err := s.pw.Write(p)
+	s.size += int64(n)
+	s.hash.Write(p[:n])
+	return n, err
+}
+
+// Close ends the stream and waits for the upload to complete
+func (s *uploadSink) Close() error {
+	return s.finish(nil)
+}
+
+// Discard aborts the upload, no object is created
+func (s *uploadSink) Discard() error {
+	_ = s.finish(errUploadDiscarded)
+	return nil
+}
+
+// finish closes the pipe, with abort as the reader error if set, and waits for the upload
+func (s *uploadSink) finish(abort error) error {
+	if s.closed {
+		return s.err
+	}
+	s.closed = true
+	if abort != nil {
+		_ = s.pw.CloseWithError(abort)
+	} else {
+		_ = s.pw.Close()
+	}
+	s.err = <-s.done
+	s.cancel()
+	return s.err
+}
+
+// Size returns the number of bytes sent to the upload
+func (s *uploadSink) Size() int64 {
+	return s.size
+}
+
+// Checksum returns the hex SHA-256 of the bytes sent to the upload
+func (s *uploadSink) Checksum() string {
+	return hex.EncodeToString(s.hash.Sum(nil))
+}
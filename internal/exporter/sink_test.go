@@ -0,0 +1,182 @@
+package exporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/parquet-go/parquet-go"
+)
+
+// memBlobStore keeps uploaded objects in memory
+// With failUpload set, uploads fail without reading their data.
+type memBlobStore struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	encodings  map[string]string
+	failUpload error
+}
+
+func newMemBlobStore() *memBlobStore {
+	return &memBlobStore{objects: map[string][]byte{}, encodings: map[string]string{}}
+}
+
+func (m *memBlobStore) Name() string               { return "Mem" }
+func (m *memBlobStore) Key(filename string) string { return filename }
+
+func (m *memBlobStore) UploadStream(ctx context.Context, key string, r io.Reader) error {
+	return m.UploadStreamWithEncoding(ctx, key, "", r)
+}
+
+func (m *memBlobStore) UploadStreamWithEncoding(ctx context.Context, key, contentEncoding string, r io.Reader) error {
+	if m.failUpload != nil {
+		return m.failUpload
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.objects[key] = data
+	m.encodings[key] = contentEncoding
+	return nil
+}
+
+func (m *memBlobStore) DownloadStream(ctx context.Context, key string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.objects[key]
+	if !ok {
+		return nil, errors.New("key not found: " + key)
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (m *memBlobStore) Exists(ctx context.Context, key string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+func (m *memBlobStore) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.objects, key)
+	return nil
+}
+
+func (m *memBlobStore) CheckConnection(ctx context.Context) error { return nil }
+
+// writeStreamed writes rows through a streaming cloud writer
+func writeStreamed(t *testing.T, store *memBlobStore, opts CSVWriterOptions, rows [][]string) (*CloudStreamingCSVWriter, error) {
+	t.Helper()
+	columns := []string{"ID", "NAME"}
+	writer, err := NewCloudStreamingCSVWriter(store, "crm.orders/crm.orders.csv", "", "", len(columns), opts)
+	testutil.AssertNoError(t, err)
+	if err := writer.WriteHeaders(columns); err != nil {
+		return writer, err
+	}
+	if _, err := streamRows(db.NewMockRowScanner(columns, rows), writer, nil, streamLimits{}, logging.New(false)); err != nil {
+		return writer, err
+	}
+	return writer, writer.Flush()
+}
+
+func TestCloudStreamingCSVWriter_Streaming(t *testing.T) {
+	t.Run("uploads while writing", func(t *testing.T) {
+		store := newMemBlobStore()
+		writer, err := writeStreamed(t, store, CSVWriterOptions{}, [][]string{{"1", "Alice"}, {"2", "Bob"}})
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, "", writer.GetLocalPath())
+		testutil.AssertNoError(t, writer.Close())
+
+		want := "ID,NAME\n1,Alice\n2,Bob\n"
+		testutil.AssertEqual(t, want, string(store.objects["crm.orders/crm.orders.csv"]))
+		size, checksum := writer.Digest()
+		sum := sha256.Sum256([]byte(want))
+		testutil.AssertEqual(t, int64(len(want)), size)
+		testutil.AssertEqual(t, hex.EncodeToString(sum[:]), checksum)
+	})
+
+	t.Run("gzip", func(t *testing.T) {
+		store := newMemBlobStore()
+		writer, err := writeStreamed(t, store, CSVWriterOptions{Compress: CompressGzip}, [][]string{{"1", "Alice"}})
+		testutil.AssertNoError(t, err)
+		testutil.AssertNoError(t, writer.Close())
+
+		testutil.AssertEqual(t, CompressGzip, store.encodings["crm.orders/crm.orders.csv"])
+		gz, err := gzip.NewReader(bytes.NewReader(store.objects["crm.orders/crm.orders.csv"]))
+		testutil.AssertNoError(t, err)
+		data, err := io.ReadAll(gz)
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, "ID,NAME\n1,Alice\n", string(data))
+	})
+
+	t.Run("parquet", func(t *testing.T) {
+		store := newMemBlobStore()
+		opts := CSVWriterOptions{Format: FormatParquet, ColumnTypes: []ColumnType{{DatabaseType: "NUMBER", Precision: 10}, {DatabaseType: "VARCHAR"}}}
+		writer, err := writeStreamed(t, store, opts, [][]string{{"1", "Alice"}, {"2", "Bob"}})
+		testutil.AssertNoError(t, err)
+		testutil.AssertNoError(t, writer.Close())
+
+		data := store.objects["crm.orders/crm.orders.csv"]
+		file, err := parquet.OpenFile(bytes.NewReader(data), int64(len(data)))
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, int64(2), file.NumRows())
+		testutil.AssertEqual(t, "ID", file.Schema().Fields()[0].Name())
+	})
+
+	t.Run("remove aborts the upload", func(t *testing.T) {
+		store := newMemBlobStore()
+		writer, err := writeStreamed(t, store, CSVWriterOptions{}, nil)
+		testutil.AssertNoError(t, err)
+		testutil.AssertNoError(t, writer.Remove())
+		testutil.AssertNoError(t, writer.Close())
+		if len(store.objects) != 0 {
+			t.Errorf("objects = %v, want none", store.objects)
+		}
+	})
+
+	t.Run("upload failure unblocks the writer", func(t *testing.T) {
+		store := newMemBlobStore()
+		store.failUpload = errors.New("access denied")
+		rows := make([][]string, 5000)
+		for i := range rows {
+			rows[i] = []string{"1", strings.Repeat("x", 100)}
+		}
+
+		writer, err := writeStreamed(t, store, CSVWriterOptions{}, rows)
+		if err == nil || !strings.Contains(err.Error(), "access denied") {
+			t.Errorf("write error = %v, want the upload error", err)
+		}
+		if err := writer.Close(); err == nil || !strings.Contains(err.Error(), "Mem upload failed") {
+			t.Errorf("Close() error = %v, want upload failure", err)
+		}
+	})
+}
+
+func TestCheckFileSize_Streaming(t *testing.T) {
+	store := newMemBlobStore()
+	writer, err := writeStreamed(t, store, CSVWriterOptions{}, [][]string{{"1", "Alice"}})
+	testutil.AssertNoError(t, err)
+	defer func() { _ = writer.Remove() }()
+
+	limits := streamLimits{maxBytes: 10, outputSize: writer.OutputSize}
+	if err := checkFileSize(writer, limits, logging.New(false)); err == nil {
+		t.Error("checkFileSize() should fail above maxBytes")
+	}
+	limits.maxBytes = 1024
+	testutil.AssertNoError(t, checkFileSize(writer, limits, logging.New(false)))
+}
@@ -0,0 +1,207 @@
+package exporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// partSeparator precedes the part number in the names of split export files
+const partSeparator = "__part"
+
+// PartPath returns the name of a part file of a split export, e.g. crm.orders__2025-01-01T00-00-00__part001.csv
+// The part number goes before ext, the output file extension; names without it get it appended.
+// It works for object keys as well as local paths, an empty name stays empty.
+func PartPath(name, ext string, part int) string {
+	if name == "" {
+		return ""
+	}
+	suffix := fmt.Sprintf("%s%03d", partSeparator, part)
+	if ext != "" && strings.HasSuffix(name, ext) {
+		return strings.TrimSuffix(name, ext) + suffix + ext
+	}
+	return name + suffix
+}
+
+// trimPartNumber removes the part number PartPath inserts into name, other names are returned unchanged
+func trimPartNumber(name string) string {
+	i := strings.LastIndex(name, partSeparator)
+	if i < 0 {
+		return name
+	}
+	j := i + len(partSeparator)
+	for j < len(name) && name[j] >= '0' && name[j] <= '9' {
+		j++
+	}
+	if j == i+len(partSeparator) || (j < len(name) && name[j] != '.') {
+		return name
+	}
+	return name[:i] + name[j:]
+}
+
+// SplitPart is a finished part file of a SplittingCSVWriter
+type SplitPart struct {
+	Name     string // Object key when uploaded, otherwise the local path
+	Key      string // Object key, empty for local files
+	Rows     int
+	Size     int64
+	Checksum string
+}
+
+// SplittingCSVWriter writes rows to numbered part files of at most maxRows rows each
+// Every part starts with the header row. The next part is only created when a row no longer
+// fits, so a result of maxRows rows has a single part. Rows are scanned into the targets of
+// the first part and copied to the current one.
+type SplittingCSVWriter struct {
+	maxRows  int64
+	maxBytes int64 // Set with LimitSize, 0 is unlimited
+	every    int64 // Rows between size checks
+	newPart  func(part int) (*outputFile, error)
+	headers  []string
+	current  *outputFile
+	rows     int64 // Rows in current
+	scan     []interface{}
+	finished []*outputFile
+	counts   []int64 // Rows of each part, finished ones first
+}
+
+// NewSplittingCSVWriter creates the first part with newPart(1), later parts are numbered from 2
+func NewSplittingCSVWriter(maxRows int64, newPart func(part int) (*outputFile, error)) (*SplittingCSVWriter, error) {
+	if maxRows <= 0 {
+		return nil, fmt.Errorf("max rows per file must be positive")
+	}
+	first, err := newPart(1)
+	if err != nil {
+		return nil, err
+	}
+	return &SplittingCSVWriter{
+		maxRows: maxRows,
+		newPart: newPart,
+		current: first,
+		scan:    first.GetScanTargets(),
+	}, nil
+}
+
+// LimitSize also starts a new part once the current one reaches maxBytes
+// The size is checked every checkEvery rows of a part, like max_file_size_mb for unsplit files,
+// so a part can grow past maxBytes by the rows written since the last check.
+func (w *SplittingCSVWriter) LimitSize(maxBytes, checkEvery int64) {
+	w.maxBytes, w.every = maxBytes, max(checkEvery, 1)
+}
+
+// WriteHeaders writes the header row of the current part and keeps it for the next ones
+func (w *SplittingCSVWriter) WriteHeaders(columns []string) error {
+	w.headers = columns
+	return w.current.WriteHeaders(columns)
+}
+
+// GetScanTargets returns the scan targets of the first part, valid for all parts
+func (w *SplittingCSVWriter) GetScanTargets() []interface{} {
+	return w.scan
+}
+
+// WriteScannedRow writes the scanned row, starting a new part when the current one is full
+func (w *SplittingCSVWriter) WriteScannedRow() error {
+	full, err := w.full()
+	if err != nil {
+		return err
+	}
+	if full {
+		if err := w.nextPart(); err != nil {
+			return err
+		}
+	}
+	if len(w.finished) > 0 {
+		copyScanTargets(w.current.GetScanTargets(), w.scan)
+	}
+	if err := w.current.WriteScannedRow(); err != nil {
+		return err
+	}
+	w.rows++
+	return nil
+}
+
+// full reports whether the current part has maxRows rows or, at a size check, maxBytes
+func (w *SplittingCSVWriter) full() (bool, error) {
+	if w.rows >= w.maxRows {
+		return true, nil
+	}
+	if w.maxBytes <= 0 || w.rows == 0 || w.rows%w.every != 0 {
+		return false, nil
+	}
+	if err := w.current.Flush(); err != nil {
+		return false, fmt.Errorf("failed to flush part %d: %w", len(w.finished)+1, err)
+	}
+	size, err := w.current.outputSize()
+	if err != nil {
+		return false, fmt.Errorf("failed to check part %d size: %w", len(w.finished)+1, err)
+	}
+	return size >= w.maxBytes, nil
+}
+
+// nextPart closes the current part and opens the next one with the header row
+func (w *SplittingCSVWriter) nextPart() error {
+	if err := w.current.Close(); err != nil {
+		return fmt.Errorf("failed to finalize part %d: %w", len(w.finished)+1, err)
+	}
+	w.finished = append(w.finished, w.current)
+	w.counts = append(w.counts, w.rows)
+
+	next, err := w.newPart(len(w.finished) + 1)
+	if err != nil {
+		return err
+	}
+	w.current, w.rows = next, 0
+	if err := next.WriteHeaders(w.headers); err != nil {
+		return fmt.Errorf("failed to write headers: %w", err)
+	}
+	return nil
+}
+
+// copyScanTargets copies scanned values between targets of the same types
+func copyScanTargets(dst, src []interface{}) {
+	for i := range src {
+		reflect.ValueOf(dst[i]).Elem().Set(reflect.ValueOf(src[i]).Elem())
+	}
+}
+
+// Flush flushes the current part
+func (w *SplittingCSVWriter) Flush() error {
+	return w.current.Flush()
+}
+
+// Remove removes the current part and deletes the finished ones
+func (w *SplittingCSVWriter) Remove() error {
+	errs := []error{w.current.Remove()}
+	for _, part := range w.finished {
+		errs = append(errs, part.delete(context.Background()))
+	}
+	w.finished = nil
+	return errors.Join(errs...)
+}
+
+// Close closes the current part
+func (w *SplittingCSVWriter) Close() error {
+	return w.current.Close()
+}
+
+// Parts returns the part files in order, after Close
+func (w *SplittingCSVWriter) Parts() ([]SplitPart, error) {
+	all := append(append([]*outputFile(nil), w.finished...), w.current)
+	counts := append(append([]int64(nil), w.counts...), w.rows)
+	parts := make([]SplitPart, len(all))
+	for i, file := range all {
+		size, checksum, err := file.digest()
+		if err != nil {
+			return nil, err
+		}
+		name := file.key
+		if name == "" {
+			name = file.path
+		}
+		parts[i] = SplitPart{Name: name, Key: file.key, Rows: int(counts[i]), Size: size, Checksum: checksum}
+	}
+	return parts, nil
+}
@@ -0,0 +1,173 @@
+package exporter
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPartPath(t *testing.T) {
+	tests := []struct {
+		name, ext string
+		part      int
+		want      string
+	}{
+		{"/export/crm.orders__2025-01-01T00-00-00.csv", ".csv", 1, "/export/crm.orders__2025-01-01T00-00-00__part001.csv"},
+		{"crm/crm.orders__2025-01-01T00-00-00.csv.gz", ".csv.gz", 12, "crm/crm.orders__2025-01-01T00-00-00__part012.csv.gz"},
+		{"crm.orders", ".csv", 2, "crm.orders__part002"},
+		{"", ".csv", 1, ""},
+	}
+	for _, tt := range tests {
+		if got := PartPath(tt.name, tt.ext, tt.part); got != tt.want {
+			t.Errorf("PartPath(%q, %q, %d) = %q, want %q", tt.name, tt.ext, tt.part, got, tt.want)
+		}
+		if tt.want != "" && tt.ext != "" && trimPartNumber(tt.want) != tt.name {
+			t.Errorf("trimPartNumber(%q) = %q, want %q", tt.want, trimPartNumber(tt.want), tt.name)
+		}
+	}
+}
+
+func TestSplittingCSVWriter(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "crm.orders__2025-01-01T00-00-00.csv")
+	newPart := func(part int) (*outputFile, error) {
+		path := PartPath(base, ".csv", part)
+		w, err := newFileWriter(path, 2, CSVWriterOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &outputFile{csvWriter: w, path: path}, nil
+	}
+
+	w, err := NewSplittingCSVWriter(2, newPart)
+	if err != nil {
+		t.Fatalf("NewSplittingCSVWriter() error: %v", err)
+	}
+	if err := w.WriteHeaders([]string{"ID", "NAME"}); err != nil {
+		t.Fatalf("WriteHeaders() error: %v", err)
+	}
+	targets := w.GetScanTargets()
+	for _, row := range [][2]string{{"1", "a"}, {"2", "b"}, {"3", "c"}, {"4", "d"}, {"5", "e"}} {
+		*targets[0].(*sql.NullString) = sql.NullString{String: row[0], Valid: true}
+		*targets[1].(*sql.NullString) = sql.NullString{String: row[1], Valid: true}
+		if err := w.WriteScannedRow(); err != nil {
+			t.Fatalf("WriteScannedRow() error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	parts, err := w.Parts()
+	if err != nil {
+		t.Fatalf("Parts() error: %v", err)
+	}
+	want := []struct {
+		content string
+		rows    int
+	}{
+		{"ID,NAME\n1,a\n2,b\n", 2},
+		{"ID,NAME\n3,c\n4,d\n", 2},
+		{"ID,NAME\n5,e\n", 1},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("Parts() returned %d parts, want %d", len(parts), len(want))
+	}
+	for i, part := range parts {
+		if part.Name != PartPath(base, ".csv", i+1) || part.Key != "" || part.Rows != want[i].rows {
+			t.Errorf("part %d = %+v", i+1, part)
+		}
+		data, err := os.ReadFile(part.Name)
+		if err != nil {
+			t.Fatalf("ReadFile() error: %v", err)
+		}
+		if string(data) != want[i].content || part.Size != int64(len(data)) {
+			t.Errorf("part %d content = %q (size %d), want %q", i+1, data, part.Size, want[i].content)
+		}
+	}
+}
+
+func TestSplittingCSVWriter_Remove(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "crm.orders.csv")
+	w, err := NewSplittingCSVWriter(1, func(part int) (*outputFile, error) {
+		path := PartPath(base, ".csv", part)
+		fw, err := newFileWriter(path, 1, CSVWriterOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &outputFile{csvWriter: fw, path: path}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewSplittingCSVWriter() error: %v", err)
+	}
+	if err := w.WriteHeaders([]string{"ID"}); err != nil {
+		t.Fatalf("WriteHeaders() error: %v", err)
+	}
+	targets := w.GetScanTargets()
+	for _, id := range []string{"1", "2", "3"} {
+		*targets[0].(*sql.NullString) = sql.NullString{String: id, Valid: true}
+		if err := w.WriteScannedRow(); err != nil {
+			t.Fatalf("WriteScannedRow() error: %v", err)
+		}
+	}
+	if err := w.Remove(); err != nil {
+		t.Fatalf("Remove() error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("Remove() left %d files", len(entries))
+	}
+}
+
+func TestSplittingCSVWriter_LimitSize(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "crm.orders.csv")
+	w, err := NewSplittingCSVWriter(100, func(part int) (*outputFile, error) {
+		path := PartPath(base, ".csv", part)
+		fw, err := newFileWriter(path, 1, CSVWriterOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return &outputFile{csvWriter: fw, path: path}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewSplittingCSVWriter() error: %v", err)
+	}
+	// "ID\n" and three rows of "N\n" fill a part of 8 bytes
+	w.LimitSize(8, 1)
+	if err := w.WriteHeaders([]string{"ID"}); err != nil {
+		t.Fatalf("WriteHeaders() error: %v", err)
+	}
+	targets := w.GetScanTargets()
+	for _, id := range []string{"1", "2", "3", "4", "5", "6", "7"} {
+		*targets[0].(*sql.NullString) = sql.NullString{String: id, Valid: true}
+		if err := w.WriteScannedRow(); err != nil {
+			t.Fatalf("WriteScannedRow() error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	parts, err := w.Parts()
+	if err != nil {
+		t.Fatalf("Parts() error: %v", err)
+	}
+	want := []string{"ID\n1\n2\n3\n", "ID\n4\n5\n6\n", "ID\n7\n"}
+	if len(parts) != len(want) {
+		t.Fatalf("Parts() returned %d parts, want %d", len(parts), len(want))
+	}
+	for i, part := range parts {
+		data, err := os.ReadFile(part.Name)
+		if err != nil {
+			t.Fatalf("ReadFile() error: %v", err)
+		}
+		if string(data) != want[i] {
+			t.Errorf("part %d content = %q, want %q", i+1, data, want[i])
+		}
+	}
+}
@@ -0,0 +1,4 @@
+package exporter
+
+// Pure Go SQLite driver registered as "sqlite" for sqlitetest
+import _ "modernc.org/sqlite"
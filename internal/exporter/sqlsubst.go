@@ -0,0 +1,86 @@
+package exporter
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+var sqlVariable = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// SubstituteVariables replaces ${NAME} placeholders with values from vars
+// Placeholders without a value are left unchanged.
+func SubstituteVariables(sql string, vars map[string]string) string {
+	if len(vars) == 0 {
+		return sql
+	}
+	return sqlVariable.ReplaceAllStringFunc(sql, func(match string) string {
+		if value, ok := vars[match[2:len(match)-1]]; ok {
+			return value
+		}
+		return match
+	})
+}
+
+// RenderSQLTemplate executes sql as a text/template with params as data, e.g. {{.Table}}
+// SQL without {{ is returned unchanged. Referencing a missing param is an error.
+// Bind variables such as :startDate are plain text to the template and left as-is.
+func RenderSQLTemplate(sql string, params map[string]string) (string, error) {
+	if !strings.Contains(sql, "{{") {
+		return sql, nil
+	}
+	tmpl, err := template.New("sql").Option("missingkey=error").Parse(sql)
+	if err != nil {
+		return "", fmt.Errorf("invalid SQL template: %w", err)
+	}
+	if params == nil {
+		params = map[string]string{}
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, params); err != nil {
+		return "", fmt.Errorf("failed to execute SQL template: %w", err)
+	}
+	return b.String(), nil
+}
+
+// templateData returns the SQL template data of an entity, its sqlParams override global params
+func (e *Exporter) templateData(entity types.EntityState) map[string]string {
+	if len(e.cfg.GlobalParams) == 0 {
+		return entity.SQLParams
+	}
+	data := make(map[string]string, len(e.cfg.GlobalParams)+len(entity.SQLParams))
+	for k, v := range e.cfg.GlobalParams {
+		data[k] = v
+	}
+	for k, v := range entity.SQLParams {
+		data[k] = v
+	}
+	return data
+}
+
+// paramOverrides returns the entity's sqlParams that override global params as bind variables
+// The other global params are bound by the database layer.
+func (e *Exporter) paramOverrides(entity types.EntityState) map[string]interface{} {
+	binds := make(map[string]interface{}, len(e.cfg.GlobalParams)+2)
+	for k := range e.cfg.GlobalParams {
+		if v, ok := entity.SQLParams[k]; ok {
+			binds[k] = v
+		}
+	}
+	return binds
+}
+
+// sqlVariables returns the ${...} values available to an entity's SQL file
+func (e *Exporter) sqlVariables(entity types.EntityState, startDate string, runTime time.Time) map[string]string {
+	return map[string]string{
+		"NOW":        runTime.UTC().Format("2006-01-02T15:04:05"),
+		"DAYS_BACK":  strconv.Itoa(e.daysBack(entity)),
+		"ENTITY":     entity.Entity,
+		"START_DATE": startDate,
+	}
+}
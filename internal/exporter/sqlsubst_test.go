@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func TestSubstituteVariables(t *testing.T) {
+	e := &Exporter{cfg: &config.Config{DefaultDaysBack: 30}}
+	runTime := time.Date(2025, 1, 15, 8, 30, 0, 0, time.UTC)
+	vars := e.sqlVariables(types.EntityState{Entity: "crm.products"}, "2025-01-01T00:00:00", runTime)
+
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{"now", "WHERE ts < '${NOW}'", "WHERE ts < '2025-01-15T08:30:00'"},
+		{"days back", "WHERE ts > TRUNC(SYSDATE-${DAYS_BACK})", "WHERE ts > TRUNC(SYSDATE-30)"},
+		{"entity", "-- ${ENTITY}\nSELECT 1 FROM dual", "-- crm.products\nSELECT 1 FROM dual"},
+		{"start date", "WHERE ts >= '${START_DATE}'", "WHERE ts >= '2025-01-01T00:00:00'"},
+		{"repeated", "${ENTITY}/${ENTITY}", "crm.products/crm.products"},
+		{"unknown passed through", "SELECT '${OTHER}', '${now}' FROM dual", "SELECT '${OTHER}', '${now}' FROM dual"},
+		{"not a placeholder", "SELECT '$ENTITY', '${}' FROM dual", "SELECT '$ENTITY', '${}' FROM dual"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SubstituteVariables(tt.sql, vars); got != tt.want {
+				t.Errorf("SubstituteVariables() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderSQLTemplate(t *testing.T) {
+	params := map[string]string{"Table": "ORDERS_2024"}
+
+	tests := []struct {
+		name    string
+		sql     string
+		params  map[string]string
+		want    string
+		wantErr bool
+	}{
+		{"table", "SELECT * FROM {{.Table}} WHERE updated >= :startDate", params, "SELECT * FROM ORDERS_2024 WHERE updated >= :startDate", false},
+		{"no template", "SELECT '${ENTITY}' FROM dual", nil, "SELECT '${ENTITY}' FROM dual", false},
+		{"missing param", "SELECT * FROM {{.Schema}}.{{.Table}}", params, "", true},
+		{"missing params", "SELECT * FROM {{.Table}}", nil, "", true},
+		{"invalid template", "SELECT * FROM {{.Table", params, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderSQLTemplate(tt.sql, tt.params)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RenderSQLTemplate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("RenderSQLTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
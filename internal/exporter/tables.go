@@ -0,0 +1,87 @@
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+)
+
+// DefaultTimestampColumn is the column generated SQL templates filter the export window on
+const DefaultTimestampColumn = "LAST_UPDATED"
+
+// Table is a database table found by DiscoverTables
+type Table struct {
+	Owner string // Empty for tables of the connected user
+	Name  string
+}
+
+// Entity returns the entity name of the table, owner.table in lower case
+func (t Table) Entity() string {
+	return strings.ToLower(t.QualifiedName())
+}
+
+// QualifiedName returns the table name as used in SQL, OWNER.TABLE or TABLE
+func (t Table) QualifiedName() string {
+	if t.Owner == "" {
+		return t.Name
+	}
+	return t.Owner + "." + t.Name
+}
+
+// DiscoverTables lists the tables of schema from ALL_TABLES, or those of the connected user
+// from USER_TABLES when schema is empty, sorted by name. pattern, when set, is a regular
+// expression the whole table name must match, case-insensitive.
+func DiscoverTables(ctx context.Context, database db.DB, schema, pattern string) ([]Table, error) {
+	var match *regexp.Regexp
+	if pattern != "" {
+		var err error
+		if match, err = regexp.Compile("(?i)^(?:" + pattern + ")$"); err != nil {
+			return nil, fmt.Errorf("invalid table pattern: %w", err)
+		}
+	}
+
+	// Unquoted Oracle names are stored upper-cased
+	schema = strings.ToUpper(schema)
+	query := "SELECT table_name FROM user_tables ORDER BY table_name"
+	var args map[string]interface{}
+	if schema != "" {
+		query = "SELECT table_name FROM all_tables WHERE owner = :owner ORDER BY table_name"
+		args = map[string]interface{}{"owner": schema}
+	}
+
+	rows, err := database.QueryContext(ctx, query, args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var tables []Table
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to read table name: %w", err)
+		}
+		if match != nil && !match.MatchString(name) {
+			continue
+		}
+		tables = append(tables, Table{Owner: schema, Name: name})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	return tables, nil
+}
+
+// TableSQLTemplate returns a SQL file selecting all columns of table over the export window of tsColumn
+func TableSQLTemplate(table Table, tsColumn string) string {
+	return fmt.Sprintf(`-- Generated by ora2csv discover, review the columns and filter before exporting
+SELECT *
+FROM %[1]s
+WHERE %[2]s >= TO_DATE(:startDate, 'YYYY-MM-DD"T"HH24:MI:SS')
+  AND %[2]s < TO_DATE(:tillDate, 'YYYY-MM-DD"T"HH24:MI:SS')
+ORDER BY %[2]s ASC
+`, table.QualifiedName(), tsColumn)
+}
@@ -0,0 +1,58 @@
+package exporter
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+// catalogSchema mimics the Oracle table catalog views
+const catalogSchema = `
+CREATE TABLE all_tables (owner TEXT, table_name TEXT);
+CREATE TABLE user_tables (table_name TEXT);
+INSERT INTO all_tables VALUES ('CRM', 'ORDERS'), ('CRM', 'AUDIT_LOG'), ('CRM', 'LOGIN_LOG'), ('CRM', 'LOGINS'), ('HR', 'EMPLOYEES');
+INSERT INTO user_tables VALUES ('SETTINGS');
+`
+
+func TestDiscoverTables(t *testing.T) {
+	sqlitetest.Run(t, catalogSchema, func(database db.DB) {
+		tables, err := DiscoverTables(context.Background(), database, "crm", ".*_log")
+		testutil.AssertNoError(t, err)
+		want := []Table{{Owner: "CRM", Name: "AUDIT_LOG"}, {Owner: "CRM", Name: "LOGIN_LOG"}}
+		if !reflect.DeepEqual(tables, want) {
+			t.Errorf("tables = %v, want %v", tables, want)
+		}
+
+		tables, err = DiscoverTables(context.Background(), database, "", "")
+		testutil.AssertNoError(t, err)
+		if len(tables) != 1 || tables[0].Entity() != "settings" {
+			t.Errorf("user tables = %v, want [SETTINGS]", tables)
+		}
+
+		if _, err := DiscoverTables(context.Background(), database, "crm", "("); err == nil {
+			t.Error("expected error for invalid pattern")
+		}
+	})
+}
+
+func TestTableSQLTemplate(t *testing.T) {
+	table := Table{Owner: "CRM", Name: "ORDERS"}
+	testutil.AssertEqual(t, "crm.orders", table.Entity())
+
+	sql := TableSQLTemplate(table, "MODIFIED_AT")
+	for _, want := range []string{
+		"FROM CRM.ORDERS\n",
+		"WHERE MODIFIED_AT >= TO_DATE(:startDate,",
+		"AND MODIFIED_AT < TO_DATE(:tillDate,",
+		"ORDER BY MODIFIED_AT ASC",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("template missing %q:\n%s", want, sql)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package exporter
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// LatestExports returns the newest successfully exported local file of each entity
+// ManifestFile is read first, then the run manifests in ManifestDir from newest to oldest,
+// so entities skipped by the last run are found in an earlier one. Uploaded files and
+// runs without output are ignored. Missing manifests return an empty map.
+func LatestExports(exportDir string) (map[string]types.ManifestEntity, error) {
+	latest := make(map[string]types.ManifestEntity)
+	err := forEachManifest(exportDir, func(manifest *types.ExportManifest) {
+		for _, entity := range manifest.Entities {
+			if _, ok := latest[entity.Entity]; ok || !entity.Success || entity.FilePath == "" {
+				continue
+			}
+			latest[entity.Entity] = entity
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return latest, nil
+}
+
+// previousRowCounts returns the row count of the newest successful export of each entity
+// Unlike LatestExports, uploaded files count too.
+func previousRowCounts(exportDir string) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := forEachManifest(exportDir, func(manifest *types.ExportManifest) {
+		for _, entity := range manifest.Entities {
+			if _, ok := counts[entity.Entity]; ok || !entity.Success {
+				continue
+			}
+			counts[entity.Entity] = entity.RowCount
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+// forEachManifest calls fn with ManifestFile and then the run manifests in ManifestDir from
+// newest to oldest. Missing manifests and dry runs are skipped.
+func forEachManifest(exportDir string, fn func(manifest *types.ExportManifest)) error {
+	paths := []string{filepath.Join(exportDir, ManifestFile)}
+	history, err := listManifests(filepath.Join(exportDir, ManifestDir))
+	if err != nil {
+		return err
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].time.After(history[j].time) })
+	for _, m := range history {
+		paths = append(paths, m.path)
+	}
+
+	for _, path := range paths {
+		manifest, err := types.LoadManifest(path)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return err
+		}
+		if manifest.DryRun {
+			continue
+		}
+		fn(manifest)
+	}
+	return nil
+}
+
+// ExportFiles returns the local files of a manifest record, the parts of a split export in order
+func ExportFiles(entity types.ManifestEntity) []string {
+	if len(entity.Parts) > 0 {
+		return entity.Parts
+	}
+	return []string{entity.FilePath}
+}
+
+// CountExportRows counts the data rows of an export file written with opts
+// CSV files are counted by records outside quotes without the header row, JSON Lines files
+// by lines and Parquet files from their metadata. Files ending in .gz are decompressed.
+func CountExportRows(path string, opts CSVWriterOptions) (int, error) {
+	if opts.Format == FormatParquet {
+		return countParquetRows(path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	var r io.Reader = file
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return 0, fmt.Errorf("failed to read gzip export file: %w", err)
+		}
+		defer func() { _ = gz.Close() }()
+		r = gz
+	}
+
+	if opts.Format == FormatJSONLines {
+		return countRecords(r, nil, []byte("\n"))
+	}
+
+	quote := opts.QuoteChar
+	if quote == 0 {
+		quote = '"'
+	}
+	recordSep := opts.RecordSeparator
+	if recordSep == "" {
+		recordSep = "\n"
+	}
+	records, err := countRecords(r, []byte(string(quote)), []byte(recordSep))
+	if err != nil || records == 0 {
+		return 0, err
+	}
+	return records - 1, nil
+}
+
+// countRecords counts the record separators of r outside quoted fields
+// A nil quote disables quoting. Doubled quotes toggle twice and leave the state unchanged.
+func countRecords(r io.Reader, quote, recordSep []byte) (int, error) {
+	br := bufio.NewReader(r)
+	tail := make([]byte, 0, max(len(quote), len(recordSep)))
+	quoted := false
+	count := 0
+	for {
+		b, err := br.ReadByte()
+		if err == io.EOF {
+			return count, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read export file: %w", err)
+		}
+
+		if len(tail) == cap(tail) {
+			tail = append(tail[:0], tail[1:]...)
+		}
+		tail = append(tail, b)
+		switch {
+		case quote != nil && bytes.HasSuffix(tail, quote):
+			quoted = !quoted
+			tail = tail[:0]
+		case !quoted && bytes.HasSuffix(tail, recordSep):
+			count++
+			tail = tail[:0]
+		}
+	}
+}
+
+// countParquetRows returns the row count stored in the Parquet file footer
+func countParquetRows(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open export file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat export file: %w", err)
+	}
+	pf, err := parquet.OpenFile(file, info.Size())
+	if err != nil {
+		return 0, fmt.Errorf("failed to read Parquet export file: %w", err)
+	}
+	return int(pf.NumRows()), nil
+}
+
+// RecountRows counts the rows the SQL of an entity returns over a past export window
+// startDate and tillDate use the 2006-01-02T15:04:05 layout, like the manifest start_date and
+// till_date. Sequence entities cannot be recounted since their start value is not recorded.
+func (e *Exporter) RecountRows(ctx context.Context, entityName, startDate, tillDate string) (int, error) {
+	entity, ok := e.st.FindEntity(entityName)
+	if !ok {
+		return 0, fmt.Errorf("entity not found in state: %s", entityName)
+	}
+	if entity.IsSequence() {
+		return 0, fmt.Errorf("sequence entity %s cannot be recounted", entityName)
+	}
+	if startDate == "" || tillDate == "" {
+		return 0, fmt.Errorf("export window of %s is not recorded in the manifest", entityName)
+	}
+	runTime, err := time.ParseInLocation("2006-01-02T15:04:05", tillDate, time.UTC)
+	if err != nil {
+		return 0, fmt.Errorf("invalid till date: %w", err)
+	}
+
+	sqlContent, err := e.loadSQLFile(*entity, e.sqlVariables(*entity, startDate, runTime))
+	if err != nil {
+		return 0, fmt.Errorf("failed to load SQL file: %w", err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, e.cfg.QueryTimeout)
+	defer cancel()
+	params, _, err := e.queryParams(queryCtx, *entity, sqlContent, startDate, tillDate)
+	if err != nil {
+		return 0, err
+	}
+	return e.countRows(queryCtx, sqlContent, params)
+}
+
+// CSVOptions returns the output options of the export files, e.g. for CountExportRows
+func (e *Exporter) CSVOptions() CSVWriterOptions {
+	return e.csvOptions()
+}
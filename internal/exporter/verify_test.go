@@ -0,0 +1,119 @@
+package exporter
+
+import (
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func TestCountExportRows(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	gzPath := filepath.Join(tmpDir, "crm.orders__2025-01-01T00-00-00.csv.gz")
+	file, err := os.Create(gzPath)
+	if err != nil {
+		t.Fatalf("Create() error: %v", err)
+	}
+	gz := gzip.NewWriter(file)
+	_, _ = gz.Write([]byte("ID\n1\n2\n3\n"))
+	_ = gz.Close()
+	_ = file.Close()
+
+	tests := []struct {
+		name    string
+		content string
+		opts    CSVWriterOptions
+		want    int
+	}{
+		{"header only", "ID,NAME\n", CSVWriterOptions{}, 0},
+		{"quoted line breaks", "ID,NOTE\n1,\"a\nb\"\n2,\"say \"\"hi\"\"\"\n", CSVWriterOptions{}, 2},
+		{"CRLF", "ID\r\n1\r\n2\r\n", CSVWriterOptions{RecordSeparator: "\r\n"}, 2},
+		{"custom quote and separator", "ID;NOTE|\n1;'a|\nb'|\n", CSVWriterOptions{QuoteChar: '\'', RecordSeparator: "|\n"}, 1},
+		{"JSON Lines", "{\"ID\":\"1\"}\n{\"ID\":\"2\"}\n", CSVWriterOptions{Format: FormatJSONLines}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(tmpDir, "export.out")
+			mustWriteTestFile(t, path, tt.content)
+			got, err := CountExportRows(path, tt.opts)
+			if err != nil {
+				t.Fatalf("CountExportRows() error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("CountExportRows() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+
+	if got, err := CountExportRows(gzPath, CSVWriterOptions{Compress: CompressGzip}); err != nil || got != 3 {
+		t.Errorf("CountExportRows() of gzip file = %d, %v, want 3", got, err)
+	}
+	if _, err := CountExportRows(filepath.Join(tmpDir, "missing.csv"), CSVWriterOptions{}); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestLatestExports(t *testing.T) {
+	exportDir := t.TempDir()
+	historyDir := filepath.Join(exportDir, ManifestDir)
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		t.Fatalf("MkdirAll() error: %v", err)
+	}
+
+	older := &types.ExportResult{
+		RunTime: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Results: []types.EntityResult{
+			{Entity: "crm.orders", Success: true, RowCount: 5, FilePath: "orders-old.csv"},
+			{Entity: "crm.users", Success: true, RowCount: 7, FilePath: "users-old.csv"},
+		},
+	}
+	latest := &types.ExportResult{
+		RunTime: time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC),
+		Results: []types.EntityResult{
+			{Entity: "crm.orders", Success: true, RowCount: 6, FilePath: "orders-new.csv"},
+			{Entity: "crm.users", Success: false},
+			{Entity: "crm.empty", Success: true},
+		},
+	}
+	for _, result := range []*types.ExportResult{older, latest} {
+		if err := types.WriteManifest(filepath.Join(historyDir, manifestName(result.RunTime)), result); err != nil {
+			t.Fatalf("WriteManifest() error: %v", err)
+		}
+	}
+	if err := types.WriteManifest(filepath.Join(exportDir, ManifestFile), latest); err != nil {
+		t.Fatalf("WriteManifest() error: %v", err)
+	}
+
+	exports, err := LatestExports(exportDir)
+	if err != nil {
+		t.Fatalf("LatestExports() error: %v", err)
+	}
+	if len(exports) != 2 {
+		t.Fatalf("LatestExports() = %+v, want crm.orders and crm.users", exports)
+	}
+	if got := exports["crm.orders"]; got.FilePath != "orders-new.csv" || got.RowCount != 6 {
+		t.Errorf("crm.orders = %+v, want the latest run", got)
+	}
+	if got := exports["crm.users"]; got.FilePath != "users-old.csv" || got.RowCount != 7 {
+		t.Errorf("crm.users = %+v, want the last successful run", got)
+	}
+
+	if exports, err := LatestExports(filepath.Join(exportDir, "missing")); err != nil || len(exports) != 0 {
+		t.Errorf("LatestExports() of a missing directory = %+v, %v, want none", exports, err)
+	}
+
+	// Progress estimates also use exports without a local file
+	counts, err := previousRowCounts(exportDir)
+	if err != nil {
+		t.Fatalf("previousRowCounts() error: %v", err)
+	}
+	want := map[string]int{"crm.orders": 6, "crm.users": 7, "crm.empty": 0}
+	if !reflect.DeepEqual(counts, want) {
+		t.Errorf("previousRowCounts() = %v, want %v", counts, want)
+	}
+}
@@ -0,0 +1,51 @@
+package logging
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entry is a single log record passed to a Formatter
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Entity  string
+	Message string
+	Fields  map[string]string
+}
+
+// Formatter turns a log entry into one output line without the trailing newline
+type Formatter interface {
+	Format(entry Entry) string
+}
+
+// JSONFormatter writes each entry as a single JSON object
+type JSONFormatter struct{}
+
+type jsonEntry struct {
+	Time   string            `json:"time"`
+	Level  string            `json:"level"`
+	Entity string            `json:"entity"`
+	Msg    string            `json:"msg"`
+	Fields map[string]string `json:"fields"`
+}
+
+// Format implements Formatter
+func (JSONFormatter) Format(entry Entry) string {
+	fields := entry.Fields
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	data, err := json.Marshal(jsonEntry{
+		Time:   entry.Time.Format(time.RFC3339),
+		Level:  entry.Level.String(),
+		Entity: entry.Entity,
+		Msg:    entry.Message,
+		Fields: fields,
+	})
+	if err != nil {
+		// Only strings are encoded, so this is not expected to happen
+		return `{"level":"error","msg":"failed to encode log entry"}`
+	}
+	return string(data)
+}
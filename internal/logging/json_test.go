@@ -0,0 +1,59 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestLogger_JSONFormat(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewWithWriter(&buf, false)
+	logger.SetFormat(FormatJSON)
+
+	entityLogger := logger.WithEntity("crm.orders", "").WithField("attempt", "2")
+	entityLogger.Info("retrying: %s", "ORA-03113")
+	logger.Info("done")
+	logger.Debug("hidden")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %d: %q", len(lines), buf.String())
+	}
+
+	var entry struct {
+		Time   string            `json:"time"`
+		Level  string            `json:"level"`
+		Entity string            `json:"entity"`
+		Msg    string            `json:"msg"`
+		Fields map[string]string `json:"fields"`
+	}
+	if err := json.Unmarshal([]byte(lines[0]), &entry); err != nil {
+		t.Fatalf("invalid JSON %q: %v", lines[0], err)
+	}
+	if entry.Level != "info" || entry.Entity != "crm.orders" || entry.Msg != "retrying: ORA-03113" {
+		t.Errorf("unexpected entry: %+v", entry)
+	}
+	if entry.Fields["attempt"] != "2" || entry.Time == "" {
+		t.Errorf("unexpected fields or time: %+v", entry)
+	}
+
+	if want := `"fields":{}`; !strings.Contains(lines[1], want) {
+		t.Errorf("line %q does not contain %s", lines[1], want)
+	}
+}
+
+func TestLogger_WithField(t *testing.T) {
+	var buf bytes.Buffer
+	parent := NewWithWriter(&buf, false)
+	child := parent.WithField("b", "2").WithField("a", "1")
+
+	if len(parent.fields) != 0 {
+		t.Errorf("parent fields = %v, want none", parent.fields)
+	}
+	child.Info("hello")
+	if !strings.HasSuffix(strings.TrimSpace(buf.String()), "hello a=1 b=2") {
+		t.Errorf("unexpected text output: %q", buf.String())
+	}
+}
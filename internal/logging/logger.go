@@ -5,6 +5,8 @@ import (
 	"io"
 	"log"
 	"os"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 )
@@ -18,23 +20,49 @@ const (
 	LevelDebug
 )
 
+// Log formats
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// String returns the lower case level name
+func (lv Level) String() string {
+	switch lv {
+	case LevelError:
+		return "error"
+	case LevelDebug:
+		return "debug"
+	default:
+		return "info"
+	}
+}
+
 // Logger provides thread-safe logging with timestamps
 type Logger struct {
 	mu     *sync.Mutex
 	writer io.Writer
 	level  Level
-	file   *os.File
+	file   io.Closer
 	prefix string
 	std    *log.Logger
+
+	// formatter writes entries when set, otherwise the text format is used
+	formatter Formatter
+	fields    map[string]string
 }
 
 // New creates a new Logger
 func New(verbose bool) *Logger {
+	return NewWithWriter(os.Stdout, verbose)
+}
+
+// NewWithWriter creates a new Logger that writes to the given writer
+func NewWithWriter(writer io.Writer, verbose bool) *Logger {
 	level := LevelInfo
 	if verbose {
 		level = LevelDebug
 	}
-	writer := os.Stdout
 
 	return &Logger{
 		mu:     &sync.Mutex{},
@@ -46,13 +74,19 @@ func New(verbose bool) *Logger {
 
 // NewWithFile creates a new Logger that writes to both file and stdout
 func NewWithFile(path string, verbose bool) (*Logger, error) {
-	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	return NewWithRotatingFile(os.Stdout, path, verbose, 0, 0)
+}
+
+// NewWithRotatingFile creates a new Logger that writes to console and appends to a log file
+// The file is rotated when it exceeds maxBytes (0 disables), keeping at most keep old files.
+func NewWithRotatingFile(console io.Writer, path string, verbose bool, maxBytes int64, keep int) (*Logger, error) {
+	file, err := NewRotatingWriter(path, maxBytes, keep)
 	if err != nil {
-		return nil, fmt.Errorf("failed to open log file: %w", err)
+		return nil, err
 	}
 
-	// Multi-writer for both file and stdout
-	multiWriter := io.MultiWriter(os.Stdout, file)
+	// Multi-writer for both file and console
+	multiWriter := io.MultiWriter(console, file)
 
 	level := LevelInfo
 	if verbose {
@@ -83,6 +117,17 @@ func (l *Logger) SetPrefix(prefix string) {
 	l.prefix = prefix
 }
 
+// SetFormat switches between the text and JSON log formats
+func (l *Logger) SetFormat(format string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if format == FormatJSON {
+		l.formatter = JSONFormatter{}
+	} else {
+		l.formatter = nil
+	}
+}
+
 // formatTimestamp returns a formatted timestamp
 func (l *Logger) formatTimestamp() string {
 	return time.Now().Format("2006-01-02 15:04:05")
@@ -97,13 +142,40 @@ func (l *Logger) log(level Level, format string, args ...interface{}) {
 	l.mu.Lock()
 	defer l.mu.Unlock()
 
+	msg := fmt.Sprintf(format, args...)
+	if l.formatter != nil {
+		l.std.Println(l.formatter.Format(Entry{
+			Time:    time.Now(),
+			Level:   level,
+			Entity:  l.prefix,
+			Message: msg,
+			Fields:  l.fields,
+		}))
+		return
+	}
+
 	prefix := l.prefix
 	if prefix != "" {
 		prefix = "[" + prefix + "] "
 	}
+	l.std.Printf("[%s] %s%s%s\n", l.formatTimestamp(), prefix, msg, textFields(l.fields))
+}
 
-	msg := fmt.Sprintf(format, args...)
-	l.std.Printf("[%s] %s%s\n", l.formatTimestamp(), prefix, msg)
+// textFields formats fields as " key=value" pairs sorted by key
+func textFields(fields map[string]string) string {
+	if len(fields) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	for _, k := range keys {
+		b.WriteString(" " + k + "=" + fields[k])
+	}
+	return b.String()
 }
 
 // Info logs an info message
@@ -122,19 +194,46 @@ func (l *Logger) Debug(format string, args ...interface{}) {
 }
 
 // WithPrefix returns a new logger with the given prefix
+// The format and fields of the parent are kept.
 func (l *Logger) WithPrefix(prefix string) *Logger {
+	child := l.clone()
+	child.prefix = prefix
+	return child
+}
+
+// WithField returns a new logger that adds the key-value pair to every message
+func (l *Logger) WithField(key, value string) *Logger {
+	child := l.clone()
+	child.fields = make(map[string]string, len(l.fields)+1)
+	for k, v := range l.fields {
+		child.fields[k] = v
+	}
+	child.fields[key] = value
+	return child
+}
+
+// clone copies the logger, sharing its writer and lock
+func (l *Logger) clone() *Logger {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return &Logger{
-		mu:     l.mu,
-		writer: l.writer,
-		level:  l.level,
-		file:   l.file,
-		prefix: prefix,
-		std:    l.std,
+		mu:        l.mu,
+		writer:    l.writer,
+		level:     l.level,
+		file:      l.file,
+		prefix:    l.prefix,
+		std:       l.std,
+		formatter: l.formatter,
+		fields:    l.fields,
 	}
 }
 
 // WithEntity returns a new logger with entity prefix
-func (l *Logger) WithEntity(entity string) *Logger {
+// The display name is used as the prefix when set, otherwise the entity name
+func (l *Logger) WithEntity(entity, displayName string) *Logger {
+	if displayName != "" {
+		return l.WithPrefix(displayName)
+	}
 	return l.WithPrefix(entity)
 }
 
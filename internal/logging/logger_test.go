@@ -113,13 +113,22 @@ func TestLogger_WithPrefix(t *testing.T) {
 
 func TestLogger_WithEntity(t *testing.T) {
 	logger := New(false)
-	entityLogger := logger.WithEntity("test.entity")
+	entityLogger := logger.WithEntity("test.entity", "")
 
 	if entityLogger.prefix != "test.entity" {
 		t.Errorf("prefix = %q, want %q", entityLogger.prefix, "test.entity")
 	}
 }
 
+func TestLogger_WithEntity_DisplayName(t *testing.T) {
+	logger := New(false)
+	entityLogger := logger.WithEntity("schema.vw_order_detail_audit", "Order audit")
+
+	if entityLogger.prefix != "Order audit" {
+		t.Errorf("prefix = %q, want %q", entityLogger.prefix, "Order audit")
+	}
+}
+
 func TestLogger_LogLevels(t *testing.T) {
 	// Note: The logger uses log.Printf which writes to os.Stdout directly.
 	// These tests verify the logger doesn't panic and handles different log levels.
@@ -0,0 +1,145 @@
+// Package metrics exposes export progress to Prometheus
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/common/expfmt"
+)
+
+// DurationBuckets are the upper bounds in seconds of the entity duration histogram
+var DurationBuckets = []float64{1, 5, 10, 30, 60, 120, 300, 600, 1800, 3600}
+
+// Registry holds the export metrics in a dedicated Prometheus registry
+// Only the ora2csv metrics are registered, not the Go runtime collectors.
+// All methods are safe for concurrent use.
+type Registry struct {
+	registry  *prometheus.Registry
+	active    prometheus.Gauge
+	rows      *prometheus.CounterVec
+	errors    *prometheus.CounterVec
+	durations *prometheus.HistogramVec
+}
+
+// NewRegistry creates a registry with the export metrics
+func NewRegistry() *Registry {
+	r := &Registry{
+		registry: prometheus.NewRegistry(),
+		active: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ora2csv_export_active",
+			Help: "Whether an export run is in progress.",
+		}),
+		rows: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ora2csv_entity_rows_total",
+			Help: "Rows exported per entity.",
+		}, []string{"entity"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ora2csv_export_errors_total",
+			Help: "Failed entity exports.",
+		}, []string{"entity"}),
+		durations: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ora2csv_entity_duration_seconds",
+			Help:    "Entity export duration.",
+			Buckets: DurationBuckets,
+		}, []string{"entity"}),
+	}
+	r.registry.MustRegister(r.active, r.rows, r.errors, r.durations)
+	return r
+}
+
+// SetActive sets the export active gauge
+func (r *Registry) SetActive(active bool) {
+	if active {
+		r.active.Set(1)
+	} else {
+		r.active.Set(0)
+	}
+}
+
+// ObserveEntity records a finished entity export
+func (r *Registry) ObserveEntity(entity string, rows int, duration time.Duration, failed bool) {
+	r.rows.WithLabelValues(entity).Add(float64(rows))
+	// The error counter starts at 0 so rate() works from the first failure
+	errs := r.errors.WithLabelValues(entity)
+	if failed {
+		errs.Inc()
+	}
+	r.durations.WithLabelValues(entity).Observe(duration.Seconds())
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	families, err := r.registry.Gather()
+	if err != nil {
+		return 0, fmt.Errorf("failed to gather metrics: %w", err)
+	}
+	var buf bytes.Buffer
+	enc := expfmt.NewEncoder(&buf, expfmt.NewFormat(expfmt.TypeTextPlain))
+	for _, family := range families {
+		if err := enc.Encode(family); err != nil {
+			return 0, fmt.Errorf("failed to encode metrics: %w", err)
+		}
+	}
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP serves the metrics to a Prometheus scrape
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{}).ServeHTTP(w, req)
+}
+
+// Server serves a Registry at /metrics
+type Server struct {
+	server   *http.Server
+	listener net.Listener
+	done     chan struct{}
+}
+
+// Serve listens on addr and serves reg in the background until ctx is cancelled
+// Listen errors, e.g. an address in use, are returned before serving starts.
+func Serve(ctx context.Context, addr string, reg *Registry) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", reg)
+	s := &Server{
+		server:   &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	go func() {
+		defer close(s.done)
+		if err := s.server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			_ = listener.Close()
+		}
+	}()
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = s.server.Shutdown(shutdownCtx)
+	}()
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *Server) Addr() string {
+	return s.listener.Addr().String()
+}
+
+// Done is closed once the server has stopped
+func (s *Server) Done() <-chan struct{} {
+	return s.done
+}
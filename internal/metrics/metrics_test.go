@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	reg := NewRegistry()
+	reg.SetActive(true)
+	reg.ObserveEntity("crm.orders", 120, 3*time.Second, false)
+	reg.ObserveEntity("crm.orders", 30, 45*time.Second, false)
+	reg.ObserveEntity(`crm."quoted"`, 0, 500*time.Millisecond, true)
+
+	var b strings.Builder
+	if _, err := reg.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		"# TYPE ora2csv_export_active gauge\nora2csv_export_active 1\n",
+		"# TYPE ora2csv_entity_rows_total counter\n",
+		`ora2csv_entity_rows_total{entity="crm.orders"} 150`,
+		`ora2csv_export_errors_total{entity="crm.orders"} 0`,
+		`ora2csv_export_errors_total{entity="crm.\"quoted\""} 1`,
+		"# TYPE ora2csv_entity_duration_seconds histogram\n",
+		`ora2csv_entity_duration_seconds_bucket{entity="crm.orders",le="1"} 0`,
+		`ora2csv_entity_duration_seconds_bucket{entity="crm.orders",le="5"} 1`,
+		`ora2csv_entity_duration_seconds_bucket{entity="crm.orders",le="60"} 2`,
+		`ora2csv_entity_duration_seconds_bucket{entity="crm.orders",le="+Inf"} 2`,
+		`ora2csv_entity_duration_seconds_sum{entity="crm.orders"} 48`,
+		`ora2csv_entity_duration_seconds_count{entity="crm.orders"} 2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	reg.SetActive(false)
+	b.Reset()
+	_, _ = reg.WriteTo(&b)
+	if !strings.Contains(b.String(), "ora2csv_export_active 0\n") {
+		t.Errorf("active gauge not reset:\n%s", b.String())
+	}
+}
+
+func TestServe(t *testing.T) {
+	reg := NewRegistry()
+	reg.ObserveEntity("crm.orders", 5, time.Second, false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server, err := Serve(ctx, "127.0.0.1:0", reg)
+	if err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+
+	resp, err := http.Get("http://" + server.Addr() + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics error = %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), `ora2csv_entity_rows_total{entity="crm.orders"} 5`) {
+		t.Errorf("unexpected metrics:\n%s", body)
+	}
+
+	// The server stops with the context
+	cancel()
+	select {
+	case <-server.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop after cancel")
+	}
+
+	if _, err := Serve(context.Background(), "not-an-address", reg); err == nil {
+		t.Error("Serve() with invalid address should fail")
+	}
+}
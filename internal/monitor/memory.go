@@ -0,0 +1,73 @@
+package monitor
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"time"
+)
+
+// ErrMemoryLimitExceeded is the cancellation cause when heap usage exceeds the limit
+var ErrMemoryLimitExceeded = errors.New("memory limit exceeded")
+
+// defaultSampleInterval is how often the heap size is sampled
+const defaultSampleInterval = time.Second
+
+// MemoryWatcher cancels work when the Go heap grows beyond a limit
+type MemoryWatcher struct {
+	limit    uint64
+	interval time.Duration
+	heapSize func() uint64
+}
+
+// NewMemoryWatcher creates a watcher for a heap limit in megabytes
+func NewMemoryWatcher(limitMB int) *MemoryWatcher {
+	return &MemoryWatcher{
+		limit:    uint64(limitMB) * 1024 * 1024,
+		interval: defaultSampleInterval,
+		heapSize: heapAlloc,
+	}
+}
+
+// Limit returns the heap limit in bytes
+func (w *MemoryWatcher) Limit() uint64 {
+	return w.limit
+}
+
+// Watch returns a context that is cancelled with ErrMemoryLimitExceeded once
+// HeapAlloc exceeds the limit. The returned stop function must be called to
+// release the sampling goroutine.
+func (w *MemoryWatcher) Watch(ctx context.Context) (context.Context, context.CancelFunc) {
+	watchCtx, cancel := context.WithCancelCause(ctx)
+
+	go func() {
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-watchCtx.Done():
+				return
+			case <-ticker.C:
+				if w.heapSize() > w.limit {
+					cancel(ErrMemoryLimitExceeded)
+					return
+				}
+			}
+		}
+	}()
+
+	return watchCtx, func() { cancel(context.Canceled) }
+}
+
+// Exceeded reports whether ctx was cancelled by the watcher
+func Exceeded(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrMemoryLimitExceeded)
+}
+
+// heapAlloc returns the bytes of allocated heap objects
+func heapAlloc() uint64 {
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	return stats.HeapAlloc
+}
@@ -0,0 +1,70 @@
+package monitor
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestMemoryWatcher_FiresAboveLimit(t *testing.T) {
+	w := NewMemoryWatcher(1)
+	w.interval = 5 * time.Millisecond
+	w.heapSize = func() uint64 { return 2 * 1024 * 1024 }
+
+	ctx, stop := w.Watch(context.Background())
+	defer stop()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not cancel the context")
+	}
+	if !Exceeded(ctx) {
+		t.Errorf("cause = %v, want ErrMemoryLimitExceeded", context.Cause(ctx))
+	}
+}
+
+func TestMemoryWatcher_BelowLimit(t *testing.T) {
+	w := NewMemoryWatcher(100)
+	w.interval = 5 * time.Millisecond
+	w.heapSize = func() uint64 { return 1024 }
+
+	ctx, stop := w.Watch(context.Background())
+	time.Sleep(30 * time.Millisecond)
+	if ctx.Err() != nil {
+		t.Fatalf("context cancelled below the limit: %v", context.Cause(ctx))
+	}
+
+	stop()
+	if Exceeded(ctx) {
+		t.Error("stop reported as memory limit exceeded")
+	}
+}
+
+func TestMemoryWatcher_RealHeap(t *testing.T) {
+	// Keep a live allocation and set the limit below the resulting heap
+	ballast := make([]byte, 16*1024*1024)
+	for i := range ballast {
+		ballast[i] = 1
+	}
+	var stats runtime.MemStats
+	runtime.ReadMemStats(&stats)
+	limitMB := int(stats.HeapAlloc/(1024*1024)) - 8
+
+	w := NewMemoryWatcher(limitMB)
+	w.interval = 5 * time.Millisecond
+
+	ctx, stop := w.Watch(context.Background())
+	defer stop()
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("watcher did not fire with %d MB heap limit", limitMB)
+	}
+	if !Exceeded(ctx) {
+		t.Errorf("cause = %v, want ErrMemoryLimitExceeded", context.Cause(ctx))
+	}
+	runtime.KeepAlive(ballast)
+}
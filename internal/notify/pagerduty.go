@@ -0,0 +1,228 @@
+// Package notify sends export run notifications to external services
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+const (
+	// DefaultPagerDutyEndpoint is the PagerDuty Events API v2 enqueue URL
+	DefaultPagerDutyEndpoint = "https://events.pagerduty.com/v2/enqueue"
+	// PagerDutyIncidentFile tracks the open incident in the export directory
+	PagerDutyIncidentFile = ".pagerduty-incident"
+
+	// maxSummaryLen is the PagerDuty limit for the event summary
+	maxSummaryLen = 1024
+)
+
+// PagerDuty triggers an incident when an export run fails or has too many failed entities
+// The open incident is recorded in IncidentFile and resolved by the next run below
+// the threshold with the same routing key.
+type PagerDuty struct {
+	RoutingKey   string
+	Threshold    int
+	Endpoint     string
+	IncidentFile string
+	HTTPClient   *http.Client
+}
+
+// NewPagerDuty creates a notifier that records open incidents in exportDir
+func NewPagerDuty(routingKey string, threshold int, exportDir string) *PagerDuty {
+	return &PagerDuty{
+		RoutingKey:   routingKey,
+		Threshold:    threshold,
+		Endpoint:     DefaultPagerDutyEndpoint,
+		IncidentFile: filepath.Join(exportDir, PagerDutyIncidentFile),
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// pagerDutyEvent is an Events API v2 request
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+// pagerDutyPayload describes a triggered alert
+type pagerDutyPayload struct {
+	Summary       string      `json:"summary"`
+	Source        string      `json:"source"`
+	Severity      string      `json:"severity"`
+	Component     string      `json:"component"`
+	CustomDetails interface{} `json:"custom_details,omitempty"`
+}
+
+// pagerDutyIncident is the content of the incident file
+type pagerDutyIncident struct {
+	DedupKey   string `json:"dedupKey"`
+	RoutingKey string `json:"routingKey"` // SHA-256 of the routing key
+}
+
+// Notify triggers or resolves an incident for an export result
+// A run aborted with runErr always triggers one, result is then nil or partial.
+// It returns the event action sent, or an empty string when nothing was sent.
+func (p *PagerDuty) Notify(ctx context.Context, result *types.ExportResult, runErr error) (string, error) {
+	open, err := p.loadIncident()
+	if err != nil {
+		return "", err
+	}
+
+	if runErr != nil || result.FailedCount >= p.Threshold {
+		var durationMs int64
+		if result != nil {
+			durationMs = result.Duration.Milliseconds()
+		}
+		event := pagerDutyEvent{
+			RoutingKey:  p.RoutingKey,
+			EventAction: "trigger",
+			Payload: &pagerDutyPayload{
+				Summary:       failureSummary(result, runErr),
+				Source:        source(),
+				Severity:      "error",
+				Component:     "ora2csv",
+				CustomDetails: types.NewMachineReadableSummary(result, runErr, durationMs),
+			},
+		}
+		// Repeated failures update the open incident
+		if open != nil {
+			event.DedupKey = open.DedupKey
+		}
+		dedupKey, err := p.send(ctx, event)
+		if err != nil {
+			return "", err
+		}
+		return event.EventAction, p.saveIncident(dedupKey)
+	}
+
+	if open == nil {
+		return "", nil
+	}
+	if _, err := p.send(ctx, pagerDutyEvent{RoutingKey: p.RoutingKey, EventAction: "resolve", DedupKey: open.DedupKey}); err != nil {
+		return "", err
+	}
+	if err := os.Remove(p.IncidentFile); err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to remove incident file: %w", err)
+	}
+	return "resolve", nil
+}
+
+// failureSummary lists the failed entities, or the run error, within the PagerDuty summary limit
+func failureSummary(result *types.ExportResult, runErr error) string {
+	var summary string
+	if runErr != nil {
+		summary = "ora2csv: export failed: " + runErr.Error()
+	} else {
+		var failed []string
+		for _, r := range result.Results {
+			if !r.Success {
+				failed = append(failed, r.Entity)
+			}
+		}
+		summary = fmt.Sprintf("ora2csv: %d of %d entities failed: %s", result.FailedCount, result.ProcessedCount, strings.Join(failed, ", "))
+	}
+	if len(summary) > maxSummaryLen {
+		summary = summary[:maxSummaryLen-3] + "..."
+	}
+	return summary
+}
+
+// source identifies the host running the export
+func source() string {
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return host
+	}
+	return "ora2csv"
+}
+
+// send posts an event and returns the dedup key assigned by PagerDuty
+func (p *PagerDuty) send(ctx context.Context, event pagerDutyEvent) (string, error) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal PagerDuty event: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("failed to create PagerDuty request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := p.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send PagerDuty %s event: %w", event.EventAction, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("PagerDuty %s event rejected: %s: %s", event.EventAction, resp.Status, strings.TrimSpace(string(respBody)))
+	}
+
+	var result struct {
+		DedupKey string `json:"dedup_key"`
+	}
+	if err := json.Unmarshal(respBody, &result); err != nil || result.DedupKey == "" {
+		return event.DedupKey, nil
+	}
+	return result.DedupKey, nil
+}
+
+// routingKeyHash identifies the routing key in the incident file without storing it
+func (p *PagerDuty) routingKeyHash() string {
+	sum := sha256.Sum256([]byte(p.RoutingKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// loadIncident returns the open incident for this routing key, nil if there is none
+func (p *PagerDuty) loadIncident() (*pagerDutyIncident, error) {
+	data, err := os.ReadFile(p.IncidentFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read incident file: %w", err)
+	}
+
+	var incident pagerDutyIncident
+	if err := json.Unmarshal(data, &incident); err != nil {
+		return nil, fmt.Errorf("failed to parse incident file %s: %w", p.IncidentFile, err)
+	}
+	if incident.RoutingKey != p.routingKeyHash() || incident.DedupKey == "" {
+		return nil, nil
+	}
+	return &incident, nil
+}
+
+// saveIncident records the open incident
+func (p *PagerDuty) saveIncident(dedupKey string) error {
+	if dedupKey == "" {
+		return nil
+	}
+	data, err := json.Marshal(pagerDutyIncident{DedupKey: dedupKey, RoutingKey: p.routingKeyHash()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal incident: %w", err)
+	}
+	if err := os.WriteFile(p.IncidentFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to write incident file: %w", err)
+	}
+	return nil
+}
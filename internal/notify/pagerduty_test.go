@@ -0,0 +1,169 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// newPagerDutyServer records received events and answers like the Events API
+func newPagerDutyServer(t *testing.T, events *[]map[string]interface{}) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			http.Error(w, `{"status":"invalid event"}`, http.StatusBadRequest)
+			return
+		}
+		*events = append(*events, event)
+		w.WriteHeader(http.StatusAccepted)
+		_, _ = w.Write([]byte(`{"status":"success","message":"Event processed","dedup_key":"dk-123"}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestPagerDuty points a notifier at server
+func newTestPagerDuty(t *testing.T, server *httptest.Server, threshold int) *PagerDuty {
+	p := NewPagerDuty("routing-key", threshold, t.TempDir())
+	p.Endpoint = server.URL
+	p.HTTPClient = server.Client()
+	return p
+}
+
+func failedResult() *types.ExportResult {
+	return &types.ExportResult{
+		ProcessedCount: 2,
+		SuccessCount:   1,
+		FailedCount:    1,
+		Results: []types.EntityResult{
+			{Entity: "crm.products", Success: true, RowCount: 10},
+			{Entity: "crm.orders", Success: false, Error: errors.New("ORA-00942"), Duration: time.Second},
+		},
+	}
+}
+
+func TestPagerDuty_TriggerThenResolve(t *testing.T) {
+	var events []map[string]interface{}
+	server := newPagerDutyServer(t, &events)
+	p := newTestPagerDuty(t, server, 1)
+
+	action, err := p.Notify(context.Background(), failedResult(), nil)
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if action != "trigger" || len(events) != 1 {
+		t.Fatalf("action = %q with %d events, want one trigger", action, len(events))
+	}
+
+	trigger := events[0]
+	if trigger["routing_key"] != "routing-key" || trigger["event_action"] != "trigger" {
+		t.Errorf("trigger event = %v", trigger)
+	}
+	payload, _ := trigger["payload"].(map[string]interface{})
+	if payload["severity"] != "error" {
+		t.Errorf("severity = %v, want error", payload["severity"])
+	}
+	if payload["summary"] != "ora2csv: 1 of 2 entities failed: crm.orders" {
+		t.Errorf("summary = %v", payload["summary"])
+	}
+	details, _ := payload["custom_details"].(map[string]interface{})
+	if entities, _ := details["entities"].([]interface{}); len(entities) != 2 {
+		t.Errorf("custom_details.entities = %v, want 2 entities", details["entities"])
+	}
+	if _, err := os.Stat(p.IncidentFile); err != nil {
+		t.Errorf("incident file not written: %v", err)
+	}
+
+	action, err = p.Notify(context.Background(), &types.ExportResult{ProcessedCount: 2, SuccessCount: 2}, nil)
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if action != "resolve" || len(events) != 2 {
+		t.Fatalf("action = %q with %d events, want a resolve", action, len(events))
+	}
+	if events[1]["event_action"] != "resolve" || events[1]["dedup_key"] != "dk-123" {
+		t.Errorf("resolve event = %v", events[1])
+	}
+	if _, err := os.Stat(p.IncidentFile); !os.IsNotExist(err) {
+		t.Errorf("incident file not removed: %v", err)
+	}
+}
+
+func TestPagerDuty_NoEvent(t *testing.T) {
+	var events []map[string]interface{}
+	server := newPagerDutyServer(t, &events)
+
+	t.Run("below threshold", func(t *testing.T) {
+		p := newTestPagerDuty(t, server, 2)
+		action, err := p.Notify(context.Background(), failedResult(), nil)
+		if err != nil || action != "" {
+			t.Errorf("Notify() = %q, %v, want no event", action, err)
+		}
+	})
+
+	t.Run("success without open incident", func(t *testing.T) {
+		p := newTestPagerDuty(t, server, 1)
+		action, err := p.Notify(context.Background(), &types.ExportResult{SuccessCount: 1}, nil)
+		if err != nil || action != "" {
+			t.Errorf("Notify() = %q, %v, want no event", action, err)
+		}
+	})
+
+	t.Run("incident for another routing key", func(t *testing.T) {
+		p := newTestPagerDuty(t, server, 1)
+		other := *p
+		other.RoutingKey = "other-key"
+		if err := other.saveIncident("dk-other"); err != nil {
+			t.Fatalf("saveIncident() error = %v", err)
+		}
+		action, err := p.Notify(context.Background(), &types.ExportResult{SuccessCount: 1}, nil)
+		if err != nil || action != "" {
+			t.Errorf("Notify() = %q, %v, want no event", action, err)
+		}
+	})
+
+	if len(events) != 0 {
+		t.Errorf("got %d events, want none", len(events))
+	}
+}
+
+func TestPagerDuty_AbortedRun(t *testing.T) {
+	var events []map[string]interface{}
+	server := newPagerDutyServer(t, &events)
+	// The threshold applies to failed entities, an aborted run triggers regardless
+	p := newTestPagerDuty(t, server, 5)
+
+	action, err := p.Notify(context.Background(), nil, errors.New("connection lost"))
+	if err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if action != "trigger" || len(events) != 1 {
+		t.Fatalf("action = %q with %d events, want one trigger", action, len(events))
+	}
+	payload, _ := events[0]["payload"].(map[string]interface{})
+	if payload["summary"] != "ora2csv: export failed: connection lost" {
+		t.Errorf("summary = %v", payload["summary"])
+	}
+	details, _ := payload["custom_details"].(map[string]interface{})
+	if details["success"] != false || details["error"] != "connection lost" {
+		t.Errorf("custom_details = %v", details)
+	}
+
+	// A partial result keeps the entities exported before the abort
+	action, err = p.Notify(context.Background(), &types.ExportResult{ProcessedCount: 1, SuccessCount: 1,
+		Results: []types.EntityResult{{Entity: "crm.products", Success: true}}}, errors.New("state upload failed"))
+	if err != nil || action != "trigger" {
+		t.Fatalf("Notify() = %q, %v, want trigger", action, err)
+	}
+	if events[1]["dedup_key"] != "dk-123" {
+		t.Errorf("dedup_key = %v, want the open incident", events[1]["dedup_key"])
+	}
+}
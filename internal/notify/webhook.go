@@ -0,0 +1,137 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// Webhook posts the result of an export run as JSON to a URL
+type Webhook struct {
+	URL string
+	// OnFailureOnly skips successful runs
+	OnFailureOnly bool
+	HTTPClient    *http.Client
+}
+
+// NewWebhook creates a webhook notifier with a request timeout
+func NewWebhook(url string, onFailureOnly bool, timeout time.Duration) *Webhook {
+	return &Webhook{
+		URL:           url,
+		OnFailureOnly: onFailureOnly,
+		HTTPClient:    &http.Client{Timeout: timeout},
+	}
+}
+
+// WebhookPayload is the JSON body posted to the webhook URL
+type WebhookPayload struct {
+	Event          string                `json:"event"` // "export.completed" or "export.failed"
+	Success        bool                  `json:"success"`
+	Source         string                `json:"source"`
+	TotalEntities  int                   `json:"total_entities"`
+	ProcessedCount int                   `json:"processed_count"`
+	SuccessCount   int                   `json:"success_count"`
+	FailedCount    int                   `json:"failed_count"`
+	SkippedCount   int                   `json:"skipped_count"`
+	DurationMs     int64                 `json:"duration_ms"`
+	FailedEntities []FailedEntity        `json:"failed_entities"`
+	Error          string                `json:"error,omitempty"` // Full run error message
+	RunTime        *time.Time            `json:"run_time,omitempty"`
+	Version        string                `json:"version,omitempty"`
+	Entities       []types.EntitySummary `json:"entities,omitempty"`
+}
+
+// FailedEntity is a failed entity with its full error message
+type FailedEntity struct {
+	Entity string `json:"entity"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewWebhookPayload builds the payload for an export result and the run error
+// result may be nil when the run failed before exporting.
+func NewWebhookPayload(result *types.ExportResult, runErr error) WebhookPayload {
+	payload := WebhookPayload{
+		Success:        runErr == nil,
+		Source:         source(),
+		FailedEntities: []FailedEntity{},
+	}
+	if runErr != nil {
+		payload.Error = runErr.Error()
+	}
+	if result != nil {
+		summary := types.NewMachineReadableSummary(result, runErr, result.Duration.Milliseconds())
+		payload.Success = summary.Success
+		payload.Error = summary.Error
+		payload.TotalEntities = result.TotalEntities
+		payload.ProcessedCount = result.ProcessedCount
+		payload.SuccessCount = result.SuccessCount
+		payload.FailedCount = result.FailedCount
+		payload.SkippedCount = result.SkippedCount
+		payload.DurationMs = summary.DurationMs
+		payload.Version = result.Version
+		payload.Entities = summary.Entities
+		if !result.RunTime.IsZero() {
+			runTime := result.RunTime
+			payload.RunTime = &runTime
+		}
+		for _, r := range result.Results {
+			if r.Success {
+				continue
+			}
+			failed := FailedEntity{Entity: r.Entity}
+			if r.Error != nil {
+				failed.Error = r.Error.Error()
+			}
+			payload.FailedEntities = append(payload.FailedEntities, failed)
+		}
+	}
+
+	payload.Event = "export.completed"
+	if !payload.Success {
+		payload.Event = "export.failed"
+	}
+	return payload
+}
+
+// Notify posts the run result, it returns false when OnFailureOnly skipped a successful run
+func (w *Webhook) Notify(ctx context.Context, result *types.ExportResult, runErr error) (bool, error) {
+	payload := NewWebhookPayload(result, runErr)
+	if w.OnFailureOnly && payload.Success {
+		return false, nil
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal webhook payload: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ora2csv")
+
+	client := w.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("failed to send webhook: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		return false, fmt.Errorf("webhook rejected: %s: %s", resp.Status, strings.TrimSpace(string(respBody)))
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return true, nil
+}
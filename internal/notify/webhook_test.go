@@ -0,0 +1,108 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// newWebhookServer records received payloads and answers with status
+func newWebhookServer(t *testing.T, status int, payloads *[]WebhookPayload) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if r.Header.Get("Content-Type") != "application/json" || json.NewDecoder(r.Body).Decode(&payload) != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		*payloads = append(*payloads, payload)
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	succeeded := &types.ExportResult{TotalEntities: 1, ProcessedCount: 1, SuccessCount: 1, Duration: 2 * time.Second,
+		Results: []types.EntityResult{{Entity: "crm.orders", Success: true, RowCount: 5}}}
+	failed := &types.ExportResult{TotalEntities: 2, ProcessedCount: 2, SuccessCount: 1, FailedCount: 1,
+		Results: []types.EntityResult{
+			{Entity: "crm.orders", Success: true},
+			{Entity: "crm.products", Error: errors.New("query execution failed: ORA-00942: table or view does not exist")},
+		}}
+
+	tests := []struct {
+		name          string
+		onFailureOnly bool
+		result        *types.ExportResult
+		runErr        error
+		wantSent      bool
+		wantEvent     string
+	}{
+		{"success", false, succeeded, nil, true, "export.completed"},
+		{"success skipped on failure only", true, succeeded, nil, false, ""},
+		{"entity failure", true, failed, nil, true, "export.failed"},
+		{"run failure", true, nil, errors.New("failed to save state: disk full"), true, "export.failed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var payloads []WebhookPayload
+			server := newWebhookServer(t, http.StatusNoContent, &payloads)
+
+			sent, err := NewWebhook(server.URL, tt.onFailureOnly, time.Second).Notify(context.Background(), tt.result, tt.runErr)
+			if err != nil {
+				t.Fatalf("Notify() error = %v", err)
+			}
+			if sent != tt.wantSent || len(payloads) != map[bool]int{true: 1}[tt.wantSent] {
+				t.Fatalf("Notify() sent = %v with %d payloads, want %v", sent, len(payloads), tt.wantSent)
+			}
+			if sent && payloads[0].Event != tt.wantEvent {
+				t.Errorf("event = %q, want %q", payloads[0].Event, tt.wantEvent)
+			}
+		})
+	}
+}
+
+func TestNewWebhookPayload(t *testing.T) {
+	result := &types.ExportResult{TotalEntities: 3, ProcessedCount: 2, SuccessCount: 1, FailedCount: 1, SkippedCount: 1,
+		Duration: 1500 * time.Millisecond,
+		Results: []types.EntityResult{
+			{Entity: "crm.orders", Success: true, RowCount: 10},
+			{Entity: "crm.products", Error: errors.New("query execution failed: ORA-00942: table or view does not exist")},
+		}}
+
+	payload := NewWebhookPayload(result, nil)
+	if payload.Success || payload.Event != "export.failed" {
+		t.Errorf("payload = %+v, want a failed run", payload)
+	}
+	if payload.DurationMs != 1500 || payload.SkippedCount != 1 || len(payload.Entities) != 2 {
+		t.Errorf("unexpected payload counts: %+v", payload)
+	}
+	if len(payload.FailedEntities) != 1 || payload.FailedEntities[0].Entity != "crm.products" ||
+		!strings.Contains(payload.FailedEntities[0].Error, "ORA-00942") {
+		t.Errorf("FailedEntities = %+v", payload.FailedEntities)
+	}
+
+	payload = NewWebhookPayload(nil, errors.New("failed to save state: disk full"))
+	if payload.Success || payload.Error != "failed to save state: disk full" || payload.FailedEntities == nil {
+		t.Errorf("run error payload = %+v", payload)
+	}
+}
+
+func TestWebhook_Rejected(t *testing.T) {
+	var payloads []WebhookPayload
+	server := newWebhookServer(t, http.StatusInternalServerError, &payloads)
+
+	_, err := NewWebhook(server.URL, false, time.Second).Notify(context.Background(), &types.ExportResult{}, nil)
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("Notify() error = %v, want rejection", err)
+	}
+}
@@ -0,0 +1,65 @@
+// Package render formats query results for the terminal
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// NullText is shown in tables for NULL values
+const NullText = "NULL"
+
+// Table writes rows as an aligned ASCII table with a header and separator line
+// A nil value is written as NullText. Tabs and newlines in values are replaced by spaces.
+func Table(w io.Writer, columns []string, rows [][]*string) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	separators := make([]string, len(columns))
+	for i, column := range columns {
+		separators[i] = strings.Repeat("-", len(column))
+	}
+	writeLine(tw, columns)
+	writeLine(tw, separators)
+
+	cells := make([]string, len(columns))
+	for _, row := range rows {
+		for i := range cells {
+			cells[i] = NullText
+			if i < len(row) && row[i] != nil {
+				cells[i] = cellText(*row[i])
+			}
+		}
+		writeLine(tw, cells)
+	}
+	return tw.Flush()
+}
+
+// JSONLines writes each row as one JSON object keyed by column name, NULLs as null
+func JSONLines(w io.Writer, columns []string, rows [][]*string) error {
+	enc := json.NewEncoder(w)
+	for _, row := range rows {
+		obj := make(map[string]*string, len(columns))
+		for i, column := range columns {
+			if i < len(row) {
+				obj[column] = row[i]
+			}
+		}
+		if err := enc.Encode(obj); err != nil {
+			return fmt.Errorf("failed to write row: %w", err)
+		}
+	}
+	return nil
+}
+
+// writeLine writes cells as one tab-separated tabwriter line
+func writeLine(w io.Writer, cells []string) {
+	_, _ = fmt.Fprintln(w, strings.Join(cells, "\t"))
+}
+
+// cellText keeps a value on one table line
+func cellText(s string) string {
+	return strings.NewReplacer("\t", " ", "\r\n", " ", "\n", " ", "\r", " ").Replace(s)
+}
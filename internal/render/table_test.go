@@ -0,0 +1,47 @@
+package render
+
+import (
+	"bytes"
+	"testing"
+)
+
+func str(s string) *string {
+	return &s
+}
+
+func TestTable(t *testing.T) {
+	var buf bytes.Buffer
+	err := Table(&buf, []string{"ID", "NAME"}, [][]*string{
+		{str("1"), str("Widget")},
+		{str("22"), nil},
+		{str("3"), str("multi\nline")},
+	})
+	if err != nil {
+		t.Fatalf("Table() error = %v", err)
+	}
+
+	want := "ID  NAME\n" +
+		"--  ----\n" +
+		"1   Widget\n" +
+		"22  NULL\n" +
+		"3   multi line\n"
+	if buf.String() != want {
+		t.Errorf("Table() =\n%s\nwant\n%s", buf.String(), want)
+	}
+}
+
+func TestJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	err := JSONLines(&buf, []string{"ID", "NAME"}, [][]*string{
+		{str("1"), str("Widget")},
+		{str("2"), nil},
+	})
+	if err != nil {
+		t.Fatalf("JSONLines() error = %v", err)
+	}
+
+	want := `{"ID":"1","NAME":"Widget"}` + "\n" + `{"ID":"2","NAME":null}` + "\n"
+	if buf.String() != want {
+		t.Errorf("JSONLines() = %q, want %q", buf.String(), want)
+	}
+}
@@ -0,0 +1,153 @@
+// Package schedule parses entity export windows like "Mon-Fri 06:00-08:00"
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// window is a parsed schedule spec
+type window struct {
+	days       [7]bool // indexed by time.Weekday
+	start, end int     // minutes since midnight, end exclusive; end < start wraps past midnight
+	allDay     bool
+}
+
+// dayNames maps lowercase day abbreviations to weekdays
+var dayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// Validate checks that spec is a valid schedule, see InWindow
+func Validate(spec string) error {
+	_, err := parse(spec)
+	return err
+}
+
+// InWindow reports whether t is inside the schedule spec
+// A spec has a day list, a time range or both, e.g. "Mon-Fri 06:00-08:00", "Sat,Sun" or
+// "22:00-02:00". Days are comma-separated names or ranges (Mon, Mon-Fri); the time range
+// end is exclusive and may wrap past midnight, in which case the hours after midnight
+// belong to the day the window started. An empty spec matches any time.
+func InWindow(spec string, t time.Time) (bool, error) {
+	w, err := parse(spec)
+	if err != nil {
+		return false, err
+	}
+	if w.allDay {
+		return w.days[t.Weekday()], nil
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	switch {
+	case w.start <= w.end:
+		return w.days[t.Weekday()] && minute >= w.start && minute < w.end, nil
+	case minute >= w.start:
+		return w.days[t.Weekday()], nil
+	case minute < w.end:
+		// After midnight of a window started the day before
+		return w.days[t.AddDate(0, 0, -1).Weekday()], nil
+	}
+	return false, nil
+}
+
+// parse parses spec into a window
+func parse(spec string) (window, error) {
+	w := window{allDay: true}
+	for i := range w.days {
+		w.days[i] = true
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) > 2 {
+		return w, fmt.Errorf("invalid schedule %q: want days, a time range or both, e.g. Mon-Fri 06:00-08:00", spec)
+	}
+	if len(fields) == 2 && (strings.Contains(fields[0], ":") || !strings.Contains(fields[1], ":")) {
+		return w, fmt.Errorf("invalid schedule %q: want days followed by a time range", spec)
+	}
+	for _, field := range fields {
+		var err error
+		if strings.Contains(field, ":") {
+			w.allDay = false
+			w.start, w.end, err = parseTimeRange(field)
+		} else {
+			w.days, err = parseDays(field)
+		}
+		if err != nil {
+			return w, fmt.Errorf("invalid schedule %q: %w", spec, err)
+		}
+	}
+	return w, nil
+}
+
+// parseDays parses a comma-separated list of days and day ranges, e.g. Mon,Wed-Fri
+// Ranges may wrap past Sunday, e.g. Fri-Mon.
+func parseDays(field string) ([7]bool, error) {
+	var days [7]bool
+	for _, part := range strings.Split(field, ",") {
+		from, to, isRange := strings.Cut(part, "-")
+		first, err := parseDay(from)
+		if err != nil {
+			return days, err
+		}
+		last := first
+		if isRange {
+			if last, err = parseDay(to); err != nil {
+				return days, err
+			}
+		}
+		for d := first; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == last {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// parseDay parses a three-letter day name, case-insensitive
+func parseDay(name string) (time.Weekday, error) {
+	day, ok := dayNames[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown day %q, want Mon, Tue, Wed, Thu, Fri, Sat or Sun", name)
+	}
+	return day, nil
+}
+
+// parseTimeRange parses HH:MM-HH:MM into minutes since midnight
+func parseTimeRange(field string) (int, int, error) {
+	from, to, ok := strings.Cut(field, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid time range %q, want HH:MM-HH:MM", field)
+	}
+	start, err := parseClock(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == 24*60 {
+		return 0, 0, fmt.Errorf("invalid time range %q, 24:00 can only end a range", field)
+	}
+	end, err := parseClock(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("empty time range %q", field)
+	}
+	return start, end, nil
+}
+
+// parseClock parses HH:MM, 24:00 is accepted as the end of the day
+func parseClock(value string) (int, error) {
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, want HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
@@ -0,0 +1,64 @@
+package schedule
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestInWindow(t *testing.T) {
+	// 2025-01-13 is a Monday
+	at := func(day int, clock string) time.Time {
+		ts, err := time.Parse("2006-01-02 15:04", fmt.Sprintf("2025-01-%02d %s", day, clock))
+		if err != nil {
+			t.Fatalf("time.Parse() error: %v", err)
+		}
+		return ts
+	}
+
+	tests := []struct {
+		spec string
+		t    time.Time
+		want bool
+	}{
+		{"", at(13, "12:00"), true},
+		{"Mon-Fri 06:00-08:00", at(13, "06:00"), true},
+		{"Mon-Fri 06:00-08:00", at(13, "07:59"), true},
+		{"Mon-Fri 06:00-08:00", at(13, "08:00"), false},
+		{"Mon-Fri 06:00-08:00", at(18, "07:00"), false}, // Saturday
+		{"sat,sun", at(19, "23:00"), true},
+		{"Sat,Sun", at(17, "23:00"), false},
+		{"Mon,Wed-Thu", at(15, "01:00"), true},
+		{"Fri-Mon", at(13, "01:00"), true},
+		{"Fri-Mon", at(14, "01:00"), false},
+		{"22:00-02:00", at(14, "23:30"), true},
+		{"22:00-02:00", at(14, "01:30"), true},
+		{"22:00-02:00", at(14, "12:00"), false},
+		// After midnight belongs to the window started on Friday
+		{"Fri 22:00-02:00", at(18, "01:00"), true},
+		{"Fri 22:00-02:00", at(17, "01:00"), false},
+		{"Mon 20:00-24:00", at(13, "23:59"), true},
+	}
+	for _, tt := range tests {
+		got, err := InWindow(tt.spec, tt.t)
+		if err != nil {
+			t.Fatalf("InWindow(%q) error: %v", tt.spec, err)
+		}
+		if got != tt.want {
+			t.Errorf("InWindow(%q, %s) = %t, want %t", tt.spec, tt.t.Format("Mon 15:04"), got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	for _, spec := range []string{"Mon-Fri 06:00-08:00", "Sun", "00:00-24:00", "mon,TUE 6:00-7:30"} {
+		if err := Validate(spec); err != nil {
+			t.Errorf("Validate(%q) error: %v", spec, err)
+		}
+	}
+	for _, spec := range []string{"Monday", "Mon-Fri 06:00", "06:00-06:00", "Mon 06:00-08:00 extra", "06:00-08:00 Mon", "25:00-26:00", "24:00-02:00"} {
+		if err := Validate(spec); err == nil {
+			t.Errorf("Validate(%q) expected error", spec)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGCPEndpoint is the Secret Manager REST API endpoint
+	DefaultGCPEndpoint = "https://secretmanager.googleapis.com"
+	// DefaultGCPMetadataURL is the GCE metadata server used for the project and access token
+	DefaultGCPMetadataURL = "http://metadata.google.internal/computeMetadata/v1"
+	// EnvGCPAccessToken supplies an OAuth2 access token outside of Google Cloud
+	EnvGCPAccessToken = "GOOGLE_OAUTH_ACCESS_TOKEN"
+	// DefaultGCPRetryDelay is the first backoff delay, doubled on every retry
+	DefaultGCPRetryDelay = 500 * time.Millisecond
+
+	// gcpMaxRetries is the number of retries of a request that failed with a transient error
+	gcpMaxRetries = 4
+)
+
+// gcpCache holds secret values for the process lifetime, keyed by version resource name
+var gcpCache sync.Map
+
+// GCPSecretManager reads the database password from Google Cloud Secret Manager
+// It calls the accessSecretVersion REST method. The access token comes from
+// GOOGLE_OAUTH_ACCESS_TOKEN or the metadata server (GCE, GKE, Cloud Run), and an
+// empty project ID is also looked up on the metadata server. Requests failing with
+// network errors, 429 or 5xx responses are retried with exponential backoff.
+type GCPSecretManager struct {
+	ProjectID   string
+	SecretName  string
+	Version     string
+	Endpoint    string
+	MetadataURL string
+	HTTPClient  *http.Client
+	RetryDelay  time.Duration
+}
+
+// NewGCPSecretManager creates a provider for a secret version; an empty version means latest
+func NewGCPSecretManager(projectID, secretName, version string) *GCPSecretManager {
+	if version == "" {
+		version = "latest"
+	}
+	return &GCPSecretManager{
+		ProjectID:   projectID,
+		SecretName:  secretName,
+		Version:     version,
+		Endpoint:    DefaultGCPEndpoint,
+		MetadataURL: DefaultGCPMetadataURL,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+		RetryDelay:  DefaultGCPRetryDelay,
+	}
+}
+
+// Name identifies the provider
+func (g *GCPSecretManager) Name() string {
+	return "GCP Secret Manager"
+}
+
+// GetPassword returns the secret payload, fetching it once per process
+func (g *GCPSecretManager) GetPassword(ctx context.Context) (string, error) {
+	projectID := g.ProjectID
+	if projectID == "" {
+		id, err := g.metadata(ctx, "/project/project-id")
+		if err != nil {
+			return "", fmt.Errorf("gcp_project_id is not set and metadata lookup failed: %w", err)
+		}
+		projectID = strings.TrimSpace(id)
+	}
+
+	resource := fmt.Sprintf("projects/%s/secrets/%s/versions/%s", projectID, g.SecretName, g.Version)
+	if cached, ok := gcpCache.Load(resource); ok {
+		return cached.(string), nil
+	}
+
+	value, err := g.accessSecretVersion(ctx, resource)
+	if err != nil {
+		return "", err
+	}
+	gcpCache.Store(resource, value)
+	return value, nil
+}
+
+// accessSecretVersion calls GET /v1/<resource>:access and decodes the payload
+func (g *GCPSecretManager) accessSecretVersion(ctx context.Context, resource string) (string, error) {
+	token, err := g.accessToken(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(g.Endpoint, "/")+"/v1/"+resource+":access", nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create secret request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	body, err := g.do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to access secret %s: %w", resource, err)
+	}
+
+	var resp struct {
+		Payload struct {
+			Data       string `json:"data"`
+			DataCrc32c string `json:"dataCrc32c"`
+		} `json:"payload"`
+	}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", fmt.Errorf("failed to decode secret response: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(resp.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret payload: %w", err)
+	}
+	if resp.Payload.DataCrc32c != "" {
+		want, err := strconv.ParseUint(resp.Payload.DataCrc32c, 10, 32)
+		if err != nil || crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)) != uint32(want) {
+			return "", fmt.Errorf("secret payload checksum mismatch for %s", resource)
+		}
+	}
+	if len(data) == 0 {
+		return "", fmt.Errorf("secret %s is empty", resource)
+	}
+	return string(data), nil
+}
+
+// accessToken returns a token from the environment or the metadata server
+func (g *GCPSecretManager) accessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv(EnvGCPAccessToken); token != "" {
+		return token, nil
+	}
+
+	body, err := g.metadata(ctx, "/instance/service-accounts/default/token")
+	if err != nil {
+		return "", fmt.Errorf("failed to get access token (set %s outside Google Cloud): %w", EnvGCPAccessToken, err)
+	}
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil || resp.AccessToken == "" {
+		return "", fmt.Errorf("metadata server returned no access token")
+	}
+	return resp.AccessToken, nil
+}
+
+// metadata reads a value from the metadata server
+func (g *GCPSecretManager) metadata(ctx context.Context, path string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimRight(g.MetadataURL, "/")+path, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create metadata request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	body, err := g.do(req)
+	if err != nil {
+		return "", err
+	}
+	return string(body), nil
+}
+
+// do sends a request and returns the body of a 200 response
+// Network errors, 429 and 5xx responses are retried up to gcpMaxRetries times.
+func (g *GCPSecretManager) do(req *http.Request) ([]byte, error) {
+	ctx := req.Context()
+	delay := g.RetryDelay
+	for attempt := 0; ; attempt++ {
+		body, retryable, err := g.send(req.Clone(ctx))
+		if err == nil || !retryable || attempt == gcpMaxRetries || ctx.Err() != nil {
+			return body, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		}
+		delay *= 2
+	}
+}
+
+// send sends a request once and reports whether a failure is worth retrying
+func (g *GCPSecretManager) send(req *http.Request) ([]byte, bool, error) {
+	client := g.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, true, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+		return nil, retryable, fmt.Errorf("unexpected status %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, false, nil
+}
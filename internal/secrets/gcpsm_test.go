@@ -0,0 +1,178 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+)
+
+// newGCPServer mocks the Secret Manager API and the metadata server
+func newGCPServer(t *testing.T, payload string, requests *int) *httptest.Server {
+	t.Helper()
+	data := []byte(payload)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/projects/my-project/secrets/db-password/versions/", func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			http.Error(w, `{"error":{"code":401}}`, http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path != "/v1/projects/my-project/secrets/db-password/versions/7:access" &&
+			r.URL.Path != "/v1/projects/my-project/secrets/db-password/versions/latest:access" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"name":%q,"payload":{"data":%q,"dataCrc32c":"%d"}}`,
+			r.URL.Path, base64.StdEncoding.EncodeToString(data), crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+	})
+	mux.HandleFunc("/metadata/project/project-id", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			http.Error(w, "missing header", http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte("my-project"))
+	})
+	mux.HandleFunc("/metadata/instance/service-accounts/default/token", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"test-token","expires_in":3599,"token_type":"Bearer"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newTestProvider points a provider at the mock server
+func newTestProvider(server *httptest.Server, projectID, version string) *GCPSecretManager {
+	g := NewGCPSecretManager(projectID, "db-password", version)
+	g.Endpoint = server.URL
+	g.MetadataURL = server.URL + "/metadata"
+	g.HTTPClient = server.Client()
+	return g
+}
+
+func TestGCPSecretManager_GetPassword(t *testing.T) {
+	t.Run("env token and pinned version", func(t *testing.T) {
+		gcpCache.Clear()
+		var requests int
+		server := newGCPServer(t, "s3cr3t:p@ss", &requests)
+		t.Setenv(EnvGCPAccessToken, "test-token")
+
+		password, err := newTestProvider(server, "my-project", "7").GetPassword(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if password != "s3cr3t:p@ss" {
+			t.Errorf("password = %v, want s3cr3t:p@ss", password)
+		}
+	})
+
+	t.Run("metadata token and project, cached", func(t *testing.T) {
+		gcpCache.Clear()
+		var requests int
+		server := newGCPServer(t, "from-metadata", &requests)
+		t.Setenv(EnvGCPAccessToken, "")
+
+		for i := 0; i < 2; i++ {
+			password, err := newTestProvider(server, "", "").GetPassword(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if password != "from-metadata" {
+				t.Errorf("password = %v, want from-metadata", password)
+			}
+		}
+		if requests != 1 {
+			t.Errorf("requests = %v, want 1", requests)
+		}
+	})
+
+	t.Run("access denied", func(t *testing.T) {
+		gcpCache.Clear()
+		var requests int
+		server := newGCPServer(t, "unused", &requests)
+		t.Setenv(EnvGCPAccessToken, "wrong-token")
+
+		if _, err := newTestProvider(server, "my-project", "latest").GetPassword(context.Background()); err == nil {
+			t.Error("expected error for rejected token")
+		}
+		if requests != 1 {
+			t.Errorf("requests = %v, want 1 (client errors are not retried)", requests)
+		}
+	})
+}
+
+func TestGCPSecretManager_Retry(t *testing.T) {
+	t.Setenv(EnvGCPAccessToken, "test-token")
+
+	tests := []struct {
+		name         string
+		failures     int
+		wantErr      bool
+		wantRequests int
+	}{
+		{name: "transient failures", failures: 2, wantRequests: 3},
+		{name: "retries exhausted", failures: 100, wantErr: true, wantRequests: gcpMaxRetries + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gcpCache.Clear()
+			var requests int
+			backend := newGCPServer(t, "s3cr3t", new(int))
+			failures := tt.failures
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				if failures > 0 {
+					failures--
+					http.Error(w, `{"error":{"code":503}}`, http.StatusServiceUnavailable)
+					return
+				}
+				backend.Config.Handler.ServeHTTP(w, r)
+			}))
+			t.Cleanup(server.Close)
+
+			g := newTestProvider(server, "my-project", "latest")
+			g.RetryDelay = time.Millisecond
+			password, err := g.GetPassword(context.Background())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetPassword() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && password != "s3cr3t" {
+				t.Errorf("password = %v, want s3cr3t", password)
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("requests = %v, want %v", requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestResolveDBPassword(t *testing.T) {
+	t.Run("no provider keeps password", func(t *testing.T) {
+		cfg := &config.Config{DBPassword: "from-env"}
+		if err := ResolveDBPassword(context.Background(), cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.DBPassword != "from-env" {
+			t.Errorf("cfg.DBPassword = %v, want from-env", cfg.DBPassword)
+		}
+	})
+
+	t.Run("cached gcp secret", func(t *testing.T) {
+		gcpCache.Clear()
+		gcpCache.Store("projects/my-project/secrets/db-password/versions/latest", "cached")
+		cfg := &config.Config{GCPSecretName: "db-password", GCPProjectID: "my-project", GCPSecretVersion: "latest"}
+
+		if err := ResolveDBPassword(context.Background(), cfg); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if cfg.DBPassword != "cached" {
+			t.Errorf("cfg.DBPassword = %v, want cached", cfg.DBPassword)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+// Package secrets fetches the database password from external secret stores
+package secrets
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+)
+
+// SecretProvider returns the database password from a secret store
+type SecretProvider interface {
+	// Name identifies the secret store in logs and errors
+	Name() string
+	// GetPassword returns the secret value
+	GetPassword(ctx context.Context) (string, error)
+}
+
+// NewProvider returns the secret provider configured in cfg, or nil when none is
+func NewProvider(cfg *config.Config) (SecretProvider, error) {
+	if cfg.GCPSecretName != "" {
+		return NewGCPSecretManager(cfg.GCPProjectID, cfg.GCPSecretName, cfg.GCPSecretVersion), nil
+	}
+	return nil, nil
+}
+
+// ResolveDBPassword sets cfg.DBPassword from the configured secret provider
+// It does nothing when no provider is configured.
+func ResolveDBPassword(ctx context.Context, cfg *config.Config) error {
+	provider, err := NewProvider(cfg)
+	if err != nil || provider == nil {
+		return err
+	}
+
+	password, err := provider.GetPassword(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read database password from %s: %w", provider.Name(), err)
+	}
+	cfg.DBPassword = password
+	return nil
+}
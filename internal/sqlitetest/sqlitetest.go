@@ -0,0 +1,56 @@
+// Package sqlitetest runs export code against an in-memory SQLite database in tests
+//
+// The "sqlite" driver is not imported here. Test packages register it with a blank
+// import of modernc.org/sqlite in a _test.go file, so the driver stays a test-only dependency.
+package sqlitetest
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+)
+
+// EnvIntegration is the environment variable that enables integration tests
+const EnvIntegration = "TEST_INTEGRATION"
+
+// NewDB creates an in-memory SQLite database behind the db.DB interface
+// The schema (if any) is executed before the database is returned.
+// SQL files used against it must use ? placeholders for :startDate and :tillDate.
+func NewDB(t testing.TB, schema string) *db.OracleDB {
+	t.Helper()
+
+	conn, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	// Every connection to :memory: is a separate database, keep a single one
+	conn.SetMaxOpenConns(1)
+
+	if schema != "" {
+		if _, err := conn.Exec(schema); err != nil {
+			_ = conn.Close()
+			t.Fatalf("failed to apply SQLite schema: %v", err)
+		}
+	}
+
+	database := db.NewFromConn(conn, db.BindPositional)
+	t.Cleanup(func() {
+		if err := database.Close(); err != nil {
+			t.Errorf("failed to close SQLite database: %v", err)
+		}
+	})
+
+	return database
+}
+
+// Run runs fn against a SQLite database initialized with schema
+// The test is skipped unless TEST_INTEGRATION=1 is set, as make test does
+func Run(t testing.TB, schema string, fn func(db.DB)) {
+	t.Helper()
+	if os.Getenv(EnvIntegration) != "1" {
+		t.Skip("integration test skipped (run make test or set " + EnvIntegration + "=1)")
+	}
+	fn(NewDB(t, schema))
+}
@@ -0,0 +1,56 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// backupTimeLayout is the timestamp layout of dated backups, e.g. state.json.2025-01-14T08-00-00
+const backupTimeLayout = "2006-01-02T15-04-05"
+
+// BackupPath returns the dated backup path of a state file
+func BackupPath(path string, t time.Time) string {
+	return path + "." + t.UTC().Format(backupTimeLayout)
+}
+
+// Backup copies the state file at path to dest, replacing an existing file
+func Backup(path, dest string, mode os.FileMode) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read state file: %w", err)
+	}
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+	if err := os.WriteFile(dest, data, mode); err != nil {
+		return fmt.Errorf("failed to write state backup: %w", err)
+	}
+	return nil
+}
+
+// EnableBackup makes the next save copy the previous state file to its BackupPath first
+// Only the first save is backed up, so a command changing several entities leaves one backup
+// of the state before it ran.
+func (f *File) EnableBackup() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.backup = true
+}
+
+// backupBeforeSave copies the state file before the first save after EnableBackup
+// A state file that does not exist yet has nothing to back up.
+func (f *File) backupBeforeSave() error {
+	if !f.backup || f.backedUp {
+		return nil
+	}
+	if _, err := os.Stat(f.path); os.IsNotExist(err) {
+		f.backedUp = true
+		return nil
+	}
+	if err := Backup(f.path, BackupPath(f.path, time.Now()), f.mode); err != nil {
+		return err
+	}
+	f.backedUp = true
+	return nil
+}
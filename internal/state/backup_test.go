@@ -0,0 +1,60 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBackupPath(t *testing.T) {
+	got := BackupPath("state.json", time.Date(2025, 1, 14, 8, 30, 0, 0, time.UTC))
+	if got != "state.json.2025-01-14T08-30-00" {
+		t.Errorf("BackupPath() = %s", got)
+	}
+}
+
+func TestFile_EnableBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	path := filepath.Join(tmpDir, "state.json")
+	original := `[{"entity":"crm.orders","lastRunTime":"","active":true},{"entity":"crm.users","lastRunTime":"","active":true}]`
+	mustWriteFile(t, path, original)
+
+	f, err := Load(path, nil, "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	// Without EnableBackup saves leave no backup
+	if err := f.SetActive("crm.orders", true); err != nil {
+		t.Fatalf("SetActive() error: %v", err)
+	}
+	if matches, _ := filepath.Glob(path + ".20*"); len(matches) != 0 {
+		t.Fatalf("unexpected backups: %v", matches)
+	}
+	before, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+
+	f.EnableBackup()
+	if err := f.SetActive("crm.orders", false); err != nil {
+		t.Fatalf("SetActive() error: %v", err)
+	}
+	if err := f.SetActive("crm.users", false); err != nil {
+		t.Fatalf("SetActive() error: %v", err)
+	}
+
+	matches, _ := filepath.Glob(path + ".20*")
+	if len(matches) != 1 {
+		t.Fatalf("backups = %v, want one", matches)
+	}
+	data, err := os.ReadFile(matches[0])
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if string(data) != string(before) {
+		t.Errorf("backup = %s, want the state before the first change %s", data, before)
+	}
+}
@@ -0,0 +1,60 @@
+package state
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/internal/storage"
+)
+
+// LoadFromConfig loads the state file, or combines all --state-files when set
+// With S3 or GCS, the state is synced to state.json under the configured prefix,
+// and each of multiple state files to a key named after its file
+func LoadFromConfig(cfg *config.Config, remote storage.StateStore) (Store, error) {
+	if len(cfg.StateFiles) == 0 {
+		key := ""
+		if remote != nil {
+			key = remote.Key("state.json")
+		}
+		st, err := LoadWithMode(cfg.StateFile, remote, key, cfg.StateFileMode)
+		if err != nil {
+			return nil, err
+		}
+		return st, nil
+	}
+
+	files := make([]*File, 0, len(cfg.StateFiles))
+	// Release the locks taken so far when a later file fails
+	closeAll := func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}
+	keys := make(map[string]string, len(cfg.StateFiles))
+	for _, path := range cfg.StateFiles {
+		key := ""
+		if remote != nil {
+			key = remote.Key(filepath.Base(path))
+			if other, ok := keys[key]; ok {
+				closeAll()
+				return nil, fmt.Errorf("state files %s and %s map to the same remote key %s", other, path, key)
+			}
+			keys[key] = path
+		}
+
+		f, err := LoadWithMode(path, remote, key, cfg.StateFileMode)
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		files = append(files, f)
+	}
+
+	st, err := NewMultiFile(files...)
+	if err != nil {
+		closeAll()
+		return nil, err
+	}
+	return st, nil
+}
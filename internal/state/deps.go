@@ -0,0 +1,103 @@
+package state
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// SortByDependencies orders entities so each comes after the entities in its dependsOn list
+// It uses Kahn's algorithm and otherwise keeps the given order. Dependencies on entities
+// not in the list are ignored, a dependency cycle is an error naming the cycle.
+func SortByDependencies(entities []types.EntityState) ([]types.EntityState, error) {
+	index := make(map[string]int, len(entities))
+	for i, entity := range entities {
+		index[entity.Entity] = i
+	}
+
+	indegree := make([]int, len(entities))
+	dependents := make([][]int, len(entities))
+	for i, entity := range entities {
+		for _, dep := range entity.DependsOn {
+			if j, ok := index[dep]; ok {
+				indegree[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	sorted := make([]types.EntityState, 0, len(entities))
+	done := make([]bool, len(entities))
+	for len(sorted) < len(entities) {
+		// Take the first ready entity so independent entities keep their order
+		next := -1
+		for i := range entities {
+			if !done[i] && indegree[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			return nil, fmt.Errorf("dependency cycle: %s", findCycle(entities, index, done))
+		}
+		done[next] = true
+		sorted = append(sorted, entities[next])
+		for _, i := range dependents[next] {
+			indegree[i]--
+		}
+	}
+	return sorted, nil
+}
+
+// findCycle follows dependencies among the unsorted entities until one repeats
+func findCycle(entities []types.EntityState, index map[string]int, done []bool) string {
+	start := 0
+	for done[start] {
+		start++
+	}
+
+	seen := map[int]int{}
+	var path []string
+	for i := start; ; {
+		if pos, ok := seen[i]; ok {
+			return strings.Join(append(path[pos:], entities[i].Entity), " -> ")
+		}
+		seen[i] = len(path)
+		path = append(path, entities[i].Entity)
+		for _, dep := range entities[i].DependsOn {
+			if j, ok := index[dep]; ok && !done[j] {
+				i = j
+				break
+			}
+		}
+	}
+}
+
+// validateDependencies checks that dependsOn names known entities and has no cycles
+func validateDependencies(entities []types.EntityState) error {
+	known := make(map[string]bool, len(entities))
+	for _, entity := range entities {
+		known[entity.Entity] = true
+	}
+
+	var errs []string
+	for _, entity := range entities {
+		for _, dep := range entity.DependsOn {
+			switch {
+			case dep == entity.Entity:
+				errs = append(errs, fmt.Sprintf("%s depends on itself", entity.Entity))
+			case !known[dep]:
+				errs = append(errs, fmt.Sprintf("%s depends on unknown entity %s", entity.Entity, dep))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid dependencies: %s", strings.Join(errs, "; "))
+	}
+
+	if _, err := SortByDependencies(entities); err != nil {
+		return err
+	}
+	return nil
+}
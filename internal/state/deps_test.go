@@ -0,0 +1,76 @@
+package state
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func entityNames(entities []types.EntityState) string {
+	names := make([]string, len(entities))
+	for i, entity := range entities {
+		names[i] = entity.Entity
+	}
+	return strings.Join(names, ",")
+}
+
+func TestSortByDependencies(t *testing.T) {
+	entities := []types.EntityState{
+		{Entity: "crm.order_lines", DependsOn: []string{"crm.orders", "crm.products"}},
+		{Entity: "crm.orders", DependsOn: []string{"crm.customers"}},
+		{Entity: "crm.notes"},
+		{Entity: "crm.customers"},
+		{Entity: "crm.products", DependsOn: []string{"archive.legacy"}}, // not in this run
+	}
+
+	sorted, err := SortByDependencies(entities)
+	if err != nil {
+		t.Fatalf("SortByDependencies() error = %v", err)
+	}
+	want := "crm.notes,crm.customers,crm.orders,crm.products,crm.order_lines"
+	if got := entityNames(sorted); got != want {
+		t.Errorf("SortByDependencies() = %s, want %s", got, want)
+	}
+}
+
+func TestSortByDependencies_Cycle(t *testing.T) {
+	entities := []types.EntityState{
+		{Entity: "crm.notes"},
+		{Entity: "crm.a", DependsOn: []string{"crm.c"}},
+		{Entity: "crm.b", DependsOn: []string{"crm.a"}},
+		{Entity: "crm.c", DependsOn: []string{"crm.b"}},
+	}
+
+	_, err := SortByDependencies(entities)
+	if err == nil {
+		t.Fatal("expected error for dependency cycle")
+	}
+	if !strings.Contains(err.Error(), "crm.a -> crm.c -> crm.b -> crm.a") {
+		t.Errorf("error = %v, want the cycle listed", err)
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	st := &File{entities: []types.EntityState{
+		{Entity: "crm.orders", DependsOn: []string{"crm.customers"}},
+		{Entity: "crm.customers"},
+	}}
+	if err := st.ValidateDependencies(); err != nil {
+		t.Errorf("ValidateDependencies() error = %v", err)
+	}
+
+	st.entities = append(st.entities,
+		types.EntityState{Entity: "crm.notes", DependsOn: []string{"crm.missing"}},
+		types.EntityState{Entity: "crm.self", DependsOn: []string{"crm.self"}},
+	)
+	err := st.ValidateDependencies()
+	if err == nil {
+		t.Fatal("expected error for invalid dependencies")
+	}
+	for _, want := range []string{"crm.notes depends on unknown entity crm.missing", "crm.self depends on itself"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error = %v, want %q", err, want)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package state
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// Diff changes, see DiffEntry
+const (
+	DiffAdded    = "added"
+	DiffRemoved  = "removed"
+	DiffModified = "modified"
+)
+
+// DiffEntry is an entity that differs between two state files, see Diff
+type DiffEntry struct {
+	Entity string   `json:"entity"`
+	Change string   `json:"change"`           // added, removed or modified
+	Active bool     `json:"active"`           // Active flag in the new state, or the old one if removed
+	Fields []string `json:"fields,omitempty"` // JSON names of the changed fields of a modified entity
+}
+
+// Diff returns the entities added, removed or modified from a to b, sorted by name
+func Diff(a, b *File) []DiffEntry {
+	before := make(map[string]types.EntityState)
+	for _, entity := range a.GetEntities() {
+		before[entity.Entity] = entity
+	}
+	after := make(map[string]types.EntityState)
+	for _, entity := range b.GetEntities() {
+		after[entity.Entity] = entity
+	}
+
+	var entries []DiffEntry
+	for name, old := range before {
+		current, ok := after[name]
+		if !ok {
+			entries = append(entries, DiffEntry{Entity: name, Change: DiffRemoved, Active: old.Active})
+			continue
+		}
+		if fields := changedFields(old, current); len(fields) > 0 {
+			entries = append(entries, DiffEntry{Entity: name, Change: DiffModified, Active: current.Active, Fields: fields})
+		}
+	}
+	for name, current := range after {
+		if _, ok := before[name]; !ok {
+			entries = append(entries, DiffEntry{Entity: name, Change: DiffAdded, Active: current.Active})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Entity < entries[j].Entity })
+	return entries
+}
+
+// changedFields returns the JSON names of the fields that differ between a and b
+// Empty and omitted lists or maps are equal, as they are in the state file.
+func changedFields(a, b types.EntityState) []string {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	var fields []string
+	for i := 0; i < va.NumField(); i++ {
+		fa, fb := va.Field(i), vb.Field(i)
+		if isEmptyCollection(fa) && isEmptyCollection(fb) {
+			continue
+		}
+		if !reflect.DeepEqual(fa.Interface(), fb.Interface()) {
+			name, _, _ := strings.Cut(va.Type().Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// isEmptyCollection reports whether v is a nil or empty slice or map
+func isEmptyCollection(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
@@ -0,0 +1,46 @@
+package state
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	tmpDir := t.TempDir()
+	fromPath := filepath.Join(tmpDir, "state.json")
+	toPath := filepath.Join(tmpDir, "state.json.new")
+	mustWriteFile(t, fromPath, `[
+  {"entity":"crm.orders","lastRunTime":"2025-01-13T08:00:00","active":true},
+  {"entity":"crm.users","lastRunTime":"","active":true,"tags":[]},
+  {"entity":"crm.legacy","lastRunTime":"","active":false}
+]`)
+	mustWriteFile(t, toPath, `[
+  {"entity":"crm.orders","lastRunTime":"2025-01-14T08:00:00","active":false},
+  {"entity":"crm.users","lastRunTime":"","active":true},
+  {"entity":"crm.invoices","lastRunTime":"","active":true}
+]`)
+
+	from, err := Load(fromPath, nil, "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	defer func() { _ = from.Close() }()
+	to, err := Load(toPath, nil, "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	defer func() { _ = to.Close() }()
+
+	want := []DiffEntry{
+		{Entity: "crm.invoices", Change: DiffAdded, Active: true},
+		{Entity: "crm.legacy", Change: DiffRemoved},
+		{Entity: "crm.orders", Change: DiffModified, Fields: []string{"lastRunTime", "active"}},
+	}
+	if got := Diff(from, to); !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+	if got := Diff(from, from); len(got) != 0 {
+		t.Errorf("Diff() of the same state = %+v, want no entries", got)
+	}
+}
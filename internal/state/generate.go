@@ -0,0 +1,58 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// GenerateStateFromSQLDir builds state entries for the *.sql files in sqlDir, sorted by name
+// Entities matching the glob pattern are active and others inactive; an empty pattern
+// makes all entities active. A positive initialDaysBack sets lastRunTime that many days
+// before now (UTC), otherwise lastRunTime is empty and the export default applies.
+func GenerateStateFromSQLDir(sqlDir, pattern string, initialDaysBack int) ([]types.EntityState, error) {
+	if pattern != "" {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid active pattern %q: %w", pattern, err)
+		}
+	}
+
+	dirEntries, err := os.ReadDir(sqlDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read SQL directory: %w", err)
+	}
+
+	var lastRunTime string
+	if initialDaysBack > 0 {
+		lastRunTime = time.Now().UTC().AddDate(0, 0, -initialDaysBack).Format("2006-01-02T15:04:05")
+	}
+
+	entities := []types.EntityState{}
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), ".sql") {
+			continue
+		}
+		name := strings.TrimSuffix(de.Name(), ".sql")
+		active := true
+		if pattern != "" {
+			active, _ = filepath.Match(pattern, name)
+		}
+		entities = append(entities, types.EntityState{Entity: name, LastRunTime: lastRunTime, Active: active})
+	}
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].Entity < entities[j].Entity
+	})
+
+	return entities, nil
+}
+
+// WriteNew writes entities as a new state file at path, replacing an existing file
+func WriteNew(path string, entities []types.EntityState, mode os.FileMode) error {
+	f := &File{path: path, entities: entities, mode: mode}
+	return f.save()
+}
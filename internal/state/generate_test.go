@@ -0,0 +1,92 @@
+package state
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGenerateStateFromSQLDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"hr.employees.sql", "crm.products.sql", "crm.orders.sql", "notes.txt"} {
+		mustWriteFile(t, filepath.Join(dir, name), "SELECT 1 FROM dual")
+	}
+	if err := os.Mkdir(filepath.Join(dir, "archive.sql"), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("pattern sets active flags", func(t *testing.T) {
+		entities, err := GenerateStateFromSQLDir(dir, "crm.*", 0)
+		if err != nil {
+			t.Fatalf("GenerateStateFromSQLDir() error: %v", err)
+		}
+
+		want := []struct {
+			name   string
+			active bool
+		}{
+			{"crm.orders", true},
+			{"crm.products", true},
+			{"hr.employees", false},
+		}
+		if len(entities) != len(want) {
+			t.Fatalf("got %d entities, want %d: %v", len(entities), len(want), entities)
+		}
+		for i, w := range want {
+			if entities[i].Entity != w.name || entities[i].Active != w.active {
+				t.Errorf("entity %d = %s (active %t), want %s (active %t)", i, entities[i].Entity, entities[i].Active, w.name, w.active)
+			}
+			if entities[i].LastRunTime != "" {
+				t.Errorf("LastRunTime = %q, want empty", entities[i].LastRunTime)
+			}
+		}
+	})
+
+	t.Run("no pattern and initial days back", func(t *testing.T) {
+		entities, err := GenerateStateFromSQLDir(dir, "", 7)
+		if err != nil {
+			t.Fatalf("GenerateStateFromSQLDir() error: %v", err)
+		}
+		for _, e := range entities {
+			if !e.Active {
+				t.Errorf("%s inactive, want active", e.Entity)
+			}
+			lastRun, err := time.Parse("2006-01-02T15:04:05", e.LastRunTime)
+			if err != nil {
+				t.Fatalf("LastRunTime %q: %v", e.LastRunTime, err)
+			}
+			if age := time.Since(lastRun); age < 7*24*time.Hour-time.Minute || age > 7*24*time.Hour+time.Minute {
+				t.Errorf("LastRunTime %s is %s ago, want 7 days", e.LastRunTime, age)
+			}
+		}
+	})
+
+	t.Run("invalid pattern", func(t *testing.T) {
+		if _, err := GenerateStateFromSQLDir(dir, "[", 0); err == nil {
+			t.Error("expected error for invalid pattern")
+		}
+	})
+}
+
+func TestWriteNew(t *testing.T) {
+	dir := t.TempDir()
+	mustWriteFile(t, filepath.Join(dir, "crm.products.sql"), "SELECT 1 FROM dual")
+	entities, err := GenerateStateFromSQLDir(dir, "", 0)
+	if err != nil {
+		t.Fatalf("GenerateStateFromSQLDir() error: %v", err)
+	}
+
+	statePath := filepath.Join(dir, "state.json")
+	if err := WriteNew(statePath, entities, 0600); err != nil {
+		t.Fatalf("WriteNew() error: %v", err)
+	}
+
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st.ActiveCount() != 1 {
+		t.Errorf("ActiveCount() = %d, want 1", st.ActiveCount())
+	}
+}
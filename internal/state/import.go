@@ -0,0 +1,247 @@
+package state
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// ImportResult reports the outcome of an entity import
+type ImportResult struct {
+	Added    []string // Entities added to the state
+	Replaced []string // Existing entities overwritten
+	Skipped  []string // Entities that already existed
+	Failed   []error  // Rows that could not be imported
+}
+
+// ImportOptions controls how an import treats existing entities and SQL files
+type ImportOptions struct {
+	// FailOnDuplicate stops the import at an existing entity, Overwrite replaces it, otherwise it is skipped
+	FailOnDuplicate bool
+	Overwrite       bool
+
+	// SQLDir, when set, is where the SQL file of each entity must exist for it to be imported
+	SQLDir string
+}
+
+// ErrDuplicateEntity is returned by an import for an existing entity when FailOnDuplicate is set
+var ErrDuplicateEntity = errors.New("entity already exists")
+
+// IsJSONLinesFile reports whether an import file is read as JSON Lines rather than CSV, by its extension
+func IsJSONLinesFile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".jsonl", ".ndjson", ".json":
+		return true
+	}
+	return false
+}
+
+// ImportEntitiesCSV adds entities from CSV to the state
+// The header row must contain entity and active columns; last_run_time, days_back and
+// tags (semicolon-separated) are optional. Invalid rows are reported in Failed.
+func ImportEntitiesCSV(st Store, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"entity", "active"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("CSV header is missing required column: %s", required)
+		}
+	}
+
+	// Reading by column name lets optional columns be omitted
+	reader.FieldsPerRecord = -1
+	field := func(record []string, name string) string {
+		i, ok := columns[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	result := &ImportResult{}
+	line := 1
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return result, fmt.Errorf("failed to read CSV: %w", err)
+		}
+
+		var tags []string
+		for _, tag := range strings.Split(field(record, "tags"), ";") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		row := importRow{
+			Entity:      field(record, "entity"),
+			LastRunTime: field(record, "last_run_time"),
+			Tags:        tags,
+		}
+		active := field(record, "active")
+		if active != "" {
+			isActive, err := strconv.ParseBool(active)
+			if err != nil {
+				result.Failed = append(result.Failed, fmt.Errorf("line %d: invalid active value %q for %s", line, active, row.Entity))
+				continue
+			}
+			row.Active = &isActive
+		}
+		if daysBack := field(record, "days_back"); daysBack != "" {
+			days, err := strconv.Atoi(daysBack)
+			if err != nil {
+				result.Failed = append(result.Failed, fmt.Errorf("line %d: invalid days_back %q for %s", line, daysBack, row.Entity))
+				continue
+			}
+			row.DaysBack = &days
+		}
+
+		if err := importEntity(st, row, opts, result); err != nil {
+			if errors.Is(err, ErrDuplicateEntity) {
+				return result, fmt.Errorf("line %d: %w", line, err)
+			}
+			result.Failed = append(result.Failed, fmt.Errorf("line %d: %w", line, err))
+		}
+	}
+
+	return result, nil
+}
+
+// ImportEntitiesJSONL adds entities from JSON Lines to the state, one object per line
+// with the fields of the CSV columns, e.g. {"entity": "crm.orders", "active": true, "days_back": 7, "tags": ["crm"]}.
+// Blank lines are ignored, invalid lines are reported in Failed.
+func ImportEntitiesJSONL(st Store, r io.Reader, opts ImportOptions) (*ImportResult, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	result := &ImportResult{}
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		var row importRow
+		decoder := json.NewDecoder(strings.NewReader(text))
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&row); err != nil {
+			result.Failed = append(result.Failed, fmt.Errorf("line %d: invalid JSON: %w", line, err))
+			continue
+		}
+
+		if err := importEntity(st, row, opts, result); err != nil {
+			if errors.Is(err, ErrDuplicateEntity) {
+				return result, fmt.Errorf("line %d: %w", line, err)
+			}
+			result.Failed = append(result.Failed, fmt.Errorf("line %d: %w", line, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read JSON Lines: %w", err)
+	}
+
+	return result, nil
+}
+
+// importRow is an entity as listed in an import file
+type importRow struct {
+	Entity      string   `json:"entity"`
+	Active      *bool    `json:"active"`
+	LastRunTime string   `json:"last_run_time"`
+	DaysBack    *int     `json:"days_back"`
+	Tags        []string `json:"tags"`
+}
+
+// importEntity validates a row and adds, replaces or skips its entity, recording the outcome in result
+// Only ErrDuplicateEntity stops the import, other errors fail the row.
+func importEntity(st Store, row importRow, opts ImportOptions, result *ImportResult) error {
+	entity, err := row.entityState()
+	if err != nil {
+		return err
+	}
+
+	_, exists := st.FindEntity(entity.Entity)
+	if exists && opts.FailOnDuplicate {
+		return fmt.Errorf("%w: %s", ErrDuplicateEntity, entity.Entity)
+	}
+	if exists && !opts.Overwrite {
+		result.Skipped = append(result.Skipped, entity.Entity)
+		return nil
+	}
+
+	if opts.SQLDir != "" {
+		sqlPath := st.GetSQLPath(opts.SQLDir, entity.Entity)
+		if _, err := os.Stat(sqlPath); err != nil {
+			if os.IsNotExist(err) {
+				return fmt.Errorf("SQL file %s not found for %s", sqlPath, entity.Entity)
+			}
+			return fmt.Errorf("failed to check SQL file: %w", err)
+		}
+	}
+
+	if exists {
+		if err := st.ReplaceEntity(entity); err != nil {
+			return err
+		}
+		result.Replaced = append(result.Replaced, entity.Entity)
+		return nil
+	}
+	if err := st.AddEntity(entity); err != nil {
+		return err
+	}
+	result.Added = append(result.Added, entity.Entity)
+	return nil
+}
+
+// entityState validates the row and builds the entity state, days_back is its daysBack
+func (row importRow) entityState() (types.EntityState, error) {
+	name := strings.TrimSpace(row.Entity)
+	entity := types.EntityState{Entity: name, LastRunTime: row.LastRunTime}
+	if name == "" {
+		return entity, fmt.Errorf("entity name is empty")
+	}
+	if row.Active == nil {
+		return entity, fmt.Errorf("active is missing for %s", name)
+	}
+	entity.Active = *row.Active
+
+	if row.DaysBack != nil {
+		entity.DaysBack = *row.DaysBack
+	}
+	if _, err := entity.GetLastRunTime(); err != nil {
+		return entity, fmt.Errorf("invalid last_run_time %q for %s", row.LastRunTime, name)
+	}
+
+	for _, tag := range row.Tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			entity.Tags = append(entity.Tags, tag)
+		}
+	}
+	if err := validateEntity(entity); err != nil {
+		return entity, err
+	}
+	return entity, nil
+}
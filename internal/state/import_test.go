@@ -0,0 +1,176 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// importCSV builds a CSV with n active entities
+func importCSV(n int) string {
+	var sb strings.Builder
+	sb.WriteString("entity,active,last_run_time,tags\n")
+	for i := 1; i <= n; i++ {
+		fmt.Fprintf(&sb, "crm.entity%02d,true,2025-01-14T00:00:00,crm;daily\n", i)
+	}
+	return sb.String()
+}
+
+func TestImportEntitiesCSV(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	mustWriteFile(t, statePath, `[]`)
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result, err := ImportEntitiesCSV(st, strings.NewReader(importCSV(10)), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportEntitiesCSV() error: %v", err)
+	}
+	if len(result.Added) != 10 || len(result.Skipped) != 0 || len(result.Failed) != 0 {
+		t.Fatalf("added=%d skipped=%d failed=%d, want 10/0/0", len(result.Added), len(result.Skipped), len(result.Failed))
+	}
+
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	reloaded, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reloaded.TotalCount() != 10 {
+		t.Errorf("got %d entities, want 10", reloaded.TotalCount())
+	}
+	entity, ok := reloaded.FindEntity("crm.entity01")
+	if !ok {
+		t.Fatal("crm.entity01 not found")
+	}
+	if !reflect.DeepEqual(entity.Tags, []string{"crm", "daily"}) {
+		t.Errorf("Tags = %v, want [crm daily]", entity.Tags)
+	}
+
+	t.Run("second import skips existing", func(t *testing.T) {
+		result, err := ImportEntitiesCSV(reloaded, strings.NewReader(importCSV(10)), ImportOptions{})
+		if err != nil {
+			t.Fatalf("ImportEntitiesCSV() error: %v", err)
+		}
+		if len(result.Added) != 0 || len(result.Skipped) != 10 {
+			t.Errorf("added=%d skipped=%d, want 0/10", len(result.Added), len(result.Skipped))
+		}
+	})
+
+	t.Run("fail on duplicate", func(t *testing.T) {
+		_, err := ImportEntitiesCSV(reloaded, strings.NewReader(importCSV(1)), ImportOptions{FailOnDuplicate: true})
+		if !errors.Is(err, ErrDuplicateEntity) {
+			t.Errorf("error = %v, want ErrDuplicateEntity", err)
+		}
+	})
+}
+
+func TestImportEntitiesCSV_InvalidRows(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	mustWriteFile(t, statePath, `[]`)
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	csvData := "entity,active,last_run_time,tags\n" +
+		"crm.ok,false,,daily\n" +
+		",true,,\n" +
+		"crm.bad,maybe,,\n" +
+		"crm.tagged,true,,Billing\n"
+	result, err := ImportEntitiesCSV(st, strings.NewReader(csvData), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportEntitiesCSV() error: %v", err)
+	}
+	if len(result.Added) != 1 || len(result.Failed) != 3 {
+		t.Errorf("added=%d failed=%d, want 1/3", len(result.Added), len(result.Failed))
+	}
+
+	if _, err := ImportEntitiesCSV(st, strings.NewReader("name,active\n"), ImportOptions{}); err == nil {
+		t.Error("expected error for missing entity column")
+	}
+}
+
+func TestImportEntitiesJSONL(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	mustWriteFile(t, statePath, `[{"entity": "crm.orders", "lastRunTime": "2025-01-01T00:00:00", "active": true}]`)
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data := `{"entity": "crm.orders", "active": false, "days_back": 7, "tags": ["crm"]}` + "\n" +
+		"\n" +
+		`{"entity": "crm.products", "active": true, "last_run_time": "2025-01-14T00:00:00"}` + "\n" +
+		`{"entity": "crm.users"}` + "\n" +
+		`{"entity": "crm.bad", "active": true, "days_back": 4000}` + "\n" +
+		`{"entity": "crm.typo", "activ": true}` + "\n"
+
+	result, err := ImportEntitiesJSONL(st, strings.NewReader(data), ImportOptions{})
+	if err != nil {
+		t.Fatalf("ImportEntitiesJSONL() error: %v", err)
+	}
+	if len(result.Added) != 1 || len(result.Skipped) != 1 || len(result.Failed) != 3 {
+		t.Fatalf("added=%d skipped=%d failed=%d, want 1/1/3", len(result.Added), len(result.Skipped), len(result.Failed))
+	}
+
+	t.Run("overwrite", func(t *testing.T) {
+		result, err := ImportEntitiesJSONL(st, strings.NewReader(data), ImportOptions{Overwrite: true})
+		if err != nil {
+			t.Fatalf("ImportEntitiesJSONL() error: %v", err)
+		}
+		if len(result.Replaced) != 2 || len(result.Added) != 0 {
+			t.Errorf("replaced=%d added=%d, want 2/0", len(result.Replaced), len(result.Added))
+		}
+		orders, _ := st.FindEntity("crm.orders")
+		if orders.Active || orders.DaysBack != 7 || orders.LastRunTime != "" || !reflect.DeepEqual(orders.Tags, []string{"crm"}) {
+			t.Errorf("crm.orders not replaced: %+v", *orders)
+		}
+	})
+}
+
+func TestImportEntitiesCSV_SQLDir(t *testing.T) {
+	dir := t.TempDir()
+	statePath := filepath.Join(dir, "state.json")
+	mustWriteFile(t, statePath, `[]`)
+	mustWriteFile(t, filepath.Join(dir, "crm.orders.sql"), "SELECT 1 FROM dual")
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	csvData := "entity,active,days_back\n" +
+		"crm.orders,true,3\n" +
+		"crm.products,true,\n" +
+		"crm.users,true,-1\n"
+	result, err := ImportEntitiesCSV(st, strings.NewReader(csvData), ImportOptions{SQLDir: dir})
+	if err != nil {
+		t.Fatalf("ImportEntitiesCSV() error: %v", err)
+	}
+	if !reflect.DeepEqual(result.Added, []string{"crm.orders"}) || len(result.Failed) != 2 {
+		t.Errorf("added=%v failed=%v, want [crm.orders] and 2 failures", result.Added, result.Failed)
+	}
+	if !strings.Contains(result.Failed[0].Error(), "SQL file") {
+		t.Errorf("unexpected error: %v", result.Failed[0])
+	}
+}
+
+func TestIsJSONLinesFile(t *testing.T) {
+	for path, want := range map[string]bool{
+		"entities.jsonl":  true,
+		"entities.NDJSON": true,
+		"entities.json":   true,
+		"entities.csv":    false,
+		"entities":        false,
+	} {
+		if got := IsJSONLinesFile(path); got != want {
+			t.Errorf("IsJSONLinesFile(%q) = %t, want %t", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// EntityStatus is the listing of a single entity, see ListEntities
+type EntityStatus struct {
+	Entity      string `json:"entity"`
+	Active      bool   `json:"active"`
+	LastRunTime string `json:"last_run_time"`
+	Age         string `json:"age"`                   // Human-readable time since the last run, "never" without one
+	AgeSeconds  int64  `json:"age_seconds,omitempty"` // Zero without a last run
+	SQLFile     string `json:"sql_file"`
+	SQLExists   bool   `json:"sql_exists"`
+}
+
+// ListEntities returns the status of every entity in the store, in state order
+// Ages are measured from now; an unparseable lastRunTime is reported as the age.
+func ListEntities(st Store, sqlDir string, now time.Time) []EntityStatus {
+	entities := st.GetEntities()
+	statuses := make([]EntityStatus, 0, len(entities))
+	for _, entity := range entities {
+		status := EntityStatus{
+			Entity:      entity.Entity,
+			Active:      entity.Active,
+			LastRunTime: entity.LastRunTime,
+			SQLFile:     st.GetSQLPath(sqlDir, entity.Entity),
+		}
+		if _, err := os.Stat(status.SQLFile); err == nil {
+			status.SQLExists = true
+		}
+
+		lastRun, err := entity.GetLastRunTime()
+		switch {
+		case err != nil:
+			status.Age = "invalid lastRunTime"
+		case lastRun.IsZero():
+			status.Age = "never"
+		default:
+			age := now.Sub(lastRun)
+			status.Age = FormatAge(age)
+			status.AgeSeconds = int64(age.Seconds())
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// FormatAge formats a duration as its two largest units, e.g. "3d 4h" or "5m 12s"
+func FormatAge(d time.Duration) string {
+	if d < 0 {
+		return "in the future"
+	}
+
+	days := int(d / (24 * time.Hour))
+	hours := int(d/time.Hour) % 24
+	minutes := int(d/time.Minute) % 60
+	seconds := int(d/time.Second) % 60
+
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd %dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh %dm", hours, minutes)
+	case minutes > 0:
+		return fmt.Sprintf("%dm %ds", minutes, seconds)
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// ActiveEntityNames returns the names of the active entities in the store, in state order
+func ActiveEntityNames(st Store) []string {
+	entities := st.GetActiveEntities()
+	names := make([]string, len(entities))
+	for i, entity := range entities {
+		names[i] = entity.Entity
+	}
+	return names
+}
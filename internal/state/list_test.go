@@ -0,0 +1,60 @@
+package state
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListEntities(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	sqlDir := filepath.Join(tmpDir, "sql")
+	mustMkdirAll(t, sqlDir)
+	mustWriteFile(t, filepath.Join(sqlDir, "crm.orders.sql"), "SELECT 1 FROM dual")
+	mustWriteFile(t, statePath, `[
+  {"entity":"crm.orders","lastRunTime":"2025-01-13T08:00:00","active":true},
+  {"entity":"crm.users","lastRunTime":"","active":false}
+]`)
+
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	now := time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC)
+	statuses := ListEntities(st, sqlDir, now)
+
+	if len(statuses) != 2 {
+		t.Fatalf("got %d statuses, want 2", len(statuses))
+	}
+	orders := statuses[0]
+	if orders.Entity != "crm.orders" || !orders.Active || !orders.SQLExists || orders.Age != "2d 4h" || orders.AgeSeconds != 189000 {
+		t.Errorf("unexpected status: %+v", orders)
+	}
+	users := statuses[1]
+	if users.Active || users.SQLExists || users.Age != "never" || users.AgeSeconds != 0 {
+		t.Errorf("unexpected status: %+v", users)
+	}
+	if users.SQLFile != filepath.Join(sqlDir, "crm.users.sql") {
+		t.Errorf("SQLFile = %q", users.SQLFile)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{42 * time.Second, "42s"},
+		{5*time.Minute + 12*time.Second, "5m 12s"},
+		{3*time.Hour + 7*time.Minute, "3h 7m"},
+		{50 * time.Hour, "2d 2h"},
+		{-time.Minute, "in the future"},
+	}
+	for _, tt := range tests {
+		if got := FormatAge(tt.d); got != tt.want {
+			t.Errorf("FormatAge(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// errLocked is returned by lockFile when another process holds the lock
+var errLocked = errors.New("lock is held by another process")
+
+// fileLock is an advisory lock on the <state>.lock sidecar file
+// The lock file holds the PID of the owner and is left in place when released.
+type fileLock struct {
+	file *os.File
+}
+
+// lockPath returns the sidecar lock file of a state file
+func lockPath(statePath string) string {
+	return statePath + ".lock"
+}
+
+// acquireLock takes the exclusive lock for a state file without waiting
+// The operating system releases the lock when its holder exits, so a crashed run leaves no stale lock.
+func acquireLock(statePath string) (*fileLock, error) {
+	f, err := os.OpenFile(lockPath(statePath), os.O_CREATE|os.O_RDWR, defaultFileMode)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state lock file: %w", err)
+	}
+
+	if err := lockFile(f); err != nil {
+		holder := lockHolder(f)
+		_ = f.Close()
+		if errors.Is(err, errLocked) {
+			return nil, fmt.Errorf("state file %s is locked by another ora2csv process%s", statePath, holder)
+		}
+		return nil, fmt.Errorf("failed to lock state file: %w", err)
+	}
+
+	if err := writeLockHolder(f); err != nil {
+		_ = unlockFile(f)
+		_ = f.Close()
+		return nil, err
+	}
+	return &fileLock{file: f}, nil
+}
+
+// release clears the owner PID and unlocks the lock file
+func (l *fileLock) release() error {
+	if l == nil || l.file == nil {
+		return nil
+	}
+	truncErr := l.file.Truncate(0)
+	unlockErr := unlockFile(l.file)
+	closeErr := l.file.Close()
+	l.file = nil
+	if err := errors.Join(truncErr, unlockErr, closeErr); err != nil {
+		return fmt.Errorf("failed to release state lock: %w", err)
+	}
+	return nil
+}
+
+// writeLockHolder records the current PID in the lock file
+func writeLockHolder(f *os.File) error {
+	if err := f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to write state lock file: %w", err)
+	}
+	if _, err := f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0); err != nil {
+		return fmt.Errorf("failed to write state lock file: %w", err)
+	}
+	return nil
+}
+
+// lockHolder returns " (pid N)" for error messages, or an empty string when unknown
+func lockHolder(f *os.File) string {
+	data, err := io.ReadAll(io.NewSectionReader(f, 0, 32))
+	if err != nil {
+		return ""
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (pid %d)", pid)
+}
@@ -0,0 +1,72 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad_Lock(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(statePath, []byte(`[{"entity":"crm.orders","active":true}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+
+	_, err = Load(statePath, nil, "")
+	if err == nil {
+		t.Fatal("expected error while the state file is locked")
+	}
+	if want := fmt.Sprintf("locked by another ora2csv process (pid %d)", os.Getpid()); !strings.Contains(err.Error(), want) {
+		t.Errorf("error %q does not contain %q", err, want)
+	}
+
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	if err := st.Close(); err != nil {
+		t.Fatalf("second Close() error: %v", err)
+	}
+
+	st2, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("Load() after Close error: %v", err)
+	}
+	if err := st2.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+}
+
+// A long export keeps its lock, however old the lock file is
+func TestLoad_OldLockStaysHeld(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	if err := os.WriteFile(statePath, []byte(`[]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	running, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	defer func() { _ = running.Close() }()
+
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(lockPath(statePath), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	if st, err := Load(statePath, nil, ""); err == nil {
+		_ = st.Close()
+		t.Fatal("expected error while the state file is locked")
+	}
+	if _, err := os.Stat(lockPath(statePath)); err != nil {
+		t.Errorf("lock file removed: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package state
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// MultiFile combines several state files into a single Store
+// Each entity belongs to exactly one file and updates are saved to that file
+type MultiFile struct {
+	files []*File
+	owner map[string]*File
+}
+
+// NewMultiFile combines state files, entity names must be unique across all files
+func NewMultiFile(files ...*File) (*MultiFile, error) {
+	m := &MultiFile{
+		files: files,
+		owner: make(map[string]*File),
+	}
+
+	for _, f := range files {
+		for _, e := range f.GetEntities() {
+			if other, ok := m.owner[e.Entity]; ok {
+				return nil, fmt.Errorf("entity %s is defined in both %s and %s", e.Entity, other.path, f.path)
+			}
+			m.owner[e.Entity] = f
+		}
+	}
+
+	return m, nil
+}
+
+// GetEntities returns all entities from all files
+func (m *MultiFile) GetEntities() []types.EntityState {
+	var result []types.EntityState
+	for _, f := range m.files {
+		result = append(result, f.GetEntities()...)
+	}
+	return result
+}
+
+// GetActiveEntities returns only active entities from all files
+func (m *MultiFile) GetActiveEntities() []types.EntityState {
+	var active []types.EntityState
+	for _, f := range m.files {
+		active = append(active, f.GetActiveEntities()...)
+	}
+	return active
+}
+
+// FindEntity finds an entity by name in its owning file
+func (m *MultiFile) FindEntity(name string) (*types.EntityState, bool) {
+	f, ok := m.owner[name]
+	if !ok {
+		return nil, false
+	}
+	return f.FindEntity(name)
+}
+
+// UpdateEntityTimestamp updates the lastRunTime in the file the entity came from
+func (m *MultiFile) UpdateEntityTimestamp(entityName string, timestamp string) error {
+	f, ok := m.owner[entityName]
+	if !ok {
+		return fmt.Errorf("entity not found: %s", entityName)
+	}
+	return f.UpdateEntityTimestamp(entityName, timestamp)
+}
+
+// UpdateEntityValue updates the lastRunTime and lastRunValue in the file the entity came from
+func (m *MultiFile) UpdateEntityValue(entityName string, timestamp string, value string) error {
+	f, ok := m.owner[entityName]
+	if !ok {
+		return fmt.Errorf("entity not found: %s", entityName)
+	}
+	return f.UpdateEntityValue(entityName, timestamp, value)
+}
+
+// SetActive sets the active flag in the file the entity came from
+func (m *MultiFile) SetActive(entityName string, active bool) error {
+	f, ok := m.owner[entityName]
+	if !ok {
+		return fmt.Errorf("entity not found: %s", entityName)
+	}
+	return f.SetActive(entityName, active)
+}
+
+// ResetTimestamp clears the lastRunTime in the file the entity came from
+func (m *MultiFile) ResetTimestamp(entityName string) error {
+	f, ok := m.owner[entityName]
+	if !ok {
+		return fmt.Errorf("entity not found: %s", entityName)
+	}
+	return f.ResetTimestamp(entityName)
+}
+
+// ResetAll clears the lastRunTime of every entity in all files
+func (m *MultiFile) ResetAll() ([]string, error) {
+	var reset []string
+	for _, f := range m.files {
+		names, err := f.ResetAll()
+		reset = append(reset, names...)
+		if err != nil {
+			return reset, fmt.Errorf("%s: %w", f.path, err)
+		}
+	}
+	return reset, nil
+}
+
+// AddEntity adds a new entity to the first state file
+func (m *MultiFile) AddEntity(entity types.EntityState) error {
+	if _, ok := m.owner[entity.Entity]; ok {
+		return fmt.Errorf("entity already exists: %s", entity.Entity)
+	}
+	if len(m.files) == 0 {
+		return fmt.Errorf("no state file to add entity %s to", entity.Entity)
+	}
+
+	f := m.files[0]
+	if err := f.AddEntity(entity); err != nil {
+		return err
+	}
+	m.owner[entity.Entity] = f
+	return nil
+}
+
+// ReplaceEntity replaces the entity in the file it came from
+func (m *MultiFile) ReplaceEntity(entity types.EntityState) error {
+	f, ok := m.owner[entity.Entity]
+	if !ok {
+		return fmt.Errorf("entity not found: %s", entity.Entity)
+	}
+	return f.ReplaceEntity(entity)
+}
+
+// GetSQLPath returns the path to the SQL file for an entity
+func (m *MultiFile) GetSQLPath(sqlDir, entityName string) string {
+	return filepath.Join(sqlDir, entityName+".sql")
+}
+
+// ValidateDependencies checks dependsOn across all files, entities may depend on another file's entities
+func (m *MultiFile) ValidateDependencies() error {
+	return validateDependencies(m.GetEntities())
+}
+
+// ValidateSQLFiles checks if SQL files exist for all active entities
+func (m *MultiFile) ValidateSQLFiles(sqlDir string) error {
+	var missing []string
+	for _, e := range m.GetActiveEntities() {
+		if _, err := os.Stat(m.GetSQLPath(sqlDir, e.Entity)); os.IsNotExist(err) {
+			missing = append(missing, e.Entity)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("missing SQL files for entities: %s", strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// TotalCount returns the total number of entities
+func (m *MultiFile) TotalCount() int {
+	count := 0
+	for _, f := range m.files {
+		count += f.TotalCount()
+	}
+	return count
+}
+
+// ActiveCount returns the number of active entities
+func (m *MultiFile) ActiveCount() int {
+	count := 0
+	for _, f := range m.files {
+		count += f.ActiveCount()
+	}
+	return count
+}
+
+// ExportStateCSV writes the entities of all files as one CSV
+func (m *MultiFile) ExportStateCSV(w io.Writer) error {
+	return writeStateCSV(w, m.GetEntities())
+}
+
+// EnableBackup backs up each state file before its first save
+func (m *MultiFile) EnableBackup() {
+	for _, f := range m.files {
+		f.EnableBackup()
+	}
+}
+
+// Close releases the locks of all state files
+func (m *MultiFile) Close() error {
+	var errs []error
+	for _, f := range m.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", f.path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
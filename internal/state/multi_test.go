@@ -0,0 +1,130 @@
+package state
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func loadTestFile(t *testing.T, dir, name, content string) *File {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	mustWriteFile(t, path, content)
+	f, err := Load(path, nil, "")
+	if err != nil {
+		t.Fatalf("Load(%s) error: %v", name, err)
+	}
+	return f
+}
+
+func TestNewMultiFile_DuplicateEntity(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := loadTestFile(t, tmpDir, "a.json", `[{"entity":"crm.orders","lastRunTime":"","active":true}]`)
+	b := loadTestFile(t, tmpDir, "b.json", `[{"entity":"crm.orders","lastRunTime":"","active":false}]`)
+
+	_, err := NewMultiFile(a, b)
+	if err == nil {
+		t.Fatal("expected error for entity defined in two state files")
+	}
+	if !strings.Contains(err.Error(), "crm.orders") {
+		t.Errorf("error %q does not name the duplicate entity", err)
+	}
+}
+
+func TestMultiFile_RoutesUpdates(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := loadTestFile(t, tmpDir, "a.json", `[
+		{"entity":"crm.orders","lastRunTime":"","active":true},
+		{"entity":"crm.archive","lastRunTime":"","active":false}
+	]`)
+	b := loadTestFile(t, tmpDir, "b.json", `[{"entity":"hr.people","lastRunTime":"","active":true}]`)
+
+	m, err := NewMultiFile(a, b)
+	if err != nil {
+		t.Fatalf("NewMultiFile() error: %v", err)
+	}
+	if m.TotalCount() != 3 || m.ActiveCount() != 2 {
+		t.Errorf("counts = %d/%d, want 3/2", m.TotalCount(), m.ActiveCount())
+	}
+	if got := len(m.GetActiveEntities()); got != 2 {
+		t.Errorf("got %d active entities, want 2", got)
+	}
+
+	if err := m.UpdateEntityTimestamp("hr.people", "2025-01-15T12:00:00"); err != nil {
+		t.Fatalf("UpdateEntityTimestamp() error: %v", err)
+	}
+	if err := m.UpdateEntityTimestamp("missing.entity", "2025-01-15T12:00:00"); err == nil {
+		t.Error("expected error for unknown entity")
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	// The update must land in b.json only
+	reloadedA, err := Load(filepath.Join(tmpDir, "a.json"), nil, "")
+	if err != nil {
+		t.Fatalf("Load(a.json) error: %v", err)
+	}
+	if _, ok := reloadedA.FindEntity("hr.people"); ok {
+		t.Error("hr.people was written to a.json")
+	}
+
+	reloadedB, err := Load(filepath.Join(tmpDir, "b.json"), nil, "")
+	if err != nil {
+		t.Fatalf("Load(b.json) error: %v", err)
+	}
+	entity, ok := reloadedB.FindEntity("hr.people")
+	if !ok || entity.LastRunTime != "2025-01-15T12:00:00" {
+		t.Errorf("hr.people not updated in b.json: %+v", entity)
+	}
+}
+
+func TestMultiFile_ValidateSQLFiles(t *testing.T) {
+	tmpDir := t.TempDir()
+	sqlDir := filepath.Join(tmpDir, "sql")
+	mustMkdirAll(t, sqlDir)
+	mustWriteFile(t, filepath.Join(sqlDir, "crm.orders.sql"), "SELECT 1 FROM dual")
+
+	a := loadTestFile(t, tmpDir, "a.json", `[{"entity":"crm.orders","lastRunTime":"","active":true}]`)
+	b := loadTestFile(t, tmpDir, "b.json", `[{"entity":"hr.people","lastRunTime":"","active":true}]`)
+
+	m, err := NewMultiFile(a, b)
+	if err != nil {
+		t.Fatalf("NewMultiFile() error: %v", err)
+	}
+
+	err = m.ValidateSQLFiles(sqlDir)
+	if err == nil || !strings.Contains(err.Error(), "hr.people") {
+		t.Errorf("ValidateSQLFiles() error = %v, want missing hr.people", err)
+	}
+}
+
+func TestMultiFile_ResetAll(t *testing.T) {
+	tmpDir := t.TempDir()
+	a := loadTestFile(t, tmpDir, "a.json", `[{"entity":"crm.orders","lastRunTime":"2025-01-01T00:00:00","active":true}]`)
+	b := loadTestFile(t, tmpDir, "b.json", `[{"entity":"hr.people","lastRunTime":"2025-01-02T00:00:00","active":true}]`)
+
+	m, err := NewMultiFile(a, b)
+	if err != nil {
+		t.Fatalf("NewMultiFile() error: %v", err)
+	}
+	reset, err := m.ResetAll()
+	if err != nil {
+		t.Fatalf("ResetAll() error: %v", err)
+	}
+	if len(reset) != 2 {
+		t.Errorf("ResetAll() = %v, want both entities", reset)
+	}
+
+	if err := m.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	reloaded, err := Load(filepath.Join(tmpDir, "b.json"), nil, "")
+	if err != nil {
+		t.Fatalf("Load(b.json) error: %v", err)
+	}
+	if e, _ := reloaded.FindEntity("hr.people"); e.LastRunTime != "" {
+		t.Errorf("b.json lastRunTime = %q, want empty", e.LastRunTime)
+	}
+}
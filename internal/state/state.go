@@ -1,49 +1,129 @@
 package state
 
 import (
+	"bytes"
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/internal/schedule"
 	"github.com/koltyakov/ora2csv/internal/storage"
 	"github.com/koltyakov/ora2csv/pkg/types"
 )
 
+// Store is the entity state used by the exporter
+// It is implemented by File and MultiFile
+type Store interface {
+	GetEntities() []types.EntityState
+	GetActiveEntities() []types.EntityState
+	FindEntity(name string) (*types.EntityState, bool)
+	UpdateEntityTimestamp(entityName string, timestamp string) error
+	UpdateEntityValue(entityName string, timestamp string, value string) error
+	ResetTimestamp(entityName string) error
+	SetActive(entityName string, active bool) error
+	ResetAll() ([]string, error)
+	AddEntity(entity types.EntityState) error
+	ReplaceEntity(entity types.EntityState) error
+	GetSQLPath(sqlDir, entityName string) string
+	ValidateSQLFiles(sqlDir string) error
+	ValidateDependencies() error
+	TotalCount() int
+	ActiveCount() int
+	ExportStateCSV(w io.Writer) error
+	EnableBackup()
+	Close() error
+}
+
 // File manages the state.json file
 type File struct {
-	mu       sync.RWMutex
-	path     string
-	entities []types.EntityState
-	s3       *storage.S3Client
-	s3Key    string // S3 key for state file
+	mu        sync.RWMutex
+	path      string
+	entities  []types.EntityState
+	remote    storage.StateStore
+	remoteKey string // S3 key or GCS object name of the state file
+	mode      os.FileMode
+	lock      *fileLock // held from Load until Close
+	backup    bool      // back up the previous file on the first save, see EnableBackup
+	backedUp  bool
+}
+
+// defaultFileMode is the permission of the state file unless configured
+const defaultFileMode os.FileMode = 0644
+
+// Load locks, reads and parses the state file
+// If remote is provided, it will try to load from S3 or GCS first, falling back to local file.
+// The lock is held until Close so concurrent runs cannot overwrite each other's state.
+func Load(path string, remote storage.StateStore, remoteKey string) (*File, error) {
+	return LoadWithMode(path, remote, remoteKey, defaultFileMode)
+}
+
+// LoadWithMode is like Load but writes the state file with the given permissions
+func LoadWithMode(path string, remote storage.StateStore, remoteKey string, mode os.FileMode) (*File, error) {
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+
+	lock, err := acquireLock(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := load(path, remote, remoteKey, mode)
+	if err != nil {
+		_ = lock.release()
+		return nil, err
+	}
+	f.mode = mode
+	f.lock = lock
+	return f, nil
 }
 
-// Load reads and parses the state file
-// If s3 is provided, it will try to load from S3 first, falling back to local file
-func Load(path string, s3 *storage.S3Client, s3Key string) (*File, error) {
+// Close releases the state file lock
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	err := f.lock.release()
+	f.lock = nil
+	return err
+}
+
+// load reads the state from remote storage or the local file
+func load(path string, remote storage.StateStore, remoteKey string, mode os.FileMode) (*File, error) {
 	var data []byte
 	var err error
 
-	// Try S3 first if available
-	if s3 != nil && s3Key != "" {
+	// Try remote storage first if available
+	if remote != nil && remoteKey != "" {
 		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 		defer cancel()
 
-		// Check if state exists in S3
-		exists, err := s3.Exists(ctx, s3Key)
+		// Check if state exists remotely
+		exists, err := remote.Exists(ctx, remoteKey)
 		if err == nil && exists {
-			// Download from S3
-			data, err = s3.DownloadBytes(ctx, s3Key)
+			// Download from remote storage
+			data, err = remote.DownloadBytes(ctx, remoteKey)
 			if err == nil {
-				// Successfully downloaded from S3, save local copy
-				_ = os.WriteFile(path, data, 0644)
-				return parseState(data, path, s3, s3Key)
+				// Fall back to the last recorded version if the state is corrupt
+				if _, parseErr := parseState(data, path, remote, remoteKey); parseErr != nil && remote.TrackVersions() {
+					restored, restoreErr := restoreVersion(ctx, remote, remoteKey, path)
+					if restoreErr != nil {
+						return nil, fmt.Errorf("%w (restore from recorded version failed: %v)", parseErr, restoreErr)
+					}
+					data = restored
+				}
+
+				// Successfully downloaded, save local copy
+				_ = os.WriteFile(path, data, mode)
+				return parseState(data, path, remote, remoteKey)
 			}
 			// On error, fall through to local file
 		}
@@ -52,36 +132,55 @@ func Load(path string, s3 *storage.S3Client, s3Key string) (*File, error) {
 	// Fall back to local file
 	data, err = os.ReadFile(path)
 	if err != nil {
-		// If local doesn't exist and S3 is enabled, return empty state
-		if s3 != nil && os.IsNotExist(err) {
+		// If local doesn't exist and remote storage is enabled, return empty state
+		if remote != nil && os.IsNotExist(err) {
 			return &File{
-				path:     path,
-				entities: []types.EntityState{},
-				s3:       s3,
-				s3Key:    s3Key,
+				path:      path,
+				entities:  []types.EntityState{},
+				remote:    remote,
+				remoteKey: remoteKey,
 			}, nil
 		}
 		return nil, fmt.Errorf("failed to read state file: %w", err)
 	}
 
-	return parseState(data, path, s3, s3Key)
+	return parseState(data, path, remote, remoteKey)
 }
 
 // parseState parses state data and returns a File
-func parseState(data []byte, path string, s3 *storage.S3Client, s3Key string) (*File, error) {
+func parseState(data []byte, path string, remote storage.StateStore, remoteKey string) (*File, error) {
 	var entities []types.EntityState
 	if err := json.Unmarshal(data, &entities); err != nil {
 		return nil, fmt.Errorf("failed to parse state file: %w", err)
 	}
+	for _, entity := range entities {
+		if err := validateEntity(entity); err != nil {
+			return nil, fmt.Errorf("invalid state file: %w", err)
+		}
+	}
 
 	return &File{
-		path:     path,
-		entities: entities,
-		s3:       s3,
-		s3Key:    s3Key,
+		path:      path,
+		entities:  entities,
+		remote:    remote,
+		remoteKey: remoteKey,
 	}, nil
 }
 
+// validateEntity checks the tags, daysBack and schedule of entity
+func validateEntity(entity types.EntityState) error {
+	if err := validateTags(entity); err != nil {
+		return err
+	}
+	if entity.DaysBack < 0 || entity.DaysBack > config.MaxDaysBack {
+		return fmt.Errorf("entity %s: daysBack must be between 0 and %d", entity.Entity, config.MaxDaysBack)
+	}
+	if err := schedule.Validate(entity.Schedule); err != nil {
+		return fmt.Errorf("entity %s: %w", entity.Entity, err)
+	}
+	return nil
+}
+
 // GetEntities returns all entities
 func (f *File) GetEntities() []types.EntityState {
 	f.mu.RLock()
@@ -140,7 +239,64 @@ func (f *File) UpdateEntityTimestamp(entityName string, timestamp string) error
 	return f.save()
 }
 
-// save writes the state to disk atomically and uploads to S3 if configured
+// UpdateEntityValue updates the lastRunTime and sequence lastRunValue for an entity
+func (f *File) UpdateEntityValue(entityName string, timestamp string, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.entities {
+		if f.entities[i].Entity == entityName {
+			f.entities[i].LastRunTime = timestamp
+			f.entities[i].LastRunValue = value
+			return f.save()
+		}
+	}
+	return fmt.Errorf("entity not found: %s", entityName)
+}
+
+// SetActive sets whether an entity is exported
+func (f *File) SetActive(entityName string, active bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.entities {
+		if f.entities[i].Entity == entityName {
+			f.entities[i].Active = active
+			return f.save()
+		}
+	}
+	return fmt.Errorf("entity not found: %s", entityName)
+}
+
+// ResetTimestamp clears the lastRunTime and lastRunValue of an entity,
+// its next export starts from the default days back (or the first sequence value)
+func (f *File) ResetTimestamp(entityName string) error {
+	return f.UpdateEntityValue(entityName, "", "")
+}
+
+// ResetAll clears the lastRunTime and lastRunValue of every entity and returns the names of those that had one
+// The file is only saved when something changed.
+func (f *File) ResetAll() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var reset []string
+	for i := range f.entities {
+		if f.entities[i].LastRunTime == "" && f.entities[i].LastRunValue == "" {
+			continue
+		}
+		f.entities[i].LastRunTime = ""
+		f.entities[i].LastRunValue = ""
+		reset = append(reset, f.entities[i].Entity)
+	}
+
+	if len(reset) == 0 {
+		return nil, nil
+	}
+	return reset, f.save()
+}
+
+// save writes the state to disk atomically and uploads to remote storage if configured
 func (f *File) save() error {
 	// Sort entities by name for consistent output
 	sorted := make([]types.EntityState, len(f.entities))
@@ -156,9 +312,17 @@ func (f *File) save() error {
 
 	// Write to temporary file first
 	tmpPath := f.path + ".tmp"
-	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+	mode := f.mode
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+	if err := os.WriteFile(tmpPath, data, mode); err != nil {
 		return fmt.Errorf("failed to write temp file: %w", err)
 	}
+	if err := f.backupBeforeSave(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
 
 	// Atomic rename
 	if err := os.Rename(tmpPath, f.path); err != nil {
@@ -168,24 +332,74 @@ func (f *File) save() error {
 		return fmt.Errorf("failed to rename temp file: %w", err)
 	}
 
-	// Upload to S3 if configured
-	if f.s3 != nil && f.s3Key != "" {
+	// Upload to remote storage if configured
+	if f.remote != nil && f.remoteKey != "" {
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
-		if err := f.s3.UploadBytes(ctx, f.s3Key, data); err != nil {
-			return fmt.Errorf("failed to upload state to S3 (key=%s): %w", f.s3Key, err)
+		versionID, err := f.remote.UploadWithVersion(ctx, f.remoteKey, bytes.NewReader(data))
+		if err != nil {
+			return fmt.Errorf("failed to upload state (key=%s): %w", f.remoteKey, err)
+		}
+
+		// Remember the version so a corrupted state can be restored later
+		if f.remote.TrackVersions() && versionID != "" {
+			if err := recordVersion(versionsPath(f.path), f.remoteKey, versionID, f.mode); err != nil {
+				return fmt.Errorf("failed to record state version: %w", err)
+			}
 		}
 	}
 
 	return nil
 }
 
+// AddEntity appends a new entity and saves the state
+func (f *File) AddEntity(entity types.EntityState) error {
+	if err := validateEntity(entity); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.entities {
+		if f.entities[i].Entity == entity.Entity {
+			return fmt.Errorf("entity already exists: %s", entity.Entity)
+		}
+	}
+	f.entities = append(f.entities, entity)
+
+	return f.save()
+}
+
+// ReplaceEntity replaces an existing entity of the same name and saves the state
+func (f *File) ReplaceEntity(entity types.EntityState) error {
+	if err := validateEntity(entity); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for i := range f.entities {
+		if f.entities[i].Entity == entity.Entity {
+			f.entities[i] = entity
+			return f.save()
+		}
+	}
+	return fmt.Errorf("entity not found: %s", entity.Entity)
+}
+
 // GetSQLPath returns the path to the SQL file for an entity
 func (f *File) GetSQLPath(sqlDir, entityName string) string {
 	return filepath.Join(sqlDir, entityName+".sql")
 }
 
+// ValidateDependencies checks that dependsOn names known entities and has no cycles
+func (f *File) ValidateDependencies() error {
+	return validateDependencies(f.GetEntities())
+}
+
 // ValidateSQLFiles checks if SQL files exist for all active entities
 func (f *File) ValidateSQLFiles(sqlDir string) error {
 	f.mu.RLock()
@@ -208,6 +422,34 @@ func (f *File) ValidateSQLFiles(sqlDir string) error {
 	return nil
 }
 
+// ExportStateCSV writes all entities as CSV for reporting tools
+func (f *File) ExportStateCSV(w io.Writer) error {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return writeStateCSV(w, f.entities)
+}
+
+// writeStateCSV writes entities as CSV with an entity, active, last_run_time, display_name, tags header
+// Tags are joined with semicolons.
+func writeStateCSV(w io.Writer, entities []types.EntityState) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"entity", "active", "last_run_time", "display_name", "tags"}); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	for _, e := range entities {
+		record := []string{e.Entity, strconv.FormatBool(e.Active), e.LastRunTime, e.DisplayName, strings.Join(e.Tags, ";")}
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write entity %s: %w", e.Entity, err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush CSV: %w", err)
+	}
+	return nil
+}
+
 // TotalCount returns the total number of entities
 func (f *File) TotalCount() int {
 	f.mu.RLock()
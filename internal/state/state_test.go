@@ -1,9 +1,18 @@
 package state
 
 import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/pkg/types"
 )
 
 func mustWriteFile(t *testing.T, path, content string) {
@@ -51,6 +60,39 @@ func TestLoad(t *testing.T) {
 		}
 	})
 
+	t.Run("invalid tag", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		statePath := filepath.Join(tmpDir, "state.json")
+		mustWriteFile(t, statePath, `[{"entity":"test.entity1","active":true,"tags":["Daily"]}]`)
+
+		_, err := Load(statePath, nil, "")
+		if err == nil || !strings.Contains(err.Error(), "test.entity1") {
+			t.Errorf("expected error naming the entity with an invalid tag, got %v", err)
+		}
+	})
+
+	t.Run("invalid schedule", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		statePath := filepath.Join(tmpDir, "state.json")
+		mustWriteFile(t, statePath, `[{"entity":"test.entity1","active":true,"schedule":"weekdays 06:00-08:00"}]`)
+
+		_, err := Load(statePath, nil, "")
+		if err == nil || !strings.Contains(err.Error(), "test.entity1") {
+			t.Errorf("expected error naming the entity with an invalid schedule, got %v", err)
+		}
+	})
+
+	t.Run("daysBack out of range", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		statePath := filepath.Join(tmpDir, "state.json")
+		mustWriteFile(t, statePath, `[{"entity":"test.entity1","active":true,"daysBack":4000}]`)
+
+		_, err := Load(statePath, nil, "")
+		if err == nil || !strings.Contains(err.Error(), "daysBack") {
+			t.Errorf("expected daysBack error, got %v", err)
+		}
+	})
+
 	t.Run("invalid JSON", func(t *testing.T) {
 		tmpDir := t.TempDir()
 		statePath := filepath.Join(tmpDir, "state.json")
@@ -177,6 +219,10 @@ func TestUpdateEntityTimestamp(t *testing.T) {
 		t.Errorf("unexpected error: %v", err)
 	}
 
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
 	// Verify state was persisted
 	st2, err := Load(statePath, nil, "")
 	if err != nil {
@@ -192,6 +238,82 @@ func TestUpdateEntityTimestamp(t *testing.T) {
 	}
 }
 
+func TestSetActive(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	mustWriteFile(t, statePath, `[{"entity":"test.entity1","lastRunTime":"2025-01-01T00:00:00","active":true}]`)
+
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := st.SetActive("test.entity1", false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := st.SetActive("nonexistent", false); err == nil {
+		t.Error("expected error for nonexistent entity, got nil")
+	}
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
+	st2, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entity, found := st2.FindEntity("test.entity1")
+	if !found {
+		t.Fatal("entity not found")
+	}
+	if entity.Active || entity.LastRunTime != "2025-01-01T00:00:00" {
+		t.Errorf("got active=%t lastRunTime=%q, want inactive with unchanged lastRunTime", entity.Active, entity.LastRunTime)
+	}
+}
+
+func TestUpdateEntityValue(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+
+	testState := `[{"entity":"test.orders","lastRunTime":"","active":true,"incrementalColumn":"ORDER_ID","incrementalType":"sequence"}]`
+	mustWriteFile(t, statePath, testState)
+
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := st.UpdateEntityValue("test.orders", "2025-01-15T12:00:00", "1042"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := st.UpdateEntityValue("nonexistent", "2025-01-15T12:00:00", "1"); err == nil {
+		t.Error("expected error for nonexistent entity, got nil")
+	}
+
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	st2, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entity, found := st2.FindEntity("test.orders")
+	if !found {
+		t.Fatal("entity not found")
+	}
+	if entity.LastRunTime != "2025-01-15T12:00:00" || entity.LastRunValue != "1042" {
+		t.Errorf("got lastRunTime %q, lastRunValue %q", entity.LastRunTime, entity.LastRunValue)
+	}
+
+	// Reset clears the sequence watermark too
+	if err := st2.ResetTimestamp("test.orders"); err != nil {
+		t.Fatalf("ResetTimestamp() error: %v", err)
+	}
+	entity, _ = st2.FindEntity("test.orders")
+	if entity.LastRunTime != "" || entity.LastRunValue != "" {
+		t.Errorf("after reset got lastRunTime %q, lastRunValue %q", entity.LastRunTime, entity.LastRunValue)
+	}
+}
+
 func TestUpdateEntityTimestamp_NotFound(t *testing.T) {
 	tmpDir := t.TempDir()
 	statePath := filepath.Join(tmpDir, "state.json")
@@ -357,6 +479,10 @@ func TestSave_SortsEntities(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
 	// Reload and verify entities are still there and sorted
 	st2, err := Load(statePath, nil, "")
 	if err != nil {
@@ -400,6 +526,10 @@ func TestSave_Atomic(t *testing.T) {
 		}
 	}
 
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+
 	// Final state should be valid
 	st2, err := Load(statePath, nil, "")
 	if err != nil {
@@ -409,3 +539,206 @@ func TestSave_Atomic(t *testing.T) {
 		t.Errorf("got %d entities, want 1", st2.TotalCount())
 	}
 }
+
+func TestSave_FileMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	mustWriteFile(t, statePath, `[{"entity":"test.entity1","lastRunTime":"","active":true}]`)
+
+	st, err := LoadWithMode(statePath, nil, "", 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := st.UpdateEntityTimestamp("test.entity1", "2025-01-15T12:00:00"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	info, err := os.Stat(statePath)
+	if err != nil {
+		t.Fatalf("Stat() error: %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("state file mode = %o, want 600", got)
+	}
+}
+
+func TestAddEntity(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	mustWriteFile(t, statePath, `[{"entity":"test.entity1","lastRunTime":"","active":true}]`)
+
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := st.AddEntity(types.EntityState{Entity: "test.entity2", LastRunTime: "2025-01-15T12:00:00", Active: true}); err != nil {
+		t.Fatalf("AddEntity() error: %v", err)
+	}
+	if err := st.AddEntity(types.EntityState{Entity: "test.entity1"}); err == nil {
+		t.Error("expected error for existing entity")
+	}
+	if err := st.AddEntity(types.EntityState{Entity: "test.entity3", Tags: []string{""}}); err == nil {
+		t.Error("expected error for empty tag")
+	}
+
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	reloaded, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reloaded.TotalCount() != 2 {
+		t.Errorf("got %d entities, want 2", reloaded.TotalCount())
+	}
+}
+
+func TestExportStateCSV(t *testing.T) {
+	statePath := filepath.Join(t.TempDir(), "state.json")
+	mustWriteFile(t, statePath, `[
+		{"entity":"crm.orders","lastRunTime":"2025-01-14T10:00:00","active":true,"displayName":"Orders","tags":["billing","daily"]},
+		{"entity":"odd,name","lastRunTime":"","active":false}
+	]`)
+
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := st.ExportStateCSV(&buf); err != nil {
+		t.Fatalf("ExportStateCSV() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), `"odd,name"`) {
+		t.Errorf("entity name with comma not quoted:\n%s", buf.String())
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse CSV: %v", err)
+	}
+	want := [][]string{
+		{"entity", "active", "last_run_time", "display_name", "tags"},
+		{"crm.orders", "true", "2025-01-14T10:00:00", "Orders", "billing;daily"},
+		{"odd,name", "false", "", "", ""},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %v, want %v", records, want)
+	}
+
+	// Several state files write the same columns
+	m, err := NewMultiFile(st)
+	if err != nil {
+		t.Fatalf("NewMultiFile() error: %v", err)
+	}
+	var multi bytes.Buffer
+	if err := m.ExportStateCSV(&multi); err != nil {
+		t.Fatalf("MultiFile.ExportStateCSV() error: %v", err)
+	}
+	if records, _ := csv.NewReader(&multi).ReadAll(); !reflect.DeepEqual(records, want) {
+		t.Errorf("MultiFile records = %v, want %v", records, want)
+	}
+}
+
+// memStateStore is an in-memory storage.StateStore
+type memStateStore struct {
+	prefix  string
+	objects map[string][]byte
+}
+
+func (m *memStateStore) Key(filename string) string { return m.prefix + filename }
+
+func (m *memStateStore) Exists(_ context.Context, key string) (bool, error) {
+	_, ok := m.objects[key]
+	return ok, nil
+}
+
+func (m *memStateStore) DownloadBytes(_ context.Context, key string) ([]byte, error) {
+	return m.objects[key], nil
+}
+
+func (m *memStateStore) UploadWithVersion(_ context.Context, key string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	m.objects[key] = data
+	return "", err
+}
+
+func (m *memStateStore) GetVersion(context.Context, string, string) (io.ReadCloser, error) {
+	return nil, os.ErrNotExist
+}
+
+func (m *memStateStore) TrackVersions() bool { return false }
+
+func TestLoadFromConfig_RemoteState(t *testing.T) {
+	tmpDir := t.TempDir()
+	remote := &memStateStore{prefix: "exports/", objects: map[string][]byte{
+		"exports/state.json": []byte(`[{"entity":"test.entity1","lastRunTime":"2025-01-01T00:00:00","active":true}]`),
+	}}
+	cfg := &config.Config{StateFile: filepath.Join(tmpDir, "state.json")}
+
+	st, err := LoadFromConfig(cfg, remote)
+	if err != nil {
+		t.Fatalf("LoadFromConfig() error: %v", err)
+	}
+	if _, found := st.FindEntity("test.entity1"); !found {
+		t.Fatal("entity from remote state not found")
+	}
+	if _, err := os.Stat(cfg.StateFile); err != nil {
+		t.Errorf("local copy of remote state not written: %v", err)
+	}
+
+	if err := st.UpdateEntityTimestamp("test.entity1", "2025-01-15T12:00:00"); err != nil {
+		t.Fatalf("UpdateEntityTimestamp() error: %v", err)
+	}
+	if !strings.Contains(string(remote.objects["exports/state.json"]), "2025-01-15T12:00:00") {
+		t.Errorf("remote state not updated: %s", remote.objects["exports/state.json"])
+	}
+
+	// Multiple state files map to keys named after their files
+	cfg.StateFiles = []string{filepath.Join(tmpDir, "a.json"), filepath.Join(tmpDir, "sub", "a.json")}
+	if _, err := LoadFromConfig(cfg, remote); err == nil || !strings.Contains(err.Error(), "exports/a.json") {
+		t.Errorf("LoadFromConfig() with clashing keys error = %v", err)
+	}
+}
+
+func TestResetTimestamps(t *testing.T) {
+	tmpDir := t.TempDir()
+	statePath := filepath.Join(tmpDir, "state.json")
+	mustWriteFile(t, statePath, `[
+  {"entity":"test.entity1","lastRunTime":"2025-01-01T00:00:00","active":true},
+  {"entity":"test.entity2","lastRunTime":"2025-01-02T00:00:00","active":false},
+  {"entity":"test.entity3","lastRunTime":"","active":true}
+]`)
+
+	st, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := st.ResetTimestamp("test.entity1"); err != nil {
+		t.Fatalf("ResetTimestamp() error: %v", err)
+	}
+	if err := st.ResetTimestamp("test.missing"); err == nil {
+		t.Error("expected error for missing entity")
+	}
+
+	reset, err := st.ResetAll()
+	if err != nil {
+		t.Fatalf("ResetAll() error: %v", err)
+	}
+	if !reflect.DeepEqual(reset, []string{"test.entity2"}) {
+		t.Errorf("ResetAll() = %v, want [test.entity2]", reset)
+	}
+
+	if err := st.Close(); err != nil {
+		t.Fatalf("Close() error: %v", err)
+	}
+	reloaded, err := Load(statePath, nil, "")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, e := range reloaded.GetEntities() {
+		if e.LastRunTime != "" {
+			t.Errorf("%s lastRunTime = %q, want empty", e.Entity, e.LastRunTime)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package state
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// validateTags checks that every entity tag is a lowercase identifier, see config.ValidateTag
+func validateTags(entity types.EntityState) error {
+	for _, tag := range entity.Tags {
+		if err := config.ValidateTag(tag); err != nil {
+			return fmt.Errorf("entity %s: %w", entity.Entity, err)
+		}
+	}
+	return nil
+}
+
+// FilterByTags returns the entities having at least one include tag and none of the exclude tags
+// An empty include list keeps every entity that is not excluded.
+func FilterByTags(entities []types.EntityState, include, exclude []string) []types.EntityState {
+	if len(include) == 0 && len(exclude) == 0 {
+		return entities
+	}
+	var result []types.EntityState
+	for _, entity := range entities {
+		if len(include) > 0 && !hasAnyTag(entity, include) {
+			continue
+		}
+		if hasAnyTag(entity, exclude) {
+			continue
+		}
+		result = append(result, entity)
+	}
+	return result
+}
+
+// hasAnyTag reports whether entity has one of tags
+func hasAnyTag(entity types.EntityState, tags []string) bool {
+	for _, tag := range tags {
+		if slices.Contains(entity.Tags, tag) {
+			return true
+		}
+	}
+	return false
+}
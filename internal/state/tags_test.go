@@ -0,0 +1,44 @@
+package state
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+func TestFilterByTags(t *testing.T) {
+	entities := []types.EntityState{
+		{Entity: "crm.invoices", Tags: []string{"billing", "daily"}},
+		{Entity: "crm.orders", Tags: []string{"daily"}},
+		{Entity: "crm.sandbox", Tags: []string{"daily", "experimental"}},
+		{Entity: "crm.archive"},
+	}
+	names := func(entities []types.EntityState) []string {
+		var result []string
+		for _, entity := range entities {
+			result = append(result, entity.Entity)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name             string
+		include, exclude []string
+		want             []string
+	}{
+		{"no filter", nil, nil, []string{"crm.invoices", "crm.orders", "crm.sandbox", "crm.archive"}},
+		{"include any", []string{"billing", "full"}, nil, []string{"crm.invoices"}},
+		{"include and exclude", []string{"daily"}, []string{"experimental"}, []string{"crm.invoices", "crm.orders"}},
+		{"exclude keeps untagged", nil, []string{"daily"}, []string{"crm.archive"}},
+		{"no match", []string{"weekly"}, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := names(FilterByTags(entities, tt.include, tt.exclude))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FilterByTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/koltyakov/ora2csv/internal/storage"
+)
+
+const (
+	// versionsFileName is the local sidecar recording uploaded state file versions
+	versionsFileName = ".s3-versions"
+	// maxTrackedVersions is the number of versions kept per S3 key
+	maxTrackedVersions = 10
+)
+
+// versionsPath returns the path of the versions sidecar for a state file
+func versionsPath(statePath string) string {
+	return filepath.Join(filepath.Dir(statePath), versionsFileName)
+}
+
+// loadVersions reads the recorded S3 version IDs keyed by S3 key (oldest first)
+// A missing sidecar returns an empty map
+func loadVersions(path string) (map[string][]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string][]string{}, nil
+		}
+		return nil, fmt.Errorf("failed to read versions file: %w", err)
+	}
+
+	versions := map[string][]string{}
+	if err := json.Unmarshal(data, &versions); err != nil {
+		return nil, fmt.Errorf("failed to parse versions file: %w", err)
+	}
+	return versions, nil
+}
+
+// recordVersion appends a version ID for an S3 key to the sidecar, created with the state file mode
+func recordVersion(path, key, versionID string, mode os.FileMode) error {
+	versions, err := loadVersions(path)
+	if err != nil {
+		return err
+	}
+
+	list := append(versions[key], versionID)
+	if len(list) > maxTrackedVersions {
+		list = list[len(list)-maxTrackedVersions:]
+	}
+	versions[key] = list
+
+	data, err := json.MarshalIndent(versions, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal versions: %w", err)
+	}
+	if mode == 0 {
+		mode = defaultFileMode
+	}
+	if err := os.WriteFile(path, data, mode); err != nil {
+		return fmt.Errorf("failed to write versions file: %w", err)
+	}
+	return nil
+}
+
+// restoreVersion looks for the newest recorded S3 version of the state file
+// that still parses and returns its content
+func restoreVersion(ctx context.Context, remote storage.StateStore, remoteKey, statePath string) ([]byte, error) {
+	versions, err := loadVersions(versionsPath(statePath))
+	if err != nil {
+		return nil, err
+	}
+
+	list := versions[remoteKey]
+	if len(list) == 0 {
+		return nil, fmt.Errorf("no recorded versions for %s", remoteKey)
+	}
+
+	for i := len(list) - 1; i >= 0; i-- {
+		data, err := downloadVersion(ctx, remote, remoteKey, list[i])
+		if err != nil {
+			continue
+		}
+		if _, err := parseState(data, statePath, remote, remoteKey); err == nil {
+			return data, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no valid recorded version for %s", remoteKey)
+}
+
+// downloadVersion reads a specific version of an S3 object
+func downloadVersion(ctx context.Context, remote storage.StateStore, key, versionID string) ([]byte, error) {
+	reader, err := remote.GetVersion(ctx, key, versionID)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	return io.ReadAll(reader)
+}
@@ -0,0 +1,82 @@
+package state
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVersionsPath(t *testing.T) {
+	got := versionsPath(filepath.Join("data", "state.json"))
+	want := filepath.Join("data", ".s3-versions")
+	if got != want {
+		t.Errorf("versionsPath() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadVersions_Missing(t *testing.T) {
+	versions, err := loadVersions(filepath.Join(t.TempDir(), ".s3-versions"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(versions) != 0 {
+		t.Errorf("got %d keys, want 0", len(versions))
+	}
+}
+
+func TestLoadVersions_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".s3-versions")
+	mustWriteFile(t, path, "not json")
+
+	if _, err := loadVersions(path); err == nil {
+		t.Error("expected error for invalid versions file")
+	}
+}
+
+func TestRecordVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".s3-versions")
+
+	for i := 0; i < maxTrackedVersions+2; i++ {
+		if err := recordVersion(path, "prefix/state.json", fmt.Sprintf("v%d", i), 0); err != nil {
+			t.Fatalf("recordVersion() error = %v", err)
+		}
+	}
+	if err := recordVersion(path, "other/state.json", "x1", 0); err != nil {
+		t.Fatalf("recordVersion() error = %v", err)
+	}
+
+	versions, err := loadVersions(path)
+	if err != nil {
+		t.Fatalf("loadVersions() error = %v", err)
+	}
+
+	list := versions["prefix/state.json"]
+	if len(list) != maxTrackedVersions {
+		t.Fatalf("got %d versions, want %d", len(list), maxTrackedVersions)
+	}
+	if list[0] != "v2" {
+		t.Errorf("oldest version = %q, want v2", list[0])
+	}
+	if list[len(list)-1] != fmt.Sprintf("v%d", maxTrackedVersions+1) {
+		t.Errorf("newest version = %q", list[len(list)-1])
+	}
+	if got := versions["other/state.json"]; len(got) != 1 || got[0] != "x1" {
+		t.Errorf("other key versions = %v, want [x1]", got)
+	}
+}
+
+func TestRecordVersion_FileMode(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".s3-versions")
+	if err := recordVersion(path, "prefix/state.json", "v1", 0600); err != nil {
+		t.Fatalf("recordVersion() error = %v", err)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() error: %v", err)
+	}
+	if got := info.Mode().Perm(); got != 0600 {
+		t.Errorf("versions file mode = %o, want 600", got)
+	}
+}
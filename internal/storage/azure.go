@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/policy"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+)
+
+const (
+	// azureBlockSize is the size of the blocks a blob is uploaded in
+	azureBlockSize = 5 * 1024 * 1024 // 5MB
+	// azureMaxRetries is the number of retries of a failed Blob service request
+	azureMaxRetries = 5
+
+	// Azurite (local emulator) well-known development account
+	azuriteAccount  = "devstoreaccount1"
+	azuriteKey      = "Eby8vdM02xNOcqFlqUwJPLlmEtlCDXJ1OUzFT50uSRZ6IFsuFq2UVErCz4I6tq/K1SZFPTOtr/KBHBeksoGMGw=="
+	azuriteEndpoint = "http://127.0.0.1:10000/devstoreaccount1"
+)
+
+// AzureBlobClient uploads to an Azure Blob Storage container with the Azure SDK
+// Requests are signed with the account key (SharedKey) or carry a SAS token, and
+// failed requests are retried with exponential backoff by the SDK pipeline.
+type AzureBlobClient struct {
+	client    *azblob.Client
+	container string
+	prefix    string
+	blockSize int64
+}
+
+// azureCredentials holds the parts of an Azure Storage connection string
+type azureCredentials struct {
+	account  string
+	key      []byte
+	sas      url.Values
+	endpoint string
+}
+
+// NewAzureBlobClient creates a client for container from an Azure Storage connection string
+// The connection string needs AccountName with AccountKey, or a SharedAccessSignature with
+// a BlobEndpoint; UseDevelopmentStorage=true targets a local Azurite emulator.
+func NewAzureBlobClient(connectionString, container, prefix string) (*AzureBlobClient, error) {
+	if container == "" {
+		return nil, fmt.Errorf("azure container is required")
+	}
+	creds, err := parseAzureConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &azblob.ClientOptions{}
+	opts.Retry = policy.RetryOptions{MaxRetries: azureMaxRetries}
+
+	var client *azblob.Client
+	if creds.sas != nil {
+		client, err = azblob.NewClientWithNoCredential(creds.endpoint+"/?"+creds.sas.Encode(), opts)
+	} else {
+		var cred *azblob.SharedKeyCredential
+		cred, err = azblob.NewSharedKeyCredential(creds.account, base64.StdEncoding.EncodeToString(creds.key))
+		if err == nil {
+			client, err = azblob.NewClientWithSharedKeyCredential(creds.endpoint+"/", cred, opts)
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Azure client: %w", err)
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	return &AzureBlobClient{
+		client:    client,
+		container: container,
+		prefix:    prefix,
+		blockSize: azureBlockSize,
+	}, nil
+}
+
+// parseAzureConnectionString parses a "Key=Value;..." Azure Storage connection string
+func parseAzureConnectionString(cs string) (*azureCredentials, error) {
+	if strings.TrimSpace(cs) == "" {
+		return nil, fmt.Errorf("azure connection string is required")
+	}
+
+	values := map[string]string{}
+	for _, part := range strings.Split(cs, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// Values such as base64 account keys may contain '='
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid azure connection string element %q", part)
+		}
+		values[strings.ToLower(name)] = value
+	}
+
+	if strings.EqualFold(values["usedevelopmentstorage"], "true") {
+		values["accountname"] = azuriteAccount
+		values["accountkey"] = azuriteKey
+		if values["blobendpoint"] == "" {
+			values["blobendpoint"] = azuriteEndpoint
+		}
+	}
+
+	creds := &azureCredentials{account: values["accountname"]}
+	if key := values["accountkey"]; key != "" {
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return nil, fmt.Errorf("invalid azure account key: %w", err)
+		}
+		creds.key = decoded
+	}
+	if sas := values["sharedaccesssignature"]; sas != "" {
+		parsed, err := url.ParseQuery(strings.TrimPrefix(sas, "?"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid azure shared access signature: %w", err)
+		}
+		creds.sas = parsed
+	}
+
+	creds.endpoint = strings.TrimSuffix(values["blobendpoint"], "/")
+	if creds.endpoint == "" {
+		if creds.account == "" {
+			return nil, fmt.Errorf("azure connection string needs AccountName or BlobEndpoint")
+		}
+		protocol := values["defaultendpointsprotocol"]
+		if protocol == "" {
+			protocol = "https"
+		}
+		suffix := values["endpointsuffix"]
+		if suffix == "" {
+			suffix = "core.windows.net"
+		}
+		creds.endpoint = fmt.Sprintf("%s://%s.blob.%s", protocol, creds.account, suffix)
+	}
+
+	if creds.sas == nil && (creds.account == "" || creds.key == nil) {
+		return nil, fmt.Errorf("azure connection string needs AccountName and AccountKey or SharedAccessSignature")
+	}
+	return creds, nil
+}
+
+// Name identifies the backend in logs
+func (c *AzureBlobClient) Name() string {
+	return "Azure"
+}
+
+// Key returns the blob name for a file name, including the configured prefix
+func (c *AzureBlobClient) Key(filename string) string {
+	return path.Join(c.prefix, filename)
+}
+
+// UploadStream uploads data from an io.Reader as a block blob
+func (c *AzureBlobClient) UploadStream(ctx context.Context, key string, r io.Reader) error {
+	return c.UploadStreamWithEncoding(ctx, key, "", r)
+}
+
+// UploadStreamWithEncoding uploads data from an io.Reader as a block blob with a Content-Encoding
+// The data is sent in blocks and committed with a block list, so size is not needed upfront.
+func (c *AzureBlobClient) UploadStreamWithEncoding(ctx context.Context, key, contentEncoding string, r io.Reader) error {
+	opts := &azblob.UploadStreamOptions{BlockSize: c.blockSize}
+	if contentEncoding != "" {
+		opts.HTTPHeaders = &blob.HTTPHeaders{BlobContentEncoding: &contentEncoding}
+	}
+	if _, err := c.client.UploadStream(ctx, c.container, key, r, opts); err != nil {
+		return fmt.Errorf("failed to upload to Azure (key=%s): %w", key, err)
+	}
+	return nil
+}
+
+// DownloadStream downloads a blob as an io.ReadCloser
+func (c *AzureBlobClient) DownloadStream(ctx context.Context, key string) (io.ReadCloser, error) {
+	resp, err := c.client.DownloadStream(ctx, c.container, key, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil, fmt.Errorf("key not found: %s", key)
+		}
+		return nil, fmt.Errorf("failed to download from Azure (key=%s): %w", key, err)
+	}
+	return resp.Body, nil
+}
+
+// Exists checks if a blob exists
+func (c *AzureBlobClient) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := c.client.ServiceClient().NewContainerClient(c.container).NewBlobClient(key).GetProperties(ctx, nil)
+	if err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check Azure blob existence (key=%s): %w", key, err)
+	}
+	return true, nil
+}
+
+// Delete deletes a blob, a missing blob is not an error
+func (c *AzureBlobClient) Delete(ctx context.Context, key string) error {
+	if _, err := c.client.DeleteBlob(ctx, c.container, key, nil); err != nil {
+		if bloberror.HasCode(err, bloberror.BlobNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to delete from Azure (key=%s): %w", key, err)
+	}
+	return nil
+}
+
+// CheckConnection verifies container access and write permissions
+// It uploads a small test blob and then deletes it
+func (c *AzureBlobClient) CheckConnection(ctx context.Context) error {
+	testKey := ".ora2csv-connectivity-test"
+	if err := c.UploadStream(ctx, testKey, strings.NewReader("connectivity check")); err != nil {
+		return fmt.Errorf("azure connection check failed: %w", err)
+	}
+	if err := c.Delete(ctx, testKey); err != nil {
+		return fmt.Errorf("azure connection check cleanup failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestParseAzureConnectionString(t *testing.T) {
+	key := base64.StdEncoding.EncodeToString([]byte("secret"))
+
+	tests := []struct {
+		name         string
+		cs           string
+		wantEndpoint string
+		wantAccount  string
+		wantSAS      bool
+		wantErr      bool
+	}{
+		{
+			name:         "account key",
+			cs:           "DefaultEndpointsProtocol=https;AccountName=acct;AccountKey=" + key + ";EndpointSuffix=core.windows.net",
+			wantEndpoint: "https://acct.blob.core.windows.net",
+			wantAccount:  "acct",
+		},
+		{
+			name:         "blob endpoint",
+			cs:           "AccountName=acct;AccountKey=" + key + ";BlobEndpoint=http://localhost:10000/acct/",
+			wantEndpoint: "http://localhost:10000/acct",
+			wantAccount:  "acct",
+		},
+		{
+			name:         "development storage",
+			cs:           "UseDevelopmentStorage=true",
+			wantEndpoint: azuriteEndpoint,
+			wantAccount:  azuriteAccount,
+		},
+		{
+			name:         "shared access signature",
+			cs:           "BlobEndpoint=https://acct.blob.core.windows.net;SharedAccessSignature=sv=2021-08-06&sig=abc%3D",
+			wantEndpoint: "https://acct.blob.core.windows.net",
+			wantSAS:      true,
+		},
+		{name: "empty", cs: "", wantErr: true},
+		{name: "missing key", cs: "AccountName=acct", wantErr: true},
+		{name: "invalid key", cs: "AccountName=acct;AccountKey=not-base64!", wantErr: true},
+		{name: "invalid element", cs: "AccountName", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := parseAzureConnectionString(tt.cs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseAzureConnectionString() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if creds.endpoint != tt.wantEndpoint || creds.account != tt.wantAccount || (creds.sas != nil) != tt.wantSAS {
+				t.Errorf("parseAzureConnectionString() = %+v", creds)
+			}
+		})
+	}
+}
+
+// fakeAzureServer is an in-memory Blob service handling block uploads
+type fakeAzureServer struct {
+	mu       sync.Mutex
+	blocks   map[string][]byte
+	blobs    map[string][]byte
+	encoding map[string]string
+	auth     []string
+	queries  []string
+	failures int // requests answered with 503 before serving
+}
+
+func newFakeAzureServer(t *testing.T) (*fakeAzureServer, *httptest.Server) {
+	t.Helper()
+	f := &fakeAzureServer{blocks: map[string][]byte{}, blobs: map[string][]byte{}, encoding: map[string]string{}}
+	server := httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeAzureServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+	f.queries = append(f.queries, r.URL.RawQuery)
+	if f.failures > 0 {
+		f.failures--
+		http.Error(w, "ServerBusy", http.StatusServiceUnavailable)
+		return
+	}
+	if r.Header.Get("x-ms-version") == "" {
+		http.Error(w, "missing x-ms-version header", http.StatusBadRequest)
+		return
+	}
+
+	name := r.URL.Path
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "block":
+		f.blocks[name+"#"+r.URL.Query().Get("blockid")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "blocklist":
+		var list struct {
+			Latest []string `xml:"Latest"`
+		}
+		if err := xml.Unmarshal(body, &list); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		var data []byte
+		for _, id := range list.Latest {
+			data = append(data, f.blocks[name+"#"+id]...)
+		}
+		f.blobs[name] = data
+		f.encoding[name] = r.Header.Get("x-ms-blob-content-encoding")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodPut && r.URL.Query().Get("comp") == "":
+		// Put Blob, used for data that fits in a single block
+		f.blobs[name] = body
+		f.encoding[name] = r.Header.Get("x-ms-blob-content-encoding")
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		data, ok := f.blobs[name]
+		if !ok {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case r.Method == http.MethodDelete:
+		if _, ok := f.blobs[name]; !ok {
+			w.Header().Set("x-ms-error-code", "BlobNotFound")
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.blobs, name)
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.Error(w, "unsupported", http.StatusBadRequest)
+	}
+}
+
+func TestAzureBlobClient(t *testing.T) {
+	fake, server := newFakeAzureServer(t)
+	cs := "AccountName=acct;AccountKey=" + base64.StdEncoding.EncodeToString([]byte("secret")) + ";BlobEndpoint=" + server.URL
+	client, err := NewAzureBlobClient(cs, "exports", "/daily/")
+	if err != nil {
+		t.Fatalf("NewAzureBlobClient() error = %v", err)
+	}
+	client.blockSize = 1024 * 1024
+	ctx := context.Background()
+
+	key := client.Key("crm.products/crm.products__2025-01-01T00-00-00.csv.gz")
+	if key != "daily/crm.products/crm.products__2025-01-01T00-00-00.csv.gz" {
+		t.Errorf("Key() = %q", key)
+	}
+
+	// Three blocks of the 1MB minimum block size
+	content := "id,name\n" + strings.Repeat("1,Alice\n", 300000)
+	if err := client.UploadStreamWithEncoding(ctx, key, "gzip", strings.NewReader(content)); err != nil {
+		t.Fatalf("UploadStreamWithEncoding() error = %v", err)
+	}
+	blobPath := "/exports/" + key
+	if got := string(fake.blobs[blobPath]); got != content {
+		t.Errorf("uploaded blob has %d bytes, want %d", len(got), len(content))
+	}
+	if fake.encoding[blobPath] != "gzip" {
+		t.Errorf("content encoding = %q, want gzip", fake.encoding[blobPath])
+	}
+	if !strings.HasPrefix(fake.auth[0], "SharedKey acct:") {
+		t.Errorf("Authorization = %q, want SharedKey", fake.auth[0])
+	}
+
+	exists, err := client.Exists(ctx, key)
+	if err != nil || !exists {
+		t.Errorf("Exists() = %v, %v, want true", exists, err)
+	}
+	reader, err := client.DownloadStream(ctx, key)
+	if err != nil {
+		t.Fatalf("DownloadStream() error = %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	_ = reader.Close()
+	if string(data) != content {
+		t.Errorf("downloaded %d bytes, want %d", len(data), len(content))
+	}
+
+	if err := client.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if exists, err := client.Exists(ctx, key); err != nil || exists {
+		t.Errorf("Exists() after delete = %v, %v, want false", exists, err)
+	}
+	if _, err := client.DownloadStream(ctx, key); err == nil || !strings.Contains(err.Error(), "key not found") {
+		t.Errorf("DownloadStream() of missing blob error = %v", err)
+	}
+	if err := client.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of missing blob error = %v", err)
+	}
+	if err := client.CheckConnection(ctx); err != nil {
+		t.Errorf("CheckConnection() error = %v", err)
+	}
+}
+
+func TestAzureBlobClient_SAS(t *testing.T) {
+	fake, server := newFakeAzureServer(t)
+	client, err := NewAzureBlobClient("BlobEndpoint="+server.URL+";SharedAccessSignature=sv=2021-08-06&sig=abc", "exports", "")
+	if err != nil {
+		t.Fatalf("NewAzureBlobClient() error = %v", err)
+	}
+
+	if err := client.UploadStream(context.Background(), "state.json", strings.NewReader("[]")); err != nil {
+		t.Fatalf("UploadStream() error = %v", err)
+	}
+	for i, auth := range fake.auth {
+		if auth != "" {
+			t.Errorf("request %d Authorization = %q, want none with SAS", i, auth)
+		}
+		if !strings.Contains(fake.queries[i], "sig=abc") {
+			t.Errorf("request %d query = %q, want SAS token", i, fake.queries[i])
+		}
+	}
+}
+
+func TestAzureBlobClient_RetriesServerErrors(t *testing.T) {
+	fake, server := newFakeAzureServer(t)
+	fake.failures = 2
+	cs := "AccountName=acct;AccountKey=" + base64.StdEncoding.EncodeToString([]byte("secret")) + ";BlobEndpoint=" + server.URL
+	client, err := NewAzureBlobClient(cs, "exports", "")
+	if err != nil {
+		t.Fatalf("NewAzureBlobClient() error = %v", err)
+	}
+
+	if err := client.UploadStream(context.Background(), "state.json", strings.NewReader("[]")); err != nil {
+		t.Fatalf("UploadStream() error = %v", err)
+	}
+	if got := string(fake.blobs["/exports/state.json"]); got != "[]" {
+		t.Errorf("uploaded blob = %q", got)
+	}
+}
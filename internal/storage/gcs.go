@@ -0,0 +1,617 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultGCSEndpoint is the Cloud Storage JSON API base URL
+	DefaultGCSEndpoint = "https://storage.googleapis.com"
+	// EnvGoogleCredentials points to a service account or authorized user key file
+	EnvGoogleCredentials = "GOOGLE_APPLICATION_CREDENTIALS"
+	// EnvGoogleAccessToken provides a ready OAuth access token, e.g. from gcloud
+	EnvGoogleAccessToken = "GOOGLE_OAUTH_ACCESS_TOKEN"
+
+	gcsMetadataURL   = "http://metadata.google.internal/computeMetadata/v1"
+	gcsDefaultToken  = "https://oauth2.googleapis.com/token"
+	gcsReadWriteAuth = "https://www.googleapis.com/auth/devstorage.read_write"
+
+	// gcsChunkSize is the size of the resumable upload chunks, a multiple of 256KB
+	gcsChunkSize = 8 * 1024 * 1024 // 8MB
+	// gcsRequestTimeout bounds a single request; downloads only wait this long for headers
+	gcsRequestTimeout = 2 * time.Minute
+	// gcsMaxRetries is the number of retries of a request that failed with a transient error
+	gcsMaxRetries = 5
+	// gcsRetryDelay is the first backoff delay, doubled on every retry up to gcsMaxRetryDelay
+	gcsRetryDelay    = time.Second
+	gcsMaxRetryDelay = 30 * time.Second
+)
+
+// errUploadIncomplete is returned when a resumable upload session kept fewer bytes than sent
+var errUploadIncomplete = errors.New("upload session did not persist all data")
+
+// GCSClient uploads to a Google Cloud Storage bucket through the JSON API
+// Access tokens come from GOOGLE_OAUTH_ACCESS_TOKEN, the GOOGLE_APPLICATION_CREDENTIALS
+// key file (service account or authorized user) or the metadata server, in that order.
+// Uploads use resumable sessions sent in chunks, and requests failing with network
+// errors, 408, 429 or 5xx responses are retried with exponential backoff.
+type GCSClient struct {
+	bucket      string
+	prefix      string
+	endpoint    string
+	metadataURL string
+	credentials string // Key file path, empty to use the metadata server
+	httpClient  *http.Client
+	chunkSize   int
+	retryDelay  time.Duration
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+// NewGCSClient creates a client for bucket, reading credentials from the environment
+func NewGCSClient(bucket, prefix string) (*GCSClient, error) {
+	if bucket == "" {
+		return nil, fmt.Errorf("GCS bucket is required")
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+
+	credentials := os.Getenv(EnvGoogleCredentials)
+	if credentials != "" {
+		if _, err := os.Stat(credentials); err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvGoogleCredentials, err)
+		}
+	}
+
+	return &GCSClient{
+		bucket:      bucket,
+		prefix:      prefix,
+		endpoint:    DefaultGCSEndpoint,
+		metadataURL: gcsMetadataURL,
+		credentials: credentials,
+		httpClient:  newGCSHTTPClient(),
+		chunkSize:   gcsChunkSize,
+		retryDelay:  gcsRetryDelay,
+	}, nil
+}
+
+// newGCSHTTPClient creates an HTTP client without a total timeout, so long downloads are
+// not cut off; requests are bounded by their context and the response header timeout.
+func newGCSHTTPClient() *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = gcsRequestTimeout
+	return &http.Client{Transport: transport}
+}
+
+// Name identifies the backend in logs
+func (c *GCSClient) Name() string {
+	return "GCS"
+}
+
+// Key returns the object name for a file name, including the configured prefix
+func (c *GCSClient) Key(filename string) string {
+	return path.Join(c.prefix, filename)
+}
+
+// objectURL returns the JSON API URL of an object's metadata
+func (c *GCSClient) objectURL(key string) string {
+	return c.endpoint + "/storage/v1/b/" + url.PathEscape(c.bucket) + "/o/" + url.PathEscape(key)
+}
+
+// UploadStream uploads data from an io.Reader
+func (c *GCSClient) UploadStream(ctx context.Context, key string, r io.Reader) error {
+	_, err := c.upload(ctx, key, "", r)
+	return err
+}
+
+// UploadStreamWithEncoding uploads data from an io.Reader with a Content-Encoding such as "gzip"
+func (c *GCSClient) UploadStreamWithEncoding(ctx context.Context, key, contentEncoding string, r io.Reader) error {
+	_, err := c.upload(ctx, key, contentEncoding, r)
+	return err
+}
+
+// UploadWithVersion uploads data and returns the generation of the new object
+func (c *GCSClient) UploadWithVersion(ctx context.Context, key string, r io.Reader) (string, error) {
+	return c.upload(ctx, key, "", r)
+}
+
+// gcsRequest is a JSON API request, kept as data so a failed attempt can be resent
+type gcsRequest struct {
+	method string
+	url    string
+	header http.Header
+	body   []byte
+	stream bool // return the response body unread, without the per-request timeout
+}
+
+// upload sends r in chunks through a resumable upload session and returns the object generation
+// Only one chunk is held in memory, so the size is not needed upfront.
+func (c *GCSClient) upload(ctx context.Context, key, contentEncoding string, r io.Reader) (string, error) {
+	session, err := c.startUpload(ctx, key, contentEncoding)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to GCS (key=%s): %w", key, err)
+	}
+
+	buf := make([]byte, c.chunkSize)
+	var offset int64
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		last := errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF)
+		if readErr != nil && !last {
+			return "", fmt.Errorf("failed to read upload data (key=%s): %w", key, readErr)
+		}
+
+		object, err := c.uploadChunk(ctx, session, buf[:n], offset, last)
+		if err != nil {
+			return "", fmt.Errorf("failed to upload to GCS (key=%s): %w", key, err)
+		}
+		offset += int64(n)
+		if last {
+			var meta struct {
+				Generation string `json:"generation"`
+			}
+			_ = json.Unmarshal(object, &meta)
+			return meta.Generation, nil
+		}
+	}
+}
+
+// startUpload opens a resumable upload session and returns its URL
+func (c *GCSClient) startUpload(ctx context.Context, key, contentEncoding string) (string, error) {
+	meta := map[string]string{"name": key}
+	if contentEncoding != "" {
+		meta["contentEncoding"] = contentEncoding
+	}
+	body, err := json.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode object metadata: %w", err)
+	}
+	query := url.Values{"uploadType": {"resumable"}, "name": {key}}
+	req := gcsRequest{
+		method: http.MethodPost,
+		url:    c.endpoint + "/upload/storage/v1/b/" + url.PathEscape(c.bucket) + "/o?" + query.Encode(),
+		header: http.Header{"Content-Type": {"application/json; charset=UTF-8"}},
+		body:   body,
+	}
+
+	var session string
+	err = c.retry(ctx, func() error {
+		resp, err := c.send(ctx, req)
+		if err != nil {
+			return err
+		}
+		session = resp.Header.Get("Location")
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if session == "" {
+		return "", fmt.Errorf("no upload session URL returned")
+	}
+	return session, nil
+}
+
+// uploadChunk sends chunk at offset of a resumable upload and returns the object metadata
+// once the last chunk completes the upload. After a failed attempt the session is asked
+// how many bytes it persisted and only the rest of the chunk is resent.
+func (c *GCSClient) uploadChunk(ctx context.Context, session string, chunk []byte, offset int64, last bool) ([]byte, error) {
+	end := offset + int64(len(chunk))
+	total := "*"
+	if last {
+		total = strconv.FormatInt(end, 10)
+	}
+
+	var object []byte
+	resume := false
+	err := c.retry(ctx, func() error {
+		persisted := offset
+		if resume {
+			resp, err := c.send(ctx, gcsRequest{method: http.MethodPut, url: session, header: http.Header{"Content-Range": {"bytes */" + total}}})
+			if err != nil {
+				return err
+			}
+			if resp.StatusCode != http.StatusPermanentRedirect {
+				object, _ = io.ReadAll(resp.Body)
+				return nil
+			}
+			if persisted = gcsPersisted(resp); persisted < offset || persisted > end {
+				return fmt.Errorf("upload session persisted %d bytes, expected %d to %d", persisted, offset, end)
+			}
+		}
+		resume = true
+
+		contentRange := "bytes */" + total
+		if persisted < end {
+			contentRange = fmt.Sprintf("bytes %d-%d/%s", persisted, end-1, total)
+		}
+		resp, err := c.send(ctx, gcsRequest{
+			method: http.MethodPut,
+			url:    session,
+			header: http.Header{"Content-Range": {contentRange}},
+			body:   chunk[persisted-offset:],
+		})
+		if err != nil {
+			return err
+		}
+		// 308 Resume Incomplete acknowledges a chunk that is not the last one
+		if resp.StatusCode == http.StatusPermanentRedirect {
+			if last || gcsPersisted(resp) < end {
+				return errUploadIncomplete
+			}
+			return nil
+		}
+		object, _ = io.ReadAll(resp.Body)
+		return nil
+	})
+	return object, err
+}
+
+// gcsPersisted returns the number of bytes a resumable session kept, from its Range header
+func gcsPersisted(resp *http.Response) int64 {
+	// The header is "bytes=0-N", missing when nothing was persisted
+	_, last, ok := strings.Cut(resp.Header.Get("Range"), "-")
+	if !ok {
+		return 0
+	}
+	n, err := strconv.ParseInt(last, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return n + 1
+}
+
+// TrackVersions returns false, GCS state generations are not recorded
+func (c *GCSClient) TrackVersions() bool {
+	return false
+}
+
+// DownloadStream downloads an object as an io.ReadCloser
+func (c *GCSClient) DownloadStream(ctx context.Context, key string) (io.ReadCloser, error) {
+	return c.download(ctx, key, "")
+}
+
+// GetVersion downloads a specific generation of an object
+func (c *GCSClient) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	return c.download(ctx, key, versionID)
+}
+
+// download opens the object media, the latest generation when generation is empty
+func (c *GCSClient) download(ctx context.Context, key, generation string) (io.ReadCloser, error) {
+	query := url.Values{"alt": {"media"}}
+	if generation != "" {
+		query.Set("generation", generation)
+	}
+	req := gcsRequest{method: http.MethodGet, url: c.objectURL(key) + "?" + query.Encode(), stream: true}
+
+	var resp *http.Response
+	err := c.retry(ctx, func() error {
+		var err error
+		resp, err = c.send(ctx, req)
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return nil, fmt.Errorf("key not found: %s", key)
+		}
+		return nil, fmt.Errorf("failed to download from GCS (key=%s): %w", key, err)
+	}
+	return resp.Body, nil
+}
+
+// DownloadBytes downloads an object as a byte slice
+func (c *GCSClient) DownloadBytes(ctx context.Context, key string) (data []byte, retErr error) {
+	reader, err := c.DownloadStream(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to close GCS download stream: %w", err))
+		}
+	}()
+	return io.ReadAll(reader)
+}
+
+// Exists checks if an object exists
+func (c *GCSClient) Exists(ctx context.Context, key string) (bool, error) {
+	req := gcsRequest{method: http.MethodGet, url: c.objectURL(key)}
+	err := c.retry(ctx, func() error {
+		_, err := c.send(ctx, req)
+		return err
+	})
+	if err != nil {
+		if isNotFound(err) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check GCS object existence (key=%s): %w", key, err)
+	}
+	return true, nil
+}
+
+// Delete deletes an object, a missing object is not an error
+func (c *GCSClient) Delete(ctx context.Context, key string) error {
+	req := gcsRequest{method: http.MethodDelete, url: c.objectURL(key)}
+	err := c.retry(ctx, func() error {
+		_, err := c.send(ctx, req)
+		return err
+	})
+	if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to delete from GCS (key=%s): %w", key, err)
+	}
+	return nil
+}
+
+// CheckConnection verifies bucket access and IAM write permissions
+// It writes a zero-byte test object and then deletes it
+func (c *GCSClient) CheckConnection(ctx context.Context) error {
+	testKey := ".ora2csv-connectivity-test"
+	if err := c.UploadStream(ctx, testKey, bytes.NewReader(nil)); err != nil {
+		return fmt.Errorf("GCS connection check failed: %w", err)
+	}
+	if err := c.Delete(ctx, testKey); err != nil {
+		return fmt.Errorf("GCS connection check cleanup failed: %w", err)
+	}
+	return nil
+}
+
+// retry runs fn until it succeeds, fails with a permanent error or runs out of retries,
+// waiting with exponential backoff between attempts
+func (c *GCSClient) retry(ctx context.Context, fn func() error) error {
+	delay := c.retryDelay
+	for attempt := 0; ; attempt++ {
+		err := fn()
+		if err == nil || attempt == gcsMaxRetries || ctx.Err() != nil || !gcsRetryable(err) {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		delay = min(2*delay, gcsMaxRetryDelay)
+	}
+}
+
+// gcsRetryable reports whether a failed request may succeed when sent again
+func gcsRetryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		code := statusErr.StatusCode
+		return code == http.StatusRequestTimeout || code == http.StatusTooManyRequests || code >= 500
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, errUploadIncomplete) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// send sends an authorized request once and returns the response for a 2xx or 308 status
+// The body of the returned response is buffered unless r.stream is set.
+func (c *GCSClient) send(ctx context.Context, r gcsRequest) (*http.Response, error) {
+	token, err := c.accessToken(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if !r.stream {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, gcsRequestTimeout)
+		defer cancel()
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, r.url, bytes.NewReader(r.body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCS request: %w", err)
+	}
+	for name, values := range r.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if (resp.StatusCode < 200 || resp.StatusCode > 299) && resp.StatusCode != http.StatusPermanentRedirect {
+		defer func() { _ = resp.Body.Close() }()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &statusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: strings.TrimSpace(string(body))}
+	}
+	if r.stream {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// accessToken returns a cached OAuth token, refreshed a minute before it expires
+func (c *GCSClient) accessToken(ctx context.Context) (string, error) {
+	if token := os.Getenv(EnvGoogleAccessToken); token != "" {
+		return token, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	var token gcsToken
+	var err error
+	if c.credentials != "" {
+		token, err = c.credentialsToken(ctx)
+	} else {
+		token, err = c.metadataToken(ctx)
+	}
+	if err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("no GCS access token returned")
+	}
+
+	c.token = token.AccessToken
+	c.tokenExpiry = time.Now().Add(time.Duration(token.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
+
+// gcsToken is an OAuth token response
+type gcsToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// gcsCredentials is a GOOGLE_APPLICATION_CREDENTIALS key file
+type gcsCredentials struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// credentialsToken exchanges the key file for an access token
+func (c *GCSClient) credentialsToken(ctx context.Context) (gcsToken, error) {
+	data, err := os.ReadFile(c.credentials)
+	if err != nil {
+		return gcsToken{}, fmt.Errorf("failed to read %s: %w", EnvGoogleCredentials, err)
+	}
+	var creds gcsCredentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return gcsToken{}, fmt.Errorf("failed to parse %s: %w", EnvGoogleCredentials, err)
+	}
+	tokenURI := creds.TokenURI
+	if tokenURI == "" {
+		tokenURI = gcsDefaultToken
+	}
+
+	form := url.Values{}
+	switch creds.Type {
+	case "service_account":
+		assertion, err := serviceAccountJWT(creds, tokenURI, time.Now())
+		if err != nil {
+			return gcsToken{}, err
+		}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+	case "authorized_user":
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", creds.ClientID)
+		form.Set("client_secret", creds.ClientSecret)
+		form.Set("refresh_token", creds.RefreshToken)
+	default:
+		return gcsToken{}, fmt.Errorf("unsupported credentials type %q in %s", creds.Type, EnvGoogleCredentials)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return gcsToken{}, fmt.Errorf("failed to create token request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.fetchToken(req)
+}
+
+// metadataToken reads the default service account token from the metadata server
+func (c *GCSClient) metadataToken(ctx context.Context) (gcsToken, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.metadataURL+"/instance/service-accounts/default/token", nil)
+	if err != nil {
+		return gcsToken{}, fmt.Errorf("failed to create metadata request: %w", err)
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	token, err := c.fetchToken(req)
+	if err != nil {
+		return gcsToken{}, fmt.Errorf("failed to get access token (set %s outside Google Cloud): %w", EnvGoogleCredentials, err)
+	}
+	return token, nil
+}
+
+// fetchToken sends a token request and decodes the response
+func (c *GCSClient) fetchToken(req *http.Request) (gcsToken, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), gcsRequestTimeout)
+	defer cancel()
+	resp, err := c.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return gcsToken{}, fmt.Errorf("token request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode != http.StatusOK {
+		return gcsToken{}, fmt.Errorf("token request failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var token gcsToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return gcsToken{}, fmt.Errorf("failed to parse token response: %w", err)
+	}
+	return token, nil
+}
+
+// serviceAccountJWT builds the RS256-signed assertion for the JWT bearer grant
+func serviceAccountJWT(creds gcsCredentials, audience string, now time.Time) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", fmt.Errorf("service account private_key is not PEM encoded")
+	}
+	var key *rsa.PrivateKey
+	if parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := parsed.(*rsa.PrivateKey)
+		if !ok {
+			return "", fmt.Errorf("service account private_key is not an RSA key")
+		}
+		key = rsaKey
+	} else if key, err = x509.ParsePKCS1PrivateKey(block.Bytes); err != nil {
+		return "", fmt.Errorf("failed to parse service account private_key: %w", err)
+	}
+
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": gcsReadWriteAuth,
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign service account assertion: %w", err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
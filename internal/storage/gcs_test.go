@@ -0,0 +1,365 @@
+package storage
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGCSServer is an in-memory Cloud Storage JSON API with a token endpoint
+type fakeGCSServer struct {
+	mu          sync.Mutex
+	objects     map[string][]byte
+	encoding    map[string]string
+	generations map[string]int
+	sessions    map[string]*fakeGCSSession
+	auth        []string
+	grants      []string
+	key         *rsa.PublicKey // verifies JWT assertions when set
+	failures    int            // chunk uploads that keep half the data and fail with 503
+	chunks      int            // chunk requests received
+}
+
+// fakeGCSSession is a resumable upload in progress
+type fakeGCSSession struct {
+	name     string
+	encoding string
+	data     []byte
+}
+
+func newFakeGCSServer(t *testing.T) (*fakeGCSServer, *httptest.Server) {
+	t.Helper()
+	f := &fakeGCSServer{
+		objects:     map[string][]byte{},
+		encoding:    map[string]string{},
+		generations: map[string]int{},
+		sessions:    map[string]*fakeGCSSession{},
+	}
+	server := httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(server.Close)
+	return f, server
+}
+
+func (f *fakeGCSServer) handle(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		f.token(w, r)
+		return
+	}
+	f.auth = append(f.auth, r.Header.Get("Authorization"))
+
+	const uploadPath = "/upload/storage/v1/b/bkt/o"
+	const sessionPath = "/upload/session/"
+	const objectPath = "/storage/v1/b/bkt/o/"
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == uploadPath && r.URL.Query().Get("uploadType") == "resumable":
+		var meta struct {
+			Name            string `json:"name"`
+			ContentEncoding string `json:"contentEncoding"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&meta)
+		id := strconv.Itoa(len(f.sessions) + 1)
+		f.sessions[id] = &fakeGCSSession{name: meta.Name, encoding: meta.ContentEncoding}
+		w.Header().Set("Location", "http://"+r.Host+sessionPath+id)
+	case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, sessionPath):
+		f.chunk(w, r, f.sessions[strings.TrimPrefix(r.URL.Path, sessionPath)])
+	case strings.HasPrefix(r.URL.Path, objectPath):
+		name := strings.TrimPrefix(r.URL.Path, objectPath)
+		data, ok := f.objects[name]
+		if !ok {
+			http.Error(w, `{"error":{"code":404,"message":"No such object"}}`, http.StatusNotFound)
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(f.objects, name)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Query().Get("alt") == "media":
+			_, _ = w.Write(data)
+		default:
+			_ = json.NewEncoder(w).Encode(map[string]string{"name": name})
+		}
+	default:
+		http.Error(w, "unsupported", http.StatusBadRequest)
+	}
+}
+
+// chunk handles a resumable upload request with a "bytes first-last/total" or
+// "bytes */total" Content-Range, where the total is * until the last chunk
+func (f *fakeGCSServer) chunk(w http.ResponseWriter, r *http.Request, session *fakeGCSSession) {
+	if session == nil {
+		http.Error(w, "no such upload session", http.StatusNotFound)
+		return
+	}
+	body, _ := io.ReadAll(r.Body)
+	spec := strings.TrimPrefix(r.Header.Get("Content-Range"), "bytes ")
+	rng, total, _ := strings.Cut(spec, "/")
+	if rng != "*" {
+		f.chunks++
+		var first, last int
+		if _, err := fmt.Sscanf(rng, "%d-%d", &first, &last); err != nil || first != len(session.data) || last-first+1 != len(body) {
+			http.Error(w, "invalid Content-Range "+spec, http.StatusBadRequest)
+			return
+		}
+		if f.failures > 0 {
+			f.failures--
+			session.data = append(session.data, body[:len(body)/2]...)
+			http.Error(w, "backend error", http.StatusServiceUnavailable)
+			return
+		}
+		session.data = append(session.data, body...)
+	}
+
+	if total == strconv.Itoa(len(session.data)) {
+		f.objects[session.name] = session.data
+		f.encoding[session.name] = session.encoding
+		f.generations[session.name]++
+		_ = json.NewEncoder(w).Encode(map[string]string{"name": session.name, "generation": strconv.Itoa(f.generations[session.name])})
+		return
+	}
+	if len(session.data) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(session.data)-1))
+	}
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
+
+// token verifies the grant and issues an access token
+func (f *fakeGCSServer) token(w http.ResponseWriter, r *http.Request) {
+	_ = r.ParseForm()
+	grant := r.PostForm.Get("grant_type")
+	f.grants = append(f.grants, grant)
+	if f.key != nil {
+		parts := strings.Split(r.PostForm.Get("assertion"), ".")
+		if len(parts) != 3 {
+			http.Error(w, "malformed assertion", http.StatusBadRequest)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], signature); err != nil {
+			http.Error(w, "bad signature", http.StatusUnauthorized)
+			return
+		}
+	}
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "tok-" + grant, "expires_in": 3600})
+}
+
+func newTestGCSClient(t *testing.T, server *httptest.Server, prefix string) *GCSClient {
+	t.Helper()
+	client, err := NewGCSClient("bkt", prefix)
+	if err != nil {
+		t.Fatalf("NewGCSClient() error = %v", err)
+	}
+	client.endpoint = server.URL
+	client.metadataURL = server.URL + "/metadata"
+	client.retryDelay = time.Millisecond
+	return client
+}
+
+func TestGCSClient(t *testing.T) {
+	t.Setenv(EnvGoogleCredentials, "")
+	t.Setenv(EnvGoogleAccessToken, "static-token")
+	fake, server := newFakeGCSServer(t)
+	client := newTestGCSClient(t, server, "/daily/")
+	ctx := context.Background()
+
+	key := client.Key("crm.products/crm.products__2025-01-01T00-00-00.csv.gz")
+	if key != "daily/crm.products/crm.products__2025-01-01T00-00-00.csv.gz" {
+		t.Errorf("Key() = %q", key)
+	}
+
+	if err := client.UploadStreamWithEncoding(ctx, key, "gzip", strings.NewReader("id,name\n1,Alice\n")); err != nil {
+		t.Fatalf("UploadStreamWithEncoding() error = %v", err)
+	}
+	if got := string(fake.objects[key]); got != "id,name\n1,Alice\n" {
+		t.Errorf("uploaded object = %q", got)
+	}
+	if fake.encoding[key] != "gzip" {
+		t.Errorf("content encoding = %q, want gzip", fake.encoding[key])
+	}
+	if fake.auth[0] != "Bearer static-token" {
+		t.Errorf("Authorization = %q, want Bearer static-token", fake.auth[0])
+	}
+
+	exists, err := client.Exists(ctx, key)
+	if err != nil || !exists {
+		t.Errorf("Exists() = %v, %v, want true", exists, err)
+	}
+	data, err := client.DownloadBytes(ctx, key)
+	if err != nil || string(data) != "id,name\n1,Alice\n" {
+		t.Errorf("DownloadBytes() = %q, %v", data, err)
+	}
+
+	if err := client.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if exists, err := client.Exists(ctx, key); err != nil || exists {
+		t.Errorf("Exists() after delete = %v, %v, want false", exists, err)
+	}
+	if _, err := client.DownloadStream(ctx, key); err == nil || !strings.Contains(err.Error(), "key not found") {
+		t.Errorf("DownloadStream() of missing object error = %v", err)
+	}
+	if err := client.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of missing object error = %v", err)
+	}
+
+	if err := client.CheckConnection(ctx); err != nil {
+		t.Errorf("CheckConnection() error = %v", err)
+	}
+	if len(fake.objects) != 0 {
+		t.Errorf("CheckConnection() left objects behind: %v", fake.objects)
+	}
+}
+
+func TestGCSClient_UploadWithVersion(t *testing.T) {
+	t.Setenv(EnvGoogleCredentials, "")
+	t.Setenv(EnvGoogleAccessToken, "static-token")
+	_, server := newFakeGCSServer(t)
+	client := newTestGCSClient(t, server, "")
+	ctx := context.Background()
+
+	for want := 1; want <= 2; want++ {
+		generation, err := client.UploadWithVersion(ctx, "state.json", strings.NewReader("[]"))
+		if err != nil {
+			t.Fatalf("UploadWithVersion() error = %v", err)
+		}
+		if generation != strconv.Itoa(want) {
+			t.Errorf("UploadWithVersion() generation = %q, want %d", generation, want)
+		}
+	}
+}
+
+func TestGCSClient_ServiceAccount(t *testing.T) {
+	t.Setenv(EnvGoogleAccessToken, "")
+	fake, server := newFakeGCSServer(t)
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fake.key = &key.PublicKey
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	creds, _ := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "exporter@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL + "/token",
+	})
+	credsPath := filepath.Join(t.TempDir(), "key.json")
+	if err := os.WriteFile(credsPath, creds, 0600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(EnvGoogleCredentials, credsPath)
+
+	client := newTestGCSClient(t, server, "")
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if err := client.UploadStream(ctx, "state.json", strings.NewReader("[]")); err != nil {
+			t.Fatalf("UploadStream() error = %v", err)
+		}
+	}
+
+	// The token is cached between requests
+	if len(fake.grants) != 1 || fake.grants[0] != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		t.Errorf("token grants = %v, want one jwt-bearer grant", fake.grants)
+	}
+	if fake.auth[1] != "Bearer tok-urn:ietf:params:oauth:grant-type:jwt-bearer" {
+		t.Errorf("Authorization = %q", fake.auth[1])
+	}
+}
+
+func TestNewGCSClient_Errors(t *testing.T) {
+	if _, err := NewGCSClient("", ""); err == nil {
+		t.Error("NewGCSClient() without bucket should fail")
+	}
+
+	t.Setenv(EnvGoogleCredentials, filepath.Join(t.TempDir(), "missing.json"))
+	if _, err := NewGCSClient("bkt", ""); err == nil || !strings.Contains(err.Error(), EnvGoogleCredentials) {
+		t.Errorf("NewGCSClient() with missing credentials file error = %v", err)
+	}
+}
+
+func TestGCSClient_ResumableUpload(t *testing.T) {
+	t.Setenv(EnvGoogleCredentials, "")
+	t.Setenv(EnvGoogleAccessToken, "static-token")
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		content    string
+		failures   int
+		wantChunks int
+	}{
+		{name: "several chunks", content: "id,name\n1,Alice\n2,Bob\n", wantChunks: 6},
+		{name: "exact chunk multiple", content: "0123456789abcdef", wantChunks: 4},
+		{name: "empty", content: "", wantChunks: 0},
+		// Each failed chunk keeps half of its data, only the rest is resent
+		{name: "transient failures", content: "id,name\n1,Alice\n2,Bob\n", failures: 2, wantChunks: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, server := newFakeGCSServer(t)
+			fake.failures = tt.failures
+			client := newTestGCSClient(t, server, "")
+			client.chunkSize = 4
+
+			generation, err := client.UploadWithVersion(ctx, "export.csv", strings.NewReader(tt.content))
+			if err != nil {
+				t.Fatalf("UploadWithVersion() error = %v", err)
+			}
+			if got := string(fake.objects["export.csv"]); got != tt.content {
+				t.Errorf("uploaded object = %q, want %q", got, tt.content)
+			}
+			if generation != "1" {
+				t.Errorf("generation = %q, want 1", generation)
+			}
+			if fake.chunks != tt.wantChunks {
+				t.Errorf("chunk requests = %d, want %d", fake.chunks, tt.wantChunks)
+			}
+		})
+	}
+}
+
+func TestGCSClient_RetryLimit(t *testing.T) {
+	t.Setenv(EnvGoogleCredentials, "")
+	t.Setenv(EnvGoogleAccessToken, "static-token")
+	fake, server := newFakeGCSServer(t)
+	fake.failures = 100
+	client := newTestGCSClient(t, server, "")
+
+	err := client.UploadStream(context.Background(), "state.json", strings.NewReader("[]"))
+	if err == nil || !strings.Contains(err.Error(), "503") {
+		t.Fatalf("UploadStream() error = %v, want 503 after retries", err)
+	}
+	if fake.chunks != gcsMaxRetries+1 {
+		t.Errorf("chunk requests = %d, want %d", fake.chunks, gcsMaxRetries+1)
+	}
+
+	// Client errors are not retried
+	requests := len(fake.auth)
+	if _, err := client.DownloadStream(context.Background(), "missing.csv"); err == nil {
+		t.Fatal("DownloadStream() of missing object should fail")
+	}
+	if got := len(fake.auth) - requests; got != 1 {
+		t.Errorf("download requests = %d, want 1", got)
+	}
+}
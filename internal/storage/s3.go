@@ -98,6 +98,16 @@ func NewS3Client(cfg *config.S3Config) (*S3Client, error) {
 	}, nil
 }
 
+// Name identifies the backend in logs
+func (s *S3Client) Name() string {
+	return "S3"
+}
+
+// Key returns the S3 key for a file name, including the configured prefix
+func (s *S3Client) Key(filename string) string {
+	return s.cfg.Key(filename)
+}
+
 // UploadFile uploads a local file to S3
 func (s *S3Client) UploadFile(ctx context.Context, key, path string) error {
 	// For streaming, we should use UploadStream with a file reader
@@ -107,18 +117,71 @@ func (s *S3Client) UploadFile(ctx context.Context, key, path string) error {
 
 // UploadStream uploads data from an io.Reader to S3 using multipart upload
 func (s *S3Client) UploadStream(ctx context.Context, key string, r io.Reader) error {
-	input := &s3.PutObjectInput{
+	_, err := s.UploadWithVersion(ctx, key, r)
+	return err
+}
+
+// UploadWithVersion uploads data from an io.Reader to S3 and returns the VersionId
+// assigned to the new object. The version is empty if bucket versioning is disabled.
+func (s *S3Client) UploadWithVersion(ctx context.Context, key string, r io.Reader) (string, error) {
+	return s.upload(ctx, &s3.PutObjectInput{
 		Bucket: aws.String(s.cfg.Bucket),
 		Key:    aws.String(key),
 		Body:   r,
-	}
+	})
+}
+
+// UploadStreamWithEncoding uploads data from an io.Reader to S3 with a Content-Encoding header
+// such as "gzip", so clients can decompress the object transparently
+func (s *S3Client) UploadStreamWithEncoding(ctx context.Context, key, contentEncoding string, r io.Reader) error {
+	_, err := s.upload(ctx, &s3.PutObjectInput{
+		Bucket:          aws.String(s.cfg.Bucket),
+		Key:             aws.String(key),
+		Body:            r,
+		ContentEncoding: aws.String(contentEncoding),
+	})
+	return err
+}
 
-	_, err := s.uploader.Upload(ctx, input)
+// upload runs a multipart upload and returns the VersionId of the new object
+func (s *S3Client) upload(ctx context.Context, input *s3.PutObjectInput) (string, error) {
+	key := aws.ToString(input.Key)
+	s.applySSE(input)
+	if s.cfg.StorageClass != "" {
+		input.StorageClass = types.StorageClass(s.cfg.StorageClass)
+	}
+	output, err := s.uploader.Upload(ctx, input)
 	if err != nil {
-		return fmt.Errorf("failed to upload to S3 (key=%s): %w", key, err)
+		return "", fmt.Errorf("failed to upload to S3 (key=%s): %w", key, err)
 	}
 
-	return nil
+	return aws.ToString(output.VersionID), nil
+}
+
+// WithStorageClass returns a client that uploads with the given storage class
+// The client shares the connection; the receiver is not changed.
+func (s *S3Client) WithStorageClass(class string) BlobStore {
+	cfg := *s.cfg
+	cfg.StorageClass = class
+	return &S3Client{client: s.client, uploader: s.uploader, cfg: &cfg}
+}
+
+// applySSE sets the configured server-side encryption on an upload
+func (s *S3Client) applySSE(input *s3.PutObjectInput) {
+	switch s.cfg.ServerSideEncryption() {
+	case config.S3SSEAES256:
+		input.ServerSideEncryption = types.ServerSideEncryptionAes256
+	case config.S3SSEKMS:
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		if s.cfg.SSEKMSKeyID != "" {
+			input.SSEKMSKeyId = aws.String(s.cfg.SSEKMSKeyID)
+		}
+	}
+}
+
+// TrackVersions returns true if uploaded object versions should be recorded
+func (s *S3Client) TrackVersions() bool {
+	return s.cfg.TrackVersions
 }
 
 // DownloadStream downloads an object from S3 as an io.ReadCloser
@@ -140,6 +203,26 @@ func (s *S3Client) DownloadStream(ctx context.Context, key string) (io.ReadClose
 	return output.Body, nil
 }
 
+// GetVersion downloads a specific version of an object from S3 as an io.ReadCloser
+func (s *S3Client) GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket:    aws.String(s.cfg.Bucket),
+		Key:       aws.String(key),
+		VersionId: aws.String(versionID),
+	}
+
+	output, err := s.client.GetObject(ctx, input)
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if ok := errors.As(err, &nsk); ok {
+			return nil, fmt.Errorf("key not found: %s (version=%s)", key, versionID)
+		}
+		return nil, fmt.Errorf("failed to download from S3 (key=%s, version=%s): %w", key, versionID, err)
+	}
+
+	return output.Body, nil
+}
+
 // DownloadFile downloads an object from S3 and writes it to a local file
 func (s *S3Client) DownloadFile(ctx context.Context, key, path string) (retErr error) {
 	reader, err := s.DownloadStream(ctx, key)
@@ -208,12 +291,31 @@ func (s *S3Client) Delete(ctx context.Context, key string) error {
 
 // ListPrefix lists all objects with a given prefix
 func (s *S3Client) ListPrefix(ctx context.Context, prefix string) ([]string, error) {
+	objects, err := s.ListObjects(ctx, prefix)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, len(objects))
+	for i, obj := range objects {
+		keys[i] = obj.Key
+	}
+	return keys, nil
+}
+
+// S3Object is an object listed by ListObjects
+type S3Object struct {
+	Key  string
+	Size int64
+}
+
+// ListObjects lists all objects with a given prefix and their sizes
+func (s *S3Client) ListObjects(ctx context.Context, prefix string) ([]S3Object, error) {
 	input := &s3.ListObjectsV2Input{
 		Bucket: aws.String(s.cfg.Bucket),
 		Prefix: aws.String(prefix),
 	}
 
-	var keys []string
+	var objects []S3Object
 	paginator := s3.NewListObjectsV2Paginator(s.client, input, func(o *s3.ListObjectsV2PaginatorOptions) {
 		o.Limit = 1000
 	})
@@ -225,11 +327,11 @@ func (s *S3Client) ListPrefix(ctx context.Context, prefix string) ([]string, err
 		}
 
 		for _, obj := range page.Contents {
-			keys = append(keys, aws.ToString(obj.Key))
+			objects = append(objects, S3Object{Key: aws.ToString(obj.Key), Size: aws.ToInt64(obj.Size)})
 		}
 	}
 
-	return keys, nil
+	return objects, nil
 }
 
 // UploadBytes uploads a byte slice to S3
@@ -257,7 +359,8 @@ func (s *S3Client) DownloadBytes(ctx context.Context, key string) (data []byte,
 }
 
 // CheckConnection verifies S3 connectivity and PutObject permissions
-// It uploads a small test object and then deletes it
+// It uploads a small test object and then deletes it. The object uses the configured
+// server-side encryption, so missing KMS key permissions are reported too.
 func (s *S3Client) CheckConnection(ctx context.Context) error {
 	testKey := ".ora2csv-connectivity-test"
 
@@ -267,6 +370,7 @@ func (s *S3Client) CheckConnection(ctx context.Context) error {
 		Key:    aws.String(testKey),
 		Body:   bytes.NewReader([]byte("connectivity check")),
 	}
+	s.applySSE(putInput)
 
 	_, err := s.client.PutObject(ctx, putInput)
 	if err != nil {
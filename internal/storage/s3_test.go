@@ -3,10 +3,16 @@ package storage
 import (
 	"context"
 	"errors"
+	"io"
+	"net/http"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/koltyakov/ora2csv/internal/config"
 )
@@ -398,3 +404,190 @@ func TestLocalFileOperations(t *testing.T) {
 		}
 	})
 }
+
+// mockHTTPClient serves canned S3 responses without network access
+type mockHTTPClient struct {
+	handler  func(req *http.Request) *http.Response
+	requests []*http.Request
+}
+
+func (m *mockHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		_, _ = io.Copy(io.Discard, req.Body)
+	}
+	m.requests = append(m.requests, req)
+	return m.handler(req), nil
+}
+
+func mockResponse(status int, headers map[string]string, body string) *http.Response {
+	resp := &http.Response{
+		StatusCode: status,
+		Header:     http.Header{},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+	for k, v := range headers {
+		resp.Header.Set(k, v)
+	}
+	return resp
+}
+
+// newMockS3Client creates an S3Client backed by a mock HTTP client
+func newMockS3Client(handler func(req *http.Request) *http.Response) (*S3Client, *mockHTTPClient) {
+	httpClient := &mockHTTPClient{handler: handler}
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String("http://s3.mock.local"),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+		HTTPClient:   httpClient,
+	})
+
+	return &S3Client{
+		client:   client,
+		uploader: manager.NewUploader(client),
+		cfg: &config.S3Config{
+			Bucket:        "test-bucket",
+			TrackVersions: true,
+		},
+	}, httpClient
+}
+
+func TestS3Client_UploadWithVersion(t *testing.T) {
+	client, httpClient := newMockS3Client(func(req *http.Request) *http.Response {
+		return mockResponse(http.StatusOK, map[string]string{
+			"ETag":             `"abc"`,
+			"x-amz-version-id": "v-123",
+		}, "")
+	})
+
+	versionID, err := client.UploadWithVersion(context.Background(), "state.json", strings.NewReader("[]"))
+	if err != nil {
+		t.Fatalf("UploadWithVersion() error = %v", err)
+	}
+	if versionID != "v-123" {
+		t.Errorf("versionID = %q, want %q", versionID, "v-123")
+	}
+	if len(httpClient.requests) != 1 || httpClient.requests[0].Method != http.MethodPut {
+		t.Errorf("expected a single PUT request, got %d", len(httpClient.requests))
+	}
+}
+
+func TestS3Client_UploadWithVersion_Unversioned(t *testing.T) {
+	client, _ := newMockS3Client(func(req *http.Request) *http.Response {
+		return mockResponse(http.StatusOK, map[string]string{"ETag": `"abc"`}, "")
+	})
+
+	versionID, err := client.UploadWithVersion(context.Background(), "state.json", strings.NewReader("[]"))
+	if err != nil {
+		t.Fatalf("UploadWithVersion() error = %v", err)
+	}
+	if versionID != "" {
+		t.Errorf("versionID = %q, want empty", versionID)
+	}
+}
+
+func TestS3Client_ServerSideEncryption(t *testing.T) {
+	client, httpClient := newMockS3Client(func(req *http.Request) *http.Response {
+		return mockResponse(http.StatusOK, map[string]string{"ETag": `"abc"`}, "")
+	})
+	client.cfg.SSEKMSKeyID = "alias/exports"
+
+	if err := client.UploadBytes(context.Background(), "state.json", []byte("[]")); err != nil {
+		t.Fatalf("UploadBytes() error = %v", err)
+	}
+	if err := client.CheckConnection(context.Background()); err != nil {
+		t.Fatalf("CheckConnection() error = %v", err)
+	}
+
+	puts := 0
+	for _, req := range httpClient.requests {
+		if req.Method != http.MethodPut {
+			continue
+		}
+		puts++
+		if got := req.Header.Get("x-amz-server-side-encryption"); got != "aws:kms" {
+			t.Errorf("PUT %s encryption = %q, want aws:kms", req.URL.Path, got)
+		}
+		if got := req.Header.Get("x-amz-server-side-encryption-aws-kms-key-id"); got != "alias/exports" {
+			t.Errorf("PUT %s KMS key = %q, want alias/exports", req.URL.Path, got)
+		}
+	}
+	if puts != 2 {
+		t.Errorf("expected 2 PUT requests, got %d", puts)
+	}
+
+	client.cfg.SSE, client.cfg.SSEKMSKeyID = config.S3SSEAES256, ""
+	httpClient.requests = nil
+	if err := client.UploadBytes(context.Background(), "state.json", []byte("[]")); err != nil {
+		t.Fatalf("UploadBytes() error = %v", err)
+	}
+	req := httpClient.requests[0]
+	if got := req.Header.Get("x-amz-server-side-encryption"); got != "AES256" {
+		t.Errorf("encryption = %q, want AES256", got)
+	}
+	if got := req.Header.Get("x-amz-server-side-encryption-aws-kms-key-id"); got != "" {
+		t.Errorf("unexpected KMS key %q", got)
+	}
+}
+
+func TestS3Client_StorageClass(t *testing.T) {
+	client, httpClient := newMockS3Client(func(req *http.Request) *http.Response {
+		return mockResponse(http.StatusOK, map[string]string{"ETag": `"abc"`}, "")
+	})
+	client.cfg.StorageClass = "STANDARD_IA"
+	archive := client.WithStorageClass("GLACIER_IR")
+
+	if err := client.UploadBytes(context.Background(), "state.json", []byte("[]")); err != nil {
+		t.Fatalf("UploadBytes() error = %v", err)
+	}
+	if err := archive.UploadStream(context.Background(), "orders/orders.csv", strings.NewReader("ID\n1\n")); err != nil {
+		t.Fatalf("UploadStream() error = %v", err)
+	}
+
+	if len(httpClient.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(httpClient.requests))
+	}
+	for i, want := range []string{"STANDARD_IA", "GLACIER_IR"} {
+		if got := httpClient.requests[i].Header.Get("x-amz-storage-class"); got != want {
+			t.Errorf("request %d storage class = %q, want %q", i, got, want)
+		}
+	}
+	if client.cfg.StorageClass != "STANDARD_IA" {
+		t.Errorf("WithStorageClass changed the original client to %q", client.cfg.StorageClass)
+	}
+}
+
+func TestS3Client_GetVersion(t *testing.T) {
+	client, httpClient := newMockS3Client(func(req *http.Request) *http.Response {
+		if req.URL.Query().Get("versionId") != "v-122" {
+			return mockResponse(http.StatusNotFound, nil, "")
+		}
+		return mockResponse(http.StatusOK, map[string]string{"x-amz-version-id": "v-122"}, `[{"entity":"a"}]`)
+	})
+
+	reader, err := client.GetVersion(context.Background(), "state.json", "v-122")
+	if err != nil {
+		t.Fatalf("GetVersion() error = %v", err)
+	}
+	defer func() {
+		_ = reader.Close()
+	}()
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if string(data) != `[{"entity":"a"}]` {
+		t.Errorf("body = %q", data)
+	}
+	if got := httpClient.requests[0].URL.Path; got != "/test-bucket/state.json" {
+		t.Errorf("request path = %q, want /test-bucket/state.json", got)
+	}
+}
+
+func TestS3Client_TrackVersions(t *testing.T) {
+	client, _ := newMockS3Client(nil)
+	if !client.TrackVersions() {
+		t.Error("TrackVersions() = false, want true")
+	}
+}
@@ -0,0 +1,259 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpDialTimeout bounds the TCP connect and SSH handshake
+const sftpDialTimeout = 30 * time.Second
+
+// SFTPClient uploads to a directory on an SFTP server
+// The connection is opened on first use and reused until Close.
+type SFTPClient struct {
+	cfg  config.SFTPConfig
+	auth []ssh.AuthMethod
+	host ssh.HostKeyCallback
+
+	mu     sync.Mutex
+	conn   *ssh.Client
+	client *sftp.Client
+}
+
+// NewSFTPClient creates a client, reading the private key and known_hosts file
+// Key authentication is offered before the password when both are set.
+func NewSFTPClient(cfg *config.SFTPConfig) (*SFTPClient, error) {
+	if cfg.Host == "" {
+		return nil, fmt.Errorf("SFTP host is required")
+	}
+
+	var auth []ssh.AuthMethod
+	if cfg.PrivateKeyPath != "" {
+		key, err := os.ReadFile(cfg.PrivateKeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read SFTP private key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse SFTP private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("SFTP private key or password is required")
+	}
+
+	knownHosts := cfg.KnownHostsPath
+	if knownHosts == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to locate known_hosts: %w", err)
+		}
+		knownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKey, err := knownhosts.New(knownHosts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SFTP known_hosts: %w", err)
+	}
+
+	return &SFTPClient{cfg: *cfg, auth: auth, host: hostKey}, nil
+}
+
+// Name identifies the backend in logs
+func (c *SFTPClient) Name() string {
+	return "SFTP"
+}
+
+// Key returns the remote path for a file name, inside the configured directory
+func (c *SFTPClient) Key(filename string) string {
+	return c.cfg.Key(filename)
+}
+
+// session returns the open SFTP session, connecting on first use
+func (c *SFTPClient) session(ctx context.Context) (*sftp.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client != nil {
+		return c.client, nil
+	}
+
+	dialer := net.Dialer{Timeout: sftpDialTimeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.cfg.Address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to SFTP server %s: %w", c.cfg.Address(), err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, c.cfg.Address(), &ssh.ClientConfig{
+		User:            c.cfg.User,
+		Auth:            c.auth,
+		HostKeyCallback: c.host,
+		Timeout:         sftpDialTimeout,
+	})
+	if err != nil {
+		_ = netConn.Close()
+		return nil, fmt.Errorf("failed to open SSH session to %s: %w", c.cfg.Address(), err)
+	}
+	conn := ssh.NewClient(sshConn, chans, reqs)
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("failed to start SFTP subsystem: %w", err)
+	}
+
+	c.conn = conn
+	c.client = client
+	return client, nil
+}
+
+// Close closes the SFTP session and the SSH connection
+func (c *SFTPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.client == nil {
+		return nil
+	}
+	err := errors.Join(c.client.Close(), c.conn.Close())
+	c.client, c.conn = nil, nil
+	return err
+}
+
+// UploadStream writes data to a temporary file and renames it into place,
+// so readers never see a partial export
+func (c *SFTPClient) UploadStream(ctx context.Context, key string, r io.Reader) error {
+	client, err := c.session(ctx)
+	if err != nil {
+		return err
+	}
+	if dir := path.Dir(key); dir != "." {
+		if err := client.MkdirAll(dir); err != nil {
+			return fmt.Errorf("failed to create SFTP directory %s: %w", dir, err)
+		}
+	}
+
+	tmp := key + ".tmp"
+	f, err := client.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("failed to create SFTP file (key=%s): %w", tmp, err)
+	}
+	if _, err := f.ReadFrom(r); err != nil {
+		_ = f.Close()
+		_ = client.Remove(tmp)
+		return fmt.Errorf("failed to upload to SFTP (key=%s): %w", key, err)
+	}
+	if err := f.Close(); err != nil {
+		_ = client.Remove(tmp)
+		return fmt.Errorf("failed to close SFTP file (key=%s): %w", tmp, err)
+	}
+	if err := client.PosixRename(tmp, key); err != nil {
+		_ = client.Remove(tmp)
+		return fmt.Errorf("failed to rename SFTP file (key=%s): %w", key, err)
+	}
+	return nil
+}
+
+// UploadStreamWithEncoding uploads data as is, SFTP has no content encoding metadata
+func (c *SFTPClient) UploadStreamWithEncoding(ctx context.Context, key, _ string, r io.Reader) error {
+	return c.UploadStream(ctx, key, r)
+}
+
+// UploadWithVersion uploads data, SFTP files have no version so it is always empty
+func (c *SFTPClient) UploadWithVersion(ctx context.Context, key string, r io.Reader) (string, error) {
+	return "", c.UploadStream(ctx, key, r)
+}
+
+// TrackVersions returns false, SFTP keeps no previous versions
+func (c *SFTPClient) TrackVersions() bool {
+	return false
+}
+
+// GetVersion is not supported, SFTP keeps no previous versions
+func (c *SFTPClient) GetVersion(_ context.Context, key, _ string) (io.ReadCloser, error) {
+	return nil, fmt.Errorf("SFTP does not keep versions (key=%s)", key)
+}
+
+// DownloadStream opens a remote file as an io.ReadCloser
+func (c *SFTPClient) DownloadStream(ctx context.Context, key string) (io.ReadCloser, error) {
+	client, err := c.session(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f, err := client.Open(key)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, fmt.Errorf("key not found: %s", key)
+		}
+		return nil, fmt.Errorf("failed to download from SFTP (key=%s): %w", key, err)
+	}
+	return f, nil
+}
+
+// DownloadBytes downloads a remote file as a byte slice
+func (c *SFTPClient) DownloadBytes(ctx context.Context, key string) (data []byte, retErr error) {
+	reader, err := c.DownloadStream(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := reader.Close(); err != nil {
+			retErr = errors.Join(retErr, fmt.Errorf("failed to close SFTP download stream: %w", err))
+		}
+	}()
+	return io.ReadAll(reader)
+}
+
+// Exists checks if a remote file exists
+func (c *SFTPClient) Exists(ctx context.Context, key string) (bool, error) {
+	client, err := c.session(ctx)
+	if err != nil {
+		return false, err
+	}
+	if _, err := client.Stat(key); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check SFTP file existence (key=%s): %w", key, err)
+	}
+	return true, nil
+}
+
+// Delete removes a remote file, a missing file is not an error
+func (c *SFTPClient) Delete(ctx context.Context, key string) error {
+	client, err := c.session(ctx)
+	if err != nil {
+		return err
+	}
+	if err := client.Remove(key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete from SFTP (key=%s): %w", key, err)
+	}
+	return nil
+}
+
+// CheckConnection verifies login and write permissions on the remote directory
+// It writes a zero-byte test file and then deletes it
+func (c *SFTPClient) CheckConnection(ctx context.Context) error {
+	testKey := c.Key(".ora2csv-connectivity-test")
+	if err := c.UploadStream(ctx, testKey, bytes.NewReader(nil)); err != nil {
+		return fmt.Errorf("SFTP connection check failed: %w", err)
+	}
+	if err := c.Delete(ctx, testKey); err != nil {
+		return fmt.Errorf("SFTP connection check cleanup failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// newFakeSFTPServer starts an in-memory SFTP server accepting the returned client key
+// It returns a config with the key file and known_hosts file written to a temp dir.
+func newFakeSFTPServer(t *testing.T) *config.SFTPConfig {
+	t.Helper()
+	dir := t.TempDir()
+
+	_, hostPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hostSigner, err := ssh.NewSignerFromKey(hostPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientPub, clientPriv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authorized, err := ssh.NewPublicKey(clientPub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(clientPriv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyPath := filepath.Join(dir, "id_ed25519")
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	serverCfg := &ssh.ServerConfig{
+		PublicKeyCallback: func(_ ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if string(key.Marshal()) == string(authorized.Marshal()) {
+				return nil, nil
+			}
+			return nil, os.ErrPermission
+		},
+	}
+	serverCfg.AddHostKey(hostSigner)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	handlers := sftp.InMemHandler()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSFTP(conn, serverCfg, handlers)
+		}
+	}()
+
+	addr := listener.Addr().(*net.TCPAddr)
+	knownHostsPath := filepath.Join(dir, "known_hosts")
+	line := knownhosts.Line([]string{knownhosts.Normalize(addr.String())}, hostSigner.PublicKey())
+	if err := os.WriteFile(knownHostsPath, []byte(line+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return &config.SFTPConfig{
+		Host:           "127.0.0.1",
+		Port:           addr.Port,
+		User:           "ora2csv",
+		PrivateKeyPath: keyPath,
+		RemotePath:     "/exports",
+		KnownHostsPath: knownHostsPath,
+	}
+}
+
+// serveSFTP runs the SSH handshake and serves the sftp subsystem on each session channel
+func serveSFTP(conn net.Conn, cfg *ssh.ServerConfig, handlers sftp.Handlers) {
+	_, chans, reqs, err := ssh.NewServerConn(conn, cfg)
+	if err != nil {
+		return
+	}
+	go ssh.DiscardRequests(reqs)
+	for newChannel := range chans {
+		channel, requests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for req := range requests {
+				_ = req.Reply(req.Type == "subsystem" && string(req.Payload[4:]) == "sftp", nil)
+			}
+		}()
+		server := sftp.NewRequestServer(channel, handlers)
+		go func() {
+			_ = server.Serve()
+			_ = server.Close()
+		}()
+	}
+}
+
+func TestSFTPClient_RoundTrip(t *testing.T) {
+	cfg := newFakeSFTPServer(t)
+	client, err := NewSFTPClient(cfg)
+	if err != nil {
+		t.Fatalf("NewSFTPClient() error = %v", err)
+	}
+	defer func() { _ = client.Close() }()
+	ctx := context.Background()
+
+	if err := client.CheckConnection(ctx); err != nil {
+		t.Fatalf("CheckConnection() error = %v", err)
+	}
+
+	key := client.Key("users/users_20250101.csv")
+	if key != "/exports/users/users_20250101.csv" {
+		t.Fatalf("Key() = %q", key)
+	}
+	if ok, err := client.Exists(ctx, key); err != nil || ok {
+		t.Fatalf("Exists() before upload = %v, %v", ok, err)
+	}
+
+	if err := client.UploadStream(ctx, key, strings.NewReader("id\n1\n")); err != nil {
+		t.Fatalf("UploadStream() error = %v", err)
+	}
+	// A second upload replaces the file
+	if _, err := client.UploadWithVersion(ctx, key, strings.NewReader("id\n2\n")); err != nil {
+		t.Fatalf("UploadWithVersion() error = %v", err)
+	}
+	if ok, err := client.Exists(ctx, key); err != nil || !ok {
+		t.Fatalf("Exists() after upload = %v, %v", ok, err)
+	}
+	if ok, _ := client.Exists(ctx, key+".tmp"); ok {
+		t.Error("temporary upload file was left behind")
+	}
+
+	data, err := client.DownloadBytes(ctx, key)
+	if err != nil {
+		t.Fatalf("DownloadBytes() error = %v", err)
+	}
+	if string(data) != "id\n2\n" {
+		t.Errorf("DownloadBytes() = %q", data)
+	}
+
+	if err := client.Delete(ctx, key); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if err := client.Delete(ctx, key); err != nil {
+		t.Errorf("Delete() of missing file error = %v", err)
+	}
+	if _, err := client.DownloadStream(ctx, key); err == nil || !strings.Contains(err.Error(), "key not found") {
+		t.Errorf("DownloadStream() of missing file error = %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	client, err := NewSFTPClient(newFakeSFTPServer(t))
+	if err != nil {
+		t.Fatalf("NewSFTPClient() error = %v", err)
+	}
+	if err := client.CheckConnection(context.Background()); err != nil {
+		t.Fatalf("CheckConnection() error = %v", err)
+	}
+
+	if err := Close(client); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	if client.client != nil || client.conn != nil {
+		t.Error("Close() left the SSH connection open")
+	}
+	// Stores without a connection and a missing store are no-ops
+	if err := Close(&AzureBlobClient{}); err != nil {
+		t.Errorf("Close() of Azure client error = %v", err)
+	}
+	if err := Close(nil); err != nil {
+		t.Errorf("Close(nil) error = %v", err)
+	}
+}
+
+func TestSFTPClient_RejectsUnknownHost(t *testing.T) {
+	cfg := newFakeSFTPServer(t)
+	cfg.KnownHostsPath = filepath.Join(t.TempDir(), "known_hosts")
+	if err := os.WriteFile(cfg.KnownHostsPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	client, err := NewSFTPClient(cfg)
+	if err != nil {
+		t.Fatalf("NewSFTPClient() error = %v", err)
+	}
+	if err := client.CheckConnection(context.Background()); err == nil {
+		t.Fatal("CheckConnection() with unknown host key should fail")
+	}
+}
+
+func TestNewSFTPClient_Errors(t *testing.T) {
+	if _, err := NewSFTPClient(&config.SFTPConfig{Host: "example.com", User: "u"}); err == nil {
+		t.Error("expected error without key or password")
+	}
+	if _, err := NewSFTPClient(&config.SFTPConfig{Host: "example.com", User: "u", PrivateKeyPath: filepath.Join(t.TempDir(), "missing")}); err == nil {
+		t.Error("expected error for missing key file")
+	}
+}
+
+func TestSFTPClient_GetVersionUnsupported(t *testing.T) {
+	client := &SFTPClient{}
+	if client.TrackVersions() {
+		t.Error("TrackVersions() = true")
+	}
+	if _, err := client.GetVersion(context.Background(), "state.json", "v1"); err == nil {
+		t.Error("GetVersion() should fail")
+	}
+	var _ io.Closer = client
+}
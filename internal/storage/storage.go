@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+)
+
+// BlobStore is a cloud object store used as an export destination
+// It is implemented by S3Client, AzureBlobClient, GCSClient and SFTPClient.
+type BlobStore interface {
+	// Name identifies the backend in logs, e.g. "S3"
+	Name() string
+	// Key returns the object key for a file name, including the configured prefix
+	Key(filename string) string
+	UploadStream(ctx context.Context, key string, r io.Reader) error
+	UploadStreamWithEncoding(ctx context.Context, key, contentEncoding string, r io.Reader) error
+	DownloadStream(ctx context.Context, key string) (io.ReadCloser, error)
+	Exists(ctx context.Context, key string) (bool, error)
+	Delete(ctx context.Context, key string) error
+	CheckConnection(ctx context.Context) error
+}
+
+// StateStore keeps the state file in remote storage, implemented by S3Client, GCSClient and SFTPClient
+type StateStore interface {
+	Key(filename string) string
+	Exists(ctx context.Context, key string) (bool, error)
+	DownloadBytes(ctx context.Context, key string) ([]byte, error)
+	// UploadWithVersion returns the object version (S3 VersionId, GCS generation)
+	UploadWithVersion(ctx context.Context, key string, r io.Reader) (string, error)
+	GetVersion(ctx context.Context, key, versionID string) (io.ReadCloser, error)
+	// TrackVersions reports whether uploaded versions are recorded for restore
+	TrackVersions() bool
+}
+
+var (
+	_ BlobStore  = (*S3Client)(nil)
+	_ BlobStore  = (*AzureBlobClient)(nil)
+	_ BlobStore  = (*GCSClient)(nil)
+	_ BlobStore  = (*SFTPClient)(nil)
+	_ StateStore = (*S3Client)(nil)
+	_ StateStore = (*GCSClient)(nil)
+	_ StateStore = (*SFTPClient)(nil)
+)
+
+// FromConfig creates the client for the configured destination (Azure, GCS, SFTP or S3)
+// Both results are nil without a destination. The state store is nil for Azure,
+// whose state file stays local.
+func FromConfig(cfg *config.Config) (BlobStore, StateStore, error) {
+	switch {
+	case cfg.AzureContainerName != "":
+		client, err := NewAzureBlobClient(cfg.AzureConnectionString, cfg.AzureContainerName, cfg.AzurePrefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize Azure client: %w", err)
+		}
+		return client, nil, nil
+	case cfg.GCSBucket != "":
+		client, err := NewGCSClient(cfg.GCSBucket, cfg.GCSPrefix)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize GCS client: %w", err)
+		}
+		return client, client, nil
+	case cfg.SFTP.Host != "":
+		client, err := NewSFTPClient(&cfg.SFTP)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize SFTP client: %w", err)
+		}
+		return client, client, nil
+	case cfg.S3.Bucket != "":
+		client, err := NewS3Client(&cfg.S3)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to initialize S3 client: %w", err)
+		}
+		return client, client, nil
+	}
+	return nil, nil, nil
+}
+
+// Close releases the connection held by a store, such as the SFTP session
+// Stores without a connection to release, and nil stores, are left alone.
+func Close(store interface{}) error {
+	if closer, ok := store.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// statusError is a non-success HTTP response from a REST storage API
+type statusError struct {
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *statusError) Error() string {
+	if e.Body == "" {
+		return e.Status
+	}
+	return e.Status + ": " + e.Body
+}
+
+// isNotFound reports whether err is a 404 response
+func isNotFound(err error) bool {
+	var statusErr *statusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
+}
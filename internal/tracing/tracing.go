@@ -0,0 +1,89 @@
+// Package tracing sends OpenTelemetry traces of export runs to an OTLP collector
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// OTLP transport protocols
+const (
+	ProtocolGRPC = "grpc"
+	ProtocolHTTP = "http"
+)
+
+// instrumentationName identifies the spans created by ora2csv
+const instrumentationName = "github.com/koltyakov/ora2csv"
+
+// Tracer returns the ora2csv tracer
+// Until Setup is called spans are no-ops, so instrumented code needs no checks.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Setup exports spans to the OTLP collector at endpoint, e.g. localhost:4317, using
+// protocol grpc or http. The collector connection is not encrypted unless the endpoint
+// is an https:// URL. The returned function flushes pending spans and must be called
+// before exiting.
+func Setup(ctx context.Context, endpoint, protocol, version string) (func(context.Context) error, error) {
+	exporter, err := newExporter(ctx, endpoint, protocol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP %s exporter: %w", protocol, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(
+		attribute.String("service.name", "ora2csv"),
+		attribute.String("service.version", version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// newExporter creates the OTLP span exporter for protocol
+func newExporter(ctx context.Context, endpoint, protocol string) (*otlptrace.Exporter, error) {
+	switch protocol {
+	case ProtocolGRPC, "":
+		return otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(endpointURL(endpoint)))
+	case ProtocolHTTP:
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpointURL(endpoint)+"/v1/traces"))
+	default:
+		return nil, fmt.Errorf("unknown protocol %q, want grpc or http", protocol)
+	}
+}
+
+// endpointURL turns a host:port endpoint into a plain http:// URL, URLs are kept as they are
+func endpointURL(endpoint string) string {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if strings.HasPrefix(endpoint, "http://") || strings.HasPrefix(endpoint, "https://") {
+		return endpoint
+	}
+	return "http://" + endpoint
+}
+
+// End records err, if any, as the span status and ends the span
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
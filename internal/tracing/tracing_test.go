@@ -0,0 +1,19 @@
+package tracing
+
+import (
+	"testing"
+
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+)
+
+func TestEndpointURL(t *testing.T) {
+	tests := map[string]string{
+		"localhost:4317":               "http://localhost:4317",
+		"collector:4318/":              "http://collector:4318",
+		"https://otel.example.com":     "https://otel.example.com",
+		"http://otel.example.com:4318": "http://otel.example.com:4318",
+	}
+	for endpoint, want := range tests {
+		testutil.AssertEqual(t, want, endpointURL(endpoint))
+	}
+}
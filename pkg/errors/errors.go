@@ -15,6 +15,7 @@ const (
 	ErrorTypeExport     ErrorType = "export"
 	ErrorTypeIO         ErrorType = "io"
 	ErrorTypeState      ErrorType = "state"
+	ErrorTypeMemory     ErrorType = "memory"
 )
 
 // AppError is a structured error with context
@@ -98,6 +99,16 @@ func NewStateError(op, message string, err error) *AppError {
 	}
 }
 
+// NewMemoryError creates a new memory limit error
+func NewMemoryError(op, message string, err error) *AppError {
+	return &AppError{
+		Type:    ErrorTypeMemory,
+		Message: message,
+		Err:     err,
+		Op:      op,
+	}
+}
+
 // IsType checks if an error is of a specific type
 func IsType(err error, errorType ErrorType) bool {
 	var appErr *AppError
@@ -0,0 +1,166 @@
+// Package ora2csv runs ora2csv exports from Go programs without the CLI
+package ora2csv
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/koltyakov/ora2csv/internal/config"
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/exporter"
+	"github.com/koltyakov/ora2csv/internal/logging"
+	"github.com/koltyakov/ora2csv/internal/state"
+	"github.com/koltyakov/ora2csv/internal/storage"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// Config is the export configuration, see ConfigFromEnvironment
+type Config = config.Config
+
+// Logger writes timestamped export logs, see NewLogger
+type Logger = logging.Logger
+
+// DB is a database connection that can be injected with WithDB
+type DB = db.DB
+
+// ConfigFromEnvironment loads configuration from ORA2CSV_* environment variables
+func ConfigFromEnvironment() (*Config, error) {
+	return config.FromEnvironment()
+}
+
+// NewLogger creates a logger writing to w; verbose enables debug messages
+func NewLogger(w io.Writer, verbose bool) *Logger {
+	return logging.NewWithWriter(w, verbose)
+}
+
+// Client runs exports for a configuration
+type Client struct {
+	cfg    *Config
+	logger *Logger
+	db     DB
+}
+
+// New creates a client that logs to stderr and connects to Oracle using cfg
+func New(cfg *Config) *Client {
+	logger := logging.NewWithWriter(os.Stderr, cfg.Verbose)
+	logger.SetFormat(cfg.LogFormat)
+	return &Client{
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// WithLogger returns a copy of the client that logs to l
+func (c *Client) WithLogger(l *Logger) *Client {
+	clone := *c
+	clone.logger = l
+	return &clone
+}
+
+// WithDB returns a copy of the client that exports from database instead of connecting
+// The caller owns database and is responsible for closing it
+func (c *Client) WithDB(database DB) *Client {
+	clone := *c
+	clone.db = database
+	return &clone
+}
+
+// Validate checks the configuration, state file and SQL files
+func (c *Client) Validate() error {
+	if err := c.cfg.Validate(); err != nil {
+		return fmt.Errorf("config validation failed: %w", err)
+	}
+
+	st, err := state.LoadFromConfig(c.cfg, nil)
+	if err != nil {
+		return fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer func() {
+		if closeErr := st.Close(); closeErr != nil {
+			c.logger.Error("Failed to release state file lock: %v", closeErr)
+		}
+	}()
+
+	return exporter.Validate(c.cfg, st, false)
+}
+
+// Export runs the export for all active entities like the export command
+// Entity failures are reported in the result rather than as an error; an error means
+// the export could not run. In dry run mode no files are exported; the result holds
+// COUNT(*) row estimates, or is empty when the database cannot be reached.
+func (c *Client) Export(ctx context.Context) (*types.ExportResult, error) {
+	if err := c.cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	store, remoteState, err := storage.FromConfig(c.cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := storage.Close(store); closeErr != nil {
+			c.logger.Error("Failed to close %s connection: %v", store.Name(), closeErr)
+		}
+	}()
+	if store != nil {
+		checkCtx, checkCancel := context.WithTimeout(ctx, 10*time.Second)
+		defer checkCancel()
+		if err := store.CheckConnection(checkCtx); err != nil {
+			return nil, fmt.Errorf("%s connectivity check failed: %w", store.Name(), err)
+		}
+	}
+
+	st, err := state.LoadFromConfig(c.cfg, remoteState)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load state file: %w", err)
+	}
+	defer func() {
+		if closeErr := st.Close(); closeErr != nil {
+			c.logger.Error("Failed to release state file lock: %v", closeErr)
+		}
+	}()
+
+	if c.cfg.DryRun {
+		if err := exporter.Validate(c.cfg, st, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := c.cfg.EnsureDirs(state.ActiveEntityNames(st)...); err != nil {
+		return nil, err
+	}
+
+	database := c.db
+	if database == nil {
+		connCfg := c.cfg
+		if c.cfg.DryRun {
+			// Estimates are best effort, a dry run does not wait for connection retries
+			dryCfg := *c.cfg
+			dryCfg.ConnectRetryCount = 0
+			connCfg = &dryCfg
+		}
+		database, err = db.Connect(ctx, connCfg, c.logger)
+		if err != nil {
+			// Row count estimates are skipped without a database
+			if c.cfg.DryRun {
+				c.logger.Info("Warning: skipping row count estimates, database unavailable: %v", err)
+				return &types.ExportResult{DryRun: true}, nil
+			}
+			return nil, err
+		}
+		defer func() {
+			if closeErr := database.Close(); closeErr != nil {
+				c.logger.Error("Failed to close database connection: %v", closeErr)
+			}
+		}()
+	}
+
+	exp := exporter.New(c.cfg, database, st, c.logger, store)
+	if c.cfg.DryRun {
+		return exp.Estimate(ctx)
+	}
+	return exp.Run(ctx)
+}
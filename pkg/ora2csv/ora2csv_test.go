@@ -0,0 +1,96 @@
+package ora2csv
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/koltyakov/ora2csv/internal/db"
+	"github.com/koltyakov/ora2csv/internal/sqlitetest"
+	testutil "github.com/koltyakov/ora2csv/pkg/test"
+	"github.com/koltyakov/ora2csv/pkg/types"
+)
+
+// newTestClient writes a state file and SQL files for entities and returns a client for them
+func newTestClient(t *testing.T, entities []types.EntityState) (*Client, *bytes.Buffer) {
+	t.Helper()
+	cfg := testutil.NewTestConfig(t)
+	testutil.AssertNoError(t, testutil.WriteStateFile(cfg.StateFile, entities))
+	testutil.AssertNoError(t, testutil.CreateTestSQLFiles(cfg.SQLDir, entities))
+
+	var logs bytes.Buffer
+	return New(cfg).WithLogger(NewLogger(&logs, false)), &logs
+}
+
+func TestClient_Export_MockDB(t *testing.T) {
+	entities := []types.EntityState{
+		{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		{Entity: "crm.archive", LastRunTime: "2025-01-01T00:00:00", Active: false},
+	}
+	client, logs := newTestClient(t, entities)
+
+	mock := db.NewMockDB()
+	queries := 0
+	mock.QueryFunc = func(ctx context.Context, query string, args map[string]interface{}) (*sql.Rows, error) {
+		queries++
+		return nil, errors.New("ORA-00942: table or view does not exist")
+	}
+
+	result, err := client.WithDB(mock).Export(context.Background())
+	testutil.AssertNoError(t, err)
+
+	testutil.AssertEqual(t, 1, queries)
+	testutil.AssertEqual(t, 2, result.TotalEntities)
+	testutil.AssertEqual(t, 0, result.SuccessCount)
+	testutil.AssertEqual(t, 1, result.FailedCount)
+	testutil.AssertEqual(t, 1, result.SkippedCount)
+	if mock.Closed {
+		t.Error("injected database closed by the client")
+	}
+	if logs.Len() == 0 {
+		t.Error("expected logs written to the configured logger")
+	}
+}
+
+func TestClient_Export_SQLite(t *testing.T) {
+	schema := `CREATE TABLE products (id INTEGER PRIMARY KEY, name TEXT, updated TEXT NOT NULL);
+INSERT INTO products VALUES (1, 'Widget', '2025-01-02T10:00:00');`
+
+	sqlitetest.Run(t, schema, func(database db.DB) {
+		entities := []types.EntityState{
+			{Entity: "crm.products", LastRunTime: "2025-01-01T00:00:00", Active: true},
+		}
+		client, _ := newTestClient(t, entities)
+		sqlPath := filepath.Join(client.cfg.SQLDir, "crm.products.sql")
+		testutil.AssertNoError(t, os.WriteFile(sqlPath,
+			[]byte("SELECT id, name FROM products WHERE updated >= ? AND updated < ?"), 0644))
+
+		result, err := client.WithDB(database).Export(context.Background())
+		testutil.AssertNoError(t, err)
+		testutil.AssertEqual(t, 1, result.SuccessCount)
+		testutil.AssertEqual(t, 1, result.Results[0].RowCount)
+	})
+}
+
+func TestClient_Export_DryRun(t *testing.T) {
+	client, _ := newTestClient(t, testutil.NewTestState())
+	client.cfg.DryRun = true
+
+	result, err := client.Export(context.Background())
+	testutil.AssertNoError(t, err)
+	testutil.AssertEqual(t, 0, result.ProcessedCount)
+}
+
+func TestClient_Validate(t *testing.T) {
+	client, _ := newTestClient(t, testutil.NewTestState())
+	testutil.AssertNoError(t, client.Validate())
+
+	testutil.AssertNoError(t, os.RemoveAll(client.cfg.SQLDir))
+	if err := client.Validate(); err == nil {
+		t.Error("expected error for missing SQL files")
+	}
+}
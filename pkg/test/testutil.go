@@ -80,6 +80,10 @@ func WriteStateFile(path string, entities []types.EntityState) error {
 		return err
 	}
 
+	if _, err := f.WriteString("[\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
 	for i, e := range entities {
 		if i > 0 {
 			if _, err := f.WriteString(",\n"); err != nil {
@@ -104,6 +108,10 @@ func WriteStateFile(path string, entities []types.EntityState) error {
 			return err
 		}
 	}
+	if _, err := f.WriteString("\n]\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
 	if err := f.Close(); err != nil {
 		return err
 	}
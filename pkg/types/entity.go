@@ -1,12 +1,70 @@
 package types
 
-import "time"
+import (
+	"strconv"
+	"time"
+)
+
+// Incremental types of an entity
+const (
+	IncrementalTimestamp = "timestamp" // :startDate/:tillDate window from lastRunTime (default)
+	IncrementalSequence  = "sequence"  // :startValue/:tillValue window from lastRunValue
+)
 
 // EntityState represents the state of a single entity from state.json
 type EntityState struct {
-	Entity      string `json:"entity"`
-	LastRunTime string `json:"lastRunTime"` // ISO 8601 format
-	Active      bool   `json:"active"`
+	Entity      string   `json:"entity"`
+	LastRunTime string   `json:"lastRunTime"` // ISO 8601 format
+	Active      bool     `json:"active"`
+	DisplayName string   `json:"displayName,omitempty"` // Optional log prefix
+	Tags        []string `json:"tags,omitempty"`        // Optional labels for grouping entities
+
+	// DependsOn lists entities exported before this one, it is skipped when one of them fails
+	DependsOn []string `json:"dependsOn,omitempty"`
+
+	// Schedule limits exports to a UTC window, e.g. "Mon-Fri 06:00-08:00"; runs outside it skip the entity
+	Schedule string `json:"schedule,omitempty"`
+
+	// DaysBack overrides the configured days_back of the first export, when lastRunTime is empty (0 uses the default)
+	DaysBack int `json:"daysBack,omitempty"`
+
+	// MaxRows overrides the configured max_result_set_rows limit (0 uses the default)
+	MaxRows int64 `json:"maxRows,omitempty"`
+
+	// MaxFileSizeMB overrides the configured output file size limit (0 uses the default)
+	MaxFileSizeMB int `json:"maxFileSizeMB,omitempty"`
+
+	// S3StorageClass overrides the configured S3 storage class of this entity's export files
+	S3StorageClass string `json:"s3StorageClass,omitempty"`
+
+	// SQLParams are the template data of the SQL file, e.g. {"Table": "ORDERS_2024"} for {{.Table}}
+	SQLParams map[string]string `json:"sqlParams,omitempty"`
+
+	// MaskRules replaces the values of the named columns with a fixed string, e.g. {"EMAIL": "***"}
+	MaskRules map[string]string `json:"mask,omitempty"`
+
+	// ColumnMap renames columns in the header row, e.g. {"CREATED_DT": "created_at"}, overriding the column map file
+	ColumnMap map[string]string `json:"columnMap,omitempty"`
+
+	// IncrementalType "sequence" exports rows by the monotonically increasing IncrementalColumn,
+	// LastRunValue holds the highest value exported so far
+	IncrementalColumn string `json:"incrementalColumn,omitempty"`
+	IncrementalType   string `json:"incrementalType,omitempty"`
+	LastRunValue      string `json:"lastRunValue,omitempty"`
+}
+
+// IsSequence reports whether the entity is exported by sequence value instead of timestamp
+func (e *EntityState) IsSequence() bool {
+	return e.IncrementalType == IncrementalSequence
+}
+
+// GetLastRunValue parses the LastRunValue sequence watermark
+// Returns 0 if LastRunValue is empty
+func (e *EntityState) GetLastRunValue() (int64, error) {
+	if e.LastRunValue == "" {
+		return 0, nil
+	}
+	return strconv.ParseInt(e.LastRunValue, 10, 64)
 }
 
 // GetLastRunTime parses the LastRunTime string into a time.Time (UTC)
@@ -31,6 +89,29 @@ type EntityResult struct {
 	FilePath string
 	Error    error
 	Duration time.Duration
+
+	// ObjectKey is the cloud storage key when the file was uploaded instead of kept locally
+	ObjectKey string
+	// FileSize and Checksum (hex SHA-256) describe the written file, empty without one
+	FileSize int64
+	Checksum string
+
+	// Parts lists the files of an export split with max_rows_per_file, FilePath and ObjectKey
+	// are then those of the first part. FileSize is their total, Checksum is empty for several parts.
+	Parts []string
+
+	// TillValue is the sequence watermark reached by a sequence entity, empty otherwise
+	TillValue string
+
+	// StartDate and TillDate are the :startDate and :tillDate of the exported file's window
+	StartDate string
+	TillDate  string
+
+	// RowsScanned and BytesScanned are what the export query read in QueryDuration (see db.QueryMetrics),
+	// rows skipped by dedup included
+	RowsScanned   int64
+	BytesScanned  int64
+	QueryDuration time.Duration
 }
 
 // ExportResult represents the overall result of an export run
@@ -42,4 +123,10 @@ type ExportResult struct {
 	SkippedCount   int
 	Results        []EntityResult
 	Duration       time.Duration
+
+	// RunTime is when the run started (UTC), Version the tool version set by the caller
+	RunTime time.Time
+	Version string
+	// DryRun marks row counts as COUNT(*) estimates, no files were written
+	DryRun bool
 }
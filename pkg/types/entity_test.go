@@ -1,6 +1,7 @@
 package types
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -66,6 +67,29 @@ func TestEntityState_SetLastRunTime(t *testing.T) {
 	}
 }
 
+func TestEntityState_GetLastRunValue(t *testing.T) {
+	e := EntityState{IncrementalType: IncrementalSequence}
+	if !e.IsSequence() {
+		t.Error("IsSequence() = false, want true")
+	}
+
+	v, err := e.GetLastRunValue()
+	if err != nil || v != 0 {
+		t.Errorf("GetLastRunValue() = %d, %v, want 0", v, err)
+	}
+
+	e.LastRunValue = "42"
+	v, err = e.GetLastRunValue()
+	if err != nil || v != 42 {
+		t.Errorf("GetLastRunValue() = %d, %v, want 42", v, err)
+	}
+
+	e.LastRunValue = "abc"
+	if _, err := e.GetLastRunValue(); err == nil {
+		t.Error("expected error for non-integer lastRunValue")
+	}
+}
+
 func TestEntityState_RoundTrip(t *testing.T) {
 	e := EntityState{
 		Entity:      "test.entity",
@@ -159,3 +183,24 @@ type testErr string
 func (e testErr) Error() string {
 	return string(e)
 }
+
+func TestEntityState_DisplayNameJSON(t *testing.T) {
+	var e EntityState
+	data := `{"entity":"schema.vw_order_detail_audit","lastRunTime":"","active":true,"displayName":"Order audit"}`
+	if err := json.Unmarshal([]byte(data), &e); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if e.DisplayName != "Order audit" {
+		t.Errorf("DisplayName = %q, want %q", e.DisplayName, "Order audit")
+	}
+
+	// Omitted when empty so existing state files are unchanged on save
+	out, err := json.Marshal(EntityState{Entity: "crm.orders", Active: true})
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"entity":"crm.orders","lastRunTime":"","active":true}`
+	if string(out) != want {
+		t.Errorf("Marshal() = %s, want %s", out, want)
+	}
+}
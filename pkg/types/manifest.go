@@ -0,0 +1,126 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ManifestEntity is the manifest record of a single entity
+type ManifestEntity struct {
+	Entity     string   `json:"entity"`
+	Success    bool     `json:"success"`
+	RowCount   int      `json:"row_count"`
+	FilePath   string   `json:"file_path,omitempty"`
+	ObjectKey  string   `json:"object_key,omitempty"`
+	FileSize   int64    `json:"file_size,omitempty"`
+	SHA256     string   `json:"sha256,omitempty"`
+	Parts      []string `json:"parts,omitempty"`
+	StartDate  string   `json:"start_date,omitempty"`
+	TillDate   string   `json:"till_date,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+}
+
+// ExportManifest lists the files produced by an export run for downstream pipelines
+type ExportManifest struct {
+	RunTimestamp string           `json:"run_timestamp"` // UTC, ISO 8601
+	Version      string           `json:"version"`
+	DryRun       bool             `json:"dry_run,omitempty"`
+	DurationMs   int64            `json:"duration_ms"`
+	Entities     []ManifestEntity `json:"entities"`
+}
+
+// NewExportManifest builds a manifest from an export result
+// Uploaded files are listed by object key only, their local temp path no longer exists.
+func NewExportManifest(result *ExportResult) ExportManifest {
+	manifest := ExportManifest{
+		RunTimestamp: result.RunTime.UTC().Format(time.RFC3339),
+		Version:      result.Version,
+		DryRun:       result.DryRun,
+		DurationMs:   result.Duration.Milliseconds(),
+		Entities:     make([]ManifestEntity, 0, len(result.Results)),
+	}
+
+	for _, r := range result.Results {
+		entity := ManifestEntity{
+			Entity:     r.Entity,
+			Success:    r.Success,
+			RowCount:   r.RowCount,
+			FilePath:   r.FilePath,
+			ObjectKey:  r.ObjectKey,
+			FileSize:   r.FileSize,
+			SHA256:     r.Checksum,
+			Parts:      r.Parts,
+			StartDate:  r.StartDate,
+			TillDate:   r.TillDate,
+			DurationMs: r.Duration.Milliseconds(),
+		}
+		if r.ObjectKey != "" {
+			entity.FilePath = ""
+		}
+		if r.Error != nil {
+			entity.Error = r.Error.Error()
+		}
+		manifest.Entities = append(manifest.Entities, entity)
+	}
+
+	return manifest
+}
+
+// WriteManifest writes the manifest of result to path as indented JSON
+// The file is replaced atomically so readers never see a partial manifest.
+func WriteManifest(path string, result *ExportResult) error {
+	return writeJSONFile(path, NewExportManifest(result), "manifest")
+}
+
+// LoadManifest reads a manifest written by WriteManifest
+// A missing file returns an error matching os.ErrNotExist.
+func LoadManifest(path string) (*ExportManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+
+	var manifest ExportManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest %s: %w", path, err)
+	}
+	return &manifest, nil
+}
+
+// writeJSONFile writes v to path as indented JSON, replacing the file atomically
+// what names the document in errors.
+func writeJSONFile(path string, v interface{}, what string) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", what, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", what, err)
+	}
+	tmpPath := tmp.Name()
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+	// CreateTemp uses 0600, these files are read by other processes
+	if err := os.Chmod(tmpPath, 0644); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return fmt.Errorf("failed to write %s: %w", what, err)
+	}
+	return nil
+}
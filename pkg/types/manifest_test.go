@@ -0,0 +1,101 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewExportManifest(t *testing.T) {
+	result := &ExportResult{
+		RunTime:  time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+		Version:  "1.2.3",
+		Duration: 3 * time.Second,
+		Results: []EntityResult{
+			{Entity: "crm.orders", Success: true, RowCount: 10, FilePath: "/export/orders.csv", FileSize: 120, Checksum: "abc", Duration: time.Second},
+			{Entity: "crm.products", Success: true, RowCount: 5, FilePath: "/tmp/products.csv", ObjectKey: "exports/crm.products/products.csv"},
+			{Entity: "crm.users", Success: false, Error: testErr("query failed")},
+		},
+	}
+
+	manifest := NewExportManifest(result)
+
+	if manifest.RunTimestamp != "2025-01-15T12:00:00Z" || manifest.Version != "1.2.3" || manifest.DurationMs != 3000 {
+		t.Errorf("unexpected manifest header: %+v", manifest)
+	}
+	if len(manifest.Entities) != 3 {
+		t.Fatalf("got %d entities, want 3", len(manifest.Entities))
+	}
+	if e := manifest.Entities[0]; e.FilePath != "/export/orders.csv" || e.FileSize != 120 || e.SHA256 != "abc" || e.DurationMs != 1000 {
+		t.Errorf("unexpected local entity: %+v", e)
+	}
+	if e := manifest.Entities[1]; e.FilePath != "" || e.ObjectKey != "exports/crm.products/products.csv" {
+		t.Errorf("uploaded entity should list the object key only: %+v", e)
+	}
+	if e := manifest.Entities[2]; e.Success || e.Error != "query failed" {
+		t.Errorf("unexpected failed entity: %+v", e)
+	}
+}
+
+func TestWriteManifest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "manifest.json")
+	result := &ExportResult{
+		RunTime: time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+		DryRun:  true,
+		Results: []EntityResult{{Entity: "crm.orders", Success: true, RowCount: 7}},
+	}
+
+	if err := WriteManifest(path, result); err != nil {
+		t.Fatalf("WriteManifest() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var manifest ExportManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if !manifest.DryRun || len(manifest.Entities) != 1 || manifest.Entities[0].RowCount != 7 {
+		t.Errorf("unexpected manifest: %s", data)
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
+
+func TestLoadManifest(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "manifest.json")
+	result := &ExportResult{
+		RunTime: time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+		Results: []EntityResult{{Entity: "crm.orders", Success: true, RowCount: 7}},
+	}
+	if err := WriteManifest(path, result); err != nil {
+		t.Fatalf("WriteManifest() error = %v", err)
+	}
+
+	manifest, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("LoadManifest() error = %v", err)
+	}
+	if manifest.RunTimestamp != "2025-01-15T12:00:00Z" || len(manifest.Entities) != 1 || manifest.Entities[0].RowCount != 7 {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	if _, err := LoadManifest(filepath.Join(dir, "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("LoadManifest() of missing file error = %v, want os.ErrNotExist", err)
+	}
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadManifest(path); err == nil {
+		t.Error("LoadManifest() of invalid JSON should fail")
+	}
+}
@@ -0,0 +1,105 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// ResultEntity is the JSON record of a single entity in the result file
+type ResultEntity struct {
+	Entity     string   `json:"entity"`
+	Success    bool     `json:"success"`
+	RowCount   int      `json:"row_count"`
+	FilePath   string   `json:"file_path,omitempty"`
+	ObjectKey  string   `json:"object_key,omitempty"`
+	FileSize   int64    `json:"file_size,omitempty"`
+	SHA256     string   `json:"sha256,omitempty"`
+	Parts      []string `json:"parts,omitempty"`
+	TillValue  string   `json:"till_value,omitempty"`
+	StartDate  string   `json:"start_date,omitempty"`
+	TillDate   string   `json:"till_date,omitempty"`
+	DurationMs int64    `json:"duration_ms"`
+	Error      string   `json:"error,omitempty"`
+
+	// Query throughput, see EntityResult.RowsScanned
+	RowsScanned     int64 `json:"rows_scanned,omitempty"`
+	BytesScanned    int64 `json:"bytes_scanned,omitempty"`
+	QueryDurationMs int64 `json:"query_duration_ms,omitempty"`
+}
+
+// ResultFile is the complete export result written by --result-file for CI pipelines
+type ResultFile struct {
+	Version        string         `json:"version"`
+	RunTimestamp   string         `json:"run_timestamp"` // UTC, ISO 8601
+	Success        bool           `json:"success"`
+	DryRun         bool           `json:"dry_run,omitempty"`
+	DurationMs     int64          `json:"duration_ms"`
+	TotalEntities  int            `json:"total_entities"`
+	ProcessedCount int            `json:"processed_count"`
+	SuccessCount   int            `json:"success_count"`
+	FailedCount    int            `json:"failed_count"`
+	SkippedCount   int            `json:"skipped_count"`
+	Entities       []ResultEntity `json:"entities"`
+}
+
+// NewResultFile builds the result file document of an export result
+func NewResultFile(result *ExportResult) ResultFile {
+	doc := ResultFile{
+		Version:        result.Version,
+		RunTimestamp:   result.RunTime.UTC().Format(time.RFC3339),
+		Success:        result.FailedCount == 0,
+		DryRun:         result.DryRun,
+		DurationMs:     result.Duration.Milliseconds(),
+		TotalEntities:  result.TotalEntities,
+		ProcessedCount: result.ProcessedCount,
+		SuccessCount:   result.SuccessCount,
+		FailedCount:    result.FailedCount,
+		SkippedCount:   result.SkippedCount,
+		Entities:       make([]ResultEntity, 0, len(result.Results)),
+	}
+
+	for _, r := range result.Results {
+		entity := ResultEntity{
+			Entity:     r.Entity,
+			Success:    r.Success,
+			RowCount:   r.RowCount,
+			FilePath:   r.FilePath,
+			ObjectKey:  r.ObjectKey,
+			FileSize:   r.FileSize,
+			SHA256:     r.Checksum,
+			Parts:      r.Parts,
+			TillValue:  r.TillValue,
+			StartDate:  r.StartDate,
+			TillDate:   r.TillDate,
+			DurationMs: r.Duration.Milliseconds(),
+
+			RowsScanned:     r.RowsScanned,
+			BytesScanned:    r.BytesScanned,
+			QueryDurationMs: r.QueryDuration.Milliseconds(),
+		}
+		if r.Error != nil {
+			entity.Error = r.Error.Error()
+		}
+		doc.Entities = append(doc.Entities, entity)
+	}
+
+	return doc
+}
+
+// WriteJSON writes the result to path as indented JSON, "-" writes it to stdout
+// Files are replaced atomically like the manifest.
+func (r *ExportResult) WriteJSON(path string) error {
+	if path != "-" {
+		return writeJSONFile(path, NewResultFile(r), "result file")
+	}
+	data, err := json.MarshalIndent(NewResultFile(r), "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode result file: %w", err)
+	}
+	if _, err := os.Stdout.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write result file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+package types
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestExportResult_WriteJSON(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "result.json")
+	result := &ExportResult{
+		TotalEntities:  3,
+		ProcessedCount: 2,
+		SuccessCount:   1,
+		FailedCount:    1,
+		SkippedCount:   1,
+		Duration:       2500 * time.Millisecond,
+		RunTime:        time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC),
+		Version:        "1.2.3",
+		Results: []EntityResult{
+			{Entity: "crm.orders", Success: true, RowCount: 10, FilePath: "/export/orders.csv", Duration: 1500 * time.Millisecond,
+				RowsScanned: 12, BytesScanned: 480, QueryDuration: 1200 * time.Millisecond},
+			{Entity: "crm.products", Success: false, Error: testErr("query failed"), Duration: 20 * time.Millisecond},
+		},
+	}
+
+	if err := result.WriteJSON(path); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var doc ResultFile
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+	if doc.Version != "1.2.3" || doc.RunTimestamp != "2025-01-15T12:00:00Z" {
+		t.Errorf("version = %q, run_timestamp = %q", doc.Version, doc.RunTimestamp)
+	}
+	if doc.Success || doc.FailedCount != 1 || doc.SkippedCount != 1 || doc.DurationMs != 2500 {
+		t.Errorf("unexpected totals: %s", data)
+	}
+	if len(doc.Entities) != 2 {
+		t.Fatalf("got %d entities, want 2", len(doc.Entities))
+	}
+	if !doc.Entities[0].Success || doc.Entities[0].RowCount != 10 || doc.Entities[0].DurationMs != 1500 {
+		t.Errorf("unexpected entity: %+v", doc.Entities[0])
+	}
+	if doc.Entities[0].RowsScanned != 12 || doc.Entities[0].BytesScanned != 480 || doc.Entities[0].QueryDurationMs != 1200 {
+		t.Errorf("unexpected query metrics: %+v", doc.Entities[0])
+	}
+	if doc.Entities[1].Success || doc.Entities[1].Error != "query failed" {
+		t.Errorf("unexpected failed entity: %+v", doc.Entities[1])
+	}
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("temp files left behind: %v", entries)
+	}
+}
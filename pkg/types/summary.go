@@ -0,0 +1,57 @@
+package types
+
+import "fmt"
+
+// EntitySummary is the machine-readable result of a single entity
+type EntitySummary struct {
+	Entity     string `json:"entity"`
+	Success    bool   `json:"success"`
+	RowCount   int    `json:"row_count"`
+	FilePath   string `json:"file_path,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+	Error      string `json:"error,omitempty"`
+}
+
+// MachineReadableSummary is the JSON summary printed by --output-format json
+type MachineReadableSummary struct {
+	Success    bool            `json:"success"`
+	DurationMs int64           `json:"duration_ms"`
+	Entities   []EntitySummary `json:"entities"`
+	Error      string          `json:"error"`
+}
+
+// NewMachineReadableSummary builds a summary from an export result and the run error
+// result may be nil when the run failed before exporting
+func NewMachineReadableSummary(result *ExportResult, runErr error, durationMs int64) MachineReadableSummary {
+	summary := MachineReadableSummary{
+		Success:    runErr == nil,
+		DurationMs: durationMs,
+		Entities:   []EntitySummary{},
+	}
+	if runErr != nil {
+		summary.Error = runErr.Error()
+	}
+	if result == nil {
+		return summary
+	}
+
+	for _, r := range result.Results {
+		entity := EntitySummary{
+			Entity:     r.Entity,
+			Success:    r.Success,
+			RowCount:   r.RowCount,
+			FilePath:   r.FilePath,
+			DurationMs: r.Duration.Milliseconds(),
+		}
+		if r.Error != nil {
+			entity.Error = r.Error.Error()
+		}
+		summary.Entities = append(summary.Entities, entity)
+	}
+	if result.FailedCount > 0 && runErr == nil {
+		summary.Success = false
+		summary.Error = fmt.Sprintf("%d entities failed", result.FailedCount)
+	}
+
+	return summary
+}
@@ -0,0 +1,49 @@
+package types
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestNewMachineReadableSummary(t *testing.T) {
+	result := &ExportResult{
+		SuccessCount: 1,
+		FailedCount:  1,
+		Results: []EntityResult{
+			{Entity: "crm.orders", Success: true, RowCount: 10, FilePath: "/export/orders.csv", Duration: 1500 * time.Millisecond},
+			{Entity: "crm.products", Success: false, Error: testErr("query failed"), Duration: 20 * time.Millisecond},
+		},
+	}
+
+	summary := NewMachineReadableSummary(result, nil, 2000)
+
+	if summary.Success {
+		t.Error("Success = true, want false with failed entities")
+	}
+	if summary.Error != "1 entities failed" {
+		t.Errorf("Error = %q, want %q", summary.Error, "1 entities failed")
+	}
+	if len(summary.Entities) != 2 {
+		t.Fatalf("got %d entities, want 2", len(summary.Entities))
+	}
+	if summary.Entities[0].DurationMs != 1500 || summary.Entities[0].RowCount != 10 {
+		t.Errorf("unexpected entity summary: %+v", summary.Entities[0])
+	}
+	if summary.Entities[1].Error != "query failed" {
+		t.Errorf("entity error = %q, want %q", summary.Entities[1].Error, "query failed")
+	}
+}
+
+func TestNewMachineReadableSummary_RunError(t *testing.T) {
+	summary := NewMachineReadableSummary(nil, testErr("connection refused"), 5)
+
+	data, err := json.Marshal(summary)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	want := `{"success":false,"duration_ms":5,"entities":[],"error":"connection refused"}`
+	if string(data) != want {
+		t.Errorf("got %s, want %s", data, want)
+	}
+}